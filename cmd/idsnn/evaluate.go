@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/datasets"
+	"github.com/saent-x/ids-nn/core/metrics"
+	"github.com/saent-x/ids-nn/core/model"
+	"gonum.org/v1/gonum/mat"
+)
+
+func runEvaluate(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("evaluate", stderr)
+	model_path := flags.String("model", "", "saved model (.json)")
+	data_path := flags.String("data", "", "labelled data to evaluate on")
+	schema_path := flags.String("schema", "", "CAN csv schema (.json, .yaml or .yml), the core/datasets layout by default")
+	labels_path := flags.String("labels", "", "label map of the class folders (.json, .yaml or .yml), overrides the classes of the schema")
+	heatmap_path := flags.String("heatmap", "confusion_matrix.png", "where to save the confusion matrix heatmap (empty to skip)")
+	batch_size := flags.Int("batch-size", 128, "evaluation batch size (0 for a single batch)")
+	report_path := flags.String("report", "", "where to save the evaluation report as JSON (optional)")
+	roc_path := flags.String("roc", "", "where to plot the ROC curves (optional)")
+	pr_path := flags.String("pr", "", "where to plot the precision-recall curves (optional)")
+	reliability_path := flags.String("reliability", "", "where to plot the reliability diagram of the confidences (optional)")
+	operational := flags.Bool("operational", false, "also report the false alarms per attack-free hour, the detection latency & the detection rate per attack episode")
+	debounce_k := flags.Int("debounce-k", 1, "with --operational, an alert needs at least k attack predictions among the last --debounce-n frames")
+	debounce_n := flags.Int("debounce-n", 1, "with --operational, the number of frames --debounce-k is counted over")
+	cooldown := flags.Float64("cooldown", 0, "with --operational, the seconds after an alert during which no new alert is raised")
+	episode_gap := flags.Float64("episode-gap", 1, "with --operational, the seconds between the attack frames of a class that separate two episodes")
+	multi_label := flags.Int("multi-label", 0, "number of classes of multi-hot targets, to evaluate a multi-label model (0 for a label per frame)")
+
+	if err := parseFlags(flags, args, "model", "data"); err != nil {
+		return err
+	}
+
+	schema, err := loadSchema(*schema_path, *labels_path)
+	if err != nil {
+		return err
+	}
+
+	m, err := loadModel(*model_path)
+	if err != nil {
+		return err
+	}
+	if len(m.ClassNames) == 0 && schema.Classes != nil {
+		m.SetClassNames(schema.Classes.Names())
+	}
+
+	if *operational && *multi_label > 0 {
+		return errors.New("--operational needs a label per frame, it can't be combined with --multi-label")
+	}
+
+	var data datamodels.TrainingData
+	var timed datasets.TimedFrames
+	if *operational {
+		if timed, err = datasets.ReadTimedFrames(*data_path, schema); err == nil {
+			data = timed.Data()
+		}
+	} else {
+		data, err = loadData(*data_path, schema, *multi_label, false)
+	}
+	if err != nil {
+		return fmt.Errorf("loading data: %v", err)
+	}
+
+	if err = m.Evaluate(datamodels.ValidationData{X: data.X, Y: data.Y}, *batch_size); err != nil {
+		return err
+	}
+
+	if *multi_label > 0 {
+		scores, err := m.EvaluateMultiLabel(datamodels.ValidationData{X: data.X, Y: data.Y}, *batch_size)
+		if err != nil {
+			return err
+		}
+		printMultiLabelScores(stdout, scores, m)
+
+		return nil
+	}
+
+	report, err := m.Report(datamodels.ValidationData{X: data.X, Y: data.Y}, *batch_size)
+	if err != nil {
+		return err
+	}
+
+	class_names := make([]string, len(report.Classes))
+	for i, class := range report.Classes {
+		class_names[i] = class.Name
+	}
+	confusion_matrix := report.ConfusionMatrix
+
+	printConfusionMatrix(stdout, confusion_matrix, class_names)
+	fmt.Fprintln(stdout)
+	printReport(stdout, report)
+
+	if *operational {
+		options := metrics.OperationalOptions{
+			Debouncer:  metrics.Debouncer{K: *debounce_k, N: *debounce_n, Cooldown: *cooldown},
+			EpisodeGap: *episode_gap,
+		}
+		outputs, err := m.Predict(data.X, *batch_size)
+		if err != nil {
+			return err
+		}
+		predictions := metrics.TimedPredictions{
+			Timestamps:  timed.Timestamps,
+			Labels:      timed.Y,
+			Predictions: flatten(m.OutputLayerActivation.Predictions(outputs)),
+			Captures:    timed.Captures,
+		}
+		if report.Operational, err = metrics.NewOperationalReport(predictions, options); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout)
+		printOperationalReport(stdout, report.Operational, m)
+	}
+
+	if *report_path != "" {
+		if err = report.SaveFile(*report_path); err != nil {
+			return fmt.Errorf("saving the report: %v", err)
+		}
+		fmt.Fprintf(stdout, "report saved to %s\n", *report_path)
+	}
+	for _, plot := range []struct {
+		path   string
+		name   string
+		save   func([]metrics.Curve, string) error
+		curves []metrics.Curve
+	}{
+		{*roc_path, "ROC curves", metrics.PlotROC, report.ROC},
+		{*pr_path, "precision-recall curves", metrics.PlotPR, report.PR},
+	} {
+		if plot.path == "" {
+			continue
+		}
+		if err = plot.save(plot.curves, plot.path); err != nil {
+			return fmt.Errorf("plotting the %s: %v", plot.name, err)
+		}
+		fmt.Fprintf(stdout, "%s saved to %s\n", plot.name, plot.path)
+	}
+	if *reliability_path != "" {
+		if err = metrics.PlotReliability(report.Reliability, *reliability_path); err != nil {
+			return fmt.Errorf("plotting the reliability diagram: %v", err)
+		}
+		fmt.Fprintf(stdout, "reliability diagram saved to %s\n", *reliability_path)
+	}
+
+	if *heatmap_path != "" {
+		if err = metrics.PlotNamedConfusionMatrix(confusion_matrix, class_names, *heatmap_path); err != nil {
+			return fmt.Errorf("plotting the confusion matrix: %v", err)
+		}
+		fmt.Fprintf(stdout, "confusion matrix heatmap saved to %s\n", *heatmap_path)
+	}
+
+	return nil
+}
+
+// flatten returns the values of a 1 x N or N x 1 matrix
+func flatten(m *mat.Dense) []float64 {
+	rows, cols := m.Dims()
+
+	values := make([]float64, 0, rows*cols)
+	for i := 0; i < rows; i++ {
+		values = append(values, m.RawRowView(i)...)
+	}
+
+	return values
+}
+
+func printConfusionMatrix(w io.Writer, confusion_matrix [][]float64, class_names []string) {
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintf(writer, "true \\ predicted\t")
+	for j := range confusion_matrix {
+		fmt.Fprintf(writer, "%s\t", class_names[j])
+	}
+	fmt.Fprintln(writer)
+
+	for i, row := range confusion_matrix {
+		fmt.Fprintf(writer, "%s\t", class_names[i])
+		for _, v := range row {
+			fmt.Fprintf(writer, "%.0f\t", v)
+		}
+		fmt.Fprintln(writer)
+	}
+
+	writer.Flush()
+}
+
+func printMultiLabelScores(w io.Writer, scores metrics.MultiLabelScores, m *model.Model) {
+	thresholds := m.Thresholds()
+
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(writer, "label\tthreshold\tprecision\trecall\tf1-score\t\n")
+	for j := range scores.F1 {
+		threshold := 0.5
+		if j < len(thresholds) {
+			threshold = thresholds[j]
+		}
+		fmt.Fprintf(writer, "%s\t%.4f\t%.4f\t%.4f\t%.4f\t\n", m.ClassName(j), threshold, scores.Precision[j], scores.Recall[j], scores.F1[j])
+	}
+	fmt.Fprintf(writer, "micro avg\t\t%.4f\t%.4f\t%.4f\t\n", scores.MicroPrecision, scores.MicroRecall, scores.MicroF1)
+	fmt.Fprintf(writer, "macro avg\t\t%.4f\t%.4f\t%.4f\t\n", scores.MacroPrecision, scores.MacroRecall, scores.MacroF1)
+	writer.Flush()
+
+	fmt.Fprintf(w, "\nhamming loss: %.4f\nsubset accuracy: %.4f\n", scores.HammingLoss, scores.SubsetAccuracy)
+}
+
+func printReport(w io.Writer, report *metrics.EvaluationReport) {
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(writer, "class\tprecision\trecall\tf1-score\tsupport\troc-auc\tpr-auc\t\n")
+	for _, class := range report.Classes {
+		fmt.Fprintf(writer, "%s\t%.4f\t%.4f\t%.4f\t%d\t%.4f\t%.4f\t\n", class.Name, class.Precision, class.Recall, class.F1, class.Support, class.ROCAUC, class.PRAUC)
+	}
+	for _, average := range []struct {
+		name string
+		metrics.Averages
+	}{
+		{"macro avg", report.Macro},
+		{"micro avg", report.Micro},
+		{"weighted avg", report.Weighted},
+	} {
+		fmt.Fprintf(writer, "%s\t%.4f\t%.4f\t%.4f\t%d\t\t\t\n", average.name, average.Precision, average.Recall, average.F1, report.Samples)
+	}
+	writer.Flush()
+
+	fmt.Fprintf(w, "\naccuracy: %.4f (%d frames)\n", report.Accuracy, report.Samples)
+	fmt.Fprintf(w, "balanced accuracy: %.4f\nmcc: %.4f\ncohen's kappa: %.4f\nroc-auc: %.4f\npr-auc: %.4f\nece: %.4f\n", report.BalancedAccuracy, report.MCC, report.CohenKappa, report.ROCAUC, report.PRAUC, report.ECE)
+}
+
+func printOperationalReport(w io.Writer, report *metrics.OperationalReport, m *model.Model) {
+	fmt.Fprintf(w, "alerts: %d, false alarms: %d over %.2f attack-free hours (%.2f per hour)\n", report.Alerts, report.FalseAlarms, report.AttackFreeHours, report.FalseAlarmsPerHour)
+	fmt.Fprintf(w, "detected episodes: %d of %d (%.4f)\n", report.DetectedEpisodes, len(report.Episodes), report.DetectionRate)
+	fmt.Fprintf(w, "detection latency: mean %.3fs, median %.3fs, max %.3fs\n\n", report.MeanLatency, report.MedianLatency, report.MaxLatency)
+
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(writer, "attack\tepisodes\tdetected\tdetection rate\tmean latency\tmax latency\t\n")
+	for _, class := range report.PerClass {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%.4f\t%.3fs\t%.3fs\t\n", m.ClassName(int(class.Class)), class.Episodes, class.Detected, class.DetectionRate, class.MeanLatency, class.MaxLatency)
+	}
+	writer.Flush()
+}
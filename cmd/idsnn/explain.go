@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/saent-x/ids-nn/core/datasets"
+	"github.com/saent-x/ids-nn/core/model"
+)
+
+func runExplain(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("explain", stderr)
+	model_path := flags.String("model", "", "saved model (.json)")
+	data_path := flags.String("data", "", "labelled frames to explain the model on")
+	schema_path := flags.String("schema", "", "CAN csv schema (.json, .yaml or .yml), the core/datasets layout by default")
+	labels_path := flags.String("labels", "", "label map of the class folders (.json, .yaml or .yml), overrides the classes of the schema")
+	method := flags.String("method", "permutation", "permutation (importance), saliency, integrated-gradients or shap (KernelSHAP)")
+	output := flags.Int("output", 1, "output of the model explained by saliency, integrated-gradients & shap, e.g. the attack class")
+	samples := flags.Int("samples", 100, "frames explained by saliency, integrated-gradients & shap (0 for all)")
+	background := flags.Int("background", 50, "attack-free frames (class 0) the features missing from a coalition are taken from with shap")
+	frame := flags.Int("frame", -1, "also print the attributions of this explained frame, to tell why it was flagged")
+	repeats := flags.Int("repeats", 5, "shuffles of every feature with permutation")
+	seed := flags.Int64("seed", 1, "seed of the shuffles & the sampled coalitions")
+	batch_size := flags.Int("batch-size", 128, "prediction batch size (0 for a single batch)")
+	plot_path := flags.String("plot", "", "where to plot the feature importances as a bar chart (optional)")
+
+	if err := parseFlags(flags, args, "model", "data"); err != nil {
+		return err
+	}
+
+	switch *method {
+	case "permutation", "saliency", "integrated-gradients", "shap":
+	default:
+		fmt.Fprintf(flags.Output(), "unknown method %q\n", *method)
+		flags.Usage()
+		return errUsage
+	}
+	if *frame >= 0 && *method == "permutation" {
+		fmt.Fprintf(flags.Output(), "--frame needs the attributions of saliency, integrated-gradients or shap\n")
+		flags.Usage()
+		return errUsage
+	}
+
+	schema, err := loadSchema(*schema_path, *labels_path)
+	if err != nil {
+		return err
+	}
+
+	m, err := loadModel(*model_path)
+	if err != nil {
+		return err
+	}
+	if len(m.FeatureNames) == 0 && m.InputShape != nil && m.InputShape.FeatureSize() == schema.NumFeatures() {
+		m.SetFeatureNames(schema.FeatureNames())
+	}
+
+	data, err := datasets.LoadCANDatasetFrom(*data_path, schema, true)
+	if err != nil {
+		return fmt.Errorf("loading data: %v", err)
+	}
+
+	var importances model.Importances
+	var attributions *model.Attributions
+	if *method == "permutation" {
+		importances, err = m.PermutationImportance(data, model.PermutationOptions{Repeats: *repeats, Seed: *seed, BatchSize: *batch_size})
+		if err != nil {
+			return err
+		}
+	} else {
+		rows, _ := data.X.Dims()
+		explained := data.X
+		if *samples > 0 && *samples < rows {
+			explained = datasets.Subset(data, seq(*samples)).X
+		}
+
+		switch *method {
+		case "saliency":
+			attributions, err = m.Saliency(explained, *output)
+		case "integrated-gradients":
+			attributions, err = m.IntegratedGradients(explained, nil, *output, 0)
+		case "shap":
+			var benign []int
+			for i, class := range datasets.ClassLabels(data.Y) {
+				if class == 0 && len(benign) < *background {
+					benign = append(benign, i)
+				}
+			}
+			if len(benign) == 0 {
+				return fmt.Errorf("the data has no attack-free frame (class 0) for the shap background")
+			}
+			attributions, err = m.KernelSHAP(explained, *output, model.SHAPOptions{Background: datasets.Subset(data, benign).X, Seed: *seed})
+		}
+		if err != nil {
+			return err
+		}
+		importances = attributions.Importances()
+	}
+
+	fmt.Fprintf(stdout, "feature importances (%s):\n%s", *method, importances)
+
+	if *frame >= 0 {
+		if *frame >= attributions.Values.RawMatrix().Rows {
+			return fmt.Errorf("--frame %d: only %d frames were explained", *frame, attributions.Values.RawMatrix().Rows)
+		}
+		fmt.Fprintf(stdout, "\nattributions of frame %d (base %.6f):\n%s", *frame, attributions.Base, attributions.Sample(*frame))
+	}
+
+	if *plot_path != "" {
+		if err = importances.Plot(fmt.Sprintf("Feature importance (%s)", *method), *plot_path); err != nil {
+			return fmt.Errorf("plotting the importances: %v", err)
+		}
+		fmt.Fprintf(stdout, "importances plotted to %s\n", *plot_path)
+	}
+
+	return nil
+}
+
+// seq returns 0, 1, ..., n-1
+func seq(n int) []int {
+	values := make([]int, n)
+	for i := range values {
+		values[i] = i
+	}
+
+	return values
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func runInspect(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("inspect", stderr)
+	model_path := flags.String("model", "", "saved model (.json)")
+	batch_size := flags.Int("batch-size", 128, "batch size used for the memory estimate")
+	dot_path := flags.String("dot", "", "also write the layer graph in Graphviz DOT format to this file ('-' for stdout)")
+
+	if err := parseFlags(flags, args, "model"); err != nil {
+		return err
+	}
+
+	m, err := loadModel(*model_path)
+	if err != nil {
+		return err
+	}
+
+	summary := m.Summary(*batch_size)
+	fmt.Fprintln(stdout, summary)
+
+	switch *dot_path {
+	case "":
+	case "-":
+		fmt.Fprint(stdout, summary.DOT())
+	default:
+		if err = os.WriteFile(*dot_path, []byte(summary.DOT()), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "layer graph written to %s\n", *dot_path)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+// Command idsnn trains, evaluates & runs the CAN intrusion detection models.
+//
+//	idsnn train    --config configs/can_ids.yaml --data core/datasets/temp --out can_ids.json
+//	idsnn evaluate --model can_ids.json --data core/datasets/can-testing
+//	idsnn predict  --model can_ids.json --input capture.csv --output predictions.csv
+//	idsnn predict  --pipeline can_ids_pipeline.json --input capture.csv
+//	idsnn inspect  --model can_ids.json
+//	idsnn explain  --model can_ids.json --data core/datasets/can-testing --method shap --plot importance.png
+//
+// --data/--input accept a csv file, a folder of csv files or a folder of class folders, laid out as described
+// by the CAN schema given with --schema (the layout of the captures in core/datasets by default).
+// The exit code is 0 on success, 1 when a command fails and 2 on invalid usage.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/datasets"
+	"github.com/saent-x/ids-nn/core/model"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage is returned by commands when their flags are missing or invalid
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"train", "train a model described by a config file & save it", runTrain},
+	{"evaluate", "report loss, accuracy, a confusion matrix & per-class metrics on a labelled dataset", runEvaluate},
+	{"predict", "classify the frames of a capture & write the predictions as csv", runPredict},
+	{"inspect", "print the architecture of a saved model", runInspect},
+	{"explain", "rank the input features by their importance to the model's predictions", runExplain},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+
+		err := cmd.run(args[1:], stdout, stderr)
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.Is(err, errUsage):
+			return exitUsage
+		default:
+			fmt.Fprintf(stderr, "idsnn %s: %v\n", cmd.name, err)
+			return exitError
+		}
+	}
+
+	fmt.Fprintf(stderr, "idsnn: unknown command %q\n\n", args[0])
+	usage(stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: idsnn <command> [flags]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nrun 'idsnn <command> -h' for the flags of a command\n")
+}
+
+// newFlagSet creates a flag set that reports errors to stderr instead of exiting
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("idsnn "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	return flags
+}
+
+// parseFlags parses args & checks that the required flags were given
+func parseFlags(flags *flag.FlagSet, args []string, required ...string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "unexpected arguments: %v\n", flags.Args())
+		flags.Usage()
+		return errUsage
+	}
+
+	for _, name := range required {
+		if flags.Lookup(name).Value.String() == "" {
+			fmt.Fprintf(flags.Output(), "flag --%s is required\n", name)
+			flags.Usage()
+			return errUsage
+		}
+	}
+
+	return nil
+}
+
+func loadModel(path string) (*model.Model, error) {
+	modelDataProvider := new(model.ModelDataProvider)
+
+	m, err := modelDataProvider.LoadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading model %s: %v", path, err)
+	}
+
+	return m, nil
+}
+
+// loadSchema reads the schema at path (nil for the default layout) & replaces its label map with the one at
+// labels_path when given
+func loadSchema(path, labels_path string) (*datasets.CANSchema, error) {
+	schema := datasets.DefaultCANSchema()
+	if path != "" {
+		var err error
+		if schema, err = datasets.LoadCANSchema(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if labels_path != "" {
+		labels, err := datasets.LoadLabelMap(labels_path)
+		if err != nil {
+			return nil, err
+		}
+		schema.Classes = labels
+	}
+
+	return schema, nil
+}
+
+// loadData loads the frames at path with a label each, or with multi-hot targets of multi_label classes when positive
+func loadData(path string, schema *datasets.CANSchema, multi_label int, shuffle bool) (datamodels.TrainingData, error) {
+	if multi_label > 0 {
+		return datasets.LoadMultiLabelDatasetFrom(path, schema, multi_label, shuffle)
+	}
+
+	return datasets.LoadCANDatasetFrom(path, schema, shuffle)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `{
+  "name": "tiny",
+  "input": {"features": 10},
+  "layers": [
+    {"type": "dense", "neurons": 16},
+    {"type": "relu"},
+    {"type": "dense", "neurons": 2},
+    {"type": "softmax"}
+  ],
+  "loss": "categorical_crossentropy",
+  "optimizer": {"type": "adam", "learning_rate": 0.01},
+  "accuracy": "categorical",
+  "training": {"epochs": 2, "batch_size": 16},
+  "scaling": {"type": "robust"}
+}`
+
+// writeCapture writes n frames in the CAN csv layout, every other frame being an attack on id 0x001
+func writeCapture(t *testing.T, path string, n int) {
+	t.Helper()
+
+	var builder strings.Builder
+	builder.WriteString("timestamp,arbitration_id,data_field,attack\n")
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			fmt.Fprintf(&builder, "%f,001,0101010101010101,1\n", float64(i)*0.001)
+		} else {
+			fmt.Fprintf(&builder, "%f,003,0000000000000000,0\n", float64(i)*0.001)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(builder.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUsageExitCodes(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want int
+	}{
+		{nil, exitUsage},
+		{[]string{"help"}, exitOK},
+		{[]string{"unknown"}, exitUsage},
+		{[]string{"train", "--config", "missing.yaml"}, exitUsage},
+		{[]string{"evaluate", "--bogus"}, exitUsage},
+		{[]string{"train", "--config", "c.json", "--data", "d.csv", "--out", "m.json", "--calibrate", "platt"}, exitUsage},
+		{[]string{"predict", "--model", "m.json", "--pipeline", "p.json", "--input", "c.csv"}, exitUsage},
+		{[]string{"inspect", "-h"}, exitOK},
+		{[]string{"inspect", "--model", "does-not-exist.json"}, exitError},
+	} {
+		var stdout, stderr bytes.Buffer
+
+		if got := run(tc.args, &stdout, &stderr); got != tc.want {
+			t.Errorf("error: %v exited with %d | want %d (stderr: %s)", tc.args, got, tc.want, stderr.String())
+		}
+	}
+}
+
+func TestTrainEvaluatePredict(t *testing.T) {
+	dir := t.TempDir()
+
+	config_path := filepath.Join(dir, "tiny.json")
+	data_path := filepath.Join(dir, "capture.csv")
+	model_path := filepath.Join(dir, "model.json")
+	heatmap_path := filepath.Join(dir, "heatmap.png")
+	predictions_path := filepath.Join(dir, "predictions.csv")
+	pipeline_path := filepath.Join(dir, "pipeline.json")
+	report_path := filepath.Join(dir, "report.json")
+	roc_path := filepath.Join(dir, "roc.png")
+	pr_path := filepath.Join(dir, "pr.png")
+	reliability_path := filepath.Join(dir, "reliability.png")
+	importance_path := filepath.Join(dir, "importance.png")
+
+	if err := os.WriteFile(config_path, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeCapture(t, data_path, 64)
+
+	steps := [][]string{
+		{"train", "--config", config_path, "--data", data_path, "--out", model_path, "--stream", "--shuffle-buffer", "8"},
+		{"train", "--config", config_path, "--data", data_path, "--out", model_path, "--pipeline", pipeline_path, "--validation", data_path, "--calibrate", "platt", "--target-fpr", "0.1"},
+		{"evaluate", "--model", model_path, "--data", data_path, "--heatmap", heatmap_path, "--report", report_path, "--roc", roc_path, "--pr", pr_path, "--reliability", reliability_path},
+		{"predict", "--pipeline", pipeline_path, "--input", data_path, "--output", predictions_path},
+		{"predict", "--model", model_path, "--input", data_path, "--output", predictions_path},
+		{"inspect", "--model", model_path, "--dot", "-"},
+		{"explain", "--model", model_path, "--data", data_path, "--repeats", "2", "--plot", importance_path},
+		{"explain", "--model", model_path, "--data", data_path, "--method", "saliency", "--frame", "0"},
+		{"explain", "--model", model_path, "--data", data_path, "--method", "integrated-gradients", "--samples", "8"},
+		{"explain", "--model", model_path, "--data", data_path, "--method", "shap", "--samples", "4", "--background", "8", "--frame", "3"},
+	}
+
+	for _, args := range steps {
+		var stdout, stderr bytes.Buffer
+
+		if code := run(args, &stdout, &stderr); code != exitOK {
+			t.Fatalf("error: %s exited with %d | want %d (stderr: %s)", args[0], code, exitOK, stderr.String())
+		}
+		fmt.Println(stdout.String())
+	}
+
+	for _, path := range []string{model_path, heatmap_path, pipeline_path, report_path, roc_path, pr_path, reliability_path, importance_path} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("error: %s was not written: %v", path, err)
+		}
+	}
+
+	// the calibration & the attack threshold picked on --validation are saved with the model
+	saved, err := os.ReadFile(model_path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"calibration"`, `"attack_threshold"`, `"features"`} {
+		if !strings.Contains(string(saved), key) {
+			t.Errorf("error: the saved model has no %s", key)
+		}
+	}
+
+	file, err := os.Open(predictions_path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 65 || len(records[0]) != 5 {
+		t.Errorf("error: got %d x %d predictions csv | want 65 x 5", len(records), len(records[0]))
+	}
+}
+
+func TestClassFolders(t *testing.T) {
+	dir := t.TempDir()
+
+	config_path := filepath.Join(dir, "tiny.json")
+	labels_path := filepath.Join(dir, "labels.yaml")
+	data_path := filepath.Join(dir, "data")
+	model_path := filepath.Join(dir, "model.json")
+
+	if err := os.WriteFile(config_path, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	labels := "folders:\n  - {folder: attack-free, class: 0, name: Normal}\n  - {folder: fuzzing-*, class: 1, name: Fuzzing}\n"
+	if err := os.WriteFile(labels_path, []byte(labels), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, folder := range []string{"attack-free", "fuzzing-attacks"} {
+		if err := os.MkdirAll(filepath.Join(data_path, folder), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeCapture(t, filepath.Join(data_path, folder, "capture.csv"), 32)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"train", "--config", config_path, "--data", data_path, "--labels", labels_path, "--out", model_path}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("error: train exited with %d (stderr: %s)", code, stderr.String())
+	}
+
+	// the class names are saved with the model, evaluate doesn't need the label map to show them
+	stdout.Reset()
+	if code := run([]string{"evaluate", "--model", model_path, "--data", data_path, "--heatmap", ""}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("error: evaluate exited with %d (stderr: %s)", code, stderr.String())
+	}
+	for _, name := range []string{"Normal", "Fuzzing"} {
+		if !strings.Contains(stdout.String(), name) {
+			t.Errorf("error: the evaluation doesn't name the %s class:\n%s", name, stdout.String())
+		}
+	}
+
+	// a heatmap that can't be saved is an error, not a crash
+	stderr.Reset()
+	if code := run([]string{"evaluate", "--model", model_path, "--data", data_path, "--heatmap", filepath.Join(dir, "missing", "heatmap.png")}, &stdout, &stderr); code != exitError || !strings.Contains(stderr.String(), "confusion matrix") {
+		t.Errorf("error: got the exit code %d (stderr: %s) | want %d", code, stderr.String(), exitError)
+	}
+
+	// the operational metrics follow the frames of each capture over time
+	report_path := filepath.Join(dir, "report.json")
+	stdout.Reset()
+	args := []string{"evaluate", "--model", model_path, "--data", data_path, "--labels", labels_path, "--heatmap", "", "--operational", "--debounce-k", "2", "--debounce-n", "3", "--report", report_path}
+	if code := run(args, &stdout, &stderr); code != exitOK {
+		t.Fatalf("error: evaluate exited with %d (stderr: %s)", code, stderr.String())
+	}
+	for _, want := range []string{"false alarms", "detected episodes", "detection latency"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("error: %q missing from the operational report:\n%s", want, stdout.String())
+		}
+	}
+	if saved, err := os.ReadFile(report_path); err != nil || !strings.Contains(string(saved), `"false_alarms_per_hour"`) {
+		t.Errorf("error: the saved report has no operational metrics (%v)", err)
+	}
+
+	// unknown folders are an error once there is a label map
+	if err := os.MkdirAll(filepath.Join(data_path, "replay"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeCapture(t, filepath.Join(data_path, "replay", "capture.csv"), 4)
+	if code := run([]string{"evaluate", "--model", model_path, "--data", data_path, "--labels", labels_path}, &stdout, &stderr); code != exitError {
+		t.Errorf("error: evaluate exited with %d for a folder missing from the label map | want %d", code, exitError)
+	}
+}
+
+func TestMultiLabelTrainEvaluate(t *testing.T) {
+	dir := t.TempDir()
+
+	config_path := filepath.Join(dir, "multi.json")
+	data_path := filepath.Join(dir, "capture.csv")
+	model_path := filepath.Join(dir, "model.json")
+
+	config := strings.NewReplacer(`"neurons": 2}`, `"neurons": 3}`, `"softmax"`, `"sigmoid"`, `"categorical_crossentropy"`, `"binary_crossentropy"`, `"categorical"`, `"multi_label"`).Replace(testConfig)
+	if err := os.WriteFile(config_path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeCapture(t, data_path, 64)
+
+	// the attack frames alternate between attack 1 alone & attacks 1 & 2 together
+	var labels strings.Builder
+	for i := 0; i < 64; i++ {
+		switch i % 4 {
+		case 0:
+			labels.WriteString("1\n")
+		case 2:
+			labels.WriteString("1 2\n")
+		default:
+			labels.WriteString("0\n")
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "capture.multilabels"), []byte(labels.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"train", "--config", config_path, "--data", data_path, "--validation", data_path, "--out", model_path, "--multi-label", "3"}
+	if code := run(args, &stdout, &stderr); code != exitOK {
+		t.Fatalf("error: train exited with %d | want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "thresholds tuned") {
+		t.Errorf("error: the thresholds weren't tuned: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	args = []string{"evaluate", "--model", model_path, "--data", data_path, "--multi-label", "3"}
+	if code := run(args, &stdout, &stderr); code != exitOK {
+		t.Fatalf("error: evaluate exited with %d | want %d (stderr: %s)", code, exitOK, stderr.String())
+	}
+	for _, want := range []string{"hamming loss", "subset accuracy", "micro avg", "macro avg"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("error: %q missing from the report: %s", want, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/saent-x/ids-nn/core/datasets"
+	"github.com/saent-x/ids-nn/core/model"
+	"github.com/saent-x/ids-nn/core/pipeline"
+	"gonum.org/v1/gonum/mat"
+)
+
+func runPredict(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("predict", stderr)
+	model_path := flags.String("model", "", "saved model (.json)")
+	pipeline_path := flags.String("pipeline", "", "saved pipeline (.json), replaces --model & --schema")
+	input_path := flags.String("input", "", "frames to classify, the attack column is optional")
+	schema_path := flags.String("schema", "", "CAN csv schema (.json, .yaml or .yml), the core/datasets layout by default")
+	labels_path := flags.String("labels", "", "label map of the class folders (.json, .yaml or .yml), overrides the classes of the schema")
+	output_path := flags.String("output", "-", "where to write the predictions as csv ('-' for stdout)")
+	batch_size := flags.Int("batch-size", 128, "prediction batch size (0 for a single batch)")
+
+	if err := parseFlags(flags, args, "input"); err != nil {
+		return err
+	}
+	if (*model_path == "") == (*pipeline_path == "") || (*pipeline_path != "" && *schema_path != "") {
+		fmt.Fprintf(flags.Output(), "either --model (& --schema) or --pipeline is required\n")
+		flags.Usage()
+		return errUsage
+	}
+
+	var schema *datasets.CANSchema
+	var m *model.Model
+	if *pipeline_path != "" {
+		p, err := pipeline.LoadFile(*pipeline_path)
+		if err != nil {
+			return fmt.Errorf("loading pipeline: %v", err)
+		}
+		schema, m = p.Schema, p.Model
+		if *labels_path != "" {
+			labels, err := datasets.LoadLabelMap(*labels_path)
+			if err != nil {
+				return err
+			}
+			schema.Classes = labels
+		}
+	} else {
+		var err error
+		if schema, err = loadSchema(*schema_path, *labels_path); err != nil {
+			return err
+		}
+		if m, err = loadModel(*model_path); err != nil {
+			return err
+		}
+	}
+	if len(m.ClassNames) == 0 && schema.Classes != nil {
+		m.SetClassNames(schema.Classes.Names())
+	}
+
+	data, err := datasets.LoadCANDatasetFrom(*input_path, schema, false)
+	if err != nil {
+		return fmt.Errorf("loading input: %v", err)
+	}
+
+	confidences, err := m.Predict(data.X, *batch_size)
+	if err != nil {
+		return err
+	}
+	predictions := flatten(m.OutputLayerActivation.Predictions(confidences))
+
+	output := stdout
+	if *output_path != "-" {
+		file, err := os.Create(*output_path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+
+	if err = writePredictions(output, predictions, confidences, m.ClassName); err != nil {
+		return fmt.Errorf("writing predictions: %v", err)
+	}
+
+	if *output_path != "-" {
+		fmt.Fprintf(stdout, "%d predictions written to %s\n", len(predictions), *output_path)
+	}
+
+	return nil
+}
+
+// writePredictions writes one row per frame: its index, the predicted class, its name & the model's output for every class
+func writePredictions(w io.Writer, predictions []float64, confidences *mat.Dense, class_name func(int) string) error {
+	writer := csv.NewWriter(w)
+
+	_, outputs := confidences.Dims()
+	header := []string{"frame", "prediction", "class"}
+	for j := 0; j < outputs; j++ {
+		header = append(header, fmt.Sprintf("output_%d", j))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for i, prediction := range predictions {
+		row := []string{strconv.Itoa(i), strconv.FormatFloat(prediction, 'f', -1, 64), class_name(int(prediction))}
+		for j := 0; j < outputs; j++ {
+			row = append(row, strconv.FormatFloat(confidences.At(i, j), 'f', 6, 64))
+		}
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/datasets"
+	"github.com/saent-x/ids-nn/core/metrics"
+	"github.com/saent-x/ids-nn/core/model"
+	"github.com/saent-x/ids-nn/core/pipeline"
+)
+
+func runTrain(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("train", stderr)
+	config_path := flags.String("config", "", "model config (.json, .yaml or .yml)")
+	data_path := flags.String("data", "", "training data")
+	validation_path := flags.String("validation", "", "validation data, evaluated after every epoch (optional)")
+	schema_path := flags.String("schema", "", "CAN csv schema (.json, .yaml or .yml), the core/datasets layout by default")
+	labels_path := flags.String("labels", "", "label map of the class folders (.json, .yaml or .yml), overrides the classes of the schema")
+	out_path := flags.String("out", "", "where to save the trained model (.json)")
+	pipeline_path := flags.String("pipeline", "", "where to also save the schema & the trained model as a single pipeline (.json, optional)")
+	epochs := flags.Int("epochs", 0, "overrides training.epochs of the config")
+	batch_size := flags.Int("batch-size", 0, "overrides training.batch_size of the config")
+	stream := flags.Bool("stream", false, "stream the training data from disk instead of loading it into memory")
+	shuffle_buffer := flags.Int("shuffle-buffer", 10000, "frames held to shuffle from when streaming")
+	scaler_samples := flags.Int("scaler-samples", 100000, "frames the scaler of the config is fit on when streaming (0 for all)")
+	multi_label := flags.Int("multi-label", 0, "number of classes of multi-hot targets for multi-label models (0 for a label per frame), the thresholds are tuned on --validation")
+	calibrate := flags.String("calibrate", "", "calibrate the outputs on --validation with temperature, platt or isotonic (optional)")
+	target_fpr := flags.Float64("target-fpr", 0, "pick the attack threshold that detects the most attacks on --validation with a false positive rate within it (optional)")
+	f_beta := flags.Float64("f-beta", 0, "pick the attack threshold that maximises the F-beta score on --validation (optional)")
+
+	if err := parseFlags(flags, args, "config", "data", "out"); err != nil {
+		return err
+	}
+
+	if (*calibrate != "" || *target_fpr > 0 || *f_beta > 0) && *validation_path == "" || *target_fpr > 0 && *f_beta > 0 {
+		fmt.Fprintf(flags.Output(), "--calibrate, --target-fpr & --f-beta need --validation, & only one of --target-fpr & --f-beta can be given\n")
+		flags.Usage()
+		return errUsage
+	}
+
+	schema, err := loadSchema(*schema_path, *labels_path)
+	if err != nil {
+		return err
+	}
+
+	config, err := model.LoadConfig(*config_path)
+	if err != nil {
+		return err
+	}
+	if *epochs > 0 {
+		config.Training.Epochs = *epochs
+	}
+	if *batch_size > 0 {
+		config.Training.BatchSize = *batch_size
+	}
+
+	m, err := model.FromConfig(config)
+	if err != nil {
+		return err
+	}
+	if schema.Classes != nil {
+		m.SetClassNames(schema.Classes.Names())
+	}
+	if m.InputShape != nil && m.InputShape.FeatureSize() == schema.NumFeatures() {
+		m.SetFeatureNames(schema.FeatureNames())
+	}
+
+	var training_data datamodels.Batcher
+	if *stream {
+		dataset, err := datasets.NewCSVDataset(*data_path, schema)
+		if err != nil {
+			return fmt.Errorf("opening training data: %v", err)
+		}
+		dataset.MultiLabel = *multi_label
+		if m.Scaler != nil {
+			sample, err := datasets.FirstSamples(dataset, *scaler_samples)
+			if err == nil {
+				err = m.FitScaler(sample)
+			}
+			if err != nil {
+				return fmt.Errorf("fitting the scaler: %v", err)
+			}
+		}
+		training_data = datasets.NewStreamingDataLoader(dataset, datasets.DataLoaderOptions{
+			Shuffle:       config.Training.Shuffle,
+			ShuffleBuffer: *shuffle_buffer,
+			Prefetch:      2,
+		})
+		fmt.Fprintf(stdout, "training %s on %d files streamed from %s\n", config.Name, len(dataset.Files), *data_path)
+	} else {
+		data, err := loadData(*data_path, schema, *multi_label, config.Training.Shuffle)
+		if err != nil {
+			return fmt.Errorf("loading training data: %v", err)
+		}
+		if err = m.FitScaler(data.X); err != nil {
+			return fmt.Errorf("fitting the scaler: %v", err)
+		}
+		training_data = data
+		fmt.Fprintf(stdout, "training %s on %d frames\n", config.Name, data.X.RawMatrix().Rows)
+	}
+
+	var validation_data datamodels.ValidationData
+	if *validation_path != "" {
+		data, err := loadData(*validation_path, schema, *multi_label, false)
+		if err != nil {
+			return fmt.Errorf("loading validation data: %v", err)
+		}
+		validation_data = datamodels.ValidationData{X: data.X, Y: data.Y}
+	}
+
+	if err = config.Training.Train(m, training_data, validation_data); err != nil {
+		return err
+	}
+
+	if *multi_label > 0 && *validation_path != "" {
+		thresholds, err := m.TuneThresholds(validation_data, config.Training.BatchSize)
+		if err != nil {
+			return fmt.Errorf("tuning the thresholds: %v", err)
+		}
+		fmt.Fprintf(stdout, "thresholds tuned on the validation data: %.4f\n", thresholds)
+	}
+
+	if *calibrate != "" {
+		if err = m.Calibrate(*calibrate, validation_data, config.Training.BatchSize); err != nil {
+			return fmt.Errorf("calibrating the model: %v", err)
+		}
+		fmt.Fprintf(stdout, "outputs calibrated on the validation data (%s)\n", *calibrate)
+	}
+
+	if *target_fpr > 0 || *f_beta > 0 {
+		objective := func(scores []float64, positives []bool) float64 {
+			return metrics.ThresholdForFPR(scores, positives, *target_fpr)
+		}
+		if *f_beta > 0 {
+			objective = func(scores []float64, positives []bool) float64 {
+				return metrics.ThresholdForFBeta(scores, positives, *f_beta)
+			}
+		}
+
+		threshold, err := m.OptimizeThreshold(validation_data, config.Training.BatchSize, objective)
+		if err != nil {
+			return fmt.Errorf("picking the attack threshold: %v", err)
+		}
+		fmt.Fprintf(stdout, "attack threshold picked on the validation data: %.4f\n", threshold)
+	}
+
+	modelDataProvider := new(model.ModelDataProvider)
+	if err = modelDataProvider.SaveFile(*out_path, m); err != nil {
+		return fmt.Errorf("saving model: %v", err)
+	}
+
+	fmt.Fprintf(stdout, "model saved to %s\n", *out_path)
+
+	if *pipeline_path != "" {
+		p, err := pipeline.New(schema, m)
+		if err == nil {
+			err = p.SaveFile(*pipeline_path)
+		}
+		if err != nil {
+			return fmt.Errorf("saving pipeline: %v", err)
+		}
+		fmt.Fprintf(stdout, "pipeline saved to %s\n", *pipeline_path)
+	}
+
+	return nil
+}
@@ -1,7 +1,7 @@
 package accuracy
 
 import (
-	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/tensor"
 	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/stat"
 )
@@ -27,12 +27,8 @@ func (categoricalAccuracy *CategoricalAccuracy) Compare(predictions *mat.Dense,
 	// get index of max value in each row in softmax
 	rows, cols := predictions.Dims()
 
-	// check if class-targets are one-hot encoded - convert them to sparse data
-	y_rows, _ := y.Dims()
-
-	if y_rows > 1 {
-		y = mat.DenseCopyOf(core.OHEToSparse(y))
-	}
+	// check if class-targets are one-hot encoded or a column - convert them to a sparse row
+	y = tensor.LabelsAsRow(y, cols)
 
 	results := mat.NewDense(rows, cols, nil)
 	// assign 1 where predictions == y, then find the cumulative mean
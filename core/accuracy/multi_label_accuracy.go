@@ -0,0 +1,45 @@
+package accuracy
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// MultiLabelAccuracy is the subset accuracy of multi-hot predictions: a sample only counts as right when every one
+// of its labels is
+type MultiLabelAccuracy struct {
+	Accuracy
+}
+
+func (multiLabelAccuracy *MultiLabelAccuracy) Init(y *mat.Dense, reinit bool) {
+
+}
+
+func (multiLabelAccuracy *MultiLabelAccuracy) Calculate(outputs *mat.Dense, y *mat.Dense) float64 {
+	comparisons := multiLabelAccuracy.Compare(outputs, y)
+	accuracy := stat.Mean(comparisons.RawMatrix().Data, nil)
+
+	multiLabelAccuracy.AccumulatedSum += mat.Sum(comparisons)
+	multiLabelAccuracy.AccumulatedCount += float64(comparisons.RawMatrix().Rows)
+
+	return accuracy
+}
+
+// Compare returns a column with 1 for the samples whose labels are all right
+func (multiLabelAccuracy *MultiLabelAccuracy) Compare(predictions *mat.Dense, y *mat.Dense) *mat.Dense {
+	rows, cols := predictions.Dims()
+	results := mat.NewDense(rows, 1, nil)
+
+	for i := 0; i < rows; i++ {
+		right := 1.
+		for j := 0; j < cols; j++ {
+			if predictions.At(i, j) != y.At(i, j) {
+				right = 0
+				break
+			}
+		}
+		results.Set(i, 0, right)
+	}
+
+	return results
+}
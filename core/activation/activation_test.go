@@ -71,3 +71,35 @@ func TestOptimizer_1(t *testing.T) {
 	fmt.Println("Gradients: separate loss and activation")
 	fmt.Println(mat.Formatted(d_values_2))
 }
+
+func TestSigmoidBinaryCrossEntropy(t *testing.T) {
+	logits := mat.NewDense(3, 2, []float64{0.5, -1, 2, 0.1, -3, 1.5})
+	targets := mat.NewDense(3, 2, []float64{1, 0, 0, 1, 1, 0})
+
+	sigmoid := new(Sigmoid)
+	sigmoid.Forward(logits, true)
+
+	fused := NewSigmoidBinaryCrossEntropy(sigmoid)
+	fused.Backward(sigmoid.Output, targets)
+
+	// the fused gradient matches going through the loss & the sigmoid separately
+	separate := new(loss.BinaryCrossEntropy)
+	separate.Backward(sigmoid.Output, targets)
+	sigmoid.Backward(separate.D_Inputs)
+	if !mat.EqualApprox(fused.D_Inputs, sigmoid.D_Inputs, 1e-9) {
+		t.Errorf("error: got %v | want %v", mat.Formatted(fused.D_Inputs), mat.Formatted(sigmoid.D_Inputs))
+	}
+
+	// a confidently wrong prediction, whose sigmoid rounds to 1, keeps its full loss & gradient
+	confident := mat.NewDense(1, 1, []float64{40})
+	sigmoid.Forward(confident, false)
+
+	got, _ := fused.Calculate(sigmoid.Output, mat.NewDense(1, 1, []float64{0}), false)
+	if got < 39.99 || got > 40.01 {
+		t.Errorf("error: got a loss of %f | want 40", got)
+	}
+	fused.Backward(sigmoid.Output, mat.NewDense(1, 1, []float64{0}))
+	if gradient := fused.D_Inputs.At(0, 0); gradient < 0.99 {
+		t.Errorf("error: got a gradient of %g | want 1", gradient)
+	}
+}
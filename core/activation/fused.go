@@ -0,0 +1,32 @@
+package activation
+
+import (
+	"github.com/saent-x/ids-nn/core/layer"
+	"github.com/saent-x/ids-nn/core/loss"
+)
+
+// FusedOutput is an output activation fused with the loss that follows it. Its gradient goes straight to the
+// activation's inputs, which is simpler & numerically more stable than going through both.
+type FusedOutput interface {
+	loss.ILoss
+
+	// Activation returns a new instance of the activation that is fused, fused outputs found among the layers of a
+	// saved model are loaded as their activation
+	Activation() layer.ILayer
+}
+
+// Fuse returns the fused output of an output activation & its loss, nil when the pair has none
+func Fuse(output layer.ILayer, lossfn loss.ILoss) FusedOutput {
+	switch output := output.(type) {
+	case *SoftMax:
+		if _, ok := lossfn.(*loss.CategoricalCrossEntropy); ok {
+			return new(SoftmaxCatCrossEntropy)
+		}
+	case *Sigmoid:
+		if _, ok := lossfn.(*loss.BinaryCrossEntropy); ok {
+			return NewSigmoidBinaryCrossEntropy(output)
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package activation
 
 import (
+	"github.com/saent-x/ids-nn/core/backend"
 	"github.com/saent-x/ids-nn/core/layer"
 	"gonum.org/v1/gonum/mat"
 	"math"
@@ -9,50 +10,60 @@ import (
 type Sigmoid struct {
 	layer.LayerCommons
 	layer.LayerNavigation
+
+	// Thresholds holds the threshold of every output (label) above which Predictions predicts it, e.g. tuned on
+	// validation data for multi-label models; outputs without one use 0.5
+	Thresholds []float64
 }
 
 func (sigmoid *Sigmoid) Forward(inputs *mat.Dense, training bool) {
 	sigmoid.Inputs = mat.DenseCopyOf(inputs)
 
-	var output mat.Dense
-	output.Apply(func(i, j int, v float64) float64 {
+	sigmoid.Output = backend.Get().Apply(func(v float64) float64 {
 		return 1 / (1 + math.Exp(-v))
 	}, inputs)
-
-	sigmoid.Output = mat.DenseCopyOf(&output)
 }
 
 func (sigmoid *Sigmoid) Backward(d_values *mat.Dense) {
-	// 1 - sigmoid.Output
-	var output_by_neg_output, one_neg_output, new_dinputs mat.Dense
+	b := backend.Get()
 
-	one_neg_output.Apply(func(i, j int, v float64) float64 {
+	// d_values * (1 - sigmoid.Output) * sigmoid.Output
+	one_neg_output := b.Apply(func(v float64) float64 {
 		return 1 - v
 	}, sigmoid.Output)
-	output_by_neg_output.MulElem(&one_neg_output, sigmoid.Output)
-
-	new_dinputs.MulElem(d_values, &output_by_neg_output)
+	output_by_neg_output := b.MulElem(one_neg_output, sigmoid.Output)
 
-	sigmoid.D_Inputs = mat.DenseCopyOf(&new_dinputs)
+	sigmoid.D_Inputs = b.MulElem(d_values, output_by_neg_output)
 }
 
 func (sigmoid *Sigmoid) Predictions(outputs *mat.Dense) *mat.Dense {
-	rows, cols := outputs.Dims()
-	result := mat.NewDense(rows, cols, nil)
+	if len(sigmoid.Thresholds) == 0 {
+		threshold := 0.5
 
-	threshold := 0.5
+		return backend.Get().Apply(func(v float64) float64 {
+			if v > threshold {
+				return 1.0
+			}
+			return 0
+		}, outputs)
+	}
+
+	rows, cols := outputs.Dims()
+	predictions := mat.NewDense(rows, cols, nil)
+	for j := 0; j < cols; j++ {
+		threshold := 0.5
+		if j < len(sigmoid.Thresholds) {
+			threshold = sigmoid.Thresholds[j]
+		}
 
-	for i := 0; i < outputs.RawMatrix().Rows; i++ {
-		for j := 0; j < outputs.RawMatrix().Cols; j++ {
+		for i := 0; i < rows; i++ {
 			if outputs.At(i, j) > threshold {
-				result.Set(i, j, 1.0)
-			} else {
-				result.Set(i, j, 0)
+				predictions.Set(i, j, 1)
 			}
 		}
 	}
 
-	return result
+	return predictions
 }
 
 func (sigmoid *Sigmoid) GetOutput() *mat.Dense { return sigmoid.Output }
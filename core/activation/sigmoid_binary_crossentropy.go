@@ -0,0 +1,36 @@
+package activation
+
+import (
+	"github.com/saent-x/ids-nn/core/layer"
+	"github.com/saent-x/ids-nn/core/loss"
+	"gonum.org/v1/gonum/mat"
+)
+
+// SigmoidBinaryCrossEntropy fuses a Sigmoid output with the binary cross-entropy: the loss & its gradient are
+// computed from the logits entering the sigmoid (see loss.BCEWithLogits) rather than from clipped probabilities
+type SigmoidBinaryCrossEntropy struct {
+	loss.BCEWithLogits
+
+	sigmoid *Sigmoid
+}
+
+func NewSigmoidBinaryCrossEntropy(sigmoid *Sigmoid) *SigmoidBinaryCrossEntropy {
+	return &SigmoidBinaryCrossEntropy{sigmoid: sigmoid}
+}
+
+// Calculate computes the loss of the sigmoid's last output from the logits it was computed from
+func (self *SigmoidBinaryCrossEntropy) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return self.BCEWithLogits.Calculate(self.sigmoid.Inputs, y, include_regularization)
+}
+
+func (self *SigmoidBinaryCrossEntropy) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	return self.BCEWithLogits.Forward(self.sigmoid.Inputs, y_true)
+}
+
+func (self *SigmoidBinaryCrossEntropy) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	self.BCEWithLogits.Backward(self.sigmoid.Inputs, y_true)
+}
+
+func (self *SigmoidBinaryCrossEntropy) Activation() layer.ILayer {
+	return new(Sigmoid)
+}
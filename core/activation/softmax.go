@@ -1,10 +1,8 @@
 package activation
 
 import (
-	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/backend"
 	"github.com/saent-x/ids-nn/core/layer"
-	"github.com/samber/lo"
-	"gonum.org/v1/gonum/floats"
 	"gonum.org/v1/gonum/mat"
 	"math"
 )
@@ -12,45 +10,28 @@ import (
 type SoftMax struct {
 	layer.LayerCommons
 	layer.LayerNavigation
+
+	// AttackThreshold, when set (not nil), is the operating point of a detector whose class 0 is attack-free
+	// traffic: Predictions predicts the most probable attack class when 1 - p(class 0) is above it & class 0
+	// otherwise, instead of the most probable class
+	AttackThreshold *float64
 }
 
 func (softmax *SoftMax) Forward(inputs *mat.Dense, training bool) {
 	softmax.Inputs = inputs
 
-	rows, columns := inputs.Dims()
-
-	var exp_values mat.Dense
-
-	// find max in each row
-	var max_in_rows []float64
-	for i := 0; i < rows; i++ {
-		max_in_rows = append(max_in_rows, lo.Max(inputs.RawRowView(i)))
-	}
-
-	max_inputs := mat.NewDense(rows, 1, max_in_rows)
-	sub_inputs := mat.NewDense(rows, columns, nil)
-
-	sub_inputs = mat.DenseCopyOf(core.SubtractUnevenMatrices(inputs, max_inputs))
-
-	// find exp of sub_inputs
-	exp_values.Apply(func(i, j int, v float64) float64 {
-		return math.Exp(v)
-	}, sub_inputs)
-
-	// find sum of each row in exp_values
-	var sum_exp_values []float64
-	for i := 0; i < rows; i++ {
-		sum_exp_values = append(sum_exp_values, lo.Sum(exp_values.RawRowView(i)))
-	}
+	b := backend.Get()
 
-	sum_exp := mat.NewDense(rows, 1, sum_exp_values)
+	// subtract the max in each row (for numerical stability) & find the exp of the result
+	exp_values := b.Apply(math.Exp, b.BroadcastSub(inputs, b.MaxRows(inputs)))
 
-	result := core.DivideUnevenMatrices(&exp_values, sum_exp)
-
-	softmax.Output = mat.DenseCopyOf(result)
+	// normalize by the sum of each row in exp_values
+	softmax.Output = b.BroadcastDiv(exp_values, b.SumRows(exp_values))
 }
 
 func (softmax *SoftMax) Backward(d_values *mat.Dense) {
+	b := backend.Get()
+
 	softmax.D_Inputs = mat.DenseCopyOf(d_values)
 	softmax.D_Inputs.Zero() // empty
 
@@ -60,28 +41,43 @@ func (softmax *SoftMax) Backward(d_values *mat.Dense) {
 		raw_row := softmax.Output.RawRowView(i)
 
 		single_output := mat.NewDense(len(raw_row), 1, raw_row)
-		diag_flat := mat.NewDiagDense(single_output.RawMatrix().Rows, raw_row)
+		diag_flat := mat.NewDiagDense(len(raw_row), raw_row)
 
-		var mul_single_output, jacobian_matrix, result mat.Dense
-
-		mul_single_output.Mul(single_output, single_output.T())
-		jacobian_matrix.Sub(diag_flat, &mul_single_output)
-		result.Mul(&jacobian_matrix, d_values.RowView(i))
+		jacobian_matrix := b.Sub(diag_flat, b.MatMul(single_output, single_output.T()))
+		result := b.MatMul(jacobian_matrix, d_values.RowView(i))
 
 		softmax.D_Inputs.SetRow(i, result.RawMatrix().Data)
 	}
 }
 
 func (softmax *SoftMax) Predictions(outputs *mat.Dense) *mat.Dense {
-	rows := outputs.RawMatrix().Rows
-	argmax := mat.NewDense(1, rows, nil)
+	argmax := backend.Get().ArgMaxRows(outputs)
+
+	predictions := mat.NewDense(1, len(argmax), nil)
+	for i, idx := range argmax {
+		if softmax.AttackThreshold != nil {
+			idx = softmax.attackClass(outputs.RawRowView(i))
+		}
+		predictions.Set(0, i, float64(idx))
+	}
+
+	return predictions
+}
+
+// attackClass applies the AttackThreshold to the probabilities of a sample
+func (softmax *SoftMax) attackClass(probabilities []float64) int {
+	if len(probabilities) < 2 || 1-probabilities[0] <= *softmax.AttackThreshold {
+		return 0
+	}
 
-	for i := 0; i < rows; i++ {
-		max_in_row := floats.MaxIdx(outputs.RawRowView(i))
-		argmax.Set(0, i, float64(max_in_row))
+	attack := 1
+	for class := 2; class < len(probabilities); class++ {
+		if probabilities[class] > probabilities[attack] {
+			attack = class
+		}
 	}
 
-	return argmax
+	return attack
 }
 
 func (softmax *SoftMax) GetOutput() *mat.Dense {
@@ -1,41 +1,46 @@
 package activation
 
 import (
+	"github.com/saent-x/ids-nn/core/backend"
+	"github.com/saent-x/ids-nn/core/layer"
 	"github.com/saent-x/ids-nn/core/loss"
-	"github.com/samber/lo"
 	"gonum.org/v1/gonum/mat"
 )
 
+// SoftmaxCatCrossEntropy fuses a SoftMax output with the categorical cross-entropy, whose combined gradient is simply
+// the predicted probabilities minus the one-hot targets
 type SoftmaxCatCrossEntropy struct {
-	loss.Loss
+	loss.CategoricalCrossEntropy
 }
 
 func (self *SoftmaxCatCrossEntropy) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	b := backend.Get()
+
 	samples, _ := d_values.Dims()
-	rows, _ := y_true.Dims()
+	rows, cols := y_true.Dims()
 
-	if rows > 1 {
+	var class_targets []int
+	if rows > 1 && cols > 1 {
 		// find index of max value in each row - convert OHE to sparse values
-		var y_true_new mat.VecDense
-		for i := 0; i < rows; i++ {
-			row := y_true.RawRowView(i)
-			arg_max := lo.IndexOf(row, lo.Max(row))
-
-			y_true_new.SetVec(i, float64(arg_max))
+		class_targets = b.ArgMaxRows(y_true)
+	} else {
+		for _, v := range y_true.RawMatrix().Data {
+			class_targets = append(class_targets, int(v))
 		}
-
-		y_true = mat.NewDense(y_true_new.Len(), 1, y_true_new.RawVector().Data)
 	}
 
-	self.D_Inputs = mat.DenseCopyOf(d_values)
+	d_inputs := mat.DenseCopyOf(d_values)
 
 	// calculate gradient
 	for i := 0; i < samples; i++ {
-		y_true_raw := y_true.RawMatrix().Data
-		self.D_Inputs.Set(i, int(y_true_raw[i]), self.D_Inputs.At(i, int(y_true_raw[i]))-1)
+		d_inputs.Set(i, class_targets[i], d_inputs.At(i, class_targets[i])-1)
 	}
 
-	self.D_Inputs.Apply(func(i, j int, v float64) float64 {
+	self.D_Inputs = self.WeighGradient(b.Apply(func(v float64) float64 {
 		return v / float64(samples)
-	}, self.D_Inputs)
+	}, d_inputs), y_true)
+}
+
+func (self *SoftmaxCatCrossEntropy) Activation() layer.ILayer {
+	return new(SoftMax)
 }
@@ -0,0 +1,247 @@
+package anomaly
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/loss"
+	"github.com/saent-x/ids-nn/core/model"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// AnomalyDetector flags traffic unlike the attack-free traffic an autoencoder was trained on, so it can catch attacks
+// that match none of the labelled classes. The model (e.g. dense layers narrowing to a bottleneck & widening back,
+// with a linear output & an mse loss, or a variational autoencoder with a layer.Reparameterization) learns to
+// reconstruct its (scaled) inputs & a sample is scored by its reconstruction error.
+// Samples are rows of features: frames or windows flattened frame after frame (see datasets.WindowDataset &
+// datasets.ReadAll).
+type AnomalyDetector struct {
+	Model *model.Model
+
+	// Error is how a reconstruction is scored: mse (the default) or mae
+	Error string
+	// Quantile is the quantile of the scores of held out attack-free data used as the threshold (0.99 by default),
+	// so about 1 - Quantile of the attack-free samples are false alarms
+	Quantile float64
+	// Threshold is the score above which a sample is an anomaly, see FitThreshold
+	Threshold float64
+}
+
+// New makes an anomaly detector of an autoencoder, a model with as many outputs as inputs
+func New(m *model.Model) (*AnomalyDetector, error) {
+	if m == nil {
+		return nil, errors.New("the anomaly detector has no model")
+	}
+	if len(m.OutputShapes) > 0 {
+		if inputs, outputs := m.InputShape.FeatureSize(), m.OutputShapes[len(m.OutputShapes)-1].FeatureSize(); inputs != outputs {
+			return nil, fmt.Errorf("an autoencoder reconstructs its inputs, the model takes %d inputs but has %d outputs", inputs, outputs)
+		}
+	}
+
+	return &AnomalyDetector{Model: m}, nil
+}
+
+// Fit fits the model's scaler on attack-free samples, trains the model to reconstruct them & fits the threshold on
+// held_out attack-free samples (on benign itself when held_out is nil, which underestimates the threshold)
+func (detector *AnomalyDetector) Fit(benign, held_out *mat.Dense, training model.TrainingConfig) error {
+	if err := detector.Model.FitScaler(benign); err != nil {
+		return fmt.Errorf("fitting the scaler: %v", err)
+	}
+
+	targets, err := detector.targets(benign)
+	if err != nil {
+		return err
+	}
+
+	var validation_data datamodels.Batcher
+	if held_out != nil {
+		held_out_targets, err := detector.targets(held_out)
+		if err != nil {
+			return err
+		}
+		validation_data = datamodels.ValidationData{X: held_out, Y: held_out_targets}
+	}
+
+	if err = training.Train(detector.Model, datamodels.TrainingData{X: benign, Y: targets}, validation_data); err != nil {
+		return err
+	}
+
+	if held_out == nil {
+		held_out = benign
+	}
+
+	return detector.FitThreshold(held_out)
+}
+
+// FitThreshold sets the threshold to the Quantile of the scores of attack-free samples
+func (detector *AnomalyDetector) FitThreshold(benign *mat.Dense) error {
+	scores, err := detector.Score(benign)
+	if err != nil {
+		return err
+	}
+
+	quantile := detector.Quantile
+	if quantile == 0 {
+		quantile = 0.99
+	}
+	if quantile < 0 || quantile > 1 {
+		return fmt.Errorf("the quantile must be in [0, 1], got %g", quantile)
+	}
+
+	sort.Float64s(scores)
+	detector.Threshold = stat.Quantile(quantile, stat.Empirical, scores, nil)
+
+	return nil
+}
+
+// Score returns the reconstruction error of every sample (row of X)
+func (detector *AnomalyDetector) Score(X *mat.Dense) ([]float64, error) {
+	lossfn, err := detector.errorFunction()
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := detector.targets(X)
+	if err != nil {
+		return nil, err
+	}
+
+	reconstructions, err := detector.Model.Predict(X, 0)
+	if err != nil {
+		return nil, err
+	}
+	if rows, cols := reconstructions.Dims(); cols != targets.RawMatrix().Cols {
+		return nil, fmt.Errorf("got %dx%d reconstructions of %dx%d inputs", rows, cols, targets.RawMatrix().Rows, targets.RawMatrix().Cols)
+	}
+
+	return lossfn.Forward(targets, reconstructions).RawVector().Data, nil
+}
+
+// Predict returns 1 for the samples whose score is above the threshold & 0 for the others
+func (detector *AnomalyDetector) Predict(X *mat.Dense) ([]float64, error) {
+	scores, err := detector.Score(X)
+	if err != nil {
+		return nil, err
+	}
+
+	predictions := make([]float64, len(scores))
+	for i, score := range scores {
+		if score > detector.Threshold {
+			predictions[i] = 1
+		}
+	}
+
+	return predictions, nil
+}
+
+// targets are the inputs the model reconstructs, scaled like the model scales them
+func (detector *AnomalyDetector) targets(X *mat.Dense) (*mat.Dense, error) {
+	if detector.Model.Scaler == nil {
+		return X, nil
+	}
+
+	scaled, err := detector.Model.Scaler.Transform(X)
+	if err != nil {
+		return nil, fmt.Errorf("scaling the inputs: %v", err)
+	}
+
+	return scaled, nil
+}
+
+func (detector *AnomalyDetector) errorFunction() (loss.ILoss, error) {
+	switch detector.Error {
+	case "", "mse":
+		return new(loss.MeanSquaredError), nil
+	case "mae":
+		return new(loss.MeanAbsoluteError), nil
+	}
+
+	return nil, fmt.Errorf("unknown reconstruction error %q (expected mse or mae)", detector.Error)
+}
+
+// artifact is the saved form of an anomaly detector, the model being saved like ModelDataProvider does
+type artifact struct {
+	Model     json.RawMessage `json:"model"`
+	Error     string          `json:"error,omitempty"`
+	Quantile  float64         `json:"quantile,omitempty"`
+	Threshold float64         `json:"threshold"`
+}
+
+// Encode writes the anomaly detector as a single JSON artifact to w
+func (detector *AnomalyDetector) Encode(w io.Writer) error {
+	var model_json bytes.Buffer
+	if err := new(model.ModelDataProvider).Encode(&model_json, detector.Model); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(artifact{Model: model_json.Bytes(), Error: detector.Error, Quantile: detector.Quantile, Threshold: detector.Threshold})
+}
+
+// SaveFile writes the anomaly detector to path, see Encode
+func (detector *AnomalyDetector) SaveFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = detector.Encode(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Load reads an anomaly detector written by Encode
+func Load(r io.Reader) (*AnomalyDetector, error) {
+	var saved artifact
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("failed to decode anomaly detector JSON: %v", err)
+	}
+	if len(saved.Model) == 0 {
+		return nil, errors.New("the anomaly detector has no model")
+	}
+
+	m, err := new(model.ModelDataProvider).Load(bytes.NewReader(saved.Model))
+	if err != nil {
+		return nil, err
+	}
+
+	detector, err := New(m)
+	if err != nil {
+		return nil, err
+	}
+	detector.Error, detector.Quantile, detector.Threshold = saved.Error, saved.Quantile, saved.Threshold
+
+	if _, err = detector.errorFunction(); err != nil {
+		return nil, err
+	}
+
+	return detector, nil
+}
+
+// LoadFile opens & reads an anomaly detector saved with SaveFile
+func LoadFile(path string) (*AnomalyDetector, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	detector, err := Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return detector, nil
+}
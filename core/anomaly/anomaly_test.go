@@ -0,0 +1,170 @@
+package anomaly
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/saent-x/ids-nn/core/model"
+	"gonum.org/v1/gonum/mat"
+)
+
+// traffic returns benign samples whose 6 features depend on 2 hidden ones, anomalous samples break that relation
+func traffic(samples int, anomalous bool, random *rand.Rand) *mat.Dense {
+	X := mat.NewDense(samples, 6, nil)
+	for i := 0; i < samples; i++ {
+		a, b := random.Float64(), random.Float64()
+		row := []float64{a, b, a + b, a - b, 2 * a, b / 2}
+		if anomalous {
+			row[2], row[4] = random.Float64()*2, random.Float64()*2
+		}
+		X.SetRow(i, row)
+	}
+
+	return X
+}
+
+func autoencoder(t *testing.T, config string) *model.Model {
+	t.Helper()
+
+	parsed, err := model.ParseConfig([]byte(config), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := model.FromConfig(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+const autoencoderConfig = `
+input: {features: 6}
+layers:
+  - {type: dense, neurons: 16}
+  - {type: relu}
+  - {type: dense, neurons: 2}
+  - {type: dense, neurons: 16}
+  - {type: relu}
+  - {type: dense, neurons: 6}
+  - {type: linear}
+loss: mse
+optimizer: {type: adam, learning_rate: 0.01}
+accuracy: regression
+scaling: {type: standard}
+`
+
+const variationalConfig = `
+input: {features: 6}
+layers:
+  - {type: dense, neurons: 16}
+  - {type: relu}
+  - {type: dense, neurons: 4}
+  - {type: reparameterization, beta: 0.01, seed: 1}
+  - {type: dense, neurons: 16}
+  - {type: relu}
+  - {type: dense, neurons: 6}
+  - {type: linear}
+loss: mse
+optimizer: {type: adam, learning_rate: 0.01}
+accuracy: regression
+scaling: {type: standard}
+`
+
+func TestAnomalyDetector(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config string
+	}{
+		{"autoencoder", autoencoderConfig},
+		{"variational autoencoder", variationalConfig},
+	} {
+		random := rand.New(rand.NewSource(1))
+		benign, held_out := traffic(500, false, random), traffic(200, false, random)
+		attacks := traffic(100, true, random)
+
+		detector, err := New(autoencoder(t, test.config))
+		if err != nil {
+			t.Fatal(err)
+		}
+		detector.Quantile = 0.95
+		if err = detector.Fit(benign, held_out, model.TrainingConfig{Epochs: 300, BatchSize: 0, PrintEvery: 1000}); err != nil {
+			t.Fatalf("error: %s: %v", test.name, err)
+		}
+		if detector.Threshold <= 0 {
+			t.Fatalf("error: %s: got a threshold of %f", test.name, detector.Threshold)
+		}
+
+		rate := func(X *mat.Dense) float64 {
+			predictions, err := detector.Predict(X)
+			if err != nil {
+				t.Fatal(err)
+			}
+			flagged := 0.
+			for _, prediction := range predictions {
+				flagged += prediction
+			}
+			return flagged / float64(len(predictions))
+		}
+		if false_alarms := rate(traffic(200, false, random)); false_alarms > 0.15 {
+			t.Errorf("error: %s: got %f of benign samples flagged | want about 0.05", test.name, false_alarms)
+		}
+		if detected := rate(attacks); detected < 0.8 {
+			t.Errorf("error: %s: got %f of anomalies flagged | want at least 0.8", test.name, detected)
+		}
+
+		// the saved detector gives the same scores
+		var buffer bytes.Buffer
+		if err = detector.Encode(&buffer); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := Load(&buffer)
+		if err != nil {
+			t.Fatalf("error: %s: %v", test.name, err)
+		}
+		want, _ := detector.Score(attacks)
+		got, err := loaded.Score(attacks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.Threshold != detector.Threshold || loaded.Quantile != 0.95 || !reflect.DeepEqual(got, want) {
+			t.Errorf("error: %s: the loaded detector differs (threshold %f | want %f)", test.name, loaded.Threshold, detector.Threshold)
+		}
+	}
+}
+
+func TestAnomalyDetectorErrors(t *testing.T) {
+	classifier := autoencoder(t, `
+input: {features: 6}
+layers:
+  - {type: dense, neurons: 2}
+  - {type: softmax}
+loss: categorical_crossentropy
+optimizer: {type: adam, learning_rate: 0.01}
+accuracy: categorical
+`)
+	if _, err := New(classifier); err == nil {
+		t.Error("error: expected an error for a model that doesn't reconstruct its inputs")
+	}
+
+	detector, err := New(autoencoder(t, autoencoderConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector.Error = "rmse"
+	if _, err = detector.Score(traffic(2, false, rand.New(rand.NewSource(1)))); err == nil {
+		t.Error("error: expected an error for an unknown reconstruction error")
+	}
+
+	if _, err = model.FromConfig(&model.Config{
+		Input:     model.InputConfig{Features: 6},
+		Layers:    []model.LayerConfig{{Type: "dense", Neurons: 3}, {Type: "reparameterization"}, {Type: "linear"}},
+		Loss:      "mse",
+		Optimizer: model.OptimizerConfig{Type: "adam", LearningRate: 0.01},
+		Accuracy:  "regression",
+	}); err == nil {
+		t.Error("error: expected an error for a reparameterization of an odd number of features")
+	}
+}
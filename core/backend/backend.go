@@ -0,0 +1,99 @@
+package backend
+
+import (
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Backend abstracts the numeric kernels used by layers, activations, losses and optimizers.
+// Every operation returns a freshly allocated matrix and never mutates its arguments, so
+// implementations are free to use whatever storage/parallelism they like internally.
+type Backend interface {
+	// Name identifies the backend (used for logging & selection)
+	Name() string
+
+	// MatMul returns the matrix product a·b
+	MatMul(a, b mat.Matrix) *mat.Dense
+
+	// Add, Sub, MulElem & DivElem are element-wise operations on equally shaped operands
+	Add(a, b mat.Matrix) *mat.Dense
+	Sub(a, b mat.Matrix) *mat.Dense
+	MulElem(a, b mat.Matrix) *mat.Dense
+	DivElem(a, b mat.Matrix) *mat.Dense
+
+	// BroadcastAdd, BroadcastSub & BroadcastDiv apply the operation between a (r x c) and b, where b is
+	// either r x c, 1 x c (broadcast across rows), r x 1 (broadcast across columns) or 1 x 1
+	BroadcastAdd(a, b mat.Matrix) *mat.Dense
+	BroadcastSub(a, b mat.Matrix) *mat.Dense
+	BroadcastDiv(a, b mat.Matrix) *mat.Dense
+
+	// Scale multiplies every element of a by f
+	Scale(f float64, a mat.Matrix) *mat.Dense
+
+	// Apply maps fn over every element of a
+	Apply(fn func(v float64) float64, a mat.Matrix) *mat.Dense
+	// Apply2 maps fn over pairs of elements of equally shaped a & b
+	Apply2(fn func(x, y float64) float64, a, b mat.Matrix) *mat.Dense
+
+	// Sum reduces a to the sum of all its elements
+	Sum(a mat.Matrix) float64
+	// SumCols sums each column, returning a 1 x c row
+	SumCols(a mat.Matrix) *mat.Dense
+	// SumRows sums each row, returning an r x 1 column
+	SumRows(a mat.Matrix) *mat.Dense
+	// MaxRows finds the max of each row, returning an r x 1 column
+	MaxRows(a mat.Matrix) *mat.Dense
+	// MeanRows averages each row (mean over the last axis)
+	MeanRows(a mat.Matrix) *mat.VecDense
+	// Norm returns the Frobenius norm of a
+	Norm(a mat.Matrix) float64
+	// ArgMaxRows returns the column index of the max value in each row
+	ArgMaxRows(a mat.Matrix) []int
+}
+
+var (
+	mu      sync.RWMutex
+	current Backend = Gonum{}
+)
+
+// Get returns the backend currently in use
+func Get() Backend {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current
+}
+
+// Use swaps the backend used by the whole network; passing nil restores the default gonum backend
+func Use(b Backend) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if b == nil {
+		b = Gonum{}
+	}
+	current = b
+}
+
+func broadcastShape(a, b mat.Matrix) (rows, cols int) {
+	rowsA, colsA := a.Dims()
+	rowsB, colsB := b.Dims()
+
+	if (rowsB != rowsA && rowsB != 1) || (colsB != colsA && colsB != 1) {
+		panic(mat.ErrShape)
+	}
+
+	return rowsA, colsA
+}
+
+func sameShape(a, b mat.Matrix) (rows, cols int) {
+	rowsA, colsA := a.Dims()
+	rowsB, colsB := b.Dims()
+
+	if rowsA != rowsB || colsA != colsB {
+		panic(mat.ErrShape)
+	}
+
+	return rowsA, colsA
+}
@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func randomDense(rows, cols int) *mat.Dense {
+	data := make([]float64, rows*cols)
+	for i := range data {
+		data[i] = rand.NormFloat64()
+	}
+
+	return mat.NewDense(rows, cols, data)
+}
+
+func assertEqual(t *testing.T, name string, got, want mat.Matrix) {
+	t.Helper()
+
+	if !mat.EqualApprox(got, want, 1e-9) {
+		t.Errorf("error: %s mismatch\ngot:\n%v\nwant:\n%v", name, mat.Formatted(got), mat.Formatted(want))
+	}
+}
+
+func TestReferenceMatchesGonum(t *testing.T) {
+	ref, gon := Reference{}, Gonum{}
+
+	a := randomDense(4, 3)
+	b := randomDense(4, 3)
+	c := randomDense(3, 5)
+	row := randomDense(1, 3)
+	col := randomDense(4, 1)
+
+	assertEqual(t, "MatMul", ref.MatMul(a, c), gon.MatMul(a, c))
+	assertEqual(t, "MatMul(T)", ref.MatMul(a.T(), b), gon.MatMul(a.T(), b))
+	assertEqual(t, "Add", ref.Add(a, b), gon.Add(a, b))
+	assertEqual(t, "Sub", ref.Sub(a, b), gon.Sub(a, b))
+	assertEqual(t, "MulElem", ref.MulElem(a, b), gon.MulElem(a, b))
+	assertEqual(t, "DivElem", ref.DivElem(a, b), gon.DivElem(a, b))
+	assertEqual(t, "BroadcastAdd(row)", ref.BroadcastAdd(a, row), gon.BroadcastAdd(a, row))
+	assertEqual(t, "BroadcastSub(col)", ref.BroadcastSub(a, col), gon.BroadcastSub(a, col))
+	assertEqual(t, "BroadcastDiv(col)", ref.BroadcastDiv(a, col), gon.BroadcastDiv(a, col))
+	assertEqual(t, "Scale", ref.Scale(-2.5, a), gon.Scale(-2.5, a))
+	assertEqual(t, "Apply", ref.Apply(math.Exp, a), gon.Apply(math.Exp, a))
+	assertEqual(t, "Apply2", ref.Apply2(math.Max, a, b), gon.Apply2(math.Max, a, b))
+	assertEqual(t, "SumCols", ref.SumCols(a), gon.SumCols(a))
+	assertEqual(t, "SumRows", ref.SumRows(a), gon.SumRows(a))
+	assertEqual(t, "MaxRows", ref.MaxRows(a), gon.MaxRows(a))
+	assertEqual(t, "MeanRows", ref.MeanRows(a), gon.MeanRows(a))
+
+	if math.Abs(ref.Sum(a)-gon.Sum(a)) > 1e-9 {
+		t.Errorf("error: Sum mismatch got %f | want %f", ref.Sum(a), gon.Sum(a))
+	}
+	if math.Abs(ref.Norm(a)-gon.Norm(a)) > 1e-9 {
+		t.Errorf("error: Norm mismatch got %f | want %f", ref.Norm(a), gon.Norm(a))
+	}
+
+	refArgmax, gonArgmax := ref.ArgMaxRows(a), gon.ArgMaxRows(a)
+	for i := range refArgmax {
+		if refArgmax[i] != gonArgmax[i] {
+			t.Errorf("error: ArgMaxRows mismatch at row %d got %d | want %d", i, refArgmax[i], gonArgmax[i])
+		}
+	}
+}
+
+func TestBroadcastShapeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("error: expected a panic on mismatched broadcast shapes")
+		}
+	}()
+
+	Reference{}.BroadcastAdd(randomDense(4, 3), randomDense(2, 3))
+}
+
+func TestUseBackend(t *testing.T) {
+	defer Use(nil)
+
+	Use(Reference{})
+	if Get().Name() != "reference" {
+		t.Errorf("error: got %s | want reference", Get().Name())
+	}
+
+	Use(nil)
+	if Get().Name() != "gonum" {
+		t.Errorf("error: got %s | want gonum", Get().Name())
+	}
+}
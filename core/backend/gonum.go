@@ -0,0 +1,172 @@
+package backend
+
+import (
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Gonum is the default backend; it delegates to gonum/mat (and through it to BLAS)
+type Gonum struct{}
+
+func (Gonum) Name() string { return "gonum" }
+
+func (Gonum) MatMul(a, b mat.Matrix) *mat.Dense {
+	var result mat.Dense
+	result.Mul(a, b)
+
+	return &result
+}
+
+func (Gonum) Add(a, b mat.Matrix) *mat.Dense {
+	var result mat.Dense
+	result.Add(a, b)
+
+	return &result
+}
+
+func (Gonum) Sub(a, b mat.Matrix) *mat.Dense {
+	var result mat.Dense
+	result.Sub(a, b)
+
+	return &result
+}
+
+func (Gonum) MulElem(a, b mat.Matrix) *mat.Dense {
+	var result mat.Dense
+	result.MulElem(a, b)
+
+	return &result
+}
+
+func (Gonum) DivElem(a, b mat.Matrix) *mat.Dense {
+	var result mat.Dense
+	result.DivElem(a, b)
+
+	return &result
+}
+
+func (g Gonum) BroadcastAdd(a, b mat.Matrix) *mat.Dense {
+	return g.broadcast(a, b, func(x, y float64) float64 { return x + y })
+}
+
+func (g Gonum) BroadcastSub(a, b mat.Matrix) *mat.Dense {
+	return g.broadcast(a, b, func(x, y float64) float64 { return x - y })
+}
+
+func (g Gonum) BroadcastDiv(a, b mat.Matrix) *mat.Dense {
+	return g.broadcast(a, b, func(x, y float64) float64 { return x / y })
+}
+
+func (Gonum) broadcast(a, b mat.Matrix, fn func(x, y float64) float64) *mat.Dense {
+	broadcastShape(a, b)
+	rowsB, colsB := b.Dims()
+
+	var result mat.Dense
+	result.Apply(func(i, j int, v float64) float64 {
+		if rowsB == 1 {
+			i = 0
+		}
+		if colsB == 1 {
+			j = 0
+		}
+		return fn(v, b.At(i, j))
+	}, a)
+
+	return &result
+}
+
+func (Gonum) Scale(f float64, a mat.Matrix) *mat.Dense {
+	var result mat.Dense
+	result.Scale(f, a)
+
+	return &result
+}
+
+func (Gonum) Apply(fn func(v float64) float64, a mat.Matrix) *mat.Dense {
+	var result mat.Dense
+	result.Apply(func(_, _ int, v float64) float64 {
+		return fn(v)
+	}, a)
+
+	return &result
+}
+
+func (Gonum) Apply2(fn func(x, y float64) float64, a, b mat.Matrix) *mat.Dense {
+	sameShape(a, b)
+
+	var result mat.Dense
+	result.Apply(func(i, j int, v float64) float64 {
+		return fn(v, b.At(i, j))
+	}, a)
+
+	return &result
+}
+
+func (Gonum) Sum(a mat.Matrix) float64 {
+	return mat.Sum(a)
+}
+
+func (Gonum) SumCols(a mat.Matrix) *mat.Dense {
+	dense := mat.DenseCopyOf(a)
+	_, cols := dense.Dims()
+
+	result := mat.NewDense(1, cols, nil)
+	for j := 0; j < cols; j++ {
+		result.Set(0, j, mat.Sum(dense.ColView(j)))
+	}
+
+	return result
+}
+
+func (Gonum) SumRows(a mat.Matrix) *mat.Dense {
+	dense := mat.DenseCopyOf(a)
+	rows, _ := dense.Dims()
+
+	result := mat.NewDense(rows, 1, nil)
+	for i := 0; i < rows; i++ {
+		result.Set(i, 0, floats.Sum(dense.RawRowView(i)))
+	}
+
+	return result
+}
+
+func (Gonum) MaxRows(a mat.Matrix) *mat.Dense {
+	dense := mat.DenseCopyOf(a)
+	rows, _ := dense.Dims()
+
+	result := mat.NewDense(rows, 1, nil)
+	for i := 0; i < rows; i++ {
+		result.Set(i, 0, floats.Max(dense.RawRowView(i)))
+	}
+
+	return result
+}
+
+func (Gonum) MeanRows(a mat.Matrix) *mat.VecDense {
+	dense := mat.DenseCopyOf(a)
+	rows, _ := dense.Dims()
+
+	means := mat.NewVecDense(rows, nil)
+	for i := 0; i < rows; i++ {
+		means.SetVec(i, stat.Mean(dense.RawRowView(i), nil))
+	}
+
+	return means
+}
+
+func (Gonum) Norm(a mat.Matrix) float64 {
+	return mat.Norm(a, 2)
+}
+
+func (Gonum) ArgMaxRows(a mat.Matrix) []int {
+	dense := mat.DenseCopyOf(a)
+	rows, _ := dense.Dims()
+
+	result := make([]int, rows)
+	for i := 0; i < rows; i++ {
+		result[i] = floats.MaxIdx(dense.RawRowView(i))
+	}
+
+	return result
+}
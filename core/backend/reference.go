@@ -0,0 +1,198 @@
+package backend
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Reference is a pure-Go backend built from plain loops. It is slow but easy to audit, which makes
+// it the yardstick new (optimized) backends are tested against.
+type Reference struct{}
+
+func (Reference) Name() string { return "reference" }
+
+func (Reference) MatMul(a, b mat.Matrix) *mat.Dense {
+	rowsA, colsA := a.Dims()
+	rowsB, colsB := b.Dims()
+
+	if colsA != rowsB {
+		panic(mat.ErrShape)
+	}
+
+	data := make([]float64, rowsA*colsB)
+	for i := 0; i < rowsA; i++ {
+		for k := 0; k < colsA; k++ {
+			aik := a.At(i, k)
+			for j := 0; j < colsB; j++ {
+				data[i*colsB+j] += aik * b.At(k, j)
+			}
+		}
+	}
+
+	return mat.NewDense(rowsA, colsB, data)
+}
+
+func (r Reference) Add(a, b mat.Matrix) *mat.Dense {
+	return r.Apply2(func(x, y float64) float64 { return x + y }, a, b)
+}
+
+func (r Reference) Sub(a, b mat.Matrix) *mat.Dense {
+	return r.Apply2(func(x, y float64) float64 { return x - y }, a, b)
+}
+
+func (r Reference) MulElem(a, b mat.Matrix) *mat.Dense {
+	return r.Apply2(func(x, y float64) float64 { return x * y }, a, b)
+}
+
+func (r Reference) DivElem(a, b mat.Matrix) *mat.Dense {
+	return r.Apply2(func(x, y float64) float64 { return x / y }, a, b)
+}
+
+func (r Reference) BroadcastAdd(a, b mat.Matrix) *mat.Dense {
+	return r.broadcast(a, b, func(x, y float64) float64 { return x + y })
+}
+
+func (r Reference) BroadcastSub(a, b mat.Matrix) *mat.Dense {
+	return r.broadcast(a, b, func(x, y float64) float64 { return x - y })
+}
+
+func (r Reference) BroadcastDiv(a, b mat.Matrix) *mat.Dense {
+	return r.broadcast(a, b, func(x, y float64) float64 { return x / y })
+}
+
+func (Reference) broadcast(a, b mat.Matrix, fn func(x, y float64) float64) *mat.Dense {
+	rows, cols := broadcastShape(a, b)
+	rowsB, colsB := b.Dims()
+
+	data := make([]float64, rows*cols)
+	for i := 0; i < rows; i++ {
+		bi := i
+		if rowsB == 1 {
+			bi = 0
+		}
+		for j := 0; j < cols; j++ {
+			bj := j
+			if colsB == 1 {
+				bj = 0
+			}
+			data[i*cols+j] = fn(a.At(i, j), b.At(bi, bj))
+		}
+	}
+
+	return mat.NewDense(rows, cols, data)
+}
+
+func (r Reference) Scale(f float64, a mat.Matrix) *mat.Dense {
+	return r.Apply(func(v float64) float64 { return f * v }, a)
+}
+
+func (Reference) Apply(fn func(v float64) float64, a mat.Matrix) *mat.Dense {
+	rows, cols := a.Dims()
+
+	data := make([]float64, rows*cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			data[i*cols+j] = fn(a.At(i, j))
+		}
+	}
+
+	return mat.NewDense(rows, cols, data)
+}
+
+func (Reference) Apply2(fn func(x, y float64) float64, a, b mat.Matrix) *mat.Dense {
+	rows, cols := sameShape(a, b)
+
+	data := make([]float64, rows*cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			data[i*cols+j] = fn(a.At(i, j), b.At(i, j))
+		}
+	}
+
+	return mat.NewDense(rows, cols, data)
+}
+
+func (Reference) Sum(a mat.Matrix) float64 {
+	rows, cols := a.Dims()
+
+	sum := 0.
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			sum += a.At(i, j)
+		}
+	}
+
+	return sum
+}
+
+func (Reference) SumCols(a mat.Matrix) *mat.Dense {
+	rows, cols := a.Dims()
+
+	data := make([]float64, cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			data[j] += a.At(i, j)
+		}
+	}
+
+	return mat.NewDense(1, cols, data)
+}
+
+func (Reference) SumRows(a mat.Matrix) *mat.Dense {
+	rows, cols := a.Dims()
+
+	data := make([]float64, rows)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			data[i] += a.At(i, j)
+		}
+	}
+
+	return mat.NewDense(rows, 1, data)
+}
+
+func (Reference) MaxRows(a mat.Matrix) *mat.Dense {
+	rows, cols := a.Dims()
+
+	data := make([]float64, rows)
+	for i := 0; i < rows; i++ {
+		data[i] = math.Inf(-1)
+		for j := 0; j < cols; j++ {
+			data[i] = math.Max(data[i], a.At(i, j))
+		}
+	}
+
+	return mat.NewDense(rows, 1, data)
+}
+
+func (r Reference) MeanRows(a mat.Matrix) *mat.VecDense {
+	rows, cols := a.Dims()
+	sums := r.SumRows(a)
+
+	means := mat.NewVecDense(rows, nil)
+	for i := 0; i < rows; i++ {
+		means.SetVec(i, sums.At(i, 0)/float64(cols))
+	}
+
+	return means
+}
+
+func (r Reference) Norm(a mat.Matrix) float64 {
+	return math.Sqrt(r.Sum(r.Apply(func(v float64) float64 { return v * v }, a)))
+}
+
+func (Reference) ArgMaxRows(a mat.Matrix) []int {
+	rows, cols := a.Dims()
+
+	result := make([]int, rows)
+	for i := 0; i < rows; i++ {
+		for j := 1; j < cols; j++ {
+			if a.At(i, j) > a.At(i, result[i]) {
+				result[i] = j
+			}
+		}
+	}
+
+	return result
+}
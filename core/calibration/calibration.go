@@ -0,0 +1,93 @@
+package calibration
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// clip keeps probabilities away from 0 & 1 before taking their logarithm or logit
+const clip = 1e-12
+
+// Calibrator learns with Fit how the probabilities predicted by a model on held out (validation) data map to the
+// observed frequencies & corrects later probabilities with Transform, e.g. so that frames predicted as attacks
+// with a probability of 0.9 are attacks 90% of the time
+type Calibrator interface {
+	// Fit learns the calibration from probabilities (a row per sample) & targets of the same shape (one-hot,
+	// multi-hot or 0/1)
+	Fit(probabilities, targets mat.Matrix) error
+	Transform(probabilities mat.Matrix) (*mat.Dense, error)
+}
+
+// Outputs tells how the probabilities being calibrated were produced
+type Outputs struct {
+	// Softmax is set when the probabilities of a row sum to 1, rather than being independent sigmoid outputs, the
+	// calibrated rows then sum to 1 too
+	Softmax bool `json:"softmax,omitempty" yaml:"softmax,omitempty"`
+}
+
+// normalize makes every row of probabilities sum to 1 for softmax outputs, rows that sum to 0 become uniform
+func (outputs Outputs) normalize(probabilities *mat.Dense) *mat.Dense {
+	if !outputs.Softmax {
+		return probabilities
+	}
+
+	rows, cols := probabilities.Dims()
+	for i := 0; i < rows; i++ {
+		row := probabilities.RawRowView(i)
+
+		sum := 0.
+		for _, p := range row {
+			sum += p
+		}
+		for j := range row {
+			if sum > 0 {
+				row[j] /= sum
+			} else {
+				row[j] = 1 / float64(cols)
+			}
+		}
+	}
+
+	return probabilities
+}
+
+func checkTargets(probabilities, targets mat.Matrix) error {
+	rows, cols := probabilities.Dims()
+	if target_rows, target_cols := targets.Dims(); target_rows != rows || target_cols != cols {
+		return fmt.Errorf("got %dx%d targets for %dx%d probabilities", target_rows, target_cols, rows, cols)
+	}
+	if rows == 0 {
+		return errors.New("can't calibrate without samples")
+	}
+
+	return nil
+}
+
+func checkColumns(fitted, cols int) error {
+	if fitted == 0 {
+		return errors.New("the calibrator isn't fitted")
+	}
+	if fitted != cols {
+		return fmt.Errorf("the calibrator was fitted on %d outputs but got %d", fitted, cols)
+	}
+
+	return nil
+}
+
+func logit(p float64) float64 {
+	p = min(max(p, clip), 1-clip)
+
+	return math.Log(p / (1 - p))
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
+
+// softplus is log(1 + e^z) without overflow
+func softplus(z float64) float64 {
+	return max(z, 0) + math.Log1p(math.Exp(-math.Abs(z)))
+}
@@ -0,0 +1,172 @@
+package calibration
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// overconfident returns sigmoid outputs that are too sure of themselves: the true probability of every sample is
+// sigmoid(z) but the model outputs sigmoid(3z)
+func overconfident(samples int) (probabilities, targets *mat.Dense) {
+	random := rand.New(rand.NewSource(1))
+
+	probabilities, targets = mat.NewDense(samples, 1, nil), mat.NewDense(samples, 1, nil)
+	for i := 0; i < samples; i++ {
+		z := random.NormFloat64() * 2
+		probabilities.Set(i, 0, sigmoid(3*z))
+		if random.Float64() < sigmoid(z) {
+			targets.Set(i, 0, 1)
+		}
+	}
+
+	return probabilities, targets
+}
+
+func nll(probabilities, targets mat.Matrix) float64 {
+	rows, cols := probabilities.Dims()
+
+	total := 0.
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			p := min(max(probabilities.At(i, j), clip), 1-clip)
+			total -= targets.At(i, j)*math.Log(p) + (1-targets.At(i, j))*math.Log(1-p)
+		}
+	}
+
+	return total / float64(rows*cols)
+}
+
+func TestCalibrators(t *testing.T) {
+	probabilities, targets := overconfident(5000)
+	before := nll(probabilities, targets)
+
+	for _, calibrator := range []Calibrator{new(TemperatureScaling), new(PlattScaling), new(IsotonicRegression)} {
+		if _, err := calibrator.Transform(probabilities); err == nil {
+			t.Errorf("error: %T: expected an error before fitting", calibrator)
+		}
+		if err := calibrator.Fit(probabilities, targets); err != nil {
+			t.Fatalf("error: %T: %v", calibrator, err)
+		}
+
+		calibrated, err := calibrator.Transform(probabilities)
+		if err != nil {
+			t.Fatalf("error: %T: %v", calibrator, err)
+		}
+		if after := nll(calibrated, targets); after >= before {
+			t.Errorf("error: %T: got a log loss of %f | want less than %f", calibrator, after, before)
+		}
+
+		// the calibration keeps the order of the probabilities
+		for i := 1; i < 50; i++ {
+			if (probabilities.At(i, 0) > probabilities.At(0, 0)) && calibrated.At(i, 0) < calibrated.At(0, 0) {
+				t.Errorf("error: %T: sample %d: the calibration reversed the order of the probabilities", calibrator, i)
+			}
+		}
+
+		// the saved calibrator gives the same probabilities
+		config, err := ConfigOf(calibrator)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Config
+		if err = json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := New(decoded)
+		if err != nil {
+			t.Fatalf("error: %T: %v", calibrator, err)
+		}
+		reloaded, err := loaded.Transform(probabilities)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !mat.EqualApprox(reloaded, calibrated, 1e-12) {
+			t.Errorf("error: %T: the loaded calibrator gives other probabilities", calibrator)
+		}
+	}
+
+	temperature := new(TemperatureScaling)
+	_ = temperature.Fit(probabilities, targets)
+	if temperature.Temperature < 2 || temperature.Temperature > 4 {
+		t.Errorf("error: got a temperature of %f | want about 3", temperature.Temperature)
+	}
+}
+
+func TestSoftmaxCalibration(t *testing.T) {
+	probabilities := mat.NewDense(4, 3, []float64{
+		0.9, 0.05, 0.05,
+		0.8, 0.1, 0.1,
+		0.1, 0.8, 0.1,
+		0.2, 0.2, 0.6,
+	})
+	targets := mat.NewDense(4, 3, []float64{
+		1, 0, 0,
+		0, 1, 0,
+		0, 1, 0,
+		0, 0, 1,
+	})
+
+	for _, calibrator := range []Calibrator{
+		&TemperatureScaling{Outputs: Outputs{Softmax: true}},
+		&PlattScaling{Outputs: Outputs{Softmax: true}},
+		&IsotonicRegression{Outputs: Outputs{Softmax: true}},
+	} {
+		if err := calibrator.Fit(probabilities, targets); err != nil {
+			t.Fatalf("error: %T: %v", calibrator, err)
+		}
+		calibrated, err := calibrator.Transform(probabilities)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 4; i++ {
+			if sum := mat.Sum(calibrated.RowView(i)); math.Abs(sum-1) > 1e-9 {
+				t.Errorf("error: %T: row %d: got a sum of %f | want 1", calibrator, i, sum)
+			}
+		}
+	}
+
+	if _, err := new(PlattScaling).Transform(probabilities); err == nil {
+		t.Error("error: expected an error before fitting")
+	}
+	fitted := &PlattScaling{A: []float64{1}, B: []float64{0}}
+	if _, err := fitted.Transform(probabilities); err == nil {
+		t.Error("error: expected an error for probabilities with another number of outputs")
+	}
+}
+
+func TestIsotonicCurve(t *testing.T) {
+	curve := fitIsotonic([]float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.5}, []float64{0, 1, 0, 1, 1, 0})
+
+	// 0.2 & 0.3 are pooled (1/2), so are 0.4 & the two samples of 0.5 (2/3)
+	want := IsotonicCurve{X: []float64{0.1, 0.2, 0.3, 0.4, 0.5}, Y: []float64{0, 0.5, 0.5, 2. / 3, 2. / 3}}
+	if len(curve.X) != len(want.X) {
+		t.Fatalf("error: got %v | want %v", curve, want)
+	}
+	for i := range want.X {
+		if curve.X[i] != want.X[i] || math.Abs(curve.Y[i]-want.Y[i]) > 1e-12 {
+			t.Fatalf("error: got %v | want %v", curve, want)
+		}
+	}
+
+	for _, test := range []struct{ x, want float64 }{{0, 0}, {0.15, 0.25}, {0.35, 7. / 12}, {0.9, 2. / 3}} {
+		if got := curve.At(test.x); math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("error: at %f: got %f | want %f", test.x, got, test.want)
+		}
+	}
+
+	if _, err := New(Config{Type: "isotonic", Curves: []IsotonicCurve{{X: []float64{0.5, 0.2}, Y: []float64{0, 1}}}}); err == nil {
+		t.Error("error: expected an error for a decreasing curve")
+	}
+	if _, err := New(Config{Type: "beta"}); err == nil {
+		t.Error("error: expected an error for an unknown calibrator")
+	}
+}
@@ -0,0 +1,62 @@
+package calibration
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Config describes a calibrator: its Type when it is created (see Model.Calibrate) & also its fitted parameters
+// when the model is saved
+type Config struct {
+	// Type is one of temperature, platt or isotonic
+	Type    string `json:"type" yaml:"type"`
+	Outputs `yaml:",inline"`
+
+	Temperature float64         `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	A           []float64       `json:"a,omitempty" yaml:"a,omitempty"`
+	B           []float64       `json:"b,omitempty" yaml:"b,omitempty"`
+	Curves      []IsotonicCurve `json:"curves,omitempty" yaml:"curves,omitempty"`
+}
+
+// New builds the calibrator described by config, it is already fitted when config holds fitted parameters
+func New(config Config) (Calibrator, error) {
+	switch config.Type {
+	case "temperature":
+		if config.Temperature < 0 {
+			return nil, fmt.Errorf("temperature calibrator: the temperature can't be negative, got %g", config.Temperature)
+		}
+		return &TemperatureScaling{Outputs: config.Outputs, Temperature: config.Temperature}, nil
+	case "platt":
+		if len(config.A) != len(config.B) {
+			return nil, fmt.Errorf("platt calibrator: got %d slopes for %d intercepts", len(config.A), len(config.B))
+		}
+		return &PlattScaling{Outputs: config.Outputs, A: config.A, B: config.B}, nil
+	case "isotonic":
+		var errs []error
+		for j, curve := range config.Curves {
+			if err := curve.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("isotonic calibrator: curve %d: %v", j, err))
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+		return &IsotonicRegression{Outputs: config.Outputs, Curves: config.Curves}, nil
+	default:
+		return nil, fmt.Errorf("unknown calibrator type %q (expected temperature, platt or isotonic)", config.Type)
+	}
+}
+
+// ConfigOf describes calibrator with its fitted parameters, so it can be saved & rebuilt with New
+func ConfigOf(calibrator Calibrator) (Config, error) {
+	switch c := calibrator.(type) {
+	case *TemperatureScaling:
+		return Config{Type: "temperature", Outputs: c.Outputs, Temperature: c.Temperature}, nil
+	case *PlattScaling:
+		return Config{Type: "platt", Outputs: c.Outputs, A: c.A, B: c.B}, nil
+	case *IsotonicRegression:
+		return Config{Type: "isotonic", Outputs: c.Outputs, Curves: c.Curves}, nil
+	default:
+		return Config{}, fmt.Errorf("can't describe calibrator %T", calibrator)
+	}
+}
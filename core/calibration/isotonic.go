@@ -0,0 +1,123 @@
+package calibration
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// IsotonicRegression fits a non-decreasing step function per output (one-vs-rest for softmax outputs) from the
+// predicted probabilities to the observed frequencies, interpolating linearly between the steps. It makes no
+// assumption on the shape of the miscalibration but needs more validation data than PlattScaling.
+type IsotonicRegression struct {
+	Outputs
+	// Curves holds the fitted function of every output, they are empty until the calibrator is fitted
+	Curves []IsotonicCurve `json:"curves,omitempty"`
+}
+
+// IsotonicCurve maps the probabilities X (increasing) to the calibrated probabilities Y (non-decreasing), values
+// outside of X are clamped to its ends
+type IsotonicCurve struct {
+	X []float64 `json:"x" yaml:"x"`
+	Y []float64 `json:"y" yaml:"y"`
+}
+
+func (regression *IsotonicRegression) Fit(probabilities, targets mat.Matrix) error {
+	if err := checkTargets(probabilities, targets); err != nil {
+		return err
+	}
+
+	_, cols := probabilities.Dims()
+	regression.Curves = make([]IsotonicCurve, cols)
+	for j := range regression.Curves {
+		regression.Curves[j] = fitIsotonic(mat.Col(nil, j, probabilities), mat.Col(nil, j, targets))
+	}
+
+	return nil
+}
+
+func (regression *IsotonicRegression) Transform(probabilities mat.Matrix) (*mat.Dense, error) {
+	rows, cols := probabilities.Dims()
+	if err := checkColumns(len(regression.Curves), cols); err != nil {
+		return nil, err
+	}
+
+	calibrated := mat.NewDense(rows, cols, nil)
+	calibrated.Apply(func(i, j int, p float64) float64 {
+		return regression.Curves[j].At(p)
+	}, probabilities)
+
+	return regression.normalize(calibrated), nil
+}
+
+// At interpolates the curve at x
+func (curve IsotonicCurve) At(x float64) float64 {
+	n := len(curve.X)
+	switch {
+	case n == 0:
+		return x
+	case x <= curve.X[0]:
+		return curve.Y[0]
+	case x >= curve.X[n-1]:
+		return curve.Y[n-1]
+	}
+
+	k := sort.SearchFloat64s(curve.X, x) // curve.X[k-1] < x <= curve.X[k]
+	ratio := (x - curve.X[k-1]) / (curve.X[k] - curve.X[k-1])
+
+	return curve.Y[k-1] + ratio*(curve.Y[k]-curve.Y[k-1])
+}
+
+func (curve IsotonicCurve) validate() error {
+	if len(curve.X) != len(curve.Y) {
+		return fmt.Errorf("got %d x for %d y", len(curve.X), len(curve.Y))
+	}
+	for i := 1; i < len(curve.X); i++ {
+		if curve.X[i] <= curve.X[i-1] || curve.Y[i] < curve.Y[i-1] {
+			return errors.New("x must increase & y mustn't decrease")
+		}
+	}
+
+	return nil
+}
+
+// fitIsotonic finds the non-decreasing fit of the targets ordered by score with the pool adjacent violators
+// algorithm & keeps the ends of every block as the points of the curve
+func fitIsotonic(scores, targets []float64) IsotonicCurve {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+
+	type block struct {
+		low, high, sum, count float64
+	}
+	var blocks []block
+	for _, i := range order {
+		blocks = append(blocks, block{scores[i], scores[i], targets[i], 1})
+
+		// merge with the previous blocks while their mean is above the new one's
+		for len(blocks) > 1 {
+			last, previous := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			if previous.sum/previous.count < last.sum/last.count && previous.high < last.low {
+				break
+			}
+			blocks = blocks[:len(blocks)-1]
+			blocks[len(blocks)-1] = block{previous.low, last.high, previous.sum + last.sum, previous.count + last.count}
+		}
+	}
+
+	var curve IsotonicCurve
+	for _, b := range blocks {
+		mean := b.sum / b.count
+		curve.X, curve.Y = append(curve.X, b.low), append(curve.Y, mean)
+		if b.high > b.low {
+			curve.X, curve.Y = append(curve.X, b.high), append(curve.Y, mean)
+		}
+	}
+
+	return curve
+}
@@ -0,0 +1,117 @@
+package calibration
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// PlattScaling fits a logistic regression sigmoid(A * logit(p) + B) per output (one-vs-rest for softmax outputs) on
+// the validation targets. Unlike TemperatureScaling it can also correct a bias of every output.
+type PlattScaling struct {
+	Outputs
+	// A & B hold the fitted slope & intercept of every output, they are empty until the calibrator is fitted
+	A []float64 `json:"a,omitempty"`
+	B []float64 `json:"b,omitempty"`
+}
+
+func (scaling *PlattScaling) Fit(probabilities, targets mat.Matrix) error {
+	if err := checkTargets(probabilities, targets); err != nil {
+		return err
+	}
+
+	rows, cols := probabilities.Dims()
+	scaling.A, scaling.B = make([]float64, cols), make([]float64, cols)
+	for j := 0; j < cols; j++ {
+		logits, positives := make([]float64, rows), make([]bool, rows)
+		for i := range logits {
+			logits[i], positives[i] = logit(probabilities.At(i, j)), targets.At(i, j) >= 0.5
+		}
+		scaling.A[j], scaling.B[j] = fitPlatt(logits, positives)
+	}
+
+	return nil
+}
+
+func (scaling *PlattScaling) Transform(probabilities mat.Matrix) (*mat.Dense, error) {
+	rows, cols := probabilities.Dims()
+	if err := checkColumns(len(scaling.A), cols); err != nil {
+		return nil, err
+	}
+
+	calibrated := mat.NewDense(rows, cols, nil)
+	calibrated.Apply(func(i, j int, p float64) float64 {
+		return sigmoid(scaling.A[j]*logit(p) + scaling.B[j])
+	}, probabilities)
+
+	return scaling.normalize(calibrated), nil
+}
+
+// fitPlatt minimises the cross-entropy of sigmoid(a * x + b) by Newton's method with a backtracking line search.
+// Like Platt, the targets are smoothed to (positives + 1) / (positives + 2) & 1 / (negatives + 2) so that
+// separable data doesn't send a to infinity.
+func fitPlatt(x []float64, positives []bool) (a, b float64) {
+	count := 0.
+	for _, positive := range positives {
+		if positive {
+			count++
+		}
+	}
+	high, low := (count+1)/(count+2), 1/(float64(len(x))-count+2)
+
+	targets := make([]float64, len(x))
+	for i, positive := range positives {
+		targets[i] = low
+		if positive {
+			targets[i] = high
+		}
+	}
+
+	loss := func(a, b float64) float64 {
+		total := 0.
+		for i, v := range x {
+			z := a*v + b
+			total += targets[i]*softplus(-z) + (1-targets[i])*softplus(z)
+		}
+		return total
+	}
+
+	a, b = 1, 0
+	current := loss(a, b)
+	for iteration := 0; iteration < 100; iteration++ {
+		grad_a, grad_b := 0., 0.
+		// a tiny ridge keeps the hessian invertible, e.g. when every x is the same
+		h_aa, h_ab, h_bb := 1e-12, 0., 1e-12
+		for i, v := range x {
+			p := sigmoid(a*v + b)
+			d, w := p-targets[i], p*(1-p)
+			grad_a += d * v
+			grad_b += d
+			h_aa += w * v * v
+			h_ab += w * v
+			h_bb += w
+		}
+		if math.Abs(grad_a) < 1e-9 && math.Abs(grad_b) < 1e-9 {
+			break
+		}
+
+		det := h_aa*h_bb - h_ab*h_ab
+		step_a, step_b := -(h_bb*grad_a-h_ab*grad_b)/det, -(h_aa*grad_b-h_ab*grad_a)/det
+		slope := grad_a*step_a + grad_b*step_b
+
+		improved := false
+		for step := 1.; step >= 1e-10; step /= 2 {
+			next := loss(a+step*step_a, b+step*step_b)
+			if next <= current+1e-4*step*slope {
+				a, b, current = a+step*step_a, b+step*step_b, next
+				improved = true
+				break
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+
+	return a, b
+}
@@ -0,0 +1,141 @@
+package calibration
+
+import (
+	"errors"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// TemperatureScaling divides the logits behind the probabilities by a single Temperature fit to minimise the
+// negative log-likelihood of the targets: above 1 it softens overconfident models, below 1 it sharpens
+// underconfident ones. It never changes the most probable class.
+type TemperatureScaling struct {
+	Outputs
+	// Temperature is 0 until the calibrator is fitted
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+// the temperatures searched by Fit
+const minTemperature, maxTemperature = 0.05, 20
+
+func (scaling *TemperatureScaling) Fit(probabilities, targets mat.Matrix) error {
+	if err := checkTargets(probabilities, targets); err != nil {
+		return err
+	}
+
+	logits := scaling.logits(probabilities)
+	loss := func(log_temperature float64) float64 {
+		return scaling.nll(logits, targets, math.Exp(log_temperature))
+	}
+
+	// golden-section search of the log of the temperature, the loss being unimodal in it
+	ratio := (math.Sqrt(5) - 1) / 2
+	low, high := math.Log(minTemperature), math.Log(maxTemperature)
+	a, b := high-ratio*(high-low), low+ratio*(high-low)
+	loss_a, loss_b := loss(a), loss(b)
+	for high-low > 1e-6 {
+		if loss_a < loss_b {
+			high, b, loss_b = b, a, loss_a
+			a = high - ratio*(high-low)
+			loss_a = loss(a)
+		} else {
+			low, a, loss_a = a, b, loss_b
+			b = low + ratio*(high-low)
+			loss_b = loss(b)
+		}
+	}
+
+	scaling.Temperature = math.Exp((low + high) / 2)
+
+	return nil
+}
+
+func (scaling *TemperatureScaling) Transform(probabilities mat.Matrix) (*mat.Dense, error) {
+	if scaling.Temperature <= 0 {
+		return nil, errors.New("the calibrator isn't fitted")
+	}
+
+	return scaling.scale(scaling.logits(probabilities), scaling.Temperature), nil
+}
+
+// logits recovers the logits of the probabilities, up to a constant per row for softmax outputs
+func (scaling *TemperatureScaling) logits(probabilities mat.Matrix) *mat.Dense {
+	rows, cols := probabilities.Dims()
+	logits := mat.NewDense(rows, cols, nil)
+	logits.Apply(func(i, j int, p float64) float64 {
+		if scaling.Softmax {
+			return math.Log(max(p, clip))
+		}
+		return logit(p)
+	}, probabilities)
+
+	return logits
+}
+
+// scale turns the logits divided by temperature back into probabilities
+func (scaling *TemperatureScaling) scale(logits *mat.Dense, temperature float64) *mat.Dense {
+	rows, cols := logits.Dims()
+	probabilities := mat.NewDense(rows, cols, nil)
+
+	for i := 0; i < rows; i++ {
+		row, scaled := logits.RawRowView(i), probabilities.RawRowView(i)
+		if !scaling.Softmax {
+			for j, z := range row {
+				scaled[j] = sigmoid(z / temperature)
+			}
+			continue
+		}
+
+		// subtracting the max for numerical stability, like activation.SoftMax
+		largest := math.Inf(-1)
+		for _, z := range row {
+			largest = max(largest, z)
+		}
+		sum := 0.
+		for j, z := range row {
+			scaled[j] = math.Exp((z - largest) / temperature)
+			sum += scaled[j]
+		}
+		for j := range scaled {
+			scaled[j] /= sum
+		}
+	}
+
+	return probabilities
+}
+
+// nll is the mean negative log-likelihood of the targets under the logits divided by temperature
+func (scaling *TemperatureScaling) nll(logits *mat.Dense, targets mat.Matrix, temperature float64) float64 {
+	rows, cols := logits.Dims()
+
+	total := 0.
+	for i := 0; i < rows; i++ {
+		row := logits.RawRowView(i)
+
+		if !scaling.Softmax {
+			for j, z := range row {
+				t := targets.At(i, j)
+				total += t*softplus(-z/temperature) + (1-t)*softplus(z/temperature)
+			}
+			continue
+		}
+
+		// log(sum(exp(z / T))) computed around the max
+		largest := math.Inf(-1)
+		for _, z := range row {
+			largest = max(largest, z/temperature)
+		}
+		sum := 0.
+		for _, z := range row {
+			sum += math.Exp(z/temperature - largest)
+		}
+		log_sum := largest + math.Log(sum)
+
+		for j, z := range row {
+			total -= targets.At(i, j) * (z/temperature - log_sum)
+		}
+	}
+
+	return total / float64(rows*max(cols, 1))
+}
@@ -0,0 +1,90 @@
+package datamodels
+
+import (
+	"fmt"
+	"iter"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Batch holds the samples of one training/evaluation step, W holds the weights of the samples (nil when they all weigh 1)
+type Batch struct {
+	X, Y *mat.Dense
+	W    []float64
+}
+
+// Batcher provides the batches of one pass over a dataset, a batch_size <= 0 asks for a single batch
+// holding every sample. Every call to Batches starts a new pass.
+type Batcher interface {
+	Batches(batch_size int) iter.Seq2[Batch, error]
+}
+
+func (data TrainingData) Batches(batch_size int) iter.Seq2[Batch, error] {
+	return batches(data.X, data.Y, batch_size)
+}
+
+func (data ValidationData) Batches(batch_size int) iter.Seq2[Batch, error] {
+	return batches(data.X, data.Y, batch_size)
+}
+
+// WeightedData is in-memory data with a weight per sample, which scales the sample's loss & gradient
+type WeightedData struct {
+	X, Y    *mat.Dense
+	Weights []float64
+}
+
+func (data WeightedData) Batches(batch_size int) iter.Seq2[Batch, error] {
+	return func(yield func(Batch, error) bool) {
+		if rows, _ := data.X.Dims(); len(data.Weights) != rows {
+			yield(Batch{}, fmt.Errorf("got %d sample weights for %d samples", len(data.Weights), rows))
+			return
+		}
+
+		start := 0
+		for batch, err := range batches(data.X, data.Y, batch_size) {
+			if err == nil {
+				rows, _ := batch.X.Dims()
+				batch.W = data.Weights[start : start+rows]
+				start += rows
+			}
+
+			if !yield(batch, err) {
+				return
+			}
+		}
+	}
+}
+
+// batches slices in-memory data, labels may be a sparse 1 x N row or have a row per sample.
+// Empty data (e.g. ValidationData{}) has no batches.
+func batches(X, y *mat.Dense, batch_size int) iter.Seq2[Batch, error] {
+	return func(yield func(Batch, error) bool) {
+		if X == nil {
+			return
+		}
+
+		rows, cols := X.Dims()
+		if batch_size <= 0 {
+			yield(Batch{X: X, Y: y}, nil)
+			return
+		}
+
+		for start := 0; start < rows; start += batch_size {
+			end := min(start+batch_size, rows)
+
+			batch := Batch{X: mat.DenseCopyOf(X.Slice(start, end, 0, cols))}
+			if y != nil {
+				yRows, yCols := y.Dims()
+				if yRows == 1 && yCols == rows {
+					batch.Y = mat.DenseCopyOf(y.Slice(0, 1, start, end))
+				} else {
+					batch.Y = mat.DenseCopyOf(y.Slice(start, end, 0, yCols))
+				}
+			}
+
+			if !yield(batch, nil) {
+				return
+			}
+		}
+	}
+}
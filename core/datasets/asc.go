@@ -0,0 +1,245 @@
+package datasets
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ASCReader reads the CAN & CAN-FD frames of a Vector ASC trace
+//
+//	date Wed Oct 11 04:00:00.000 pm 2023
+//	base hex  timestamps absolute
+//	Begin Triggerblock Wed Oct 11 04:00:00.000 pm 2023
+//	   0.000000 Start of measurement
+//	   0.001250 1  123             Rx   d 8 01 02 03 04 05 06 07 08
+//	   0.001500 2  1F334455x       Tx   r 4
+//	   0.002000 CANFD   1 Rx        321                                   1 0 9 12 01 02 03 04 05 06 07 08 09 0A 0B 0C
+//	End TriggerBlock
+//
+// Timestamps are in seconds since the start of the measurement & channels are numbered from 1 as in CANoe.
+// Events other than frames (statistics, comments, ...) are skipped, so are error frames unless KeepErrorFrames is set.
+type ASCReader struct {
+	KeepErrorFrames bool
+
+	scanner  *bufio.Scanner
+	line     int
+	base     int
+	relative bool
+	clock    float64
+}
+
+func NewASCReader(file io.Reader) *ASCReader {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	return &ASCReader{scanner: scanner, base: 16}
+}
+
+func (reader *ASCReader) Next() (Frame, error) {
+	for reader.scanner.Scan() {
+		reader.line++
+
+		fields := strings.Fields(reader.scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "//") {
+			continue
+		}
+
+		if fields[0] == "base" {
+			reader.header(fields)
+			continue
+		}
+
+		timestamp, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue // date, Begin Triggerblock, ... lines
+		}
+		if reader.relative {
+			reader.clock += timestamp
+			timestamp = reader.clock
+		}
+
+		frame, ok, err := reader.parse(fields[1:])
+		if err != nil {
+			return Frame{}, fmt.Errorf("line %d: %v", reader.line, err)
+		}
+		if !ok || (frame.ErrorFrame && !reader.KeepErrorFrames) {
+			continue
+		}
+
+		frame.Timestamp = timestamp
+		return frame, nil
+	}
+
+	if err := reader.scanner.Err(); err != nil {
+		return Frame{}, err
+	}
+
+	return Frame{}, io.EOF
+}
+
+func (reader *ASCReader) keepErrorFrames() bool {
+	kept := reader.KeepErrorFrames
+	reader.KeepErrorFrames = true
+
+	return kept
+}
+
+// header reads the `base hex|dec  timestamps absolute|relative` line
+func (reader *ASCReader) header(fields []string) {
+	for i := 0; i+1 < len(fields); i += 2 {
+		switch fields[i] {
+		case "base":
+			if fields[i+1] == "dec" {
+				reader.base = 10
+			} else {
+				reader.base = 16
+			}
+		case "timestamps":
+			reader.relative = fields[i+1] == "relative"
+		}
+	}
+}
+
+// parse reads a frame event after its timestamp, ok is false for events that aren't frames
+func (reader *ASCReader) parse(fields []string) (frame Frame, ok bool, err error) {
+	if len(fields) < 2 {
+		return frame, false, nil
+	}
+
+	if fields[0] == "CANFD" {
+		return reader.parseFD(fields[1:])
+	}
+
+	if _, err := strconv.Atoi(fields[0]); err != nil {
+		return frame, false, nil // not a channel, e.g. "Start of measurement"
+	}
+	frame.Channel = fields[0]
+
+	if strings.EqualFold(fields[1], "ErrorFrame") {
+		frame.ErrorFrame = true
+		return frame, true, nil
+	}
+
+	// <id> Rx|Tx d <dlc> <bytes> or <id> Rx|Tx r [<dlc>], anything else is a status or statistic event
+	if len(fields) < 4 || (fields[2] != "Rx" && fields[2] != "Tx") {
+		return frame, false, nil
+	}
+
+	if err := reader.parseID(fields[1], &frame); err != nil {
+		return frame, false, err
+	}
+
+	switch fields[3] {
+	case "r":
+		frame.Remote = true
+		if len(fields) > 4 {
+			if frame.DLC, err = reader.parseDLC(fields[4]); err != nil {
+				return frame, false, err
+			}
+		}
+		return frame, true, nil
+
+	case "d":
+		if len(fields) < 5 {
+			return frame, false, errors.New("data frame without a dlc")
+		}
+		if frame.DLC, err = reader.parseDLC(fields[4]); err != nil {
+			return frame, false, err
+		}
+
+		frame.Data, err = reader.parseData(fields[5:], min(frame.DLC, 8))
+		return frame, err == nil, err
+
+	default:
+		return frame, false, nil
+	}
+}
+
+// parseFD reads `<channel> Rx|Tx <id> [<name>] <brs> <esi> <dlc> <length> <bytes>`
+func (reader *ASCReader) parseFD(fields []string) (frame Frame, ok bool, err error) {
+	if len(fields) < 3 {
+		return frame, false, errors.New("truncated CAN-FD event")
+	}
+	frame.Channel = fields[0]
+	frame.FD = true
+
+	if strings.EqualFold(fields[2], "ErrorFrame") {
+		frame.ErrorFrame = true
+		return frame, true, nil
+	}
+
+	if err := reader.parseID(fields[2], &frame); err != nil {
+		return frame, false, err
+	}
+
+	rest := fields[3:]
+	if len(rest) > 0 && rest[0] != "0" && rest[0] != "1" {
+		rest = rest[1:] // symbolic name
+	}
+	if len(rest) < 4 {
+		return frame, false, errors.New("truncated CAN-FD frame")
+	}
+
+	length, err := strconv.Atoi(rest[3])
+	if err != nil || length < 0 || length > 64 {
+		return frame, false, fmt.Errorf("invalid data length %q", rest[3])
+	}
+	frame.DLC = length
+
+	// a remote CAN-FD event has the dlc of a classic remote frame & no data
+	if length == 0 && rest[2] != "0" {
+		dlc, err := strconv.ParseUint(rest[2], 16, 4)
+		if err != nil {
+			return frame, false, fmt.Errorf("invalid dlc %q", rest[2])
+		}
+		frame.FD, frame.Remote, frame.DLC = false, true, int(dlc)
+		return frame, true, nil
+	}
+
+	frame.Data, err = reader.parseData(rest[4:], length)
+	return frame, err == nil, err
+}
+
+// parseID reads an id in the trace's base, extended ids end with 'x'
+func (reader *ASCReader) parseID(value string, frame *Frame) error {
+	extended := strings.HasSuffix(value, "x") || strings.HasSuffix(value, "X")
+	id, err := strconv.ParseUint(strings.TrimRight(value, "xX"), reader.base, 32)
+	if err != nil || id > canIDMask {
+		return fmt.Errorf("invalid id %q", value)
+	}
+
+	frame.ID = uint32(id)
+	frame.Extended = extended || id > maxStandardID
+
+	return nil
+}
+
+func (reader *ASCReader) parseDLC(value string) (int, error) {
+	dlc, err := strconv.ParseUint(value, 16, 4)
+	if err != nil {
+		return 0, fmt.Errorf("invalid dlc %q", value)
+	}
+
+	return int(dlc), nil
+}
+
+func (reader *ASCReader) parseData(fields []string, size int) ([]byte, error) {
+	if len(fields) < size {
+		return nil, fmt.Errorf("expected %d data bytes, got %d", size, len(fields))
+	}
+
+	data := make([]byte, size)
+	for i := range data {
+		b, err := strconv.ParseUint(fields[i], reader.base, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data byte %q", fields[i])
+		}
+		data[i] = byte(b)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,363 @@
+package datasets
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// BLF object types read by BLFReader, see Vector's binlog_objects.h
+const (
+	blfCANMessage   = 1
+	blfCANError     = 2
+	blfLogContainer = 10
+	blfCANErrorExt  = 73
+	blfCANMessage2  = 86
+	blfCANFDMessage = 100
+	blfCANFDMsg64   = 101
+)
+
+const (
+	blfFileHeaderSize = 72 // the fields of the LOGG header we read, the header itself is usually 144 bytes
+	blfObjHeaderSize  = 16 // signature, header size, header version, object size, object type
+
+	blfTimeTenMicros = 0x1 // object timestamps in 10µs units
+	blfTimeOneNanos  = 0x2 // object timestamps in ns
+
+	blfNoCompression = 0
+	blfZlibDeflate   = 2
+
+	blfExtendedID = 0x80000000
+)
+
+// canFDLengths maps a CAN-FD dlc to its number of data bytes
+var canFDLengths = [16]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 12, 16, 20, 24, 32, 48, 64}
+
+// BLFReader reads the CAN & CAN-FD frames of a Vector binary logging file (.blf).
+// The file is streamed: log containers are decompressed one at a time as the frames are read.
+// Timestamps are in seconds since the start of the measurement & channels are numbered from 1 as in CANoe.
+// Objects other than frames are skipped, so are error frames unless KeepErrorFrames is set.
+type BLFReader struct {
+	KeepErrorFrames bool
+
+	objects *bufio.Reader // the objects of every container, one after the other
+	object  int
+}
+
+// NewBLFReader reads the LOGG header of file, the frames are read by Next
+func NewBLFReader(file io.Reader) (*BLFReader, error) {
+	header := make([]byte, blfFileHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, fmt.Errorf("reading the blf header: %v", err)
+	}
+	if string(header[:4]) != "LOGG" {
+		return nil, errors.New("not a blf file, missing the LOGG signature")
+	}
+
+	headerSize := binary.LittleEndian.Uint32(header[4:8])
+	if headerSize < blfFileHeaderSize {
+		return nil, fmt.Errorf("invalid blf header size %d", headerSize)
+	}
+	if _, err := io.CopyN(io.Discard, file, int64(headerSize-blfFileHeaderSize)); err != nil {
+		return nil, fmt.Errorf("reading the blf header: %v", err)
+	}
+
+	containers := &blfContainers{file: bufio.NewReader(file)}
+
+	return &BLFReader{objects: bufio.NewReaderSize(containers, 64*1024)}, nil
+}
+
+func (reader *BLFReader) Next() (Frame, error) {
+	for {
+		objectType, header, body, err := readBLFObject(reader.objects)
+		if err != nil {
+			if err != io.EOF {
+				err = fmt.Errorf("object %d: %v", reader.object, err)
+			}
+			return Frame{}, err
+		}
+		reader.object++
+
+		frame, ok, err := parseBLFObject(objectType, header, body)
+		if err != nil {
+			return Frame{}, fmt.Errorf("object %d: %v", reader.object-1, err)
+		}
+		if !ok || (frame.ErrorFrame && !reader.KeepErrorFrames) {
+			continue
+		}
+
+		return frame, nil
+	}
+}
+
+func (reader *BLFReader) keepErrorFrames() bool {
+	kept := reader.KeepErrorFrames
+	reader.KeepErrorFrames = true
+
+	return kept
+}
+
+// readBLFObject reads the next LOBJ object, header holds what follows the base header up to the object's data
+func readBLFObject(reader *bufio.Reader) (objectType uint32, header, body []byte, err error) {
+	if err = skipBLFPadding(reader); err != nil {
+		return 0, nil, nil, err
+	}
+
+	base := make([]byte, blfObjHeaderSize)
+	if _, err = io.ReadFull(reader, base); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errors.New("truncated object header")
+		}
+		return 0, nil, nil, err
+	}
+	if string(base[:4]) != "LOBJ" {
+		return 0, nil, nil, errors.New("missing the LOBJ signature")
+	}
+
+	headerSize := int(binary.LittleEndian.Uint16(base[4:6]))
+	objectSize := int(binary.LittleEndian.Uint32(base[8:12]))
+	objectType = binary.LittleEndian.Uint32(base[12:16])
+	if headerSize < blfObjHeaderSize || objectSize < headerSize {
+		return 0, nil, nil, fmt.Errorf("invalid object sizes (header %d, object %d)", headerSize, objectSize)
+	}
+
+	data := make([]byte, objectSize-blfObjHeaderSize)
+	if _, err = io.ReadFull(reader, data); err != nil {
+		return 0, nil, nil, errors.New("truncated object")
+	}
+
+	return objectType, data[:headerSize-blfObjHeaderSize], data[headerSize-blfObjHeaderSize:], nil
+}
+
+// skipBLFPadding skips the (up to 7) zero bytes writers align objects with, io.EOF if there is no object left
+func skipBLFPadding(reader *bufio.Reader) error {
+	for i := 0; ; i++ {
+		next, err := reader.Peek(4)
+		if bytes.Equal(next, []byte("LOBJ")) {
+			return nil
+		}
+		if err != nil {
+			if len(next) == 0 || bytes.Count(next, []byte{0}) == len(next) {
+				return io.EOF
+			}
+			return errors.New("truncated object header")
+		}
+		if i == 8 || next[0] != 0 {
+			return errors.New("missing the LOBJ signature")
+		}
+		reader.Discard(1)
+	}
+}
+
+// parseBLFObject reads the frame in a CAN object, ok is false for objects of other types
+func parseBLFObject(objectType uint32, header, body []byte) (frame Frame, ok bool, err error) {
+	switch objectType {
+	case blfCANMessage, blfCANMessage2, blfCANFDMessage, blfCANFDMsg64, blfCANError, blfCANErrorExt:
+	default:
+		return frame, false, nil
+	}
+
+	if frame.Timestamp, err = blfTimestamp(header); err != nil {
+		return frame, false, err
+	}
+
+	le := binary.LittleEndian
+
+	switch objectType {
+	case blfCANMessage, blfCANMessage2:
+		// channel u16, flags u8, dlc u8, id u32, data [8]byte
+		if len(body) < 16 {
+			return frame, false, errors.New("truncated CAN message")
+		}
+		frame.Channel = strconv.Itoa(int(le.Uint16(body[0:2])))
+		frame.Remote = body[2]&0x80 != 0
+		frame.DLC = int(body[3] & 0x0F)
+		setBLFID(&frame, le.Uint32(body[4:8]))
+		if !frame.Remote {
+			frame.Data = bytes.Clone(body[8 : 8+min(frame.DLC, 8)])
+		}
+
+	case blfCANFDMessage:
+		// channel u16, flags u8, dlc u8, id u32, frame length u32, bit count u8, fd flags u8, valid bytes u8,
+		// 5 reserved bytes, data [64]byte
+		if len(body) < 20 {
+			return frame, false, errors.New("truncated CAN-FD message")
+		}
+		frame.Channel = strconv.Itoa(int(le.Uint16(body[0:2])))
+		frame.Remote = body[2]&0x80 != 0
+		frame.FD = body[13]&0x1 != 0
+		setBLFID(&frame, le.Uint32(body[4:8]))
+
+		length := int(body[14])
+		if !frame.FD {
+			length = min(length, 8)
+		}
+		if len(body) < 20+length {
+			return frame, false, errors.New("truncated CAN-FD message")
+		}
+		frame.DLC = length
+		if frame.Remote {
+			frame.DLC = int(body[3] & 0x0F)
+		} else {
+			frame.Data = bytes.Clone(body[20 : 20+length])
+		}
+
+	case blfCANFDMsg64:
+		// channel u8, dlc u8, valid bytes u8, tx count u8, id u32, frame length u32, flags u32, 2 bit timing
+		// configs u32, 2 time offsets u32, bit count u16, direction u8, ext data offset u8, crc u32, data
+		if len(body) < 40 {
+			return frame, false, errors.New("truncated CAN-FD message")
+		}
+		frame.Channel = strconv.Itoa(int(body[0]))
+		flags := le.Uint32(body[12:16])
+		frame.Remote = flags&0x0010 != 0
+		frame.FD = flags&0x1000 != 0
+		setBLFID(&frame, le.Uint32(body[4:8]))
+
+		// like CANoe, data missing from the object is read as zeros
+		length := int(body[2])
+		frame.DLC = length
+		if frame.Remote {
+			frame.DLC = canFDLengths[body[1]&0x0F]
+		} else {
+			frame.Data = make([]byte, length)
+			copy(frame.Data, body[40:])
+		}
+
+	case blfCANError, blfCANErrorExt:
+		if len(body) < 2 {
+			return frame, false, errors.New("truncated CAN error")
+		}
+		frame.Channel = strconv.Itoa(int(le.Uint16(body[0:2])))
+		frame.ErrorFrame = true
+	}
+
+	return frame, true, nil
+}
+
+func setBLFID(frame *Frame, id uint32) {
+	frame.Extended = id&blfExtendedID != 0
+	frame.ID = id & canIDMask
+}
+
+// blfTimestamp reads the timestamp of a v1 object header (flags u32, client index u16, object version u16,
+// timestamp u64) or a v2 one (flags u32, timestamp status u8, reserved u8, object version u16, timestamp u64,
+// original timestamp u64)
+func blfTimestamp(header []byte) (float64, error) {
+	if len(header) != 16 && len(header) != 24 {
+		return 0, fmt.Errorf("unsupported object header of %d bytes", len(header)+blfObjHeaderSize)
+	}
+	flags, timestamp := binary.LittleEndian.Uint32(header[0:4]), binary.LittleEndian.Uint64(header[8:16])
+
+	switch {
+	case flags&blfTimeOneNanos != 0:
+		return float64(timestamp) / 1e9, nil
+	case flags&blfTimeTenMicros != 0:
+		return float64(timestamp) / 1e5, nil
+	default:
+		return 0, fmt.Errorf("unknown timestamp unit (flags %#x)", flags)
+	}
+}
+
+// blfContainers reads the top-level objects of a blf file as one stream of objects: the content of log
+// containers is decompressed on the fly while objects stored outside of a container are passed as they are.
+// Objects may be split across consecutive containers.
+type blfContainers struct {
+	file    *bufio.Reader
+	current io.Reader // the data of the container being read
+	closer  io.Closer
+}
+
+func (containers *blfContainers) Read(p []byte) (int, error) {
+	for {
+		if containers.current != nil {
+			n, err := containers.current.Read(p)
+			if err == io.EOF {
+				if err = containers.close(); err != nil {
+					return n, err
+				}
+				if n > 0 {
+					return n, nil
+				}
+				continue
+			}
+			return n, err
+		}
+
+		if err := containers.next(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// next opens the next top-level object
+func (containers *blfContainers) next() error {
+	if err := skipBLFPadding(containers.file); err != nil {
+		return err
+	}
+
+	base, err := containers.file.Peek(blfObjHeaderSize)
+	if err != nil {
+		return errors.New("truncated object header")
+	}
+	objectSize := int64(binary.LittleEndian.Uint32(base[8:12]))
+	objectType := binary.LittleEndian.Uint32(base[12:16])
+	if objectSize < blfObjHeaderSize {
+		return fmt.Errorf("invalid object size %d", objectSize)
+	}
+
+	if objectType != blfLogContainer {
+		containers.current = io.LimitReader(containers.file, objectSize)
+		return nil
+	}
+
+	// compression method u16, 6 reserved bytes, uncompressed size u32, 4 reserved bytes
+	header := make([]byte, blfObjHeaderSize+16)
+	if _, err := io.ReadFull(containers.file, header); err != nil || objectSize < int64(len(header)) {
+		return errors.New("truncated log container")
+	}
+	data := io.LimitReader(containers.file, objectSize-int64(len(header)))
+
+	switch method := binary.LittleEndian.Uint16(header[16:18]); method {
+	case blfNoCompression:
+		containers.current = data
+	case blfZlibDeflate:
+		inflater, err := zlib.NewReader(data)
+		if err != nil {
+			return fmt.Errorf("log container: %v", err)
+		}
+		containers.current, containers.closer = &remainder{inflater, data}, inflater
+	default:
+		return fmt.Errorf("unsupported log container compression %d", method)
+	}
+
+	return nil
+}
+
+// close finishes the current container, skipping what the decompressor left of it
+func (containers *blfContainers) close() error {
+	if r, ok := containers.current.(*remainder); ok {
+		if _, err := io.Copy(io.Discard, r.rest); err != nil {
+			return err
+		}
+	}
+	if containers.closer != nil {
+		if err := containers.closer.Close(); err != nil {
+			return fmt.Errorf("log container: %v", err)
+		}
+	}
+
+	containers.current, containers.closer = nil, nil
+	return nil
+}
+
+// remainder is a decompressed stream along with the compressed data it reads from
+type remainder struct {
+	io.Reader
+	rest io.Reader
+}
@@ -0,0 +1,275 @@
+package datasets
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	canErrorFlag = 0x20000000 // CAN_ERR_FLAG, marks error frames
+	canIDMask    = 0x1FFFFFFF // CAN_EFF_MASK
+)
+
+type CandumpOptions struct {
+	// KeepErrorFrames yields error frames too, they are skipped by default as they aren't traffic of an ECU
+	KeepErrorFrames bool
+
+	// DeltaTimestamps tells the timestamps are the time since the previous frame (candump -td)
+	DeltaTimestamps bool
+}
+
+// CandumpReader reads the frames logged by SocketCAN's candump, both the log format written by `candump -l`
+//
+//	(1697040000.123456) can0 123#DEADBEEF
+//	(1697040000.123501) can0 1F334455#R
+//	(1697040000.123610) can1 123##1112233445566778899AABB
+//
+// and the ascii output of candump, with or without timestamps
+//
+//	(1697040000.123456)  can0  123   [4]  DE AD BE EF
+//	(2023-10-11 16:00:00.123456)  can0  123  [12]  11 22 33 44 55 66 77 88 99 AA BB CC
+//	  can0  123   [4]  remote request
+//
+// An optional number after a log-format frame is taken as its label.
+type CandumpReader struct {
+	scanner *bufio.Scanner
+	options CandumpOptions
+	line    int
+	clock   float64
+}
+
+func NewCandumpReader(file io.Reader, options CandumpOptions) *CandumpReader {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	return &CandumpReader{scanner: scanner, options: options}
+}
+
+func (reader *CandumpReader) Next() (Frame, error) {
+	for reader.scanner.Scan() {
+		reader.line++
+
+		text := strings.TrimSpace(reader.scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		frame, err := reader.parse(text)
+		if err != nil {
+			return Frame{}, fmt.Errorf("line %d: %v", reader.line, err)
+		}
+		if frame.ErrorFrame && !reader.options.KeepErrorFrames {
+			continue
+		}
+
+		return frame, nil
+	}
+
+	if err := reader.scanner.Err(); err != nil {
+		return Frame{}, err
+	}
+
+	return Frame{}, io.EOF
+}
+
+func (reader *CandumpReader) parse(text string) (Frame, error) {
+	var frame Frame
+
+	if strings.HasPrefix(text, "(") {
+		end := strings.IndexByte(text, ')')
+		if end < 0 {
+			return frame, errors.New("unterminated timestamp")
+		}
+
+		timestamp, err := parseCandumpTimestamp(text[1:end])
+		if err != nil {
+			return frame, err
+		}
+		if reader.options.DeltaTimestamps {
+			reader.clock += timestamp
+			timestamp = reader.clock
+		}
+		frame.Timestamp = timestamp
+		text = text[end+1:]
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return frame, fmt.Errorf("expected an interface & a frame, got %q", text)
+	}
+	frame.Channel = fields[0]
+
+	if strings.Contains(fields[1], "#") {
+		if err := parseCompactFrame(fields[1], &frame); err != nil {
+			return frame, err
+		}
+
+		if len(fields) > 2 {
+			label, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return frame, fmt.Errorf("invalid label %q", fields[2])
+			}
+			frame.Label, frame.Labelled = label, true
+		}
+
+		return frame, nil
+	}
+
+	return frame, parseASCIIFrame(fields[1:], &frame)
+}
+
+func (reader *CandumpReader) keepErrorFrames() bool {
+	kept := reader.options.KeepErrorFrames
+	reader.options.KeepErrorFrames = true
+
+	return kept
+}
+
+func parseCandumpTimestamp(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+
+	// candump -tA prints the local date & time
+	if strings.Contains(value, "-") && strings.Contains(value, ":") {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05.999999", value, time.Local)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp: %v", err)
+		}
+		return float64(t.UnixNano()) / 1e9, nil
+	}
+
+	timestamp, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp: %v", err)
+	}
+
+	return timestamp, nil
+}
+
+// parseCandumpID reads a hex id, 8 digit ids are extended & may carry the error flag
+func parseCandumpID(value string, frame *Frame) error {
+	id, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return fmt.Errorf("invalid id %q", value)
+	}
+
+	frame.Extended = len(value) == 8
+	frame.ErrorFrame = id&canErrorFlag != 0
+	frame.ID = uint32(id) & canIDMask
+
+	return nil
+}
+
+// parseCompactFrame reads the <id>#<data> notation of candump -l & cansend
+func parseCompactFrame(value string, frame *Frame) error {
+	sep := strings.IndexByte(value, '#')
+	if err := parseCandumpID(value[:sep], frame); err != nil {
+		return err
+	}
+	payload := value[sep+1:]
+
+	switch {
+	case strings.HasPrefix(payload, "##"):
+		return errors.New("CAN XL frames are not supported")
+
+	case strings.HasPrefix(payload, "#"): // CAN-FD: a flags nibble (BRS/ESI) then the data
+		if len(payload) < 2 {
+			return errors.New("CAN-FD frame without flags")
+		}
+		frame.FD = true
+
+		data, err := parseHexBytes(strings.ReplaceAll(payload[2:], ".", ""))
+		if err != nil {
+			return err
+		}
+		frame.Data, frame.DLC = data, len(data)
+
+	case strings.HasPrefix(payload, "R") || strings.HasPrefix(payload, "r"):
+		frame.Remote = true
+		if len(payload) > 1 {
+			dlc, err := strconv.ParseUint(payload[1:], 16, 4)
+			if err != nil {
+				return fmt.Errorf("invalid remote frame length %q", payload[1:])
+			}
+			frame.DLC = int(dlc)
+		}
+
+	default:
+		// classic frames may end with _<dlc> when the DLC is above 8 (len8_dlc)
+		dlc := -1
+		if idx := strings.IndexByte(payload, '_'); idx >= 0 {
+			value, err := strconv.ParseUint(payload[idx+1:], 16, 4)
+			if err != nil {
+				return fmt.Errorf("invalid dlc %q", payload[idx+1:])
+			}
+			dlc, payload = int(value), payload[:idx]
+		}
+
+		data, err := parseHexBytes(strings.ReplaceAll(payload, ".", ""))
+		if err != nil {
+			return err
+		}
+		if len(data) > 8 {
+			return fmt.Errorf("classic CAN frame with %d data bytes", len(data))
+		}
+
+		frame.Data, frame.DLC = data, len(data)
+		if dlc >= 0 {
+			frame.DLC = dlc
+		}
+	}
+
+	return nil
+}
+
+// parseASCIIFrame reads the `<id> [<len>] <bytes>` output of candump, the length of CAN-FD frames has 2 digits
+func parseASCIIFrame(fields []string, frame *Frame) error {
+	// candump -x adds the direction, BRS & ESI flags before the id
+	if len(fields) > 3 && (fields[0] == "TX" || fields[0] == "RX") {
+		fields = fields[3:]
+	}
+	if len(fields) < 2 {
+		return errors.New("expected an id & a length")
+	}
+
+	if err := parseCandumpID(fields[0], frame); err != nil {
+		return err
+	}
+
+	length := fields[1]
+	if !strings.HasPrefix(length, "[") || !strings.HasSuffix(length, "]") {
+		return fmt.Errorf("invalid length %q", length)
+	}
+	length = strings.Trim(length, "[]")
+
+	size, err := strconv.Atoi(length)
+	if err != nil || size < 0 || size > 64 {
+		return fmt.Errorf("invalid length %q", fields[1])
+	}
+	frame.DLC = size
+	frame.FD = len(length) == 2
+
+	rest := fields[2:]
+	if len(rest) >= 2 && rest[0] == "remote" && rest[1] == "request" {
+		frame.Remote = true
+		return nil
+	}
+	if len(rest) < size {
+		return fmt.Errorf("expected %d data bytes, got %d", size, len(rest))
+	}
+
+	frame.Data = make([]byte, size)
+	for i := 0; i < size; i++ {
+		b, err := strconv.ParseUint(rest[i], 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid data byte %q", rest[i])
+		}
+		frame.Data[i] = byte(b)
+	}
+
+	return nil
+}
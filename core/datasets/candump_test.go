@@ -0,0 +1,258 @@
+package datasets
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readCandump(t *testing.T, log string, options CandumpOptions) []Frame {
+	t.Helper()
+
+	reader := NewCandumpReader(strings.NewReader(log), options)
+
+	var frames []Frame
+	for {
+		frame, err := reader.Next()
+		if err == io.EOF {
+			return frames
+		}
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		frames = append(frames, frame)
+	}
+}
+
+func TestCandumpLogFormat(t *testing.T) {
+	log := "(1697040000.100000) can0 123#DEADBEEF\n" +
+		"(1697040000.100500) can0 1F334455#1122.3344\n" +
+		"(1697040000.101000) can0 0C4#R\n" +
+		"(1697040000.101500) can0 0C4#R4\n" +
+		"(1697040000.102000) can1 321##311223344556677889900AABB\n" +
+		"(1697040000.102500) can0 20000080#0000000000000000\n" +
+		"(1697040000.103000) can0 7FF#0102030405060708_C\n" +
+		"(1697040000.103500) can0 100#AA 1\n"
+
+	frames := readCandump(t, log, CandumpOptions{})
+	if len(frames) != 7 {
+		t.Fatalf("error: got %d frames | want 7 (the error frame is skipped)", len(frames))
+	}
+
+	if f := frames[0]; f.ID != 0x123 || f.Extended || f.DLC != 4 || fmt.Sprintf("%X", f.Data) != "DEADBEEF" || f.Channel != "can0" || f.Timestamp != 1697040000.1 {
+		t.Errorf("error: got %+v for a classic frame", f)
+	}
+	if f := frames[1]; f.ID != 0x1F334455 || !f.Extended || fmt.Sprintf("%X", f.Data) != "11223344" {
+		t.Errorf("error: got %+v for an extended frame", f)
+	}
+	if f := frames[2]; !f.Remote || f.DLC != 0 || len(f.Data) != 0 {
+		t.Errorf("error: got %+v for a remote frame", f)
+	}
+	if f := frames[3]; !f.Remote || f.DLC != 4 {
+		t.Errorf("error: got %+v for a remote frame with a length", f)
+	}
+	if f := frames[4]; !f.FD || f.DLC != 12 || f.Channel != "can1" || fmt.Sprintf("%X", f.Data) != "11223344556677889900AABB" {
+		t.Errorf("error: got %+v for a CAN-FD frame", f)
+	}
+	if f := frames[5]; f.DLC != 12 || len(f.Data) != 8 {
+		t.Errorf("error: got %+v for a frame with len8_dlc", f)
+	}
+	if f := frames[6]; !f.Labelled || f.Label != 1 {
+		t.Errorf("error: got %+v for a labelled frame", f)
+	}
+
+	frames = readCandump(t, log, CandumpOptions{KeepErrorFrames: true})
+	if f := frames[5]; !f.ErrorFrame || f.ID != 0x80 {
+		t.Errorf("error: got %+v for an error frame", f)
+	}
+
+	x, y, err := ReadFrames(NewCandumpReader(strings.NewReader(log), CandumpOptions{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0x1F334455, 0x11, 0x22, 0x33, 0x44, 0, 0, 0, 0, 0.0005}
+	for i := range want {
+		if diff := x[1][i] - want[i]; diff > 1e-6 || diff < -1e-6 {
+			t.Errorf("error: got features %v | want %v", x[1], want)
+			break
+		}
+	}
+	if fmt.Sprint(y) != "[0 0 0 0 0 0 1]" {
+		t.Errorf("error: got labels %v", y)
+	}
+}
+
+func TestCandumpASCIIFormat(t *testing.T) {
+	log := " (000.000000)  can0  123   [4]  DE AD BE EF\n" +
+		" (000.000250)  can0  12345678   [2]  01 02   '..'\n" +
+		" (000.000250)  can0  456   [3]  remote request\n" +
+		" (000.000500)  can1  789  [12]  01 02 03 04 05 06 07 08 09 0A 0B 0C\n" +
+		"  can0  TX B -  0AB  [02]  FF 00\n"
+
+	frames := readCandump(t, log, CandumpOptions{DeltaTimestamps: true})
+	if len(frames) != 5 {
+		t.Fatalf("error: got %d frames | want 5", len(frames))
+	}
+
+	if f := frames[1]; f.ID != 0x12345678 || !f.Extended || f.DLC != 2 || f.Timestamp != 0.00025 {
+		t.Errorf("error: got %+v", f)
+	}
+	if f := frames[2]; !f.Remote || f.DLC != 3 || f.Timestamp != 0.0005 {
+		t.Errorf("error: got %+v for a remote frame", f)
+	}
+	if f := frames[3]; !f.FD || f.DLC != 12 || f.Data[11] != 0x0C {
+		t.Errorf("error: got %+v for a CAN-FD frame", f)
+	}
+	if f := frames[4]; f.ID != 0xAB || !f.FD || fmt.Sprintf("%X", f.Data) != "FF00" {
+		t.Errorf("error: got %+v for a frame logged with -x", f)
+	}
+
+	if _, err := NewCandumpReader(strings.NewReader("(1.0) can0 123#XYZ\n"), CandumpOptions{}).Next(); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("error: got %v | want an error on line 1", err)
+	}
+}
+
+func TestCandumpSidecarLabels(t *testing.T) {
+	dir := t.TempDir()
+
+	log := "(10.0) can0 100#01\n(10.5) can0 200#02\n(11.0) can0 100#03\n(12.0) can0 300#04\n"
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("labelled.log", log)
+	write("labelled.labels", "# a label per frame\n0\n1\n\n0\n1\n")
+	write("intervals.log", log)
+	write("intervals.intervals", "start,end,label,ids\n10.4,11.5,2,100 200\n11.9,12.1,3\n")
+
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"labelled.log", "[0 1 0 1]"},
+		{"intervals.log", "[0 2 2 3]"},
+	} {
+		_, y, err := ReadCANPath(filepath.Join(dir, tc.name), nil)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if fmt.Sprint(y) != tc.want {
+			t.Errorf("error: %s got labels %v | want %s", tc.name, y, tc.want)
+		}
+	}
+
+	// a label file shorter than the capture is an error
+	write("short.log", log)
+	write("short.labels", "0\n1\n")
+	if _, _, err := ReadCANPath(filepath.Join(dir, "short.log"), nil); err == nil {
+		t.Errorf("error: expected an error for a label file with too few labels")
+	}
+
+	// skipping a malformed frame doesn't shift the labels of the next ones
+	reader := WithLabels(NewCandumpReader(strings.NewReader("(10.0) can0 100#01\n(10.5) can0 #zz\n(11.0) can0 100#03\n"), CandumpOptions{}), SidecarLabels{0, 1, 2})
+	var labels []float64
+	for {
+		frame, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		labels = append(labels, frame.Label)
+	}
+	if fmt.Sprint(labels) != "[0 2]" {
+		t.Errorf("error: got labels %v | want [0 2]", labels)
+	}
+
+	// an error frame takes its label before it's dropped
+	write("errors.log", "(9.5) can0 20000080#0000000000000000\n"+log)
+	write("errors.labels", "9\n0\n1\n0\n1\n")
+	_, y, err := ReadCANPath(filepath.Join(dir, "errors.log"), nil)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if fmt.Sprint(y) != "[0 1 0 1]" {
+		t.Errorf("error: got labels %v after an error frame | want [0 1 0 1]", y)
+	}
+}
+
+func TestMultiLabelCaptures(t *testing.T) {
+	dir := t.TempDir()
+
+	log := "(10.0) can0 100#01\n(10.5) can0 200#02\n(11.0) can0 100#03\n(12.0) can0 300#04\n"
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("multi.log", log)
+	write("multi.multilabels", "0\n1 2\n\n2,3\n0 3\n")
+	write("overlapping.log", log)
+	write("overlapping.intervals", "start,end,label\n10.4,11.5,1\n10.9,12.1,3\n")
+
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"multi.log", "[1 0 0 0 0 1 1 0 0 0 1 1 0 0 0 1]"},
+		{"overlapping.log", "[1 0 0 0 0 1 0 0 0 1 0 1 0 0 0 1]"},
+	} {
+		data, err := LoadMultiLabelDatasetFrom(filepath.Join(dir, tc.name), nil, 4, false)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if rows, cols := data.Y.Dims(); rows != 4 || cols != 4 {
+			t.Fatalf("error: %s got %dx%d targets | want 4x4", tc.name, rows, cols)
+		}
+		if fmt.Sprint(data.Y.RawMatrix().Data) != tc.want {
+			t.Errorf("error: %s got targets %v | want %s", tc.name, data.Y.RawMatrix().Data, tc.want)
+		}
+	}
+
+	// single labels still read as the first attack class of the frame
+	if _, y, err := ReadCANPath(filepath.Join(dir, "multi.log"), nil); err != nil || fmt.Sprint(y) != "[0 1 2 3]" {
+		t.Errorf("error: got labels %v (%v) | want [0 1 2 3]", y, err)
+	}
+
+	if _, err := LoadMultiLabelDatasetFrom(filepath.Join(dir, "multi.log"), nil, 3, false); err == nil {
+		t.Errorf("error: expected an error for a class out of range")
+	}
+}
+
+func TestReadTimedFrames(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.log": "(10.0) can0 100#01\n(10.5) can0 200#02\n",
+		"b.log": "(3.0) can0 100#03\n(3.25) can0 300#04\n(4.0) can0 300#04\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	frames, err := ReadTimedFrames(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(frames.Timestamps) != "[10 10.5 3 3.25 4]" || fmt.Sprint(frames.Captures) != "[0 0 1 1 1]" {
+		t.Errorf("error: got timestamps %v & captures %v", frames.Timestamps, frames.Captures)
+	}
+	if len(frames.Files) != 2 || filepath.Base(frames.Files[1]) != "b.log" {
+		t.Errorf("error: got files %v", frames.Files)
+	}
+
+	data := frames.Data()
+	if rows, cols := data.X.Dims(); rows != 5 || cols != DefaultCANSchema().NumFeatures() {
+		t.Errorf("error: got %dx%d features", rows, cols)
+	}
+	if rows, cols := data.Y.Dims(); rows != 1 || cols != 5 {
+		t.Errorf("error: got %dx%d labels | want 1x5", rows, cols)
+	}
+}
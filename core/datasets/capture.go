@@ -0,0 +1,102 @@
+package datasets
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// captureFormat picks the reader of a capture from its extension, "" if it isn't a capture
+func captureFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".log", ".candump":
+		return "candump"
+	case ".asc":
+		return "asc"
+	case ".blf":
+		return "blf"
+	default:
+		return ""
+	}
+}
+
+func isCapture(path string) bool {
+	return captureFormat(path) != ""
+}
+
+// openCapture opens a capture file with the reader matching its extension, csv files being read with schema.
+// The frames are labelled from a sidecar file when there is one (see SidecarLabelsFor), then by the class of
+// their folder under the dataset root when the schema has a label map.
+func openCapture(root, path string, schema *CANSchema) (FrameReader, io.Closer, error) {
+	if schema == nil {
+		schema = DefaultCANSchema()
+	}
+
+	labeler, err := SidecarLabelsFor(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var folder ClassFolder
+	if schema.Classes != nil {
+		if folder, err = schema.Classes.ClassOf(root, path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reader FrameReader
+	switch captureFormat(path) {
+	case "csv":
+		reader, err = schema.NewReader(file)
+	case "candump":
+		reader = NewCandumpReader(file, CandumpOptions{})
+	case "asc":
+		reader = NewASCReader(file)
+	case "blf":
+		reader, err = NewBLFReader(file)
+	default:
+		err = fmt.Errorf("unknown capture format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if labeler != nil {
+		reader = WithLabels(reader, labeler)
+	}
+	if schema.Classes != nil {
+		reader = WithLabels(reader, folderLabels{schema.Classes, folder})
+	}
+
+	return reader, file, nil
+}
+
+// SidecarLabelsFor looks for the labels of a capture next to it: <name>.labels with a label per frame
+// (see LoadSidecarLabels), <name>.multilabels with the classes of each frame (see LoadSidecarMultiLabels)
+// or <name>.intervals with an interval list (see LoadLabelIntervals).
+// It returns nil if the capture has neither.
+func SidecarLabelsFor(path string) (FrameLabeler, error) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+
+	if _, err := os.Stat(base + ".labels"); err == nil {
+		return LoadSidecarLabels(base + ".labels")
+	}
+	if _, err := os.Stat(base + ".multilabels"); err == nil {
+		return LoadSidecarMultiLabels(base + ".multilabels")
+	}
+	if _, err := os.Stat(base + ".intervals"); err == nil {
+		return LoadLabelIntervals(base + ".intervals")
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,210 @@
+package datasets
+
+import (
+	"iter"
+	"math/rand"
+	"time"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+)
+
+const defaultShuffleBuffer = 10000
+
+type DataLoaderOptions struct {
+	// BatchSize overrides the batch size asked for by the model when > 0
+	BatchSize int
+
+	// Shuffle reorders the samples every pass. A Dataset is fully shuffled, an IterableDataset is shuffled
+	// through a buffer of ShuffleBuffer samples (10000 by default): larger buffers mix more at the cost of memory.
+	Shuffle       bool
+	ShuffleBuffer int
+
+	// Prefetch is the number of batches assembled ahead on a separate goroutine while the model trains, 0 disables it
+	Prefetch int
+
+	// DropLast skips the final batch when it has fewer than BatchSize samples, Pad instead fills it up by
+	// repeating its own samples so every batch has the same size
+	DropLast bool
+	Pad      bool
+
+	// Seed makes shuffling reproducible, 0 seeds from the clock
+	Seed int64
+}
+
+// DataLoader turns a Dataset or an IterableDataset into batches for Model.Train & Model.Evaluate,
+// only holding the shuffle buffer & the prefetched batches in memory
+type DataLoader struct {
+	samples func(rng *rand.Rand) iter.Seq2[Sample, error]
+	options DataLoaderOptions
+	rng     *rand.Rand
+}
+
+func NewDataLoader(dataset Dataset, options DataLoaderOptions) *DataLoader {
+	loader := newDataLoader(options)
+	loader.samples = func(rng *rand.Rand) iter.Seq2[Sample, error] {
+		return func(yield func(Sample, error) bool) {
+			order := make([]int, dataset.Len())
+			for i := range order {
+				order[i] = i
+			}
+			if options.Shuffle {
+				rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+			}
+
+			for _, idx := range order {
+				sample, err := dataset.Get(idx)
+				if !yield(sample, err) || err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	return loader
+}
+
+func NewStreamingDataLoader(dataset IterableDataset, options DataLoaderOptions) *DataLoader {
+	loader := newDataLoader(options)
+	loader.samples = func(rng *rand.Rand) iter.Seq2[Sample, error] {
+		if !options.Shuffle {
+			return dataset.Samples()
+		}
+
+		buffer_size := options.ShuffleBuffer
+		if buffer_size <= 0 {
+			buffer_size = defaultShuffleBuffer
+		}
+
+		return shuffleBuffer(dataset.Samples(), buffer_size, rng)
+	}
+
+	return loader
+}
+
+func newDataLoader(options DataLoaderOptions) *DataLoader {
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &DataLoader{options: options, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Batches implements datamodels.Batcher. With no batch size at all every sample ends up in a single batch.
+func (loader *DataLoader) Batches(batch_size int) iter.Seq2[datamodels.Batch, error] {
+	if loader.options.BatchSize > 0 {
+		batch_size = loader.options.BatchSize
+	}
+
+	batches := loader.batches(loader.samples(loader.rng), batch_size)
+	if loader.options.Prefetch <= 0 {
+		return batches
+	}
+
+	return prefetch(batches, loader.options.Prefetch)
+}
+
+func (loader *DataLoader) batches(samples iter.Seq2[Sample, error], batch_size int) iter.Seq2[datamodels.Batch, error] {
+	return func(yield func(datamodels.Batch, error) bool) {
+		var pending []Sample
+
+		emit := func() bool {
+			batch, err := stackSamples(pending)
+			pending = pending[:0]
+
+			return yield(batch, err) && err == nil
+		}
+
+		for sample, err := range samples {
+			if err != nil {
+				yield(datamodels.Batch{}, err)
+				return
+			}
+
+			pending = append(pending, sample)
+			if batch_size > 0 && len(pending) == batch_size && !emit() {
+				return
+			}
+		}
+
+		if len(pending) == 0 || (batch_size > 0 && loader.options.DropLast) {
+			return
+		}
+		if batch_size > 0 && loader.options.Pad {
+			for i := 0; len(pending) < batch_size; i++ {
+				pending = append(pending, pending[i])
+			}
+		}
+
+		emit()
+	}
+}
+
+// shuffleBuffer keeps buffer_size samples & yields a random one of them each time a new sample comes in
+func shuffleBuffer(samples iter.Seq2[Sample, error], buffer_size int, rng *rand.Rand) iter.Seq2[Sample, error] {
+	return func(yield func(Sample, error) bool) {
+		buffer := make([]Sample, 0, buffer_size)
+
+		for sample, err := range samples {
+			if err != nil {
+				yield(Sample{}, err)
+				return
+			}
+
+			if len(buffer) < buffer_size {
+				buffer = append(buffer, sample)
+				continue
+			}
+
+			idx := rng.Intn(buffer_size)
+			if !yield(buffer[idx], nil) {
+				return
+			}
+			buffer[idx] = sample
+		}
+
+		rng.Shuffle(len(buffer), func(i, j int) { buffer[i], buffer[j] = buffer[j], buffer[i] })
+		for _, sample := range buffer {
+			if !yield(sample, nil) {
+				return
+			}
+		}
+	}
+}
+
+// prefetch assembles up to n batches ahead on a goroutine, which is stopped (and waited for) as soon as the consumer stops
+func prefetch(batches iter.Seq2[datamodels.Batch, error], n int) iter.Seq2[datamodels.Batch, error] {
+	type result struct {
+		batch datamodels.Batch
+		err   error
+	}
+
+	return func(yield func(datamodels.Batch, error) bool) {
+		results := make(chan result, n)
+		done := make(chan struct{})
+		finished := make(chan struct{})
+		defer func() {
+			close(done)
+			<-finished
+		}()
+
+		go func() {
+			defer close(finished)
+			defer close(results)
+
+			for batch, err := range batches {
+				select {
+				case results <- result{batch, err}:
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		for r := range results {
+			if !yield(r.batch, r.err) || r.err != nil {
+				return
+			}
+		}
+	}
+}
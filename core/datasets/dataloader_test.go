@@ -0,0 +1,128 @@
+package datasets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func batchSizes(t *testing.T, loader *DataLoader, batch_size int) ([]int, []float64) {
+	t.Helper()
+
+	var sizes []int
+	var labels []float64
+	for batch, err := range loader.Batches(batch_size) {
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		sizes = append(sizes, batch.X.RawMatrix().Rows)
+		labels = append(labels, mat.Col(nil, 0, batch.Y)...)
+	}
+
+	return sizes, labels
+}
+
+func TestDataLoaderBatches(t *testing.T) {
+	X := mat.NewDense(10, 2, nil)
+	y := mat.NewDense(1, 10, nil)
+	for i := 0; i < 10; i++ {
+		X.SetRow(i, []float64{float64(i), float64(-i)})
+		y.Set(0, i, float64(i))
+	}
+	dataset := NewMatrixDataset(X, y)
+
+	for _, tc := range []struct {
+		options DataLoaderOptions
+		want    []int
+	}{
+		{DataLoaderOptions{}, []int{4, 4, 2}},
+		{DataLoaderOptions{DropLast: true}, []int{4, 4}},
+		{DataLoaderOptions{Pad: true}, []int{4, 4, 4}},
+		{DataLoaderOptions{BatchSize: 5, Prefetch: 2}, []int{5, 5}},
+	} {
+		sizes, _ := batchSizes(t, NewDataLoader(dataset, tc.options), 4)
+		if fmt.Sprint(sizes) != fmt.Sprint(tc.want) {
+			t.Errorf("error: %+v got batches %v | want %v", tc.options, sizes, tc.want)
+		}
+	}
+
+	// shuffling must keep every sample exactly once & still pair features with their labels
+	loader := NewDataLoader(dataset, DataLoaderOptions{Shuffle: true, Seed: 7})
+	seen := map[float64]bool{}
+	for batch, err := range loader.Batches(3) {
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		for i := 0; i < batch.X.RawMatrix().Rows; i++ {
+			if batch.X.At(i, 0) != batch.Y.At(i, 0) {
+				t.Errorf("error: sample %f got label %f", batch.X.At(i, 0), batch.Y.At(i, 0))
+			}
+			seen[batch.Y.At(i, 0)] = true
+		}
+	}
+	if len(seen) != 10 {
+		t.Errorf("error: got %d distinct samples | want 10", len(seen))
+	}
+}
+
+func TestStreamingDataLoader(t *testing.T) {
+	dir := t.TempDir()
+
+	for f := 0; f < 2; f++ {
+		var builder strings.Builder
+		builder.WriteString("timestamp,arbitration_id,data_field,attack\n")
+		for i := 0; i < 25; i++ {
+			fmt.Fprintf(&builder, "%f,%X,0102030405060708,%d\n", float64(i)*0.5, f*100+i, i%2)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("capture_%d.csv", f)), []byte(builder.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dataset, err := NewCSVDataset(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the streamed frames must match what ReadCANPath loads into memory
+	x, _, err := ReadCANPath(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := 0
+	for sample, err := range dataset.Samples() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(sample.X) != fmt.Sprint(x[i]) {
+			t.Errorf("error: frame %d got %v | want %v", i, sample.X, x[i])
+		}
+		i++
+	}
+	if i != 50 {
+		t.Errorf("error: got %d frames | want 50", i)
+	}
+
+	loader := NewStreamingDataLoader(dataset, DataLoaderOptions{Shuffle: true, ShuffleBuffer: 8, Prefetch: 2, Seed: 1})
+	sizes, labels := batchSizes(t, loader, 16)
+	if fmt.Sprint(sizes) != "[16 16 16 2]" {
+		t.Errorf("error: got batches %v | want [16 16 16 2]", sizes)
+	}
+
+	attacks := 0.
+	for _, label := range labels {
+		attacks += label
+	}
+	if attacks != 24 {
+		t.Errorf("error: got %f attack frames | want 24", attacks)
+	}
+
+	// stopping early must not leave the prefetching goroutine behind
+	for range loader.Batches(4) {
+		break
+	}
+}
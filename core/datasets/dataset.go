@@ -0,0 +1,265 @@
+package datasets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Sample is a single example: its features & its target(s)
+type Sample struct {
+	X []float64
+	Y []float64
+}
+
+// Dataset gives random access to its samples, e.g. data already held in memory
+type Dataset interface {
+	Len() int
+	Get(i int) (Sample, error)
+}
+
+// IterableDataset can only be read in order, e.g. files streamed from disk.
+// Every call to Samples starts a new pass over the data.
+type IterableDataset interface {
+	Samples() iter.Seq2[Sample, error]
+}
+
+// MatrixDataset exposes in-memory data as a Dataset, the labels may be a sparse 1 x N row or have a row per sample
+type MatrixDataset struct {
+	X, Y *mat.Dense
+}
+
+func NewMatrixDataset(X, y *mat.Dense) *MatrixDataset {
+	return &MatrixDataset{X: X, Y: y}
+}
+
+func (dataset *MatrixDataset) Len() int {
+	return dataset.X.RawMatrix().Rows
+}
+
+func (dataset *MatrixDataset) Get(i int) (Sample, error) {
+	rows, _ := dataset.X.Dims()
+	if i < 0 || i >= rows {
+		return Sample{}, fmt.Errorf("sample %d out of range for %d samples", i, rows)
+	}
+
+	sample := Sample{X: mat.Row(nil, i, dataset.X)}
+	if dataset.Y != nil {
+		yRows, yCols := dataset.Y.Dims()
+		if yRows == 1 && yCols == rows {
+			sample.Y = []float64{dataset.Y.At(0, i)}
+		} else {
+			sample.Y = mat.Row(nil, i, dataset.Y)
+		}
+	}
+
+	return sample, nil
+}
+
+// Samples reads the samples in order, so in-memory data can also feed an IterableDataset (e.g. a WindowDataset)
+func (dataset *MatrixDataset) Samples() iter.Seq2[Sample, error] {
+	return func(yield func(Sample, error) bool) {
+		for i := 0; i < dataset.Len(); i++ {
+			sample, err := dataset.Get(i)
+			if !yield(sample, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// FirstSamples stacks the features of the first n samples of dataset (all of them when n <= 0), e.g. to fit a
+// scaler on data streamed from disk
+func FirstSamples(dataset IterableDataset, n int) (*mat.Dense, error) {
+	var rows []float64
+	count, cols := 0, 0
+
+	for sample, err := range dataset.Samples() {
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			cols = len(sample.X)
+		} else if len(sample.X) != cols {
+			return nil, fmt.Errorf("sample %d has %d features, expected %d", count, len(sample.X), cols)
+		}
+
+		rows = append(rows, sample.X...)
+		if count++; n > 0 && count == n {
+			break
+		}
+	}
+
+	if count == 0 {
+		return nil, errors.New("the dataset has no samples")
+	}
+
+	return mat.NewDense(count, cols, rows), nil
+}
+
+// ReadAll loads every sample of dataset into memory, with a row of features & a row of targets per sample, e.g. to
+// train on the multi-hot targets of a CSVDataset without streaming it
+func ReadAll(dataset IterableDataset) (X, y *mat.Dense, err error) {
+	var samples []Sample
+	for sample, err := range dataset.Samples() {
+		if err != nil {
+			return nil, nil, err
+		}
+		samples = append(samples, sample)
+	}
+	if len(samples) == 0 {
+		return nil, nil, errors.New("the dataset has no samples")
+	}
+
+	batch, err := stackSamples(samples)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return batch.X, batch.Y, nil
+}
+
+// CSVDataset streams the frames of CAN captures (csv files or candump logs) one file at a time instead of loading
+// them into memory. Each sample has the features described by the schema & the label as its target.
+type CSVDataset struct {
+	Files  []string
+	Schema *CANSchema
+	// Root is the folder the files were collected from, the classes of a label map are only read from its folders
+	Root string
+
+	// MultiLabel, when positive, is the number of classes of multi-hot targets: each sample then has a column
+	// per class, set for every class of the frame (see MultiHot & MultiLabeler)
+	MultiLabel int
+}
+
+// NewCSVDataset collects the captures found at path: a single file, a folder of captures or a folder of class folders.
+// A nil schema reads them with DefaultCANSchema.
+func NewCSVDataset(path string, schema *CANSchema) (*CSVDataset, error) {
+	if schema == nil {
+		schema = DefaultCANSchema()
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return &CSVDataset{Files: []string{path}, Schema: schema, Root: filepath.Dir(path)}, nil
+	}
+
+	var files []string
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && isCapture(file) {
+			files = append(files, file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking through directory: %v", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no captures found in %s", path)
+	}
+	sort.Strings(files)
+
+	return &CSVDataset{Files: files, Schema: schema, Root: path}, nil
+}
+
+func (dataset *CSVDataset) Samples() iter.Seq2[Sample, error] {
+	return func(yield func(Sample, error) bool) {
+		for _, path := range dataset.Files {
+			if !dataset.readFile(path, yield) {
+				return
+			}
+		}
+	}
+}
+
+// Captures splits the dataset into a dataset per capture file
+func (dataset *CSVDataset) Captures() []IterableDataset {
+	captures := make([]IterableDataset, len(dataset.Files))
+	for i, file := range dataset.Files {
+		captures[i] = &CSVDataset{Files: []string{file}, Schema: dataset.Schema, Root: dataset.Root, MultiLabel: dataset.MultiLabel}
+	}
+
+	return captures
+}
+
+// readFile yields the frames of one file, returning false once the consumer stops or an error was yielded
+func (dataset *CSVDataset) readFile(path string, yield func(Sample, error) bool) bool {
+	reader, file, err := openCapture(dataset.Root, path, dataset.Schema)
+	if err != nil {
+		yield(Sample{}, err)
+		return false
+	}
+	defer file.Close()
+
+	extractor := dataset.Schema.NewFeatureExtractor()
+	for {
+		frame, err := reader.Next()
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			yield(Sample{}, fmt.Errorf("%s: %v", path, err))
+			return false
+		}
+
+		target, err := dataset.target(frame)
+		if err != nil {
+			yield(Sample{}, fmt.Errorf("%s: %v", path, err))
+			return false
+		}
+
+		if !yield(Sample{X: extractor.Update(frame), Y: target}, nil) {
+			return false
+		}
+	}
+}
+
+func (dataset *CSVDataset) target(frame Frame) ([]float64, error) {
+	if dataset.MultiLabel <= 0 {
+		return []float64{frame.Label}, nil
+	}
+	if frame.Labels != nil {
+		return MultiHot(frame.Labels, dataset.MultiLabel)
+	}
+
+	return MultiHot([]float64{frame.Label}, dataset.MultiLabel)
+}
+
+// stackSamples builds a batch with a row per sample
+func stackSamples(samples []Sample) (datamodels.Batch, error) {
+	features, targets := len(samples[0].X), len(samples[0].Y)
+
+	X := mat.NewDense(len(samples), features, nil)
+	var y *mat.Dense
+	if targets > 0 {
+		y = mat.NewDense(len(samples), targets, nil)
+	}
+
+	for i, sample := range samples {
+		if len(sample.X) != features || len(sample.Y) != targets {
+			return datamodels.Batch{}, fmt.Errorf("sample has %d features & %d targets, the batch expects %d & %d", len(sample.X), len(sample.Y), features, targets)
+		}
+
+		X.SetRow(i, sample.X)
+		if y != nil {
+			y.SetRow(i, sample.Y)
+		}
+	}
+
+	return datamodels.Batch{X: X, Y: y}, nil
+}
@@ -1,55 +1,34 @@
 package datasets
 
 import (
-	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"math"
 	"os"
 	"path/filepath"
-	"strconv"
 
 	"github.com/saent-x/ids-nn/core"
 	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/resampling"
 	"github.com/saent-x/ids-nn/core/scaling"
 	"gonum.org/v1/gonum/mat"
 )
 
+// LoadCANDataset loads the captures in core/datasets/temp & holds out a stratified 20% of every class for validation
 func LoadCANDataset(shuffle bool) (datamodels.TrainingData, datamodels.ValidationData) {
-	x, y, err := ReadCAN_Folder("../../core/datasets/temp")
+	data, err := LoadCANDatasetFrom("../../core/datasets/temp", nil, shuffle)
 	if err != nil {
 		panic(err)
 	}
 
-	//x, y, err = Oversample(x, y)
-	//if err != nil {
-	//	panic(err)
-	//}
-
-	// Convert data to mat.Dense
-	X_mat := mat.NewDense(len(x), len(x[0]), nil)
-	Y_mat := mat.NewDense(1, len(y), nil)
-
-	if shuffle {
-		shuffledIdxs := core.ShuffleSlice(core.GetRange(len(x)))
-		for i := 0; i < X_mat.RawMatrix().Rows; i++ {
-			idx := shuffledIdxs[i]
-			X_mat.SetRow(idx, x[i])
-			Y_mat.Set(0, idx, y[i])
-		}
-	} else {
-		for i := 0; i < X_mat.RawMatrix().Rows; i++ {
-			X_mat.SetRow(i, x[i])
-			Y_mat.Set(0, i, y[i])
-		}
+	holdout, err := StratifiedHoldout(ClassLabels(data.Y), 0.2, 0, 1)
+	if err != nil {
+		panic(err)
 	}
-
-	training_data := datamodels.TrainingData{
-		X: X_mat,
-		Y: Y_mat,
+	if len(holdout.Unsplit) > 0 {
+		fmt.Printf("warning: classes %v are too small to be held out for validation\n", holdout.Unsplit)
 	}
+	training_data, validation := Subset(data, holdout.Train), Subset(data, holdout.Validation)
 
 	// get validation file
 	//x_test, y_test, err := ReadCAN_Folder("../../core/datasets/can-testing-full-001")
@@ -91,135 +70,171 @@ func LoadCANDataset(shuffle bool) (datamodels.TrainingData, datamodels.Validatio
 	// testing_data.Y.Copy(scaledXtest)
 
 	// save training data to file
-	core.SaveMatrixToCSV(training_data, "triple.csv")
+	core.SaveMatrixToCSV(training_data, DefaultCANSchema().CSVHeader(), "triple.csv")
 
-	return training_data, datamodels.ValidationData{}
+	return training_data, datamodels.ValidationData{X: validation.X, Y: validation.Y}
 }
 
-// Oversample oversamples the attack frames to match the number of normal frames while respecting time intervals
-func Oversample(x [][]float64, y []float64) ([][]float64, []float64, error) {
-	if len(x) != len(y) {
-		return nil, nil, errors.New("error: x & y must have same nos of rows! ")
+// LoadCANDatasetFrom reads the CAN frames found at path (see ReadCANPath) into a training set with the labels as a 1 x N row
+func LoadCANDatasetFrom(path string, schema *CANSchema, shuffle bool) (datamodels.TrainingData, error) {
+	x, y, err := ReadCANPath(path, schema)
+	if err != nil {
+		return datamodels.TrainingData{}, err
+	}
+	if len(x) == 0 {
+		return datamodels.TrainingData{}, fmt.Errorf("no CAN frames found in %s", path)
 	}
 
-	var normalFrames, attackFrames [][]float64
-	var y_normalFrames, y_attackFrames []float64
+	//x, y, err = Oversample(x, y)
+	//if err != nil {
+	//	panic(err)
+	//}
+
+	// Convert data to mat.Dense
+	X_mat := mat.NewDense(len(x), len(x[0]), nil)
+	Y_mat := mat.NewDense(1, len(y), nil)
 
-	for idx, row := range x {
-		if y[idx] == 1 {
-			attackFrames = append(attackFrames, row)
-			y_attackFrames = append(y_attackFrames, y[idx])
-		} else {
-			normalFrames = append(normalFrames, row)
-			y_normalFrames = append(y_normalFrames, y[idx])
+	if shuffle {
+		shuffledIdxs := core.ShuffleSlice(core.GetRange(len(x)))
+		for i := 0; i < X_mat.RawMatrix().Rows; i++ {
+			idx := shuffledIdxs[i]
+			X_mat.SetRow(idx, x[i])
+			Y_mat.Set(0, idx, y[i])
+		}
+	} else {
+		for i := 0; i < X_mat.RawMatrix().Rows; i++ {
+			X_mat.SetRow(i, x[i])
+			Y_mat.Set(0, i, y[i])
 		}
 	}
 
-	// Calculate how many attack frames need to be added
-	attackCount := len(attackFrames)
-	normalCount := len(normalFrames)
+	return datamodels.TrainingData{
+		X: X_mat,
+		Y: Y_mat,
+	}, nil
+}
+
+// LoadMultiLabelDatasetFrom loads the captures at path like LoadCANDatasetFrom but with multi-hot targets, a row
+// per frame & a column per class (see CSVDataset.MultiLabel), for models predicting concurrent attacks
+func LoadMultiLabelDatasetFrom(path string, schema *CANSchema, classes int, shuffle bool) (datamodels.TrainingData, error) {
+	dataset, err := NewCSVDataset(path, schema)
+	if err != nil {
+		return datamodels.TrainingData{}, err
+	}
+	dataset.MultiLabel = classes
 
-	if attackCount == 0 {
-		fmt.Println("No attack frames found. Dataset cannot be oversampled.")
-		return nil, nil, nil
+	X, y, err := ReadAll(dataset)
+	if err != nil {
+		return datamodels.TrainingData{}, fmt.Errorf("%s: %v", path, err)
 	}
 
-	if attackCount >= normalCount {
-		fmt.Println("The number of attack frames is already equal or greater than normal frames.")
-		return nil, nil, nil
+	if shuffle {
+		X_mat, Y_mat := mat.DenseCopyOf(X), mat.DenseCopyOf(y)
+		for i, idx := range core.ShuffleSlice(core.GetRange(X.RawMatrix().Rows)) {
+			X_mat.SetRow(idx, X.RawRowView(i))
+			Y_mat.SetRow(idx, y.RawRowView(i))
+		}
+		X, y = X_mat, Y_mat
 	}
 
-	// Oversample attack frames while keeping time intervals realistic
-	newAttackFrames := make([][]float64, 0, normalCount)
-	newYFrames := make([]float64, 0, attackCount)
+	return datamodels.TrainingData{
+		X: X,
+		Y: y,
+	}, nil
+}
 
-	for len(newAttackFrames) < normalCount {
-		// For each duplicate, we will add a small delta to the time interval to simulate new attack frames
-		for i := 0; i < attackCount && len(newAttackFrames) < normalCount; i++ {
-			var duplicateFrame []float64
+// ReadCANPath reads CAN frames from a single capture, a folder of captures or a folder of class folders (see ReadCAN_Folder).
+// csv captures are laid out as described by schema (DefaultCANSchema if nil), which also picks the extracted features.
+func ReadCANPath(path string, schema *CANSchema) ([][]float64, []float64, error) {
+	if schema == nil {
+		schema = DefaultCANSchema()
+	}
 
-			originalFrame := attackFrames[i]
-			duplicateFrame = append(duplicateFrame, originalFrame...)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
 
-			// Adjust the time interval of the duplicated frame slightly to preserve temporal structure
-			timeDelta := math.Min(0.01, originalFrame[9]/10) // Add 1% to 10% of the original time interval
-			duplicateFrame[9] += timeDelta
+	if !info.IsDir() {
+		return readCaptureFile(filepath.Dir(path), path, schema)
+	}
 
-			newAttackFrames = append(newAttackFrames, duplicateFrame)
-			newYFrames = append(newYFrames, 1)
-		}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Combine the normal and oversampled attack frames
-	balancedDataset := append(normalFrames, newAttackFrames...)
-	balancedYFrames := append(y_normalFrames, newYFrames...)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			return readCANFolder(path, schema)
+		}
+	}
 
-	return balancedDataset, balancedYFrames, nil
+	return readCSVFolder(path, path, schema)
 }
 
-func ScaleValues(matrix *mat.Dense) error {
-	_, cols := matrix.Dims()
+// ReadCANCaptures reads the frames at path like ReadCANPath & also returns the index in CSVDataset.Files of the
+// capture each frame comes from, e.g. to keep the frames of a drive in the same fold (see GroupKFold)
+func ReadCANCaptures(path string, schema *CANSchema) ([][]float64, []float64, []int, error) {
+	dataset, err := NewCSVDataset(path, schema)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
-	for i := 0; i < cols; i++ {
-		col := matrix.ColView(i)
+	var data [][]float64
+	var labels []float64
+	var captures []int
 
-		//var scaledCol []float64
-		colSlice := core.VectorToSlice(col)
-		scaledCol, err := scaling.RobustScale(colSlice)
+	for i, file := range dataset.Files {
+		x, y, err := readCaptureFile(dataset.Root, file, dataset.Schema)
 		if err != nil {
-			return err
+			return nil, nil, nil, err
 		}
 
-		//for j := 0; j < col.Len(); j++ {
-		//	scaledValue, err := scaling.Scale(scaling.NEG_ONE_TO_POS_ONE, col.AtVec(j), maxValue)
-		//	if err != nil {
-		//		return err
-		//	}
-		//	scaledCol = append(scaledCol, scaledValue)
-		//}
-
-		matrix.SetCol(i, scaledCol)
+		data = append(data, x...)
+		labels = append(labels, y...)
+		for range x {
+			captures = append(captures, i)
+		}
 	}
 
-	return nil
+	return data, labels, captures, nil
 }
 
-func checkLabel(folder string) (float64, error) {
-	// TODO: needs to be updated to reflect actual classes after tests
-	//switch folder {
-	//case "attack-free":
-	//	return 0., nil
-	//case "combined-attacks":
-	//	return 1., nil
-	//case "DoS-attacks":
-	//	return 2., nil
-	//case "fuzzing-attacks":
-	//	return 3., nil
-	//case "gear-attacks":
-	//	return 4., nil
-	//case "interval-attacks":
-	//	return 5., nil
-	//case "rpm-attacks":
-	//	return 6., nil
-	//case "speed-attacks":
-	//	return 7., nil
-	//case "standstill-attacks":
-	//	return 8., nil
-	//case "systematic-attacks":
-	//	return 9., nil
-	//default:
-	//	return 0, errors.New("invalid folder!")
-	//}
+// Oversample duplicates random rows of every class until each has as many rows as the largest one, the duplicates
+// following the original rows. It is kept for the older scripts, see the resampling package for SMOTE, ADASYN,
+// undersampling & per-class targets.
+func Oversample(x [][]float64, y []float64) ([][]float64, []float64, error) {
+	return resampling.RandomOversample(x, y, resampling.Options{})
+}
 
-	switch folder {
-	case "attack-free":
-		return 0., nil
-	default:
-		return 1, errors.New("invalid folder!")
+// ScaleValues robust-scales every column of matrix in place with the column's own statistics, it is only fit for
+// data scaled as a whole once. Data seen later (e.g. at inference) should be scaled with the statistics of the
+// training data instead, which a scaling.Scaler saved with the model does.
+func ScaleValues(matrix *mat.Dense) error {
+	scaler := new(scaling.RobustScaler)
+	if err := scaler.Fit(matrix); err != nil {
+		return err
 	}
+
+	scaled, err := scaler.Transform(matrix)
+	if err != nil {
+		return err
+	}
+	matrix.Copy(scaled)
+
+	return nil
 }
 
+// ReadCAN_Folder reads a folder of class folders, labelled with DefaultLabelMap
 func ReadCAN_Folder(folderPath string) ([][]float64, []float64, error) {
+	schema := DefaultCANSchema()
+	schema.Classes = DefaultLabelMap()
+
+	return readCANFolder(folderPath, schema)
+}
+
+func readCANFolder(folderPath string, schema *CANSchema) ([][]float64, []float64, error) {
 	var allData [][]float64
 	var allAttackValues []float64
 
@@ -231,13 +246,8 @@ func ReadCAN_Folder(folderPath string) ([][]float64, []float64, error) {
 	for _, entry := range entries {
 		if entry.IsDir() {
 			dataPath := filepath.Join(folderPath, entry.Name())
-			//label, err := checkLabel(entry.Name()) // TBD: ignoring this for now, since we're using only two labels
-
-			if err != nil {
-				return nil, nil, err
-			}
 
-			x, y, err1 := ReadCSVFolder(dataPath, 0)
+			x, y, err1 := readCSVFolder(folderPath, dataPath, schema)
 			if err1 != nil {
 				return nil, nil, err1
 			}
@@ -251,6 +261,11 @@ func ReadCAN_Folder(folderPath string) ([][]float64, []float64, error) {
 }
 
 func ReadCSVFolder(folderPath string, label float64) ([][]float64, []float64, error) {
+	return readCSVFolder(folderPath, folderPath, DefaultCANSchema())
+}
+
+// readCSVFolder reads the captures of a folder of the dataset at root
+func readCSVFolder(root, folderPath string, schema *CANSchema) ([][]float64, []float64, error) {
 	var allData [][]float64
 	var allAttackValues []float64
 
@@ -261,10 +276,10 @@ func ReadCSVFolder(folderPath string, label float64) ([][]float64, []float64, er
 			return err
 		}
 
-		// Check if it's a CSV file
-		if !info.IsDir() && filepath.Ext(path) == ".csv" {
+		// Check if it's a capture (csv or candump log)
+		if !info.IsDir() && isCapture(path) {
 			// Read the CSV file
-			data, attackValues, err := ReadCSV(path, label)
+			data, attackValues, err := readCaptureFile(root, path, schema)
 			if err != nil {
 				return fmt.Errorf("error reading file %s: %v", path, err)
 			}
@@ -284,124 +299,43 @@ func ReadCSVFolder(folderPath string, label float64) ([][]float64, []float64, er
 	return allData, allAttackValues, nil
 }
 
-func ReadCSV(filepath string, label float64) ([][]float64, []float64, error) {
-	file, err := os.Open(filepath)
+func ReadCSV(path string, label float64) ([][]float64, []float64, error) {
+	return readCaptureFile(filepath.Dir(path), path, DefaultCANSchema())
+}
+
+// readCaptureFile reads a capture of the dataset at root in any of the formats known to openCapture
+func readCaptureFile(root, filepath string, schema *CANSchema) ([][]float64, []float64, error) {
+	reader, file, err := openCapture(root, filepath, schema)
 	if err != nil {
 		fmt.Println("Error opening file:", err)
 		return nil, nil, err
 	}
 	defer file.Close()
 
-	return readCSV(file)
+	data, attackValues, err := ReadFrames(reader, schema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", filepath, err)
+	}
+
+	return data, attackValues, nil
 }
 
 func ReadCSVFile(file io.Reader) ([][]float64, []float64, error) {
-	return readCSV(file)
+	return ReadCANCSV(file, nil)
 }
 
-func readCSV(file io.Reader) ([][]float64, []float64, error) {
-	// Create a new CSV reader
-	reader := csv.NewReader(file)
+// ReadCANCSV reads every frame of a csv capture laid out as described by schema (DefaultCANSchema if nil)
+func ReadCANCSV(file io.Reader, schema *CANSchema) ([][]float64, []float64, error) {
+	if schema == nil {
+		schema = DefaultCANSchema()
+	}
 
-	// Read the header (and discard it)
-	_, err := reader.Read()
+	reader, err := schema.NewReader(file)
 	if err != nil {
-		fmt.Println("Error reading header:", err)
 		return nil, nil, err
 	}
 
-	var data [][]float64
-	var attackValues []float64
-	var prevTimestamp float64
-	lines := 0
-	epsilon := .0
-
-	// Read the file line by line
-	for {
-		record, err := reader.Read()
-		if err != nil {
-			break // End of file or error
-		}
-
-		row := make([]float64, 12)
-		for i := 0; i < 4; i++ {
-			if i == 0 {
-				row[i], err = strconv.ParseFloat(record[i], 64)
-				if err != nil {
-					fmt.Printf("Error parsing float in row %d, column %d: %v\n", len(data)+1, i+1, err)
-					return nil, nil, err
-				}
-			} else if i == 1 {
-				val, err := strconv.ParseInt(record[i], 16, 64)
-				if err != nil {
-					log.Fatalf("Error converting hex to decimal: %v", err)
-				}
-
-				row[i] = float64(val)
-			} else if i == 2 {
-				if record[i] == "" {
-					row[2] = epsilon
-					row[3] = epsilon
-					row[4] = epsilon
-					row[5] = epsilon
-					row[6] = epsilon
-					row[7] = epsilon
-					row[8] = epsilon
-					row[9] = epsilon
-				} else {
-					hex := core.CleanHexString(record[i])
-
-					vals, err := core.ParseDataField(hex)
-					if err != nil {
-						log.Fatalf("Error converting hex to decimal: %v", err)
-					}
-
-					// for i := 0; i < len(vals); i++ {
-					// 	if vals[i] == 0 {
-					// 		vals[i] = epsilon
-					// 	}
-					// }
-
-					row[2] = vals[0]
-					row[3] = vals[1]
-					row[4] = vals[2]
-					row[5] = vals[3]
-					row[6] = vals[4]
-					row[7] = vals[5]
-					row[8] = vals[6]
-					row[9] = vals[7]
-				}
-
-			} else if i == 3 {
-				row[11], err = strconv.ParseFloat(record[i], 64)
-				if err != nil {
-					fmt.Printf("Error parsing float in row %d, column %d: %v\n", len(data)+1, i+1, err)
-					return nil, nil, err
-				}
-			}
-		}
-
-		if lines == 0 {
-			row[10] = 0 // For the first row, no previous timestamp
-		} else {
-			row[10] = row[0] - prevTimestamp
-		}
-
-		prevTimestamp = row[0]
-
-		data = append(data, row[1:11])
-		attackValue := row[11]
-
-		//if attackValue == 1 {
-		//	attackValue = label
-		//}
-
-		attackValues = append(attackValues, attackValue) // Append last column to attackValues
-
-		lines++
-	}
-
-	return data, attackValues, nil
+	return ReadFrames(reader, schema)
 }
 
 func LoadFashionMNISTDataset(shuffle bool) (datamodels.TrainingData, datamodels.ValidationData) {
@@ -431,6 +365,9 @@ func LoadFashionMNISTDataset(shuffle bool) (datamodels.TrainingData, datamodels.
 	return datamodels.TrainingData{X, y}, datamodels.ValidationData{X_test, y_test}
 }
 
+// LoadCANDatasetForInference reads a capture for the models saved without a Model.Scaler (e.g.
+// saved_models/CAN_dataset_model_full.json), which take rows robust-scaled with their own statistics. Models with a
+// scaler take the raw rows of ReadCANCSV & scale them with the statistics of their training data.
 func LoadCANDatasetForInference(filepath string, label int) (*mat.Dense, []float64) {
 	file, err := os.Open(filepath)
 	if err != nil {
@@ -458,61 +395,21 @@ func LoadCANDatasetForInference(filepath string, label int) (*mat.Dense, []float
 	return x, y
 }
 
+// RedundantReadCSV reads a capture with IntegerPayloadSchema, attack frames get the given label. The rows are
+// robust-scaled with their own statistics like LoadCANDatasetForInference's.
 func RedundantReadCSV(file io.Reader, label int) (*mat.Dense, []float64, error) {
-	// Create a new CSV reader
-	reader := csv.NewReader(file)
-
-	// Read the header (and discard it)
-	_, err := reader.Read()
+	data, attackValues, err := ReadCANCSV(file, IntegerPayloadSchema())
 	if err != nil {
-		fmt.Println("Error reading header:", err)
 		return nil, nil, err
 	}
+	if len(data) == 0 {
+		return nil, nil, errors.New("the capture has no frames")
+	}
 
-	var data [][]float64
-	var attackValues []float64
-
-	// Read the file line by line
-	for {
-		record, err := reader.Read()
-		if err != nil {
-			break // End of file or error
-		}
-
-		row := make([]float64, 4)
-		for i := 0; i < 4; i++ {
-			if i == 1 {
-				val, err := strconv.ParseInt(record[i], 16, 64)
-				if err != nil {
-					log.Fatalf("Error converting hex to decimal: %v", err)
-				}
-				row[i] = float64(val)
-			} else if i == 2 {
-				if record[i] == "" {
-					row[i] = 0
-				} else {
-					val, err := strconv.ParseUint(record[i], 16, 64)
-					if err != nil {
-						log.Fatalf("Error converting hex to decimal: %v", err)
-					}
-					row[i] = float64(val)
-				}
-			} else {
-				row[i], err = strconv.ParseFloat(record[i], 64)
-				if err != nil {
-					fmt.Printf("Error parsing float in row %d, column %d: %v\n", len(data)+1, i+1, err)
-					return nil, nil, err
-				}
-			}
-		}
-
-		data = append(data, row[:3])
-		attackValue := row[3]
-
+	for i, attackValue := range attackValues {
 		if attackValue == 1 {
-			attackValue = float64(label)
+			attackValues[i] = float64(label)
 		}
-		attackValues = append(attackValues, attackValue)
 	}
 
 	var sparseData []float64
@@ -521,7 +418,9 @@ func RedundantReadCSV(file io.Reader, label int) (*mat.Dense, []float64, error)
 	}
 
 	result := core.CreateDenseMatrix(len(data), len(data[0]), sparseData)
-	ScaleValues(result)
+	if err = ScaleValues(result); err != nil {
+		return nil, nil, err
+	}
 
 	return result, attackValues, nil
 }
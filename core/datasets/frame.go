@@ -0,0 +1,115 @@
+package datasets
+
+import "io"
+
+// Frame is a single CAN (or CAN-FD) frame as read from a capture, whatever its file format
+type Frame struct {
+	// Timestamp is in seconds
+	Timestamp float64
+	ID        uint32
+	Extended  bool
+	DLC       int
+	Data      []byte
+
+	FD         bool
+	Remote     bool
+	ErrorFrame bool
+
+	// Label is the class of the frame (0 for attack-free traffic), Labelled tells if the capture provided one
+	Label    float64
+	Labelled bool
+	// Labels lists every attack class of a frame that belongs to concurrent attacks (see MultiLabeler), Label being
+	// one of them; it is nil for frames with a single class
+	Labels []float64
+
+	Channel string
+}
+
+// maxStandardID is the largest 11-bit arbitration id, anything above needs the 29-bit extended format
+const maxStandardID = 0x7FF
+
+// FrameReader yields the frames of a capture in order & io.EOF once it is exhausted
+type FrameReader interface {
+	Next() (Frame, error)
+}
+
+// ReadFrames reads every frame of a capture into the feature rows described by schema (DefaultCANSchema if nil)
+// & their labels
+func ReadFrames(reader FrameReader, schema *CANSchema) ([][]float64, []float64, error) {
+	if schema == nil {
+		schema = DefaultCANSchema()
+	}
+
+	extractor := schema.NewFeatureExtractor()
+
+	var data [][]float64
+	var attackValues []float64
+
+	for {
+		frame, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		data = append(data, extractor.Update(frame))
+		attackValues = append(attackValues, frame.Label)
+	}
+
+	return data, attackValues, nil
+}
+
+// FeatureExtractor turns the consecutive frames of a capture into the feature rows described by its schema.
+// It keeps the state the time interval & the temporal features need, so it also extracts the features of live
+// traffic frame by frame.
+type FeatureExtractor struct {
+	schema        *CANSchema
+	prevTimestamp float64
+	frames        int
+	temporal      *TemporalFeatures // nil unless the schema has temporal features
+}
+
+// NewFeatureExtractor creates an extractor for the frames of a new capture
+func (schema *CANSchema) NewFeatureExtractor() *FeatureExtractor {
+	extractor := &FeatureExtractor{schema: schema}
+
+	for _, feature := range schema.features() {
+		if feature.temporal() {
+			options := TemporalOptions{}
+			if schema.Temporal != nil {
+				options = *schema.Temporal
+			}
+			extractor.temporal = NewTemporalFeatures(options)
+			break
+		}
+	}
+
+	return extractor
+}
+
+// Update returns the feature row of frame, which must be the next frame of the capture
+func (extractor *FeatureExtractor) Update(frame Frame) []float64 {
+	interval := 0. // the first frame has no previous timestamp
+	if extractor.frames > 0 {
+		interval = frame.Timestamp - extractor.prevTimestamp
+	}
+	extractor.prevTimestamp = frame.Timestamp
+	extractor.frames++
+
+	var temporal TemporalValues
+	if extractor.temporal != nil {
+		temporal = extractor.temporal.Update(frame)
+	}
+
+	return extractor.schema.frameFeatures(frame, interval, temporal)
+}
+
+// Reset forgets the previous frames, e.g. before the frames of another capture
+func (extractor *FeatureExtractor) Reset() {
+	extractor.prevTimestamp, extractor.frames = 0, 0
+	if extractor.temporal != nil {
+		extractor.temporal.Reset()
+	}
+}
@@ -0,0 +1,196 @@
+package datasets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RowLabelPolicy tells how the per-row attack flag of a capture combines with the class of its folder
+type RowLabelPolicy string
+
+const (
+	// FlaggedRows gives the folder's class to the rows flagged as attacks, the other rows stay attack-free (0)
+	FlaggedRows RowLabelPolicy = "flagged"
+	// AllRows gives the folder's class to every row, whatever its flag
+	AllRows RowLabelPolicy = "all"
+)
+
+// ClassFolder maps the captures found in the folders matching Folder (a name or a glob such as "dos-*",
+// compared case-insensitively) to a class
+type ClassFolder struct {
+	Folder string  `json:"folder" yaml:"folder"`
+	Class  float64 `json:"class" yaml:"class"`
+	Name   string  `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+// LabelMap labels captures by the folder they are in, for datasets with a folder per attack class.
+// The class of a capture comes from the closest of its parent folders that matches one of the entries.
+type LabelMap struct {
+	Folders []ClassFolder `json:"folders" yaml:"folders"`
+	// Rows is FlaggedRows by default
+	Rows RowLabelPolicy `json:"rows,omitempty" yaml:"rows,omitempty"`
+}
+
+// DefaultLabelMap is the 10-class layout of the CAN-MIRGU style dataset described in NOTES.txt
+func DefaultLabelMap() *LabelMap {
+	return &LabelMap{Folders: []ClassFolder{
+		{Folder: "attack-free", Class: 0, Name: "Attack-free"},
+		{Folder: "accessory*", Class: 0, Name: "Attack-free"},
+		{Folder: "combined-attacks", Class: 1, Name: "Combined"},
+		{Folder: "dos-attacks", Class: 2, Name: "DoS"},
+		{Folder: "fuzzing-attacks", Class: 3, Name: "Fuzzing"},
+		{Folder: "gear-attacks", Class: 4, Name: "Gear"},
+		{Folder: "interval-attacks", Class: 5, Name: "Interval"},
+		{Folder: "rpm-attacks", Class: 6, Name: "RPM"},
+		{Folder: "speed-attacks", Class: 7, Name: "Speed"},
+		{Folder: "standstill-attacks", Class: 8, Name: "Standstill"},
+		{Folder: "systematic-attacks", Class: 9, Name: "Systematic"},
+	}}
+}
+
+// LoadLabelMap reads a label map, the format is picked from the file extension (.json, .yaml or .yml)
+func LoadLabelMap(path string) (*LabelMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := new(LabelMap)
+
+	switch strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".") {
+	case "json":
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(labels)
+	case "yaml", "yml":
+		decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+		decoder.KnownFields(true)
+		err = decoder.Decode(labels)
+	default:
+		err = fmt.Errorf("unsupported label map format %q", filepath.Ext(path))
+	}
+	if err == nil {
+		err = labels.Validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return labels, nil
+}
+
+func (labels *LabelMap) Validate() error {
+	var errs []error
+
+	if len(labels.Folders) == 0 {
+		errs = append(errs, errors.New("the label map has no folders"))
+	}
+
+	names := make(map[float64]string)
+	for _, folder := range labels.Folders {
+		if _, err := filepath.Match(folder.Folder, ""); err != nil || folder.Folder == "" {
+			errs = append(errs, fmt.Errorf("invalid folder pattern %q", folder.Folder))
+		}
+		if folder.Class < 0 || folder.Class != float64(int(folder.Class)) {
+			errs = append(errs, fmt.Errorf("folder %q: the class must be a non-negative integer, got %g", folder.Folder, folder.Class))
+		}
+		if name, ok := names[folder.Class]; ok && folder.Name != "" && name != "" && name != folder.Name {
+			errs = append(errs, fmt.Errorf("class %g is named both %q & %q", folder.Class, name, folder.Name))
+		}
+		if folder.Name != "" || names[folder.Class] == "" {
+			names[folder.Class] = folder.Name
+		}
+	}
+
+	switch labels.Rows {
+	case "", FlaggedRows, AllRows:
+	default:
+		errs = append(errs, fmt.Errorf("unknown row label policy %q (expected flagged or all)", labels.Rows))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Match returns the entry of the first folder pattern matching name
+func (labels *LabelMap) Match(name string) (ClassFolder, bool) {
+	for _, folder := range labels.Folders {
+		if ok, _ := filepath.Match(strings.ToLower(folder.Folder), strings.ToLower(name)); ok {
+			return folder, true
+		}
+	}
+
+	return ClassFolder{}, false
+}
+
+// ClassOf returns the class of a capture from the closest of its folders found in the map, from the folder of the
+// capture up to the dataset root (included), so the folders above the dataset don't label its captures
+func (labels *LabelMap) ClassOf(root, path string) (ClassFolder, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ClassFolder{}, err
+	}
+	abs_root, err := filepath.Abs(root)
+	if err != nil {
+		return ClassFolder{}, err
+	}
+
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		if folder, ok := labels.Match(filepath.Base(dir)); ok {
+			return folder, nil
+		}
+		if parent := filepath.Dir(dir); dir == abs_root || parent == dir {
+			break
+		}
+	}
+
+	return ClassFolder{}, fmt.Errorf("none of the folders of %s under %s is in the label map", path, root)
+}
+
+// Label gives the label of a row of a capture of the given folder class, flag being the row's own label
+func (labels *LabelMap) Label(folder ClassFolder, flag float64) float64 {
+	if labels.Rows == AllRows || flag != 0 {
+		return folder.Class
+	}
+
+	return 0
+}
+
+// Names returns the name of every class, indexed by class, classes without a name are named by their number
+func (labels *LabelMap) Names() []string {
+	var names []string
+
+	for _, folder := range labels.Folders {
+		class := int(folder.Class)
+		for len(names) <= class {
+			names = append(names, "")
+		}
+		if names[class] == "" {
+			names[class] = folder.Name
+		}
+	}
+
+	for class, name := range names {
+		if name == "" {
+			names[class] = strconv.Itoa(class)
+		}
+	}
+
+	return names
+}
+
+// folderLabels labels the frames of a capture with the class of its folder
+type folderLabels struct {
+	labels *LabelMap
+	folder ClassFolder
+}
+
+func (labeler folderLabels) Label(frame Frame, index int) (float64, error) {
+	return labeler.labels.Label(labeler.folder, frame.Label), nil
+}
@@ -0,0 +1,82 @@
+package datasets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLabelMap(t *testing.T) {
+	labels, err := LoadLabelMap("../../configs/class_folders.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(labels.Folders, DefaultLabelMap().Folders) {
+		t.Errorf("error: configs/class_folders.yaml differs from DefaultLabelMap")
+	}
+
+	names := labels.Names()
+	if len(names) != 10 || names[0] != "Attack-free" || names[3] != "Fuzzing" {
+		t.Errorf("error: got class names %v", names)
+	}
+
+	for folder, want := range map[string]float64{"DoS-attacks": 2, "accessory-2": 0, "rpm-attacks": 6} {
+		if got, ok := labels.Match(folder); !ok || got.Class != want {
+			t.Errorf("error: %s got class %v (matched %v) | want %v", folder, got.Class, ok, want)
+		}
+	}
+
+	dir := t.TempDir()
+	capture := "timestamp,arbitration_id,data_field,attack\n0.0,001,00,0\n0.1,002,00,1\n"
+	for _, folder := range []string{"attack-free", filepath.Join("DoS-attacks", "day-1")} {
+		if err := os.MkdirAll(filepath.Join(dir, folder), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, folder, "capture.csv"), []byte(capture), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schema := DefaultCANSchema()
+	schema.Classes = labels
+
+	for _, tc := range []struct {
+		rows RowLabelPolicy
+		want string
+	}{
+		{FlaggedRows, "[0 2 0 0]"},
+		{AllRows, "[2 2 0 0]"},
+	} {
+		labels.Rows = tc.rows
+
+		// DoS-attacks (read first) holds its captures in day-1, they get the class of the closest parent in the map
+		_, y, err := ReadCANPath(dir, schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(y) != tc.want {
+			t.Errorf("error: %s rows got labels %v | want %s", tc.rows, y, tc.want)
+		}
+	}
+
+	// the folders above the dataset root don't label its captures, the root itself does
+	if _, _, err := ReadCANPath(filepath.Join(dir, "DoS-attacks", "day-1"), schema); err == nil || !strings.Contains(err.Error(), "label map") {
+		t.Errorf("error: got %v | want the dataset root's folders missing from the label map", err)
+	}
+	if _, y, err := ReadCANPath(filepath.Join(dir, "attack-free"), schema); err != nil || fmt.Sprint(y) != "[0 0]" {
+		t.Errorf("error: got labels %v (%v) for the attack-free root | want [0 0]", y, err)
+	}
+
+	labels.Folders = labels.Folders[:1]
+	if _, _, err := ReadCANPath(dir, schema); err == nil {
+		t.Errorf("error: expected an error for a folder missing from the label map")
+	}
+
+	invalid := &LabelMap{Folders: []ClassFolder{{Folder: "a", Class: 1, Name: "A"}, {Folder: "b", Class: 1, Name: "B"}, {Folder: "c", Class: 0.5}}}
+	if err := invalid.Validate(); err == nil {
+		t.Errorf("error: expected an error for conflicting names & a fractional class")
+	}
+}
@@ -0,0 +1,359 @@
+package datasets
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FrameLabeler labels the frames of a capture that doesn't carry its own labels, index is the position of
+// the frame in the capture
+type FrameLabeler interface {
+	Label(frame Frame, index int) (float64, error)
+}
+
+// MultiLabeler is a FrameLabeler that can give a frame several classes at once, e.g. a frame sent while
+// a fuzzing & a spoofing attack run concurrently. Labels returns nil for frames with a single class.
+type MultiLabeler interface {
+	FrameLabeler
+	Labels(frame Frame, index int) ([]float64, error)
+}
+
+// MultiHot encodes the classes of a frame (or window) as a row of 0 & 1 with a column per class. Class 0
+// (attack-free traffic) is only set when there is no attack class.
+func MultiHot(labels []float64, classes int) ([]float64, error) {
+	row := make([]float64, classes)
+
+	attack := false
+	for _, label := range labels {
+		if label < 0 || label >= float64(classes) || label != math.Trunc(label) {
+			return nil, fmt.Errorf("label %g isn't one of the %d classes", label, classes)
+		}
+		if label != 0 {
+			row[int(label)] = 1
+			attack = true
+		}
+	}
+	if !attack && classes > 0 {
+		row[0] = 1
+	}
+
+	return row, nil
+}
+
+// SidecarLabels holds a label per frame of a capture, in order
+type SidecarLabels []float64
+
+// LoadSidecarLabels reads a label file with one label per line, blank lines & lines starting with '#' are skipped
+func LoadSidecarLabels(path string) (SidecarLabels, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var labels SidecarLabels
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		label, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid label: %v", path, line, err)
+		}
+		labels = append(labels, label)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return labels, nil
+}
+
+func (labels SidecarLabels) Label(frame Frame, index int) (float64, error) {
+	if index >= len(labels) {
+		return 0, fmt.Errorf("the label file has %d labels but the capture has more frames", len(labels))
+	}
+
+	return labels[index], nil
+}
+
+// SidecarMultiLabels holds the classes of every frame of a capture, in order
+type SidecarMultiLabels [][]float64
+
+// LoadSidecarMultiLabels reads a label file with the classes of a frame per line, separated by spaces or commas
+// (e.g. "2 3" for a frame of two concurrent attacks). Blank lines & lines starting with '#' are skipped.
+func LoadSidecarMultiLabels(path string) (SidecarMultiLabels, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var labels SidecarMultiLabels
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+		frame_labels := make([]float64, len(fields))
+		for i, field := range fields {
+			if frame_labels[i], err = strconv.ParseFloat(field, 64); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid label: %v", path, line, err)
+			}
+		}
+		labels = append(labels, frame_labels)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return labels, nil
+}
+
+// Label gives the first attack class of the frame, 0 if it has none
+func (labels SidecarMultiLabels) Label(frame Frame, index int) (float64, error) {
+	frame_labels, err := labels.Labels(frame, index)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, label := range frame_labels {
+		if label != 0 {
+			return label, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func (labels SidecarMultiLabels) Labels(frame Frame, index int) ([]float64, error) {
+	if index >= len(labels) {
+		return nil, fmt.Errorf("the label file has %d label rows but the capture has more frames", len(labels))
+	}
+
+	return labels[index], nil
+}
+
+// LabelInterval gives Label to the frames sent between Start & End (inclusive, in seconds on the capture's clock),
+// restricted to the given arbitration ids if there are any
+type LabelInterval struct {
+	Start, End float64
+	Label      float64
+	IDs        []uint32
+}
+
+func (interval LabelInterval) contains(frame Frame) bool {
+	if frame.Timestamp < interval.Start || frame.Timestamp > interval.End {
+		return false
+	}
+	if len(interval.IDs) == 0 {
+		return true
+	}
+
+	for _, id := range interval.IDs {
+		if id == frame.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IntervalLabels labels frames with the first interval they fall into, other frames keep the label
+// of the capture if it has one or get Default. Frames in several overlapping intervals get all their labels
+// through Labels.
+type IntervalLabels struct {
+	Intervals []LabelInterval
+	Default   float64
+}
+
+func (labels *IntervalLabels) Label(frame Frame, index int) (float64, error) {
+	for _, interval := range labels.Intervals {
+		if interval.contains(frame) {
+			return interval.Label, nil
+		}
+	}
+
+	if frame.Labelled {
+		return frame.Label, nil
+	}
+
+	return labels.Default, nil
+}
+
+func (labels *IntervalLabels) Labels(frame Frame, index int) ([]float64, error) {
+	var frame_labels []float64
+	for _, interval := range labels.Intervals {
+		if interval.contains(frame) && !slices.Contains(frame_labels, interval.Label) {
+			frame_labels = append(frame_labels, interval.Label)
+		}
+	}
+
+	if len(frame_labels) < 2 {
+		return nil, nil
+	}
+
+	return frame_labels, nil
+}
+
+// LoadLabelIntervals reads an interval list as csv: start,end,label and optionally the space separated hex ids
+// the interval is limited to. A first row that doesn't start with a number is taken as a header.
+func LoadLabelIntervals(path string) (*IntervalLabels, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	labels := new(IntervalLabels)
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+
+		if line == 1 {
+			if _, err := strconv.ParseFloat(record[0], 64); err != nil {
+				continue // header
+			}
+		}
+
+		interval, err := parseLabelInterval(record)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		labels.Intervals = append(labels.Intervals, interval)
+	}
+
+	return labels, nil
+}
+
+func parseLabelInterval(record []string) (LabelInterval, error) {
+	var interval LabelInterval
+
+	if len(record) < 3 {
+		return interval, fmt.Errorf("expected start,end,label[,ids] but got %d fields", len(record))
+	}
+
+	values := make([]float64, 3)
+	for i, name := range []string{"start", "end", "label"} {
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+		if err != nil {
+			return interval, fmt.Errorf("invalid %s: %v", name, err)
+		}
+		values[i] = value
+	}
+	interval.Start, interval.End, interval.Label = values[0], values[1], values[2]
+
+	if interval.End < interval.Start {
+		return interval, fmt.Errorf("interval ends (%f) before it starts (%f)", interval.End, interval.Start)
+	}
+
+	if len(record) > 3 {
+		for _, field := range strings.Fields(record[3]) {
+			id, err := parseNumber(field, Hex, 32)
+			if err != nil {
+				return interval, fmt.Errorf("invalid id: %v", err)
+			}
+			interval.IDs = append(interval.IDs, uint32(id))
+		}
+	}
+
+	return interval, nil
+}
+
+// errorFrameReader is a reader that skips error frames unless it is told to keep them
+type errorFrameReader interface {
+	// keepErrorFrames makes the reader yield its error frames, returning whether it already did
+	keepErrorFrames() bool
+}
+
+// labelledReader applies a labeler to the frames of a capture
+type labelledReader struct {
+	reader  FrameReader
+	labeler FrameLabeler
+	index   int
+	// dropErrorFrames skips the error frames once they took their label, for readers that skipped them
+	dropErrorFrames bool
+}
+
+// WithLabels labels the frames read from reader with labeler
+func WithLabels(reader FrameReader, labeler FrameLabeler) FrameReader {
+	labelled := &labelledReader{reader: reader, labeler: labeler}
+	if skipping, ok := reader.(errorFrameReader); ok {
+		labelled.dropErrorFrames = !skipping.keepErrorFrames()
+	}
+
+	return labelled
+}
+
+func (reader *labelledReader) Next() (Frame, error) {
+	frame, index, err := reader.next()
+	if err != nil {
+		return frame, err
+	}
+
+	if frame.Label, err = reader.labeler.Label(frame, index); err != nil {
+		return frame, fmt.Errorf("frame %d: %v", index, err)
+	}
+	if multi, ok := reader.labeler.(MultiLabeler); ok {
+		if frame.Labels, err = multi.Labels(frame, index); err != nil {
+			return frame, fmt.Errorf("frame %d: %v", index, err)
+		}
+	}
+	frame.Labelled = true
+
+	return frame, nil
+}
+
+// next reads the next frame & its index. Every record consumed takes its index, malformed & error frames too, so
+// skipping them keeps the labels of the next frames in place.
+func (reader *labelledReader) next() (Frame, int, error) {
+	for {
+		frame, err := reader.reader.Next()
+		if err == io.EOF {
+			return frame, 0, err
+		}
+
+		index := reader.index
+		reader.index++
+		if err == nil && frame.ErrorFrame && reader.dropErrorFrames {
+			continue
+		}
+
+		return frame, index, err
+	}
+}
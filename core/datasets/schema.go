@@ -0,0 +1,671 @@
+package datasets
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Column refers to a csv column by its header name or, prefixed with '#', by its 0-based position.
+// Negative positions count from the end, e.g. "#-1" is the last column of the row.
+type Column string
+
+// NumberFormat tells how ids & payload bytes are written
+type NumberFormat string
+
+const (
+	Hex     NumberFormat = "hex"
+	Decimal NumberFormat = "decimal"
+)
+
+// PayloadMode tells how the payload is laid out in a row
+type PayloadMode string
+
+const (
+	// PayloadString is a single column holding every byte, e.g. "05200B20" or "05 20 0B 20"
+	PayloadString PayloadMode = "string"
+	// PayloadColumns has a column per byte starting at the payload column, DLC columns are read when the
+	// schema has a DLC column (the columns after the payload then shift with it) or PayloadBytes otherwise
+	PayloadColumns PayloadMode = "columns"
+	// PayloadInteger is a single column holding the payload as one number
+	PayloadInteger PayloadMode = "integer"
+)
+
+// PayloadAlignment tells where the bytes of a payload string shorter than PayloadBytes go
+type PayloadAlignment string
+
+const (
+	// AlignRight pads the hex digits on the left & keeps the first 2 x PayloadBytes of longer ones, so "0102"
+	// is read as 00 00 00 00 00 00 01 02 (the layout the saved models were trained on)
+	AlignRight PayloadAlignment = "right"
+	// AlignLeft reads the bytes in order from the first payload feature like a DLC-sized data field,
+	// so "0102" is read as 01 02 00 00 00 00 00 00
+	AlignLeft PayloadAlignment = "left"
+)
+
+// Feature names a value extracted from every frame
+type Feature string
+
+const (
+	FeatureTimestamp    Feature = "timestamp"
+	FeatureID           Feature = "arbitration_id"
+	FeatureDLC          Feature = "dlc"
+	FeaturePayload      Feature = "payload"
+	FeatureTimeInterval Feature = "time_interval"
+)
+
+// CANSchema describes the layout of a CAN capture stored as csv & the features extracted from its frames,
+// so captures from different datasets can be read by the same reader
+type CANSchema struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Header tells if the first row names the columns, columns can only be referred to by name when it does
+	Header    bool   `json:"header" yaml:"header"`
+	Delimiter string `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
+
+	Timestamp Column `json:"timestamp" yaml:"timestamp"`
+	// TimestampScale converts the timestamps to seconds, e.g. 1e-6 for microseconds (1 by default)
+	TimestampScale float64 `json:"timestamp_scale,omitempty" yaml:"timestamp_scale,omitempty"`
+
+	ID       Column       `json:"id" yaml:"id"`
+	IDFormat NumberFormat `json:"id_format,omitempty" yaml:"id_format,omitempty"`
+
+	// DLC is optional, without it the DLC is the number of payload bytes found
+	DLC Column `json:"dlc,omitempty" yaml:"dlc,omitempty"`
+	// MaskByDLC zeroes payload bytes past the DLC
+	MaskByDLC bool `json:"mask_by_dlc,omitempty" yaml:"mask_by_dlc,omitempty"`
+
+	Payload       Column       `json:"payload" yaml:"payload"`
+	PayloadMode   PayloadMode  `json:"payload_mode,omitempty" yaml:"payload_mode,omitempty"`
+	PayloadFormat NumberFormat `json:"payload_format,omitempty" yaml:"payload_format,omitempty"`
+	// PayloadBytes is the number of payload features, shorter payloads are zero padded (8 by default)
+	PayloadBytes int `json:"payload_bytes,omitempty" yaml:"payload_bytes,omitempty"`
+	// PayloadAlign places the bytes of a hex payload string, see PayloadAlignment (right by default)
+	PayloadAlign PayloadAlignment `json:"payload_align,omitempty" yaml:"payload_align,omitempty"`
+
+	// Label is optional, unlabelled rows (or captures) get DefaultLabel
+	Label Column `json:"label,omitempty" yaml:"label,omitempty"`
+	// LabelMap maps raw label values to classes, e.g. {"R": 0, "T": 1}; without it labels must be numbers
+	LabelMap     map[string]float64 `json:"label_map,omitempty" yaml:"label_map,omitempty"`
+	DefaultLabel float64            `json:"default_label,omitempty" yaml:"default_label,omitempty"`
+
+	Channel Column `json:"channel,omitempty" yaml:"channel,omitempty"`
+
+	// Features lists the values extracted from each frame in order (arbitration id, payload & time interval by default)
+	Features []Feature `json:"features,omitempty" yaml:"features,omitempty"`
+	// Temporal configures the temporal features (id_interval, bus_load, ...), see TemporalOptions
+	Temporal *TemporalOptions `json:"temporal,omitempty" yaml:"temporal,omitempty"`
+
+	// Classes labels the captures by the folder they are in, see LabelMap
+	Classes *LabelMap `json:"classes,omitempty" yaml:"classes,omitempty"`
+}
+
+// DefaultCANSchema is the layout of the captures in core/datasets: timestamp, hex arbitration id, hex data field
+// & attack flag. Every frame becomes its arbitration id, its 8 payload bytes & the time since the previous frame,
+// short data fields being right aligned like the saved models were trained on.
+func DefaultCANSchema() *CANSchema {
+	return &CANSchema{
+		Name:      "can-train-and-test",
+		Header:    true,
+		Timestamp: "#0",
+		ID:        "#1",
+		Payload:   "#2",
+		Label:     "#3",
+	}
+}
+
+// IntegerPayloadSchema reads the same captures as DefaultCANSchema but keeps the timestamp, the arbitration id
+// & the whole payload as a single number (the layout used for inference so far)
+func IntegerPayloadSchema() *CANSchema {
+	schema := DefaultCANSchema()
+	schema.Name = "integer-payload"
+	schema.PayloadMode = PayloadInteger
+	schema.Features = []Feature{FeatureTimestamp, FeatureID, FeaturePayload}
+
+	return schema
+}
+
+// CarHackingSchema reads the HCRL Car-Hacking captures: timestamp, hex id, DLC, one hex column per payload byte
+// & an R(egular)/T(injected) flag, without a header
+func CarHackingSchema() *CANSchema {
+	return &CANSchema{
+		Name:        "car-hacking",
+		Timestamp:   "#0",
+		ID:          "#1",
+		DLC:         "#2",
+		MaskByDLC:   true,
+		Payload:     "#3",
+		PayloadMode: PayloadColumns,
+		Label:       "#-1",
+		LabelMap:    map[string]float64{"R": 0, "T": 1},
+	}
+}
+
+// LoadCANSchema reads a schema, the format is picked from the file extension (.json, .yaml or .yml)
+func LoadCANSchema(path string) (*CANSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := new(CANSchema)
+
+	switch strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".") {
+	case "json":
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(schema)
+	case "yaml", "yml":
+		decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+		decoder.KnownFields(true)
+		err = decoder.Decode(schema)
+	default:
+		err = fmt.Errorf("unsupported schema format %q", filepath.Ext(path))
+	}
+	if err == nil {
+		err = schema.Validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return schema, nil
+}
+
+func (schema *CANSchema) payloadMode() PayloadMode {
+	if schema.PayloadMode == "" {
+		return PayloadString
+	}
+
+	return schema.PayloadMode
+}
+
+func (schema *CANSchema) payloadBytes() int {
+	if schema.PayloadBytes <= 0 {
+		return 8
+	}
+
+	return schema.PayloadBytes
+}
+
+func (schema *CANSchema) payloadAlign() PayloadAlignment {
+	if schema.PayloadAlign == "" {
+		return AlignRight
+	}
+
+	return schema.PayloadAlign
+}
+
+func (schema *CANSchema) features() []Feature {
+	if len(schema.Features) == 0 {
+		return []Feature{FeatureID, FeaturePayload, FeatureTimeInterval}
+	}
+
+	return schema.Features
+}
+
+// Validate checks the schema is complete & consistent
+func (schema *CANSchema) Validate() error {
+	var errs []error
+
+	for _, column := range []struct {
+		name     string
+		value    Column
+		required bool
+	}{
+		{"timestamp", schema.Timestamp, true},
+		{"id", schema.ID, true},
+		{"payload", schema.Payload, true},
+		{"dlc", schema.DLC, false},
+		{"label", schema.Label, false},
+		{"channel", schema.Channel, false},
+	} {
+		if column.value == "" {
+			if column.required {
+				errs = append(errs, fmt.Errorf("the %s column is required", column.name))
+			}
+			continue
+		}
+		if _, isIndex, err := column.value.index(); err != nil {
+			errs = append(errs, fmt.Errorf("%s column: %v", column.name, err))
+		} else if !isIndex && !schema.Header {
+			errs = append(errs, fmt.Errorf("%s column %q can only be referred to by name when the csv has a header", column.name, column.value))
+		}
+	}
+
+	for _, format := range []NumberFormat{schema.IDFormat, schema.PayloadFormat} {
+		if format != "" && format != Hex && format != Decimal {
+			errs = append(errs, fmt.Errorf("unknown number format %q (expected hex or decimal)", format))
+		}
+	}
+
+	switch schema.payloadMode() {
+	case PayloadString, PayloadColumns, PayloadInteger:
+	default:
+		errs = append(errs, fmt.Errorf("unknown payload mode %q", schema.PayloadMode))
+	}
+
+	switch schema.payloadAlign() {
+	case AlignRight, AlignLeft:
+	default:
+		errs = append(errs, fmt.Errorf("unknown payload alignment %q (expected right or left)", schema.PayloadAlign))
+	}
+
+	if len(schema.Delimiter) > 1 {
+		errs = append(errs, fmt.Errorf("delimiter must be a single character, got %q", schema.Delimiter))
+	}
+
+	for _, feature := range schema.features() {
+		switch feature {
+		case FeatureTimestamp, FeatureID, FeatureDLC, FeaturePayload, FeatureTimeInterval:
+		default:
+			if !feature.temporal() {
+				errs = append(errs, fmt.Errorf("unknown feature %q", feature))
+			}
+		}
+	}
+
+	if temporal := schema.Temporal; temporal != nil {
+		if temporal.Window < 0 || temporal.Bitrate < 0 {
+			errs = append(errs, errors.New("the temporal window & bitrate must be positive"))
+		}
+		if temporal.PeriodSmoothing < 0 || temporal.PeriodSmoothing > 1 {
+			errs = append(errs, fmt.Errorf("period smoothing must be between 0 & 1, got %g", temporal.PeriodSmoothing))
+		}
+	}
+
+	if schema.Classes != nil {
+		if err := schema.Classes.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("classes: %v", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// FeatureNames names every value of the feature rows produced for this schema
+func (schema *CANSchema) FeatureNames() []string {
+	var names []string
+
+	for _, feature := range schema.features() {
+		if feature == FeaturePayload && schema.payloadMode() != PayloadInteger {
+			for i := 1; i <= schema.payloadBytes(); i++ {
+				names = append(names, fmt.Sprintf("df%d", i))
+			}
+			continue
+		}
+		if feature == FeaturePayload {
+			names = append(names, "data_field")
+			continue
+		}
+
+		names = append(names, string(feature))
+	}
+
+	return names
+}
+
+// CSVHeader names the columns of a csv holding the feature rows followed by the label
+func (schema *CANSchema) CSVHeader() []string {
+	label := "attack"
+	if _, isIndex, _ := schema.Label.index(); schema.Label != "" && !isIndex {
+		label = string(schema.Label)
+	}
+
+	return append(schema.FeatureNames(), label)
+}
+
+// FeatureIndex is the position of feature in the feature rows (of its first byte for the payload), -1 if the
+// schema doesn't extract it
+func (schema *CANSchema) FeatureIndex(feature Feature) int {
+	idx := 0
+	for _, f := range schema.features() {
+		if f == feature {
+			return idx
+		}
+
+		idx++
+		if f == FeaturePayload && schema.payloadMode() != PayloadInteger {
+			idx += schema.payloadBytes() - 1
+		}
+	}
+
+	return -1
+}
+
+// NumFeatures is the width of the feature rows produced for this schema
+func (schema *CANSchema) NumFeatures() int {
+	return len(schema.FeatureNames())
+}
+
+// index resolves "#n" references, isIndex is false for header names
+func (column Column) index() (int, bool, error) {
+	if !strings.HasPrefix(string(column), "#") {
+		return 0, false, nil
+	}
+
+	idx, err := strconv.Atoi(strings.TrimPrefix(string(column), "#"))
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid column position %q", column)
+	}
+
+	return idx, true, nil
+}
+
+// CANReader reads the frames of a csv capture laid out as described by a CANSchema
+type CANReader struct {
+	schema  *CANSchema
+	reader  *csv.Reader
+	columns map[Column]int
+
+	line int
+}
+
+func (schema *CANSchema) NewReader(file io.Reader) (*CANReader, error) {
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if schema.Delimiter != "" {
+		reader.Comma = rune(schema.Delimiter[0])
+	}
+
+	canReader := &CANReader{schema: schema, reader: reader, columns: map[Column]int{}}
+
+	if schema.Header {
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("error reading header: %v", err)
+		}
+		canReader.line++
+
+		for i, name := range header {
+			canReader.columns[Column(strings.TrimSpace(name))] = i
+		}
+	}
+
+	return canReader, nil
+}
+
+// column returns the field referred to by column, ok is false if the row doesn't have it
+func (reader *CANReader) column(record []string, column Column) (string, bool) {
+	if column == "" {
+		return "", false
+	}
+
+	idx, isIndex, _ := column.index()
+	if !isIndex {
+		var found bool
+		if idx, found = reader.columns[column]; !found {
+			return "", false
+		}
+	}
+	if idx < 0 {
+		idx += len(record)
+	}
+	if idx < 0 || idx >= len(record) {
+		return "", false
+	}
+
+	return strings.TrimSpace(record[idx]), true
+}
+
+// Next returns the next frame of the capture or io.EOF once it is exhausted
+func (reader *CANReader) Next() (Frame, error) {
+	record, err := reader.reader.Read()
+	if err == io.EOF {
+		return Frame{}, io.EOF
+	}
+	reader.line++
+	if err != nil {
+		return Frame{}, err
+	}
+
+	frame, err := reader.parse(record)
+	if err != nil {
+		return Frame{}, fmt.Errorf("line %d: %v", reader.line, err)
+	}
+
+	return frame, nil
+}
+
+func (reader *CANReader) parse(record []string) (Frame, error) {
+	schema := reader.schema
+	var frame Frame
+
+	value, ok := reader.column(record, schema.Timestamp)
+	if !ok {
+		return frame, fmt.Errorf("missing timestamp column %q", schema.Timestamp)
+	}
+	timestamp, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return frame, fmt.Errorf("invalid timestamp: %v", err)
+	}
+	if schema.TimestampScale > 0 {
+		timestamp *= schema.TimestampScale
+	}
+	frame.Timestamp = timestamp
+
+	value, ok = reader.column(record, schema.ID)
+	if !ok {
+		return frame, fmt.Errorf("missing id column %q", schema.ID)
+	}
+	id, err := parseNumber(value, schema.IDFormat, 32)
+	if err != nil {
+		return frame, fmt.Errorf("invalid arbitration id: %v", err)
+	}
+	frame.ID = uint32(id)
+	frame.Extended = frame.ID > maxStandardID
+
+	frame.DLC = -1
+	if value, ok = reader.column(record, schema.DLC); ok {
+		dlc, err := strconv.Atoi(value)
+		if err != nil {
+			return frame, fmt.Errorf("invalid dlc: %v", err)
+		}
+		frame.DLC = dlc
+	}
+
+	if frame.Data, err = reader.payload(record, frame.DLC); err != nil {
+		return frame, err
+	}
+	if frame.DLC < 0 {
+		frame.DLC = len(frame.Data)
+	}
+	if schema.MaskByDLC && frame.DLC < len(frame.Data) {
+		for i := frame.DLC; i < len(frame.Data); i++ {
+			frame.Data[i] = 0
+		}
+	}
+
+	frame.Label = schema.DefaultLabel
+	if value, ok = reader.column(record, schema.Label); ok && value != "" {
+		if frame.Label, err = schema.parseLabel(value); err != nil {
+			return frame, err
+		}
+		frame.Labelled = true
+	}
+
+	frame.Channel, _ = reader.column(record, schema.Channel)
+
+	return frame, nil
+}
+
+func (reader *CANReader) payload(record []string, dlc int) ([]byte, error) {
+	schema := reader.schema
+
+	if schema.payloadMode() == PayloadColumns {
+		start, isIndex, _ := schema.Payload.index()
+		if !isIndex {
+			var found bool
+			if start, found = reader.columns[schema.Payload]; !found {
+				return nil, fmt.Errorf("payload column %q not in header", schema.Payload)
+			}
+		}
+
+		count := dlc
+		if count < 0 {
+			count = schema.payloadBytes()
+		}
+
+		data := make([]byte, 0, count)
+		for i := start; i < start+count && i < len(record); i++ {
+			value, err := parseNumber(strings.TrimSpace(record[i]), schema.PayloadFormat, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid payload byte %d: %v", i-start, err)
+			}
+			data = append(data, byte(value))
+		}
+
+		return data, nil
+	}
+
+	value, ok := reader.column(record, schema.Payload)
+	if !ok || value == "" {
+		// frames without a payload (e.g. remote frames) keep zeroed data bytes
+		return nil, nil
+	}
+
+	if schema.payloadMode() == PayloadInteger {
+		number, err := parseNumber(value, schema.PayloadFormat, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload: %v", err)
+		}
+
+		data := make([]byte, 8)
+		for i := 7; i >= 0; i-- {
+			data[i] = byte(number)
+			number >>= 8
+		}
+
+		return data, nil
+	}
+
+	if schema.PayloadFormat == Decimal {
+		var data []byte
+		for _, field := range strings.Fields(value) {
+			number, err := strconv.ParseUint(field, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid payload: %v", err)
+			}
+			data = append(data, byte(number))
+		}
+
+		return data, nil
+	}
+
+	if schema.payloadAlign() == AlignRight {
+		value = rightAlignHex(value, 2*schema.payloadBytes())
+	}
+
+	return parseHexBytes(value)
+}
+
+func (schema *CANSchema) parseLabel(value string) (float64, error) {
+	if schema.LabelMap != nil {
+		label, ok := schema.LabelMap[value]
+		if !ok {
+			return 0, fmt.Errorf("label %q is missing from the label map", value)
+		}
+		return label, nil
+	}
+
+	label, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid label: %v", err)
+	}
+
+	return label, nil
+}
+
+// frameFeatures builds the feature row of a frame, interval being the time since the previous frame
+func (schema *CANSchema) frameFeatures(frame Frame, interval float64, temporal TemporalValues) []float64 {
+	row := make([]float64, 0, schema.NumFeatures())
+
+	for _, feature := range schema.features() {
+		switch feature {
+		case FeatureTimestamp:
+			row = append(row, frame.Timestamp)
+		case FeatureID:
+			row = append(row, float64(frame.ID))
+		case FeatureDLC:
+			row = append(row, float64(frame.DLC))
+		case FeatureTimeInterval:
+			row = append(row, interval)
+		case FeaturePayload:
+			if schema.payloadMode() == PayloadInteger {
+				value := 0.
+				for _, b := range frame.Data {
+					value = value*256 + float64(b)
+				}
+				row = append(row, value)
+				continue
+			}
+
+			for i := 0; i < schema.payloadBytes(); i++ {
+				value := 0.
+				if i < len(frame.Data) {
+					value = float64(frame.Data[i])
+				}
+				row = append(row, value)
+			}
+		default:
+			row = append(row, temporal.get(feature))
+		}
+	}
+
+	return row
+}
+
+func parseNumber(value string, format NumberFormat, bits int) (uint64, error) {
+	if format == Decimal {
+		return strconv.ParseUint(value, 10, bits)
+	}
+
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+
+	return strconv.ParseUint(value, 16, bits)
+}
+
+// rightAlignHex pads the hex digits of a payload with leading zeros up to digits & keeps the first digits of a
+// longer one
+func rightAlignHex(value string, digits int) string {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+	value = strings.ReplaceAll(value, " ", "")
+	if len(value) < digits {
+		value = strings.Repeat("0", digits-len(value)) + value
+	}
+
+	return value[:digits]
+}
+
+// parseHexBytes reads a hex payload, bytes may be separated by spaces and an odd digit is taken as a leading zero
+func parseHexBytes(value string) ([]byte, error) {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+	value = strings.ReplaceAll(value, " ", "")
+	if len(value)%2 == 1 {
+		value = "0" + value
+	}
+
+	data := make([]byte, len(value)/2)
+	for i := range data {
+		b, err := strconv.ParseUint(value[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload %q: %v", value, err)
+		}
+		data[i] = byte(b)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,162 @@
+package datasets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, schema *CANSchema, capture string) ([][]float64, []float64) {
+	t.Helper()
+
+	x, y, err := ReadCANCSV(strings.NewReader(capture), schema)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	return x, y
+}
+
+func TestDefaultCANSchema(t *testing.T) {
+	capture := "timestamp,arbitration_id,data_field,attack\n" +
+		"1.5,316,05200B2000000001,0\n" +
+		"1.75,0,,1\n" +
+		"2.0,7FF,0102,0\n" +
+		"2.5,7FF,AABBCCDDEEFF001122,0\n"
+
+	x, y := readAll(t, nil, capture)
+
+	// short payloads are right aligned & long ones keep their first 8 bytes
+	want := [][]float64{
+		{0x316, 5, 0x20, 0xB, 0x20, 0, 0, 0, 1, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0.25},
+		{0x7FF, 0, 0, 0, 0, 0, 0, 1, 2, 0.25},
+		{0x7FF, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF, 0, 0x11, 0.5},
+	}
+	if fmt.Sprint(x) != fmt.Sprint(want) {
+		t.Errorf("error: got %v | want %v", x, want)
+	}
+	if fmt.Sprint(y) != "[0 1 0 0]" {
+		t.Errorf("error: got labels %v | want [0 1 0 0]", y)
+	}
+
+	// left aligned payloads fill the data bytes in order like a DLC-sized data field
+	schema := DefaultCANSchema()
+	schema.PayloadAlign = AlignLeft
+	x, _ = readAll(t, schema, capture)
+	want[2] = []float64{0x7FF, 1, 2, 0, 0, 0, 0, 0, 0, 0.25}
+	want[3] = []float64{0x7FF, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF, 0, 0x11, 0.5}
+	if fmt.Sprint(x) != fmt.Sprint(want) {
+		t.Errorf("error: got %v | want %v", x, want)
+	}
+
+	header := DefaultCANSchema().CSVHeader()
+	if strings.Join(header, ",") != "arbitration_id,df1,df2,df3,df4,df5,df6,df7,df8,time_interval,attack" {
+		t.Errorf("error: got header %v", header)
+	}
+}
+
+func TestCarHackingSchema(t *testing.T) {
+	// the flag column moves with the DLC
+	capture := "1478198376.389427,0316,8,05,21,68,09,21,21,00,6f,R\n" +
+		"1478198376.389636,018f,2,fe,5b,T\n"
+
+	x, y := readAll(t, CarHackingSchema(), capture)
+
+	if len(x) != 2 || x[1][0] != 0x18f || x[1][1] != 0xfe || x[1][2] != 0x5b || x[1][3] != 0 {
+		t.Errorf("error: got %v", x)
+	}
+	if fmt.Sprint(y) != "[0 1]" {
+		t.Errorf("error: got labels %v | want [0 1]", y)
+	}
+}
+
+func TestNamedColumnSchema(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schema.yaml")
+
+	err := os.WriteFile(path, []byte(`
+name: named
+header: true
+delimiter: ";"
+timestamp: Time
+timestamp_scale: 1.0e-6
+id: ID
+id_format: decimal
+dlc: Len
+payload: Payload
+payload_mode: integer
+label: Class
+label_map: {Normal: 0, DoS: 1, Fuzzy: 2}
+features: [arbitration_id, dlc, payload, time_interval]
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := LoadCANSchema(path)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	capture := "Class;Time;ID;Len;Payload\n" +
+		"Normal;1000000;790;2;0102\n" +
+		"Fuzzy;1500000;100;1;ff\n"
+
+	x, y := readAll(t, schema, capture)
+
+	want := [][]float64{{790, 2, 0x0102, 0}, {100, 1, 0xff, 0.5}}
+	if fmt.Sprint(x) != fmt.Sprint(want) {
+		t.Errorf("error: got %v | want %v", x, want)
+	}
+	if fmt.Sprint(y) != "[0 2]" {
+		t.Errorf("error: got labels %v | want [0 2]", y)
+	}
+	if strings.Join(schema.CSVHeader(), ",") != "arbitration_id,dlc,data_field,time_interval,Class" {
+		t.Errorf("error: got header %v", schema.CSVHeader())
+	}
+
+	if _, _, err = ReadCANCSV(strings.NewReader("Class;Time;ID;Len;Payload\nUnknown;1;2;0;\n"), schema); err == nil {
+		t.Errorf("error: expected an error for a label missing from the label map")
+	}
+
+	// the bytes of a payload column missing from the header aren't read from the first column
+	schema.PayloadMode = PayloadColumns
+	if _, _, err = ReadCANCSV(strings.NewReader("Class;Time;ID;Len;Data\nNormal;1;2;1;ff\n"), schema); err == nil || !strings.Contains(err.Error(), "not in header") {
+		t.Errorf("error: got %v | want a payload column missing from the header", err)
+	}
+}
+
+func TestInvalidCANSchema(t *testing.T) {
+	schema := &CANSchema{
+		Timestamp:    "time",
+		ID:           "#x",
+		PayloadMode:  "bits",
+		PayloadAlign: "centre",
+		Features:     []Feature{"entropy"},
+	}
+
+	err := schema.Validate()
+	if err == nil {
+		t.Fatalf("error: expected the schema to be rejected")
+	}
+
+	for _, want := range []string{"payload column is required", "only be referred to by name", "invalid column position", "unknown payload mode", "unknown payload alignment", "unknown feature"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error: %q is missing from %q", want, err.Error())
+		}
+	}
+}
+
+func TestCarHackingSchemaConfig(t *testing.T) {
+	schema, err := LoadCANSchema("../../configs/car_hacking_schema.yaml")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if fmt.Sprint(schema) != fmt.Sprint(CarHackingSchema()) {
+		t.Errorf("error: got %+v | want %+v", schema, CarHackingSchema())
+	}
+}
@@ -0,0 +1,283 @@
+package datasets
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Holdout holds the rows of the training, validation & test parts of a dataset
+type Holdout struct {
+	Train, Validation, Test []int
+	// Unsplit lists the classes too small to get rows in every part asked for, they are missing from the
+	// validation or the test part
+	Unsplit []float64
+}
+
+// Fold holds the rows a model is trained & tested on in one round of a cross-validation
+type Fold struct {
+	Train, Test []int
+}
+
+// StratifiedHoldout holds out the given fractions of every class for validation & testing, so every part has the
+// class balance of the whole dataset. The rows of each part stay in their original order & a seed always gives the
+// same split. The classes too small to be held out in every part are listed in Holdout.Unsplit.
+func StratifiedHoldout(labels []float64, validation, test float64, seed int64) (Holdout, error) {
+	if len(labels) == 0 {
+		return Holdout{}, errors.New("can't split an empty dataset")
+	}
+	if validation < 0 || test < 0 || validation+test >= 1 {
+		return Holdout{}, fmt.Errorf("invalid holdout fractions %g & %g, they must be non-negative & sum to less than 1", validation, test)
+	}
+
+	var holdout Holdout
+	rng := rand.New(rand.NewSource(seed))
+	for _, rows := range classRows(labels) {
+		class := labels[rows[0]]
+		rng.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+
+		n_test := int(math.Round(float64(len(rows)) * test))
+		n_validation := int(math.Round(float64(len(rows)) * validation))
+		if n_test+n_validation >= len(rows) {
+			// keep at least a row of every class to train on
+			n_test, n_validation = min(n_test, len(rows)-1), 0
+		}
+		if (test > 0 && n_test == 0) || (validation > 0 && n_validation == 0) {
+			holdout.Unsplit = append(holdout.Unsplit, class)
+		}
+
+		holdout.Test = append(holdout.Test, rows[:n_test]...)
+		holdout.Validation = append(holdout.Validation, rows[n_test:n_test+n_validation]...)
+		holdout.Train = append(holdout.Train, rows[n_test+n_validation:]...)
+	}
+
+	sort.Ints(holdout.Train)
+	sort.Ints(holdout.Validation)
+	sort.Ints(holdout.Test)
+
+	return holdout, nil
+}
+
+// TimeOrderedHoldout splits n time-ordered rows into consecutive parts: training first, then validation & the most
+// recent rows for testing, so no model is tested on traffic older than what it was trained on
+func TimeOrderedHoldout(n int, validation, test float64) (Holdout, error) {
+	if validation < 0 || test < 0 || validation+test >= 1 {
+		return Holdout{}, fmt.Errorf("invalid holdout fractions %g & %g, they must be non-negative & sum to less than 1", validation, test)
+	}
+
+	n_test := int(math.Round(float64(n) * test))
+	n_validation := int(math.Round(float64(n) * validation))
+	n_train := n - n_test - n_validation
+	if n_train <= 0 {
+		return Holdout{}, fmt.Errorf("%d rows leave nothing to train on", n)
+	}
+
+	return Holdout{
+		Train:      rowRange(0, n_train),
+		Validation: rowRange(n_train, n_train+n_validation),
+		Test:       rowRange(n_train+n_validation, n),
+	}, nil
+}
+
+// StratifiedKFold deals the rows of every class across k folds, so each fold has the class balance of the whole
+// dataset. Every row is tested exactly once.
+func StratifiedKFold(labels []float64, k int, seed int64) ([]Fold, error) {
+	if k < 2 || k > len(labels) {
+		return nil, fmt.Errorf("can't make %d folds of %d rows", k, len(labels))
+	}
+
+	tests := make([][]int, k)
+	rng := rand.New(rand.NewSource(seed))
+
+	next := 0 // the fold dealing goes on from class to class so the folds have the same size
+	for _, rows := range classRows(labels) {
+		rng.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+		for _, row := range rows {
+			tests[next] = append(tests[next], row)
+			next = (next + 1) % k
+		}
+	}
+
+	return foldsOf(tests, len(labels)), nil
+}
+
+// GroupKFold splits the rows into k folds without ever putting the rows of a group (e.g. the frames of a capture,
+// see ReadCANCaptures) in both the training & the test part of a fold. The largest groups are placed first, each in
+// the fold with the fewest rows so far.
+func GroupKFold(groups []int, k int) ([]Fold, error) {
+	rows := make(map[int][]int)
+	for row, group := range groups {
+		rows[group] = append(rows[group], row)
+	}
+	if k < 2 || k > len(rows) {
+		return nil, fmt.Errorf("can't make %d folds of %d groups", k, len(rows))
+	}
+
+	ids := make([]int, 0, len(rows))
+	for group := range rows {
+		ids = append(ids, group)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if len(rows[ids[i]]) != len(rows[ids[j]]) {
+			return len(rows[ids[i]]) > len(rows[ids[j]])
+		}
+		return ids[i] < ids[j]
+	})
+
+	tests := make([][]int, k)
+	for _, group := range ids {
+		smallest := 0
+		for fold := range tests {
+			if len(tests[fold]) < len(tests[smallest]) {
+				smallest = fold
+			}
+		}
+		tests[smallest] = append(tests[smallest], rows[group]...)
+	}
+
+	return foldsOf(tests, len(groups)), nil
+}
+
+// TimeSeriesSplit splits n time-ordered rows into k folds that test consecutive blocks of rows, each fold training on
+// every row before its test block but the last gap ones (to leave out frames the test block still depends on, e.g.
+// through the temporal features)
+func TimeSeriesSplit(n, k, gap int) ([]Fold, error) {
+	if k < 1 || gap < 0 {
+		return nil, fmt.Errorf("invalid time series split of %d folds with a gap of %d", k, gap)
+	}
+
+	size := n / (k + 1)
+	if size == 0 || n-k*size-gap <= 0 {
+		return nil, fmt.Errorf("%d rows are too few for %d folds with a gap of %d", n, k, gap)
+	}
+
+	folds := make([]Fold, k)
+	for i := range folds {
+		start := n - (k-i)*size
+		folds[i] = Fold{Train: rowRange(0, start-gap), Test: rowRange(start, start+size)}
+	}
+
+	return folds, nil
+}
+
+// ClassLabels returns the class of every row from labels given as a 1 x N row, a column or one-hot rows
+func ClassLabels(Y *mat.Dense) []float64 {
+	rows, cols := Y.Dims()
+
+	switch {
+	case rows == 1:
+		return mat.Row(nil, 0, Y)
+	case cols == 1:
+		return mat.Col(nil, 0, Y)
+	}
+
+	labels := make([]float64, rows)
+	for i := range labels {
+		labels[i] = float64(argmax(Y.RawRowView(i)))
+	}
+
+	return labels
+}
+
+// Subset returns the given rows of data, the labels keeping their layout (a 1 x N row or a row per sample)
+func Subset(data datamodels.TrainingData, rows []int) datamodels.TrainingData {
+	subset := datamodels.TrainingData{X: selectRows(data.X, rows)}
+	if data.Y == nil {
+		return subset
+	}
+
+	if y_rows, y_cols := data.Y.Dims(); y_rows == 1 && y_cols == data.X.RawMatrix().Rows {
+		subset.Y = mat.NewDense(1, len(rows), nil)
+		for i, row := range rows {
+			subset.Y.Set(0, i, data.Y.At(0, row))
+		}
+	} else {
+		subset.Y = selectRows(data.Y, rows)
+	}
+
+	return subset
+}
+
+func selectRows(X *mat.Dense, rows []int) *mat.Dense {
+	if len(rows) == 0 {
+		// gonum can't allocate empty matrices
+		return &mat.Dense{}
+	}
+
+	_, cols := X.Dims()
+	selected := mat.NewDense(len(rows), cols, nil)
+	for i, row := range rows {
+		selected.SetRow(i, X.RawRowView(row))
+	}
+
+	return selected
+}
+
+// classRows groups the rows by class, in increasing class order
+func classRows(labels []float64) [][]int {
+	rows := make(map[float64][]int)
+	for row, label := range labels {
+		rows[label] = append(rows[label], row)
+	}
+
+	classes := make([]float64, 0, len(rows))
+	for class := range rows {
+		classes = append(classes, class)
+	}
+	sort.Float64s(classes)
+
+	grouped := make([][]int, len(classes))
+	for i, class := range classes {
+		grouped[i] = rows[class]
+	}
+
+	return grouped
+}
+
+// foldsOf builds the folds testing each of the given row sets & training on every other row
+func foldsOf(tests [][]int, n int) []Fold {
+	folds := make([]Fold, len(tests))
+
+	for i, test := range tests {
+		sort.Ints(test)
+
+		tested := make([]bool, n)
+		for _, row := range test {
+			tested[row] = true
+		}
+
+		folds[i].Test = test
+		for row := 0; row < n; row++ {
+			if !tested[row] {
+				folds[i].Train = append(folds[i].Train, row)
+			}
+		}
+	}
+
+	return folds
+}
+
+func rowRange(start, end int) []int {
+	rows := make([]int, 0, max(end-start, 0))
+	for row := start; row < end; row++ {
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func argmax(values []float64) int {
+	best := 0
+	for i, v := range values {
+		if v > values[best] {
+			best = i
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,169 @@
+package datasets
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"gonum.org/v1/gonum/mat"
+)
+
+// labels has 80 attack-free rows & 20 attacks
+func splitLabels() []float64 {
+	labels := make([]float64, 100)
+	for i := 0; i < 100; i += 5 {
+		labels[i] = 1
+	}
+
+	return labels
+}
+
+func countClass(labels []float64, rows []int, class float64) int {
+	count := 0
+	for _, row := range rows {
+		if labels[row] == class {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestStratifiedHoldout(t *testing.T) {
+	labels := splitLabels()
+
+	holdout, err := StratifiedHoldout(labels, 0.1, 0.2, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, part := range []struct {
+		name            string
+		rows            []int
+		normal, attacks int
+	}{
+		{"train", holdout.Train, 56, 14},
+		{"validation", holdout.Validation, 8, 2},
+		{"test", holdout.Test, 16, 4},
+	} {
+		if got_normal, got_attacks := countClass(labels, part.rows, 0), countClass(labels, part.rows, 1); got_normal != part.normal || got_attacks != part.attacks {
+			t.Errorf("error: %s got %d/%d rows | want %d/%d", part.name, got_normal, got_attacks, part.normal, part.attacks)
+		}
+	}
+
+	if again, _ := StratifiedHoldout(labels, 0.1, 0.2, 7); !reflect.DeepEqual(again, holdout) {
+		t.Errorf("error: the same seed gave another split")
+	}
+
+	if len(holdout.Unsplit) != 0 {
+		t.Errorf("error: got the unsplit classes %v | want none", holdout.Unsplit)
+	}
+
+	if _, err := StratifiedHoldout(labels, 0.5, 0.5, 7); err == nil {
+		t.Errorf("error: expected an error for fractions leaving nothing to train on")
+	}
+
+	// a class of 2 rows can't be held out for both validation & testing
+	holdout, err = StratifiedHoldout(append(labels, 2, 2), 0.1, 0.2, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(holdout.Unsplit, []float64{2}) {
+		t.Errorf("error: got the unsplit classes %v | want [2]", holdout.Unsplit)
+	}
+}
+
+func TestStratifiedKFold(t *testing.T) {
+	labels := splitLabels()
+
+	folds, err := StratifiedKFold(labels, 5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tested := make([]int, len(labels))
+	for i, fold := range folds {
+		if len(fold.Test) != 20 || len(fold.Train) != 80 || countClass(labels, fold.Test, 1) != 4 {
+			t.Errorf("error: fold %d tests %d rows (%d attacks) & trains on %d", i, len(fold.Test), countClass(labels, fold.Test, 1), len(fold.Train))
+		}
+		for _, row := range fold.Test {
+			tested[row]++
+		}
+	}
+	for row, count := range tested {
+		if count != 1 {
+			t.Errorf("error: row %d was tested %d times", row, count)
+		}
+	}
+
+	if _, err := StratifiedKFold(labels, 1, 1); err == nil {
+		t.Errorf("error: expected an error for a single fold")
+	}
+}
+
+func TestGroupKFold(t *testing.T) {
+	// captures of 4, 3, 2 & 1 frames
+	groups := []int{0, 0, 0, 0, 1, 1, 1, 2, 2, 3}
+
+	folds, err := GroupKFold(groups, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Fold{
+		{Train: []int{4, 5, 6, 7, 8}, Test: []int{0, 1, 2, 3, 9}},
+		{Train: []int{0, 1, 2, 3, 9}, Test: []int{4, 5, 6, 7, 8}},
+	}
+	if !reflect.DeepEqual(folds, want) {
+		t.Errorf("error: got %v | want %v", folds, want)
+	}
+
+	if _, err := GroupKFold(groups, 5); err == nil {
+		t.Errorf("error: expected an error for more folds than groups")
+	}
+}
+
+func TestTimeSplits(t *testing.T) {
+	folds, err := TimeSeriesSplit(10, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Fold{
+		{Train: []int{0, 1, 2}, Test: []int{4, 5}},
+		{Train: []int{0, 1, 2, 3, 4}, Test: []int{6, 7}},
+		{Train: []int{0, 1, 2, 3, 4, 5, 6}, Test: []int{8, 9}},
+	}
+	if !reflect.DeepEqual(folds, want) {
+		t.Errorf("error: got %v | want %v", folds, want)
+	}
+
+	holdout, err := TimeOrderedHoldout(10, 0.2, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Holdout{Train: []int{0, 1, 2, 3, 4}, Validation: []int{5, 6}, Test: []int{7, 8, 9}}); !reflect.DeepEqual(holdout, want) {
+		t.Errorf("error: got %v | want %v", holdout, want)
+	}
+
+	if _, err := TimeSeriesSplit(3, 3, 0); err == nil {
+		t.Errorf("error: expected an error for too few rows")
+	}
+}
+
+func TestSubset(t *testing.T) {
+	data := datamodels.TrainingData{
+		X: mat.NewDense(4, 2, []float64{0, 0, 1, 1, 2, 2, 3, 3}),
+		Y: mat.NewDense(1, 4, []float64{0, 1, 0, 2}),
+	}
+
+	subset := Subset(data, []int{3, 1})
+	if !mat.Equal(subset.X, mat.NewDense(2, 2, []float64{3, 3, 1, 1})) || !mat.Equal(subset.Y, mat.NewDense(1, 2, []float64{2, 1})) {
+		t.Errorf("error: got %v & %v", mat.Formatted(subset.X), mat.Formatted(subset.Y))
+	}
+
+	one_hot := mat.NewDense(3, 3, []float64{0, 1, 0, 1, 0, 0, 0, 0, 1})
+	if got := ClassLabels(one_hot); !reflect.DeepEqual(got, []float64{1, 0, 2}) {
+		t.Errorf("error: got %v | want [1 0 2]", got)
+	}
+}
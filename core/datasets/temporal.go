@@ -0,0 +1,212 @@
+package datasets
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Temporal features, computed from the traffic that preceded a frame
+const (
+	// FeatureIDInterval is the time since the previous frame with the same arbitration id
+	FeatureIDInterval Feature = "id_interval"
+	// FeaturePeriodDeviation is how far the id interval is from the period learned for the id, relative to it
+	FeaturePeriodDeviation Feature = "period_deviation"
+	// FeatureIDFrequency is the number of frames per second of the id over the sliding window
+	FeatureIDFrequency Feature = "id_frequency"
+	// FeaturePayloadHamming is the number of payload bits that changed since the previous frame with the same id
+	FeaturePayloadHamming Feature = "payload_hamming"
+	// FeaturePayloadEntropy is the Shannon entropy (in bits) of the payload bytes
+	FeaturePayloadEntropy Feature = "payload_entropy"
+	// FeatureBusLoad is the share of the bus bandwidth used over the sliding window
+	FeatureBusLoad Feature = "bus_load"
+)
+
+func (feature Feature) temporal() bool {
+	switch feature {
+	case FeatureIDInterval, FeaturePeriodDeviation, FeatureIDFrequency, FeaturePayloadHamming,
+		FeaturePayloadEntropy, FeatureBusLoad:
+		return true
+	}
+
+	return false
+}
+
+type TemporalOptions struct {
+	// Window is the length in seconds of the sliding window of id_frequency & bus_load (1s by default)
+	Window float64 `json:"window,omitempty" yaml:"window,omitempty"`
+	// Bitrate of the bus in bit/s, used by bus_load (500 kbit/s by default)
+	Bitrate float64 `json:"bitrate,omitempty" yaml:"bitrate,omitempty"`
+	// PeriodSmoothing is the weight of the latest interval in the moving average that learns the period of
+	// each id (0.05 by default)
+	PeriodSmoothing float64 `json:"period_smoothing,omitempty" yaml:"period_smoothing,omitempty"`
+}
+
+func (options TemporalOptions) withDefaults() TemporalOptions {
+	if options.Window <= 0 {
+		options.Window = 1
+	}
+	if options.Bitrate <= 0 {
+		options.Bitrate = 500_000
+	}
+	if options.PeriodSmoothing <= 0 {
+		options.PeriodSmoothing = 0.05
+	}
+
+	return options
+}
+
+// TemporalValues are the temporal features of a frame
+type TemporalValues struct {
+	IDInterval      float64
+	PeriodDeviation float64
+	IDFrequency     float64
+	PayloadHamming  float64
+	PayloadEntropy  float64
+	BusLoad         float64
+}
+
+func (values TemporalValues) get(feature Feature) float64 {
+	switch feature {
+	case FeatureIDInterval:
+		return values.IDInterval
+	case FeaturePeriodDeviation:
+		return values.PeriodDeviation
+	case FeatureIDFrequency:
+		return values.IDFrequency
+	case FeaturePayloadHamming:
+		return values.PayloadHamming
+	case FeaturePayloadEntropy:
+		return values.PayloadEntropy
+	case FeatureBusLoad:
+		return values.BusLoad
+	}
+
+	return 0
+}
+
+// idHistory is what TemporalFeatures remembers of an arbitration id
+type idHistory struct {
+	last    float64 // timestamp of the previous frame
+	period  float64 // learned period, 0 until the id was seen twice
+	payload []byte
+	frames  []float64 // timestamps of the frames in the sliding window
+}
+
+type busFrame struct {
+	timestamp float64
+	bits      float64
+}
+
+// TemporalFeatures computes the temporal features of the frames of a capture (or of live traffic) as they come,
+// frame by frame & in timestamp order. The period of every id is learned online from its intervals.
+type TemporalFeatures struct {
+	options TemporalOptions
+	ids     map[uint32]*idHistory
+	bus     []busFrame
+	busBits float64
+}
+
+func NewTemporalFeatures(options TemporalOptions) *TemporalFeatures {
+	return &TemporalFeatures{options: options.withDefaults(), ids: make(map[uint32]*idHistory)}
+}
+
+// Reset forgets the traffic seen so far, e.g. between captures
+func (temporal *TemporalFeatures) Reset() {
+	temporal.ids = make(map[uint32]*idHistory)
+	temporal.bus, temporal.busBits = nil, 0
+}
+
+// Update computes the temporal features of frame & adds it to the history
+func (temporal *TemporalFeatures) Update(frame Frame) TemporalValues {
+	values := TemporalValues{PayloadEntropy: payloadEntropy(frame.Data)}
+	window := temporal.options.Window
+
+	history, seen := temporal.ids[frame.ID]
+	if !seen {
+		history = new(idHistory)
+		temporal.ids[frame.ID] = history
+	} else {
+		values.IDInterval = frame.Timestamp - history.last
+		values.PayloadHamming = float64(hammingDistance(history.payload, frame.Data))
+
+		if history.period > 0 {
+			values.PeriodDeviation = (values.IDInterval - history.period) / history.period
+			history.period += temporal.options.PeriodSmoothing * (values.IDInterval - history.period)
+		} else {
+			history.period = values.IDInterval
+		}
+	}
+	history.last = frame.Timestamp
+	history.payload = append(history.payload[:0], frame.Data...)
+
+	history.frames = append(history.frames, frame.Timestamp)
+	for len(history.frames) > 0 && history.frames[0] <= frame.Timestamp-window {
+		history.frames = history.frames[1:]
+	}
+	values.IDFrequency = float64(len(history.frames)) / window
+
+	temporal.bus = append(temporal.bus, busFrame{frame.Timestamp, frameBits(frame)})
+	temporal.busBits += temporal.bus[len(temporal.bus)-1].bits
+	for len(temporal.bus) > 0 && temporal.bus[0].timestamp <= frame.Timestamp-window {
+		temporal.busBits -= temporal.bus[0].bits
+		temporal.bus = temporal.bus[1:]
+	}
+	values.BusLoad = temporal.busBits / (window * temporal.options.Bitrate)
+
+	return values
+}
+
+// frameBits estimates the number of bits a frame takes on the bus, without stuffing bits & including the
+// interframe space (CAN-FD frames are counted at the nominal bitrate)
+func frameBits(frame Frame) float64 {
+	overhead := 47.
+	if frame.Extended {
+		overhead = 67
+	}
+
+	if frame.Remote {
+		return overhead
+	}
+
+	return overhead + 8*float64(len(frame.Data))
+}
+
+// hammingDistance counts the bits that differ between two payloads, missing bytes count as zeros
+func hammingDistance(a, b []byte) int {
+	distance := 0
+
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y byte
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		distance += bits.OnesCount8(x ^ y)
+	}
+
+	return distance
+}
+
+// payloadEntropy is the Shannon entropy of the byte values of a payload, 0 for empty payloads
+func payloadEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	entropy := 0.
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(data))
+			entropy -= p * math.Log2(p)
+		}
+	}
+
+	return entropy
+}
@@ -0,0 +1,125 @@
+package datasets
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemporalFeatures(t *testing.T) {
+	temporal := NewTemporalFeatures(TemporalOptions{Window: 0.1, Bitrate: 100_000, PeriodSmoothing: 0.5})
+
+	// 0x100 every 10ms, with an injected frame 2ms after the fourth one
+	frames := []Frame{
+		{Timestamp: 0.00, ID: 0x100, Data: []byte{0, 0, 0, 0, 0, 0, 0, 0}},
+		{Timestamp: 0.01, ID: 0x100, Data: []byte{1, 0, 0, 0, 0, 0, 0, 0}},
+		{Timestamp: 0.02, ID: 0x100, Data: []byte{3, 0, 0, 0, 0, 0, 0, 0}},
+		{Timestamp: 0.03, ID: 0x100, Data: []byte{3, 0, 0, 0, 0, 0, 0, 0}},
+		{Timestamp: 0.032, ID: 0x100, Data: []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{Timestamp: 0.2, ID: 0x200, Extended: true, Data: []byte{1, 2, 3, 4}},
+	}
+
+	var values []TemporalValues
+	for _, frame := range frames {
+		values = append(values, temporal.Update(frame))
+	}
+
+	for i, want := range []struct {
+		interval, deviation, frequency, hamming float64
+	}{
+		{0, 0, 10, 0},
+		{0.01, 0, 20, 1},
+		{0.01, 0, 30, 1},
+		{0.01, 0, 40, 0},
+		{0.002, -0.8, 50, 62},
+		{0, 0, 10, 0},
+	} {
+		got := values[i]
+		if !near(got.IDInterval, want.interval) || !near(got.PeriodDeviation, want.deviation) ||
+			!near(got.IDFrequency, want.frequency) || got.PayloadHamming != want.hamming {
+			t.Errorf("error: frame %d: got %+v | want %+v", i, got, want)
+		}
+	}
+
+	// 4 frames of 111 bits in the window, then the extended frame alone
+	if !near(values[3].BusLoad, 4*111/(0.1*100_000)) || !near(values[5].BusLoad, (67+32)/(0.1*100_000)) {
+		t.Errorf("error: got bus loads %f & %f", values[3].BusLoad, values[5].BusLoad)
+	}
+
+	if values[0].PayloadEntropy != 0 || values[4].PayloadEntropy != 0 || !near(values[1].PayloadEntropy, -(7./8*math.Log2(7./8)+1./8*math.Log2(1./8))) || values[5].PayloadEntropy != 2 {
+		t.Errorf("error: got entropies %f, %f, %f & %f", values[0].PayloadEntropy, values[1].PayloadEntropy, values[4].PayloadEntropy, values[5].PayloadEntropy)
+	}
+
+	temporal.Reset()
+	if got := temporal.Update(frames[1]); got.IDInterval != 0 || got.IDFrequency != 10 {
+		t.Errorf("error: got %+v after a reset", got)
+	}
+}
+
+func TestTemporalSchema(t *testing.T) {
+	schema, err := LoadCANSchema("../../configs/temporal_schema.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"arbitration_id", "df1", "df2", "df3", "df4", "df5", "df6", "df7", "df8", "time_interval",
+		"id_interval", "period_deviation", "id_frequency", "payload_hamming", "payload_entropy", "bus_load"}
+	if names := schema.FeatureNames(); !reflect.DeepEqual(names, want) {
+		t.Errorf("error: got %v | want %v", names, want)
+	}
+
+	capture := "timestamp,arbitration_id,data_field,attack\n" +
+		"0.00,100,0000000000000000,0\n" +
+		"0.25,200,00,0\n" +
+		"0.50,100,0100000000000000,0\n" +
+		"0.60,100,0100000000000000,1\n"
+
+	x, _, err := ReadCANCSV(strings.NewReader(capture), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second frame of 0x100, 0.5s after the first, with a bit flipped, 2 frames of the id in the 1s window
+	// & 3 frames of 111, 55 & 111 bits on the bus
+	row := x[2]
+	temporal := []float64{0.5, 0, 2, 1, -(7./8*math.Log2(7./8) + 1./8*math.Log2(1./8)), (111 + 55 + 111) / 500000.}
+	if !near(row[9], 0.25) || len(row) != len(want) {
+		t.Fatalf("error: got %v", row)
+	}
+	for i, value := range temporal {
+		if !near(row[10+i], value) {
+			t.Errorf("error: %s got %f | want %f", want[10+i], row[10+i], value)
+		}
+	}
+
+	// the next frame comes 0.1s after 0x100's learned period of 0.5s
+	if !near(x[3][11], -0.8) {
+		t.Errorf("error: got a period deviation of %f | want -0.8", x[3][11])
+	}
+
+	// the extractor gives the same rows frame by frame
+	reader, err := schema.NewReader(strings.NewReader(capture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	extractor := schema.NewFeatureExtractor()
+	for i := range x {
+		frame, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row := extractor.Update(frame); !reflect.DeepEqual(row, x[i]) {
+			t.Errorf("error: row %d: got %v | want %v", i, row, x[i])
+		}
+	}
+
+	schema.Temporal.PeriodSmoothing = 2
+	if err := schema.Validate(); err == nil {
+		t.Errorf("error: expected an error for a period smoothing above 1")
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,80 @@
+package datasets
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"gonum.org/v1/gonum/mat"
+)
+
+// TimedFrames holds the frames of captures in time order along with the timestamp of every frame (in seconds on its
+// capture's clock) & the index of the capture it comes from, e.g. to measure the false alarms & the detection
+// latency of a detector (see metrics.NewOperationalReport)
+type TimedFrames struct {
+	X          [][]float64
+	Y          []float64
+	Timestamps []float64
+	Captures   []int
+	// Files are the captures, indexed by Captures
+	Files []string
+}
+
+// ReadTimedFrames reads the frames at path (a capture, a folder of captures or a folder of class folders) like
+// ReadCANCaptures, keeping their timestamps
+func ReadTimedFrames(path string, schema *CANSchema) (TimedFrames, error) {
+	dataset, err := NewCSVDataset(path, schema)
+	if err != nil {
+		return TimedFrames{}, err
+	}
+
+	frames := TimedFrames{Files: dataset.Files}
+	for i, file := range dataset.Files {
+		if err = frames.readCapture(dataset.Root, file, i, dataset.Schema); err != nil {
+			return TimedFrames{}, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+
+	if len(frames.X) == 0 {
+		return TimedFrames{}, fmt.Errorf("no CAN frames found in %s", path)
+	}
+
+	return frames, nil
+}
+
+func (frames *TimedFrames) readCapture(root, path string, capture int, schema *CANSchema) error {
+	reader, file, err := openCapture(root, path, schema)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	extractor := schema.NewFeatureExtractor()
+	for {
+		frame, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		frames.X = append(frames.X, extractor.Update(frame))
+		frames.Y = append(frames.Y, frame.Label)
+		frames.Timestamps = append(frames.Timestamps, frame.Timestamp)
+		frames.Captures = append(frames.Captures, capture)
+	}
+}
+
+// Data returns the frames as in-memory data with a sparse 1 x N row of labels, in time order
+func (frames TimedFrames) Data() datamodels.TrainingData {
+	X := mat.NewDense(len(frames.X), len(frames.X[0]), nil)
+	for i, row := range frames.X {
+		X.SetRow(i, row)
+	}
+
+	return datamodels.TrainingData{
+		X: X,
+		Y: mat.NewDense(1, len(frames.Y), append([]float64(nil), frames.Y...)),
+	}
+}
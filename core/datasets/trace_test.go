@@ -0,0 +1,140 @@
+package datasets
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleTraceFrames are the frames of testdata/sample.asc & testdata/sample.blf, both traces hold the same
+// traffic. sample.blf splits its objects over a zlib, a raw & another zlib log container.
+var sampleTraceFrames = []Frame{
+	{Timestamp: 0.001, Channel: "1", ID: 0x123, DLC: 8, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+	{Timestamp: 0.0015, Channel: "2", ID: 0x1F334455, Extended: true, DLC: 4, Data: []byte{0x11, 0x22, 0x33, 0x44}},
+	{Timestamp: 0.002, Channel: "1", ID: 0x456, DLC: 4, Remote: true},
+	{Timestamp: 0.0025, Channel: "1", ID: 0x321, DLC: 12, FD: true, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}},
+	{Timestamp: 0.003, Channel: "2", ID: 0x7FF, DLC: 3, FD: true, Data: []byte{0xAA, 0xBB, 0xCC}},
+	{Timestamp: 0.0035, Channel: "1", ID: 0x0C4, DLC: 2, Data: []byte{0xFF, 0x00}},
+}
+
+func readTrace(t *testing.T, reader FrameReader) []Frame {
+	t.Helper()
+
+	var frames []Frame
+	for {
+		frame, err := reader.Next()
+		if err == io.EOF {
+			return frames
+		}
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		frames = append(frames, frame)
+	}
+}
+
+func openTrace(t *testing.T, path string) *os.File {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	return file
+}
+
+func TestASCReader(t *testing.T) {
+	frames := readTrace(t, NewASCReader(openTrace(t, "testdata/sample.asc")))
+	if !reflect.DeepEqual(frames, sampleTraceFrames) {
+		t.Errorf("error: got %+v | want %+v", frames, sampleTraceFrames)
+	}
+
+	reader := NewASCReader(openTrace(t, "testdata/sample.asc"))
+	reader.KeepErrorFrames = true
+	if frames = readTrace(t, reader); len(frames) != 7 || !frames[3].ErrorFrame || frames[3].Timestamp != 0.00225 {
+		t.Errorf("error: expected the error frame at 0.00225s, got %+v", frames)
+	}
+
+	trace := "base dec  timestamps relative\n" +
+		"   0.001000 1  291             Rx   d 2 1 255\n" +
+		"   0.000500 1  291             Rx   d 2 1 255\n" +
+		"   0.000500 1  291             Rx   d 2 1 2AA\n"
+	reader = NewASCReader(strings.NewReader(trace))
+	if frame, _ := reader.Next(); frame.ID != 0x123 || !bytes.Equal(frame.Data, []byte{1, 255}) {
+		t.Errorf("error: got %+v for a decimal trace", frame)
+	}
+	if frame, _ := reader.Next(); frame.Timestamp != 0.0015 {
+		t.Errorf("error: got timestamp %f | want 0.0015 for a relative trace", frame.Timestamp)
+	}
+	if _, err := reader.Next(); err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("error: got %v | want an invalid byte on line 4", err)
+	}
+}
+
+func TestBLFReader(t *testing.T) {
+	reader, err := NewBLFReader(openTrace(t, "testdata/sample.blf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames := readTrace(t, reader)
+	if !reflect.DeepEqual(frames, sampleTraceFrames) {
+		t.Errorf("error: got %+v | want %+v", frames, sampleTraceFrames)
+	}
+
+	reader, _ = NewBLFReader(openTrace(t, "testdata/sample.blf"))
+	reader.KeepErrorFrames = true
+	if frames = readTrace(t, reader); len(frames) != 7 || !frames[3].ErrorFrame || frames[3].Timestamp != 0.00225 {
+		t.Errorf("error: expected the error frame at 0.00225s, got %+v", frames)
+	}
+
+	if _, err := NewBLFReader(openTrace(t, "testdata/sample.asc")); err == nil {
+		t.Errorf("error: expected an error for a file that isn't a blf")
+	}
+
+	// a file cut in the middle of its last container
+	data, err := os.ReadFile("testdata/sample.blf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err = NewBLFReader(bytes.NewReader(data[:len(data)-40]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, err = reader.Next()
+	}
+	if err == io.EOF {
+		t.Errorf("error: expected an error for a truncated file")
+	}
+}
+
+func TestReadTraceFolder(t *testing.T) {
+	x, y, err := ReadCANPath("testdata", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(x) != 2*len(sampleTraceFrames) || len(y) != len(x) {
+		t.Fatalf("error: got %d rows | want %d", len(x), 2*len(sampleTraceFrames))
+	}
+
+	// both traces give the same feature rows
+	for i := range sampleTraceFrames {
+		if !reflect.DeepEqual(x[i], x[len(sampleTraceFrames)+i]) {
+			t.Errorf("error: row %d: asc %v | blf %v", i, x[i], x[len(sampleTraceFrames)+i])
+		}
+	}
+
+	want := []float64{0x321, 1, 2, 3, 4, 5, 6, 7, 8, 0.0005}
+	for i := range want {
+		if diff := x[3][i] - want[i]; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("error: got features %v | want %v", x[3], want)
+			break
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package datasets
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/saent-x/ids-nn/core/tensor"
+)
+
+// WindowGrouping tells which frames end up in the same window
+type WindowGrouping string
+
+const (
+	// GroupByBus windows consecutive frames of the whole bus
+	GroupByBus WindowGrouping = "bus"
+	// GroupByID windows consecutive frames of the same arbitration id
+	GroupByID WindowGrouping = "id"
+)
+
+// LabelPolicy labels a window from the labels of its frames
+type LabelPolicy string
+
+const (
+	// LabelAnyAttack labels the window as an attack as soon as one of its frames is, with the most frequent
+	// attack class of the window
+	LabelAnyAttack LabelPolicy = "any"
+	// LabelMajority labels the window with the most frequent class of its frames, ties going to the attack
+	LabelMajority LabelPolicy = "majority"
+	// LabelLastFrame labels the window with the class of its last frame
+	LabelLastFrame LabelPolicy = "last"
+	// LabelAll gives the window a multi-hot target with every class of its frames, e.g. for windows that
+	// contain several concurrent attacks; it needs WindowOptions.Classes
+	LabelAll LabelPolicy = "all"
+)
+
+type WindowOptions struct {
+	// Size is the number of frames per window
+	Size int
+	// Stride is the number of frames between the starts of consecutive windows of a group (1 by default),
+	// Stride = Size gives windows that don't overlap
+	Stride int
+
+	// GroupBy is GroupByBus by default, GroupByID reads the arbitration id from the IDFeature column of the samples
+	// (see CANSchema.FeatureIndex)
+	GroupBy   WindowGrouping
+	IDFeature int
+
+	// Label is LabelAnyAttack by default
+	Label LabelPolicy
+	// Classes is the number of columns of the multi-hot targets of LabelAll, whose frames can have a single label
+	// or multi-hot targets themselves (see CSVDataset.MultiLabel)
+	Classes int
+}
+
+func (options WindowOptions) Validate() error {
+	var errs []error
+
+	if options.Size <= 0 {
+		errs = append(errs, fmt.Errorf("window size must be positive, got %d", options.Size))
+	}
+	if options.Stride < 0 {
+		errs = append(errs, fmt.Errorf("window stride must be positive, got %d", options.Stride))
+	}
+
+	switch options.GroupBy {
+	case "", GroupByBus, GroupByID:
+	default:
+		errs = append(errs, fmt.Errorf("unknown window grouping %q (expected bus or id)", options.GroupBy))
+	}
+	if options.IDFeature < 0 {
+		errs = append(errs, fmt.Errorf("invalid id feature %d", options.IDFeature))
+	}
+
+	switch options.Label {
+	case "", LabelAnyAttack, LabelMajority, LabelLastFrame:
+	case LabelAll:
+		if options.Classes <= 0 {
+			errs = append(errs, fmt.Errorf("the all label policy needs the number of classes, got %d", options.Classes))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown label policy %q (expected any, majority, last or all)", options.Label))
+	}
+
+	return errors.Join(errs...)
+}
+
+// InputShape is the shape of the windows of frames with the given number of features, to declare with
+// Model.SetInputShape: each window is flattened frame after frame into a row of the batches
+func (options WindowOptions) InputShape(features int) tensor.Shape {
+	return tensor.SequenceShape(options.Size, features)
+}
+
+// CaptureDataset is an IterableDataset made of separate captures, windows never span two of them
+type CaptureDataset interface {
+	IterableDataset
+	Captures() []IterableDataset
+}
+
+// WindowDataset turns the frames of an IterableDataset into windows of consecutive frames, lazily: only the
+// last Size frames of each group are held in memory. Each sample is a window flattened frame after frame
+// with the label given by the label policy, so streaming loaders batch windows like any other sample.
+type WindowDataset struct {
+	Source  IterableDataset
+	Options WindowOptions
+}
+
+func NewWindowDataset(source IterableDataset, options WindowOptions) (*WindowDataset, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &WindowDataset{Source: source, Options: options}, nil
+}
+
+func (dataset *WindowDataset) Samples() iter.Seq2[Sample, error] {
+	return func(yield func(Sample, error) bool) {
+		sources := []IterableDataset{dataset.Source}
+		if captures, ok := dataset.Source.(CaptureDataset); ok {
+			sources = captures.Captures()
+		}
+
+		for _, source := range sources {
+			if !dataset.windows(source, yield) {
+				return
+			}
+		}
+	}
+}
+
+// windowGroup holds the last frames of a group & how many frames it had so far
+type windowGroup struct {
+	frames []Sample
+	count  int
+}
+
+// windows yields the windows of a single capture, returning false once the consumer stops or an error was yielded
+func (dataset *WindowDataset) windows(source IterableDataset, yield func(Sample, error) bool) bool {
+	options := dataset.Options
+	stride := max(options.Stride, 1)
+
+	groups := make(map[float64]*windowGroup)
+	features := -1
+
+	for sample, err := range source.Samples() {
+		if err != nil {
+			yield(Sample{}, err)
+			return false
+		}
+
+		if features < 0 {
+			features = len(sample.X)
+		}
+		if len(sample.X) != features || !dataset.validTarget(sample.Y) {
+			yield(Sample{}, fmt.Errorf("windows need frames with %d features & a single label, got %d & %d", features, len(sample.X), len(sample.Y)))
+			return false
+		}
+
+		key := 0.
+		if options.GroupBy == GroupByID {
+			if options.IDFeature >= features {
+				yield(Sample{}, fmt.Errorf("id feature %d out of range for %d features", options.IDFeature, features))
+				return false
+			}
+			key = sample.X[options.IDFeature]
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			group = &windowGroup{frames: make([]Sample, 0, options.Size)}
+			groups[key] = group
+		}
+
+		if len(group.frames) == options.Size {
+			copy(group.frames, group.frames[1:])
+			group.frames = group.frames[:options.Size-1]
+		}
+		group.frames = append(group.frames, sample)
+		group.count++
+
+		if group.count < options.Size || (group.count-options.Size)%stride != 0 {
+			continue
+		}
+
+		window, err := dataset.window(group.frames, features)
+		if !yield(window, err) || err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validTarget tells if a frame has a single label or, with LabelAll, multi-hot targets
+func (dataset *WindowDataset) validTarget(y []float64) bool {
+	return len(y) == 1 || (dataset.Options.Label == LabelAll && len(y) == dataset.Options.Classes)
+}
+
+func (dataset *WindowDataset) window(frames []Sample, features int) (Sample, error) {
+	X := make([]float64, 0, len(frames)*features)
+	for _, frame := range frames {
+		X = append(X, frame.X...)
+	}
+
+	if dataset.Options.Label == LabelAll {
+		target, err := windowLabels(frames, dataset.Options.Classes)
+		return Sample{X: X, Y: target}, err
+	}
+
+	labels := make([]float64, len(frames))
+	for i, frame := range frames {
+		labels[i] = frame.Y[0]
+	}
+
+	return Sample{X: X, Y: []float64{windowLabel(labels, dataset.Options.Label)}}, nil
+}
+
+// windowLabels is the multi-hot union of the classes of the frames of a window
+func windowLabels(frames []Sample, classes int) ([]float64, error) {
+	var labels []float64
+	for _, frame := range frames {
+		if len(frame.Y) == 1 {
+			labels = append(labels, frame.Y[0])
+			continue
+		}
+
+		for class, set := range frame.Y {
+			if set >= 0.5 {
+				labels = append(labels, float64(class))
+			}
+		}
+	}
+
+	return MultiHot(labels, classes)
+}
+
+// windowLabel applies a label policy to the labels of the frames of a window, 0 being attack-free traffic
+func windowLabel(labels []float64, policy LabelPolicy) float64 {
+	if policy == LabelLastFrame {
+		return labels[len(labels)-1]
+	}
+
+	counts := make(map[float64]int)
+	for _, label := range labels {
+		counts[label]++
+	}
+
+	best, best_count := 0., 0
+	for label, count := range counts {
+		if policy != LabelMajority && label == 0 {
+			continue // with LabelAnyAttack the window is attack-free only if every frame is
+		}
+		if count > best_count || (count == best_count && label > best) {
+			best, best_count = label, count
+		}
+	}
+
+	return best
+}
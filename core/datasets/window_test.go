@@ -0,0 +1,157 @@
+package datasets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func collectWindows(t *testing.T, source IterableDataset, options WindowOptions) []Sample {
+	t.Helper()
+
+	windows, err := NewWindowDataset(source, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var samples []Sample
+	for sample, err := range windows.Samples() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples
+}
+
+func TestWindowDataset(t *testing.T) {
+	// frames of ids 1 & 2 alternating, each row being (id, index)
+	X := mat.NewDense(6, 2, []float64{1, 0, 2, 1, 1, 2, 2, 3, 1, 4, 2, 5})
+	y := mat.NewDense(1, 6, []float64{0, 0, 2, 1, 1, 0})
+	source := NewMatrixDataset(X, y)
+
+	windows := collectWindows(t, source, WindowOptions{Size: 3})
+	if len(windows) != 4 {
+		t.Fatalf("error: got %d windows | want 4", len(windows))
+	}
+	if want := []float64{1, 0, 2, 1, 1, 2}; !reflect.DeepEqual(windows[0].X, want) {
+		t.Errorf("error: got window %v | want %v", windows[0].X, want)
+	}
+
+	for _, tc := range []struct {
+		policy LabelPolicy
+		want   []float64
+	}{
+		{LabelAnyAttack, []float64{2, 2, 1, 1}},
+		{LabelMajority, []float64{0, 2, 1, 1}}, // ties go to the attack
+		{LabelLastFrame, []float64{2, 1, 1, 0}},
+	} {
+		var labels []float64
+		for _, window := range collectWindows(t, source, WindowOptions{Size: 3, Label: tc.policy}) {
+			labels = append(labels, window.Y[0])
+		}
+		if !reflect.DeepEqual(labels, tc.want) {
+			t.Errorf("error: %s got labels %v | want %v", tc.policy, labels, tc.want)
+		}
+	}
+
+	windows = collectWindows(t, source, WindowOptions{Size: 2, Stride: 2})
+	if len(windows) != 3 || windows[1].X[1] != 2 || windows[2].X[1] != 4 {
+		t.Errorf("error: got %v for non-overlapping windows", windows)
+	}
+
+	// per id: windows of (0, 2) & (2, 4) for id 1, (1, 3) & (3, 5) for id 2
+	windows = collectWindows(t, source, WindowOptions{Size: 2, GroupBy: GroupByID})
+	var starts []float64
+	for _, window := range windows {
+		if window.X[0] != window.X[2] {
+			t.Errorf("error: window %v mixes ids", window.X)
+		}
+		starts = append(starts, window.X[1])
+	}
+	if !reflect.DeepEqual(starts, []float64{0, 1, 2, 3}) {
+		t.Errorf("error: got windows starting at frames %v | want [0 1 2 3]", starts)
+	}
+
+	if _, err := NewWindowDataset(source, WindowOptions{Size: 0, Label: "first"}); err == nil {
+		t.Errorf("error: expected an error for invalid options")
+	}
+}
+
+func TestWindowsOverCaptures(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.csv", "b.csv"} {
+		capture := "timestamp,arbitration_id,data_field,attack\n" +
+			"0.0,001,00,0\n0.1,002,00,0\n0.2,001,00,1\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(capture), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dataset, err := NewCSVDataset(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 windows per capture, none spanning both
+	options := WindowOptions{Size: 2}
+	windows, err := NewWindowDataset(dataset, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := NewStreamingDataLoader(windows, DataLoaderOptions{})
+
+	features := DefaultCANSchema().NumFeatures()
+	for batch, err := range loader.Batches(0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows, cols := batch.X.Dims()
+		if rows != 4 || cols != options.InputShape(features).FeatureSize() {
+			t.Errorf("error: got a %dx%d batch | want 4x%d", rows, cols, 2*features)
+		}
+		if interval := batch.X.At(2, 9); interval != 0 {
+			t.Errorf("error: the first window of the second capture starts with an interval of %v", interval)
+		}
+		if labels := mat.Col(nil, 0, batch.Y); !reflect.DeepEqual(labels, []float64{0, 1, 0, 1}) {
+			t.Errorf("error: got labels %v", labels)
+		}
+	}
+
+	schema := DefaultCANSchema()
+	if schema.FeatureIndex(FeatureID) != 0 || schema.FeatureIndex(FeatureTimeInterval) != 9 || schema.FeatureIndex(FeatureDLC) != -1 {
+		t.Errorf("error: got feature indexes %d, %d & %d", schema.FeatureIndex(FeatureID), schema.FeatureIndex(FeatureTimeInterval), schema.FeatureIndex(FeatureDLC))
+	}
+}
+
+func TestMultiLabelWindows(t *testing.T) {
+	// frames with a single label & multi-hot frames of concurrent attacks 1 & 2
+	X := mat.NewDense(4, 1, []float64{0, 1, 2, 3})
+	single := NewMatrixDataset(X, mat.NewDense(1, 4, []float64{0, 1, 0, 2}))
+	multi := NewMatrixDataset(X, mat.NewDense(4, 3, []float64{1, 0, 0, 0, 1, 1, 1, 0, 0, 1, 0, 0}))
+
+	for name, tc := range map[string]struct {
+		source IterableDataset
+		want   [][]float64
+	}{
+		"single": {single, [][]float64{{0, 1, 0}, {0, 1, 0}, {0, 0, 1}}},
+		"multi":  {multi, [][]float64{{0, 1, 1}, {0, 1, 1}, {1, 0, 0}}},
+	} {
+		var targets [][]float64
+		for _, window := range collectWindows(t, tc.source, WindowOptions{Size: 2, Label: LabelAll, Classes: 3}) {
+			targets = append(targets, window.Y)
+		}
+		if !reflect.DeepEqual(targets, tc.want) {
+			t.Errorf("error: %s got targets %v | want %v", name, targets, tc.want)
+		}
+	}
+
+	if _, err := NewWindowDataset(single, WindowOptions{Size: 2, Label: LabelAll}); err == nil {
+		t.Errorf("error: expected an error for the all policy without classes")
+	}
+}
@@ -0,0 +1,20 @@
+package core
+
+// DefaultValue returns value, or fallback when it is 0 (left unset)
+func DefaultValue(value, fallback float64) float64 {
+	if value == 0 {
+		return fallback
+	}
+
+	return value
+}
+
+// OptionalValue returns the value pointed to, or fallback when it is nil (left unset), for the settings that can be
+// set to 0
+func OptionalValue(value *float64, fallback float64) float64 {
+	if value == nil {
+		return fallback
+	}
+
+	return *value
+}
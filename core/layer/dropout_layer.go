@@ -1,6 +1,7 @@
 package layer
 
 import (
+	"github.com/saent-x/ids-nn/core/backend"
 	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/stat/distuv"
 )
@@ -24,6 +25,7 @@ func (dropoutLayer *DropoutLayer) Forward(inputs *mat.Dense, training bool) {
 
 	if !training {
 		dropoutLayer.Output = mat.DenseCopyOf(inputs)
+		dropoutLayer.BinaryMask = nil
 		return
 	}
 
@@ -43,13 +45,16 @@ func (dropoutLayer *DropoutLayer) Forward(inputs *mat.Dense, training bool) {
 	}, dropoutLayer.BinaryMask)
 
 	// Apply mask to inputs (element-wise multiplication)
-	dropoutLayer.Output = mat.NewDense(rows, cols, nil)
-	dropoutLayer.Output.MulElem(inputs, dropoutLayer.BinaryMask)
+	dropoutLayer.Output = backend.Get().MulElem(inputs, dropoutLayer.BinaryMask)
 }
 
+// Backward passes the gradients of the kept inputs, every input is kept by an inference pass (e.g. when explaining
+// the model)
 func (dropoutLayer *DropoutLayer) Backward(d_values *mat.Dense) {
-	var new_dinputs mat.Dense
-	new_dinputs.MulElem(d_values, dropoutLayer.BinaryMask)
+	if dropoutLayer.BinaryMask == nil {
+		dropoutLayer.D_Inputs = mat.DenseCopyOf(d_values)
+		return
+	}
 
-	dropoutLayer.D_Inputs = mat.DenseCopyOf(&new_dinputs)
+	dropoutLayer.D_Inputs = backend.Get().MulElem(d_values, dropoutLayer.BinaryMask)
 }
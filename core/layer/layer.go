@@ -1,7 +1,10 @@
 package layer
 
 import (
+	"fmt"
+	"github.com/saent-x/ids-nn/core/backend"
 	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/tensor"
 	"github.com/samber/lo"
 	"gonum.org/v1/gonum/mat"
 	"math"
@@ -24,10 +27,22 @@ type ILayerNavigation interface {
 	SetNextLayer(next interface{})
 }
 
+// IShapeInference is used at Finalize time to propagate shapes through the network
+type IShapeInference interface {
+	// InferShape returns the output shape for the given input shape or an error if the layer can't accept it
+	InferShape(input tensor.Shape) (tensor.Shape, error)
+}
+
+// IParameterized is implemented by layers that own parameters
+type IParameterized interface {
+	ParameterCount() (trainable int, non_trainable int)
+}
+
 // this interface abstracts all layers and activations
 type ILayer interface {
 	IDenseLayer
 	ILayerNavigation
+	IShapeInference
 }
 
 type Layer struct {
@@ -91,84 +106,55 @@ func initializeWeightsnBias(numInputs, numOutputs int) (*mat.Dense, *mat.Dense)
 func (layer *Layer) Forward(inputs *mat.Dense, training bool) {
 	layer.Inputs = mat.DenseCopyOf(inputs) // set inputs to be used for backpropagation
 
-	// calculate dot product between inputs and weights and store in output var.
-	var output mat.Dense
-	output.Mul(inputs, layer.Weights)
-
-	// adds a bias to each row of the resulting dot product
-	output.Apply(func(i, j int, value float64) float64 {
-		return value + layer.Biases.At(0, j)
-	}, &output)
+	b := backend.Get()
 
-	layer.Output = mat.DenseCopyOf(&output)
+	// calculate dot product between inputs and weights, then add a bias to each row of the result
+	layer.Output = b.BroadcastAdd(b.MatMul(inputs, layer.Weights), layer.Biases)
 }
 
 func (layer *Layer) Backward(d_values *mat.Dense) {
-	_, c := d_values.Dims()
-	inputs_T := layer.Inputs.T()
-	r0, _ := inputs_T.Dims()
+	b := backend.Get()
 
 	// Gradients on parameter - dot product between inputs and d_values
-	layer.D_Weights = mat.NewDense(r0, c, nil)
-	layer.D_Weights.Mul(inputs_T, d_values)
-
-	// sum all cols in kd_values
-	//col-wise and retain dims
-	layer.D_Biases = mat.NewDense(1, c, nil)
-	for i := 0; i < c; i++ {
-		layer.D_Biases.SetCol(i, []float64{mat.Sum(d_values.ColView(i))})
-	}
+	layer.D_Weights = b.MatMul(layer.Inputs.T(), d_values)
 
-	if layer.Weight_Regularizer_L1 > 0 {
-		d_l1 := mat.DenseCopyOf(layer.Weights)
-		d_l1.Apply(func(i, j int, v float64) float64 {
-			return lo.Ternary(v < 0, -1., 1.)
-		}, layer.Weights)
+	// sum all cols in d_values - col-wise and retain dims
+	layer.D_Biases = b.SumCols(d_values)
 
-		var new_dweights mat.Dense
-		new_dweights.Apply(func(i, j int, v float64) float64 {
-			return layer.Weight_Regularizer_L1 * v
-		}, d_l1)
+	sign := func(v float64) float64 {
+		return lo.Ternary(v < 0, -1., 1.)
+	}
 
-		layer.D_Weights.Add(layer.D_Weights, &new_dweights)
+	if layer.Weight_Regularizer_L1 > 0 {
+		d_l1 := b.Apply(sign, layer.Weights)
+		layer.D_Weights = b.Add(layer.D_Weights, b.Scale(layer.Weight_Regularizer_L1, d_l1))
 	}
 
 	if layer.Weight_Regularizer_L2 > 0 {
-		var new_dweights mat.Dense
-		new_dweights.Apply(func(i, j int, v float64) float64 {
-			return (2 * layer.Weight_Regularizer_L2) * v
-		}, layer.Weights)
-
-		layer.D_Weights.Add(layer.D_Weights, &new_dweights)
+		layer.D_Weights = b.Add(layer.D_Weights, b.Scale(2*layer.Weight_Regularizer_L2, layer.Weights))
 	}
 
 	if layer.Biases_Regularizer_L1 > 0 {
-		d_l1 := mat.DenseCopyOf(layer.Biases)
-		d_l1.Apply(func(i, j int, v float64) float64 {
-			return lo.Ternary(v < 0, -1., 1.)
-		}, layer.Biases)
-
-		var new_dbiases mat.Dense
-		new_dbiases.Apply(func(i, j int, v float64) float64 {
-			return layer.Biases_Regularizer_L1 * v
-		}, d_l1)
-
-		layer.D_Biases.Add(layer.D_Biases, &new_dbiases)
+		d_l1 := b.Apply(sign, layer.Biases)
+		layer.D_Biases = b.Add(layer.D_Biases, b.Scale(layer.Biases_Regularizer_L1, d_l1))
 	}
 
 	if layer.Biases_Regularizer_L2 > 0 {
-		var new_dbiases mat.Dense
-		new_dbiases.Apply(func(i, j int, v float64) float64 {
-			return (2 * layer.Biases_Regularizer_L2) * v
-		}, layer.Biases)
-
-		layer.D_Biases.Add(layer.D_Biases, &new_dbiases)
+		layer.D_Biases = b.Add(layer.D_Biases, b.Scale(2*layer.Biases_Regularizer_L2, layer.Biases))
 	}
 
-	var result mat.Dense
-	result.Mul(d_values, layer.Weights.T())
+	layer.D_Inputs = b.MatMul(d_values, layer.Weights.T())
+}
+
+// InferShape checks the flattened input width against the weights & replaces the non-batch axes with the neurons
+func (layer *Layer) InferShape(input tensor.Shape) (tensor.Shape, error) {
+	n_inputs, n_neurons := layer.Weights.Dims()
 
-	layer.D_Inputs = mat.DenseCopyOf(&result)
+	if input.FeatureSize() != n_inputs {
+		return nil, fmt.Errorf("dense layer expects %d input features but got %d from shape %v", n_inputs, input.FeatureSize(), input)
+	}
+
+	return tensor.FeatureShape(n_neurons).WithBatch(input.BatchSize()), nil
 }
 
 func (layer *Layer) GetParameters() datamodels.ModelParameter {
@@ -179,3 +165,11 @@ func (layer *Layer) SetParameters(parameter datamodels.ModelParameter) {
 	layer.Weights = mat.DenseCopyOf(parameter.Weights)
 	layer.Biases = mat.DenseCopyOf(parameter.Biases)
 }
+
+// ParameterCount returns the number of trainable (weights & biases) and non-trainable parameters
+func (layer *Layer) ParameterCount() (int, int) {
+	w_rows, w_cols := layer.Weights.Dims()
+	b_rows, b_cols := layer.Biases.Dims()
+
+	return w_rows*w_cols + b_rows*b_cols, 0
+}
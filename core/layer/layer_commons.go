@@ -1,6 +1,9 @@
 package layer
 
-import "gonum.org/v1/gonum/mat"
+import (
+	"github.com/saent-x/ids-nn/core/tensor"
+	"gonum.org/v1/gonum/mat"
+)
 
 type LayerCommons struct {
 	Inputs   *mat.Dense
@@ -29,3 +32,8 @@ func (layerCommons *LayerCommons) Reset() {
 	layerCommons.Output = nil
 	layerCommons.Inputs = nil
 }
+
+// InferShape defaults to passing the shape through unchanged (activations, dropout etc.)
+func (layerCommons *LayerCommons) InferShape(input tensor.Shape) (tensor.Shape, error) {
+	return input, nil
+}
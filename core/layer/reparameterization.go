@@ -0,0 +1,108 @@
+package layer
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/saent-x/ids-nn/core/tensor"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Reparameterization is the sampling layer of a variational autoencoder. Its inputs are the mean & the log-variance
+// of the latent distribution side by side (2 x latent features, e.g. from a dense layer), it outputs
+// z = mean + exp(log_var / 2) * ε with ε ~ N(0, 1) while training & the mean otherwise.
+// Its backward pass adds the gradient of Beta times the KL divergence between the latent distribution & N(0, 1),
+// averaged over the samples, which keeps the latent space smooth.
+type Reparameterization struct {
+	Beta float64
+	// Seed makes the sampled noise reproducible, 0 seeds from the clock
+	Seed int64
+	// KL is the mean KL divergence of the samples of the last training batch
+	KL float64
+
+	Epsilon *mat.Dense
+	rng     *rand.Rand
+
+	LayerCommons
+	LayerNavigation
+}
+
+func NewReparameterization(beta float64, seed int64) *Reparameterization {
+	return &Reparameterization{Beta: beta, Seed: seed}
+}
+
+// Loss is Beta times the KL divergence of the last training batch, the term its backward pass adds to the gradients
+func (reparameterization *Reparameterization) Loss() float64 {
+	return reparameterization.Beta * reparameterization.KL
+}
+
+func (reparameterization *Reparameterization) Forward(inputs *mat.Dense, training bool) {
+	reparameterization.Inputs = mat.DenseCopyOf(inputs)
+
+	rows, cols := inputs.Dims()
+	latent := cols / 2
+	reparameterization.Output = mat.NewDense(rows, latent, nil)
+
+	if !training {
+		reparameterization.Output.Copy(inputs.Slice(0, rows, 0, latent))
+		reparameterization.Epsilon = nil
+		return
+	}
+
+	if reparameterization.rng == nil {
+		seed := reparameterization.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		reparameterization.rng = rand.New(rand.NewSource(seed))
+	}
+
+	reparameterization.Epsilon = mat.NewDense(rows, latent, nil)
+	reparameterization.KL = 0
+	for i := 0; i < rows; i++ {
+		for j := 0; j < latent; j++ {
+			mean, log_var := inputs.At(i, j), inputs.At(i, latent+j)
+			epsilon := reparameterization.rng.NormFloat64()
+
+			reparameterization.Epsilon.Set(i, j, epsilon)
+			reparameterization.Output.Set(i, j, mean+math.Exp(log_var/2)*epsilon)
+			reparameterization.KL -= 0.5 * (1 + log_var - mean*mean - math.Exp(log_var)) / float64(rows)
+		}
+	}
+}
+
+func (reparameterization *Reparameterization) Backward(d_values *mat.Dense) {
+	rows, latent := d_values.Dims()
+	reparameterization.D_Inputs = mat.NewDense(rows, 2*latent, nil)
+
+	// the output of an inference pass is the mean, it doesn't depend on the log-variance
+	if reparameterization.Epsilon == nil {
+		reparameterization.D_Inputs.Slice(0, rows, 0, latent).(*mat.Dense).Copy(d_values)
+		return
+	}
+
+	for i := 0; i < rows; i++ {
+		for j := 0; j < latent; j++ {
+			mean, log_var := reparameterization.Inputs.At(i, j), reparameterization.Inputs.At(i, latent+j)
+			std := math.Exp(log_var / 2)
+			d_z := d_values.At(i, j)
+
+			// dz/dmean = 1, dz/dlog_var = ε * std / 2 & dKL/dmean = mean, dKL/dlog_var = (var - 1) / 2
+			kl_scale := reparameterization.Beta / float64(rows)
+			reparameterization.D_Inputs.Set(i, j, d_z+kl_scale*mean)
+			reparameterization.D_Inputs.Set(i, latent+j, d_z*reparameterization.Epsilon.At(i, j)*std/2+kl_scale*(std*std-1)/2)
+		}
+	}
+}
+
+// InferShape halves the features: the mean & the log-variance of every latent feature become a single sample
+func (reparameterization *Reparameterization) InferShape(input tensor.Shape) (tensor.Shape, error) {
+	features := input.FeatureSize()
+	if features <= 0 || features%2 != 0 {
+		return nil, fmt.Errorf("reparameterization layer expects an even number of features (means & log-variances) but got %d from shape %v", features, input)
+	}
+
+	return tensor.FeatureShape(features / 2).WithBatch(input.BatchSize()), nil
+}
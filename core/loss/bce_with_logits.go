@@ -0,0 +1,33 @@
+package loss
+
+import (
+	"github.com/saent-x/ids-nn/core/backend"
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// BCEWithLogits is the binary cross-entropy of sigmoid(logits), computed straight from the logits as
+// max(z, 0) - z * y + log(1 + exp(-|z|)) so it stays exact for confident predictions where the sigmoid rounds to 0 or
+// 1. A model ending with Sigmoid & BinaryCrossEntropy uses it through activation.SigmoidBinaryCrossEntropy.
+type BCEWithLogits struct {
+	Loss `json:"-"`
+}
+
+func (bceWithLogits *BCEWithLogits) Calculate(logits *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return bceWithLogits.calculate(bceWithLogits.Forward(logits, y), y, include_regularization)
+}
+
+func (bceWithLogits *BCEWithLogits) Forward(logits *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	return backend.Get().MeanRows(backend.Get().Apply2(func(y, z float64) float64 {
+		return math.Max(z, 0) - z*y + math.Log1p(math.Exp(-math.Abs(z)))
+	}, y_true, logits))
+}
+
+func (bceWithLogits *BCEWithLogits) Backward(logits *mat.Dense, y_true *mat.Dense) {
+	samples, outputs := logits.Dims()
+
+	// sigmoid(z) - y_true
+	bceWithLogits.D_Inputs = bceWithLogits.WeighGradient(backend.Get().Apply2(func(y, z float64) float64 {
+		return (1/(1+math.Exp(-z)) - y) / float64(outputs) / float64(samples)
+	}, y_true, logits), y_true)
+}
@@ -1,7 +1,7 @@
 package loss
 
 import (
-	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/backend"
 	"github.com/samber/lo"
 	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/stat"
@@ -9,11 +9,11 @@ import (
 )
 
 type BinaryCrossEntropy struct {
-	Loss
+	Loss `json:"-"`
 }
 
 func (binaryCrossEntropy *BinaryCrossEntropy) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
-	sample_losses := binaryCrossEntropy.Forward(output, y)
+	sample_losses := binaryCrossEntropy.weigh(binaryCrossEntropy.Forward(output, y), y)
 	average_loss := stat.Mean(sample_losses.RawVector().Data, nil)
 
 	binaryCrossEntropy.AccumulatedSum += mat.Sum(sample_losses)
@@ -27,62 +27,31 @@ func (binaryCrossEntropy *BinaryCrossEntropy) Calculate(output *mat.Dense, y *ma
 }
 
 func (binaryCrossEntropy *BinaryCrossEntropy) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
-	var y_pred_clipped mat.Dense
-	y_pred_clipped.Apply(func(i, j int, value float64) float64 {
+	b := backend.Get()
+
+	y_pred_clipped := b.Apply(func(value float64) float64 {
 		return lo.Clamp(value, 1e-7, 1-1e-7)
 	}, y_pred)
 
-	// -(y_true * np.log(y_pred_clipped))
-	var fn_1, fn_2, fn_3, eqn_1, eqn_2, result mat.Dense
-
-	fn_1.Apply(func(i, j int, v float64) float64 {
-		return math.Log(v)
-	}, &y_pred_clipped)
-	fn_2.Apply(func(i, j int, v float64) float64 {
-		return math.Log(1 - v)
-	}, &y_pred_clipped)
-	fn_3.Apply(func(i, j int, v float64) float64 {
-		return 1 - v
-	}, y_true)
-
-	eqn_1.MulElem(y_true, &fn_1)
-	eqn_2.MulElem(&fn_3, &fn_2)
-
-	result.Add(&eqn_1, &eqn_2)
-	result.Apply(func(i, j int, v float64) float64 {
-		return -v
-	}, &result)
-
-	sample_losses := core.MeanOnLastAxis(&result)
+	// -(y_true * log(y_pred_clipped) + (1 - y_true) * log(1 - y_pred_clipped))
+	result := b.Apply2(func(y, p float64) float64 {
+		return -(y*math.Log(p) + (1-y)*math.Log(1-p))
+	}, y_true, y_pred_clipped)
 
-	return sample_losses
+	return b.MeanRows(result)
 }
 
 func (binaryCrossEntropy *BinaryCrossEntropy) Backward(d_values *mat.Dense, y_true *mat.Dense) {
-	samples := d_values.RawMatrix().Rows
-	outputs := len(d_values.RawRowView(0))
+	samples, outputs := d_values.Dims()
+	b := backend.Get()
 
-	var clipped_d_values, one_neg_ytrue, one_neg_clipped_dvalues, y_true_div_clipped_values, one_neg_y_true_clipped_dvalues, new_dinputs mat.Dense
-	clipped_d_values.Apply(func(i, j int, value float64) float64 {
+	clipped_d_values := b.Apply(func(value float64) float64 {
 		return lo.Clamp(value, 1e-7, 1-1e-7)
 	}, d_values)
 
-	one_neg_ytrue.Apply(func(i, j int, v float64) float64 {
-		return 1 - v
-	}, y_true)
-	one_neg_clipped_dvalues.Apply(func(i, j int, v float64) float64 {
-		return 1 - v
-	}, &clipped_d_values)
-
-	y_true_div_clipped_values.DivElem(y_true, &clipped_d_values)
-	one_neg_y_true_clipped_dvalues.DivElem(&one_neg_ytrue, &one_neg_clipped_dvalues)
-
-	new_dinputs.Sub(&y_true_div_clipped_values, &one_neg_y_true_clipped_dvalues)
-
-	new_dinputs.Apply(func(i, j int, v float64) float64 {
-		result := -v / float64(outputs)
+	// -(y_true / clipped_d_values - (1 - y_true) / (1 - clipped_d_values)) / outputs / samples
+	binaryCrossEntropy.D_Inputs = binaryCrossEntropy.WeighGradient(b.Apply2(func(y, p float64) float64 {
+		result := -(y/p - (1-y)/(1-p)) / float64(outputs)
 		return result / float64(samples)
-	}, &new_dinputs)
-
-	binaryCrossEntropy.D_Inputs = mat.DenseCopyOf(&new_dinputs)
+	}, y_true, clipped_d_values), y_true)
 }
@@ -0,0 +1,89 @@
+package loss
+
+import (
+	"github.com/saent-x/ids-nn/core/backend"
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// BinaryFocalLoss is a binary cross-entropy that down-weighs the samples the model already gets right by
+// (1 - p_t)^Gamma, so training focuses on the hard & rare ones (e.g. rare attack frames). Alpha weighs the positive
+// targets & 1 - Alpha the negative ones.
+type BinaryFocalLoss struct {
+	Gamma float64 `json:"gamma"`
+	Alpha float64 `json:"alpha"`
+
+	Loss `json:"-"`
+}
+
+// NewBinaryFocalLoss creates a binary focal loss, the usual values are a gamma of 2 & an alpha of 0.25
+func NewBinaryFocalLoss(gamma, alpha float64) *BinaryFocalLoss {
+	return &BinaryFocalLoss{Gamma: gamma, Alpha: alpha}
+}
+
+func (binaryFocalLoss *BinaryFocalLoss) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return binaryFocalLoss.calculate(binaryFocalLoss.Forward(output, y), y, include_regularization)
+}
+
+func (binaryFocalLoss *BinaryFocalLoss) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	gamma, alpha := binaryFocalLoss.Gamma, binaryFocalLoss.Alpha
+
+	// -alpha * y * (1 - p)^gamma * log(p) - (1 - alpha) * (1 - y) * p^gamma * log(1 - p)
+	return backend.Get().MeanRows(backend.Get().Apply2(func(y, p float64) float64 {
+		p = clip(p)
+		return -alpha*y*math.Pow(1-p, gamma)*math.Log(p) - (1-alpha)*(1-y)*math.Pow(p, gamma)*math.Log(1-p)
+	}, y_true, y_pred))
+}
+
+func (binaryFocalLoss *BinaryFocalLoss) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	samples, outputs := d_values.Dims()
+	gamma, alpha := binaryFocalLoss.Gamma, binaryFocalLoss.Alpha
+
+	binaryFocalLoss.D_Inputs = binaryFocalLoss.WeighGradient(backend.Get().Apply2(func(y, p float64) float64 {
+		p = clip(p)
+		positive := -alpha * y * (math.Pow(1-p, gamma)/p - gamma*math.Pow(1-p, gamma-1)*math.Log(p))
+		negative := -(1 - alpha) * (1 - y) * (gamma*math.Pow(p, gamma-1)*math.Log(1-p) - math.Pow(p, gamma)/(1-p))
+		return (positive + negative) / float64(outputs) / float64(samples)
+	}, y_true, d_values), y_true)
+}
+
+// CategoricalFocalLoss is a categorical cross-entropy that down-weighs the samples the model already gets right by
+// (1 - p_t)^Gamma, the classes can be weighed with SetClassWeights (the alpha of the focal loss paper)
+type CategoricalFocalLoss struct {
+	Gamma float64 `json:"gamma"`
+
+	Loss `json:"-"`
+}
+
+// NewCategoricalFocalLoss creates a categorical focal loss, the usual gamma is 2
+func NewCategoricalFocalLoss(gamma float64) *CategoricalFocalLoss {
+	return &CategoricalFocalLoss{Gamma: gamma}
+}
+
+func (categoricalFocalLoss *CategoricalFocalLoss) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return categoricalFocalLoss.calculate(categoricalFocalLoss.Forward(output, y), y, include_regularization)
+}
+
+func (categoricalFocalLoss *CategoricalFocalLoss) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	b := backend.Get()
+	_, classes := y_pred.Dims()
+	gamma := categoricalFocalLoss.Gamma
+
+	// -sum(y * (1 - p)^gamma * log(p))
+	losses := b.SumRows(b.Apply2(func(y, p float64) float64 {
+		p = clip(p)
+		return -y * math.Pow(1-p, gamma) * math.Log(p)
+	}, oneHot(y_true, classes), y_pred))
+
+	return mat.NewVecDense(losses.RawMatrix().Rows, mat.Col(nil, 0, losses))
+}
+
+func (categoricalFocalLoss *CategoricalFocalLoss) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	samples, classes := d_values.Dims()
+	gamma := categoricalFocalLoss.Gamma
+
+	categoricalFocalLoss.D_Inputs = categoricalFocalLoss.WeighGradient(backend.Get().Apply2(func(y, p float64) float64 {
+		p = clip(p)
+		return -y * (math.Pow(1-p, gamma)/p - gamma*math.Pow(1-p, gamma-1)*math.Log(p)) / float64(samples)
+	}, oneHot(y_true, classes), d_values), y_true)
+}
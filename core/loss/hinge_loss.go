@@ -0,0 +1,67 @@
+package loss
+
+import (
+	"github.com/saent-x/ids-nn/core/backend"
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// Hinge is the max-margin loss max(0, 1 - t * y_pred) of linear outputs, the targets t being -1 or 1 (0/1 targets
+// are taken as -1/1)
+type Hinge struct {
+	Loss `json:"-"`
+}
+
+func (hinge *Hinge) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return hinge.calculate(hinge.Forward(output, y), y, include_regularization)
+}
+
+func (hinge *Hinge) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	return backend.Get().MeanRows(backend.Get().Apply2(func(y, p float64) float64 {
+		return math.Max(0, 1-signedTarget(y)*p)
+	}, y_true, y_pred))
+}
+
+func (hinge *Hinge) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	samples, outputs := d_values.Dims()
+
+	hinge.D_Inputs = hinge.WeighGradient(backend.Get().Apply2(func(y, p float64) float64 {
+		if t := signedTarget(y); 1-t*p > 0 {
+			return -t / float64(outputs) / float64(samples)
+		}
+		return 0
+	}, y_true, d_values), y_true)
+}
+
+// SquaredHinge is the square of the Hinge loss, which is smooth & punishes large margin violations more
+type SquaredHinge struct {
+	Loss `json:"-"`
+}
+
+func (squaredHinge *SquaredHinge) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return squaredHinge.calculate(squaredHinge.Forward(output, y), y, include_regularization)
+}
+
+func (squaredHinge *SquaredHinge) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	return backend.Get().MeanRows(backend.Get().Apply2(func(y, p float64) float64 {
+		return math.Pow(math.Max(0, 1-signedTarget(y)*p), 2)
+	}, y_true, y_pred))
+}
+
+func (squaredHinge *SquaredHinge) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	samples, outputs := d_values.Dims()
+
+	squaredHinge.D_Inputs = squaredHinge.WeighGradient(backend.Get().Apply2(func(y, p float64) float64 {
+		t := signedTarget(y)
+		return -2 * t * math.Max(0, 1-t*p) / float64(outputs) / float64(samples)
+	}, y_true, d_values), y_true)
+}
+
+// signedTarget maps 0/1 targets to -1/1
+func signedTarget(y float64) float64 {
+	if y == -1 {
+		return -1
+	}
+
+	return 2*y - 1
+}
@@ -0,0 +1,74 @@
+package loss
+
+import (
+	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/backend"
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// Huber is quadratic for errors up to Delta & linear beyond, so it is less sensitive to outliers than the mean
+// squared error. With a Delta of 1 it is the smooth L1 loss.
+type Huber struct {
+	Delta float64 `json:"delta"`
+
+	Loss `json:"-"`
+}
+
+func NewHuber(delta float64) *Huber {
+	return &Huber{Delta: delta}
+}
+
+func (huber *Huber) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return huber.calculate(huber.Forward(output, y), y, include_regularization)
+}
+
+func (huber *Huber) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	delta := huber.Delta
+
+	return backend.Get().MeanRows(backend.Get().Apply2(func(y, p float64) float64 {
+		e := math.Abs(p - y)
+		if e <= delta {
+			return 0.5 * e * e
+		}
+		return delta * (e - 0.5*delta)
+	}, y_true, y_pred))
+}
+
+func (huber *Huber) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	samples, outputs := d_values.Dims()
+	delta := huber.Delta
+
+	huber.D_Inputs = huber.WeighGradient(backend.Get().Apply2(func(y, p float64) float64 {
+		e := p - y
+		if math.Abs(e) > delta {
+			e = delta * core.Sign(e)
+		}
+		return e / float64(outputs) / float64(samples)
+	}, y_true, d_values), y_true)
+}
+
+// LogCosh is log(cosh(error)): about half the squared error for small errors & the absolute error for large ones
+type LogCosh struct {
+	Loss `json:"-"`
+}
+
+func (logCosh *LogCosh) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return logCosh.calculate(logCosh.Forward(output, y), y, include_regularization)
+}
+
+func (logCosh *LogCosh) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	return backend.Get().MeanRows(backend.Get().Apply2(func(y, p float64) float64 {
+		// log(cosh(e)) = |e| + log(1 + exp(-2|e|)) - log(2), which doesn't overflow for large errors
+		e := math.Abs(p - y)
+		return e + math.Log1p(math.Exp(-2*e)) - math.Ln2
+	}, y_true, y_pred))
+}
+
+func (logCosh *LogCosh) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	samples, outputs := d_values.Dims()
+
+	logCosh.D_Inputs = logCosh.WeighGradient(backend.Get().Apply2(func(y, p float64) float64 {
+		return math.Tanh(p-y) / float64(outputs) / float64(samples)
+	}, y_true, d_values), y_true)
+}
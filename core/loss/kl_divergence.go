@@ -0,0 +1,39 @@
+package loss
+
+import (
+	"github.com/saent-x/ids-nn/core/backend"
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// KLDivergence is the Kullback-Leibler divergence sum(y_true * log(y_true / y_pred)) of the predicted from the target
+// distribution, e.g. soft labels from another model. With one-hot targets it equals the categorical cross-entropy.
+type KLDivergence struct {
+	Loss `json:"-"`
+}
+
+func (klDivergence *KLDivergence) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return klDivergence.calculate(klDivergence.Forward(output, y), y, include_regularization)
+}
+
+func (klDivergence *KLDivergence) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	b := backend.Get()
+	_, classes := y_pred.Dims()
+
+	divergences := b.SumRows(b.Apply2(func(y, p float64) float64 {
+		if y <= 0 {
+			return 0
+		}
+		return y * math.Log(y/clip(p))
+	}, oneHot(y_true, classes), y_pred))
+
+	return mat.NewVecDense(divergences.RawMatrix().Rows, mat.Col(nil, 0, divergences))
+}
+
+func (klDivergence *KLDivergence) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	samples, classes := d_values.Dims()
+
+	klDivergence.D_Inputs = klDivergence.WeighGradient(backend.Get().Apply2(func(y, p float64) float64 {
+		return -y / clip(p) / float64(samples)
+	}, oneHot(y_true, classes), d_values), y_true)
+}
@@ -0,0 +1,50 @@
+package loss
+
+import (
+	"github.com/saent-x/ids-nn/core/backend"
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// LabelSmoothingCrossEntropy is a categorical cross-entropy against targets smoothed towards the uniform distribution,
+// y * (1 - Smoothing) + Smoothing / classes, which keeps the model from growing over-confident
+type LabelSmoothingCrossEntropy struct {
+	Smoothing float64 `json:"smoothing"`
+
+	Loss `json:"-"`
+}
+
+func NewLabelSmoothingCrossEntropy(smoothing float64) *LabelSmoothingCrossEntropy {
+	return &LabelSmoothingCrossEntropy{Smoothing: smoothing}
+}
+
+func (labelSmoothingCrossEntropy *LabelSmoothingCrossEntropy) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return labelSmoothingCrossEntropy.calculate(labelSmoothingCrossEntropy.Forward(output, y), y, include_regularization)
+}
+
+func (labelSmoothingCrossEntropy *LabelSmoothingCrossEntropy) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	b := backend.Get()
+
+	losses := b.SumRows(b.Apply2(func(y, p float64) float64 {
+		return -y * math.Log(clip(p))
+	}, labelSmoothingCrossEntropy.smooth(y_true, y_pred), y_pred))
+
+	return mat.NewVecDense(losses.RawMatrix().Rows, mat.Col(nil, 0, losses))
+}
+
+func (labelSmoothingCrossEntropy *LabelSmoothingCrossEntropy) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	samples, _ := d_values.Dims()
+
+	labelSmoothingCrossEntropy.D_Inputs = labelSmoothingCrossEntropy.WeighGradient(backend.Get().Apply2(func(y, p float64) float64 {
+		return -y / clip(p) / float64(samples)
+	}, labelSmoothingCrossEntropy.smooth(y_true, d_values), d_values), y_true)
+}
+
+func (labelSmoothingCrossEntropy *LabelSmoothingCrossEntropy) smooth(y_true, y_pred *mat.Dense) *mat.Dense {
+	_, classes := y_pred.Dims()
+	smoothing := labelSmoothingCrossEntropy.Smoothing
+
+	return backend.Get().Apply(func(y float64) float64 {
+		return y*(1-smoothing) + smoothing/float64(classes)
+	}, oneHot(y_true, classes))
+}
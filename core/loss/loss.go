@@ -1,8 +1,12 @@
 package loss
 
 import (
+	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/backend"
 	"github.com/saent-x/ids-nn/core/layer"
+	"github.com/samber/lo"
 	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
 	"math"
 )
 
@@ -17,6 +21,9 @@ type ILoss interface {
 	SetDInputs(inputs *mat.Dense)
 	NewPass()
 	CalculateAccumulated(include_regularization bool) (float64, float64)
+	SetClassWeights(weights []float64)
+	SetSampleWeights(weights []float64)
+	GetSampleWeights() []float64
 
 	layer.ILayerNavigation
 }
@@ -26,10 +33,119 @@ type Loss struct {
 	AccumulatedSum      float64
 	AccumulatedCount    float64
 
+	// ClassWeights scales the loss of every sample by the weight of its class (indexed by class), e.g. to make up
+	// for tiny attack classes. Classes past the end of the slice weigh 1.
+	ClassWeights []float64
+	// SampleWeights scales the loss of every sample of the next batches, see SetSampleWeights
+	SampleWeights []float64
+
 	layer.LayerCommons
 	layer.LayerNavigation
 }
 
+func (loss *Loss) SetClassWeights(weights []float64) {
+	loss.ClassWeights = weights
+}
+
+// SetSampleWeights sets the weights of the samples of the batches that follow, nil weighs every sample 1. Weights of
+// another length than a batch are ignored for it.
+func (loss *Loss) SetSampleWeights(weights []float64) {
+	loss.SampleWeights = weights
+}
+
+func (loss *Loss) GetSampleWeights() []float64 {
+	return loss.SampleWeights
+}
+
+// weights returns the weight of every sample of a batch from its sample & class weights, nil if they all weigh 1.
+// The class of a sample comes from the sparse targets or the largest of its one-hot targets.
+func (loss *Loss) weights(y_true *mat.Dense, samples int) []float64 {
+	sample_weights := loss.SampleWeights
+	if len(sample_weights) != samples {
+		sample_weights = nil
+	}
+	if loss.ClassWeights == nil && sample_weights == nil {
+		return nil
+	}
+
+	weights := make([]float64, samples)
+	for i := range weights {
+		weights[i] = 1
+		if sample_weights != nil {
+			weights[i] = sample_weights[i]
+		}
+	}
+	if loss.ClassWeights == nil {
+		return weights
+	}
+
+	rows, cols := y_true.Dims()
+	for i := range weights {
+		var class int
+		switch {
+		case rows == 1:
+			class = int(y_true.At(0, i))
+		case cols == 1:
+			class = int(math.Round(y_true.At(i, 0)))
+		default:
+			class = backend.Get().ArgMaxRows(y_true.Slice(i, i+1, 0, cols).(*mat.Dense))[0]
+		}
+
+		if class >= 0 && class < len(loss.ClassWeights) {
+			weights[i] *= loss.ClassWeights[class]
+		}
+	}
+
+	return weights
+}
+
+// weigh scales the loss of every sample by its weight
+func (loss *Loss) weigh(sample_losses *mat.VecDense, y_true *mat.Dense) *mat.VecDense {
+	weights := loss.weights(y_true, sample_losses.Len())
+	if weights == nil {
+		return sample_losses
+	}
+
+	weighted := mat.NewVecDense(sample_losses.Len(), nil)
+	weighted.MulElemVec(sample_losses, mat.NewVecDense(len(weights), weights))
+
+	return weighted
+}
+
+// WeighGradient scales the gradient of every sample by its weight
+func (loss *Loss) WeighGradient(d_inputs *mat.Dense, y_true *mat.Dense) *mat.Dense {
+	samples, _ := d_inputs.Dims()
+
+	weights := loss.weights(y_true, samples)
+	if weights == nil {
+		return d_inputs
+	}
+
+	for i, weight := range weights {
+		row := d_inputs.RawRowView(i)
+		for j := range row {
+			row[j] *= weight
+		}
+	}
+
+	return d_inputs
+}
+
+// calculate weighs & accumulates the losses of the samples of a batch, returning their mean
+func (loss *Loss) calculate(sample_losses *mat.VecDense, y_true *mat.Dense, include_regularization bool) (float64, float64) {
+	sample_losses = loss.weigh(sample_losses, y_true)
+	average_loss := stat.Mean(sample_losses.RawVector().Data, nil)
+
+	loss.AccumulatedSum += mat.Sum(sample_losses)
+	loss.AccumulatedCount += float64(sample_losses.Len())
+
+	if !include_regularization {
+		return average_loss, 0
+	}
+
+	return average_loss, loss.CalcRegularizationLoss()
+}
+
 func (loss *Loss) RememberTrainableLayers(trainable_layers []*layer.Layer) {
 	loss.TrainableLayers = trainable_layers
 }
@@ -50,41 +166,47 @@ func (loss *Loss) NewPass() {
 }
 
 func (loss *Loss) CalcRegularizationLoss() float64 {
+	b := backend.Get()
 	loss.Regularization_Loss = 0
 
 	for i := 0; i < len(loss.TrainableLayers); i++ {
 		layer := loss.TrainableLayers[i]
-		var abs_weights, abs_biases mat.Dense
-
-		abs_weights.Apply(func(i, j int, v float64) float64 {
-			return math.Abs(v)
-		}, layer.Weights)
-		abs_biases.Apply(func(i, j int, v float64) float64 {
-			return math.Abs(v)
-		}, layer.Biases)
 
 		if layer.Weight_Regularizer_L1 > 0 {
-			loss.Regularization_Loss += layer.Weight_Regularizer_L1 * mat.Sum(&abs_weights)
+			loss.Regularization_Loss += layer.Weight_Regularizer_L1 * b.Sum(b.Apply(math.Abs, layer.Weights))
 		}
 
 		if layer.Weight_Regularizer_L2 > 0 {
-			var weights_by_weights mat.Dense
-
-			weights_by_weights.MulElem(layer.Weights, layer.Weights)
-			loss.Regularization_Loss += layer.Weight_Regularizer_L2 * mat.Sum(&weights_by_weights)
+			loss.Regularization_Loss += layer.Weight_Regularizer_L2 * b.Sum(b.MulElem(layer.Weights, layer.Weights))
 		}
 
 		if layer.Biases_Regularizer_L1 > 0 {
-			loss.Regularization_Loss += layer.Biases_Regularizer_L1 * mat.Sum(&abs_biases)
+			loss.Regularization_Loss += layer.Biases_Regularizer_L1 * b.Sum(b.Apply(math.Abs, layer.Biases))
 		}
 
 		if layer.Biases_Regularizer_L2 > 0 {
-			var biases_by_biases mat.Dense
-
-			biases_by_biases.MulElem(layer.Biases, layer.Biases)
-			loss.Regularization_Loss += layer.Biases_Regularizer_L2 * mat.Sum(&biases_by_biases)
+			loss.Regularization_Loss += layer.Biases_Regularizer_L2 * b.Sum(b.MulElem(layer.Biases, layer.Biases))
 		}
 	}
 
 	return loss.Regularization_Loss
 }
+
+// oneHot returns sparse class targets (a 1 x N row or a column) as one-hot rows of the given number of classes, like
+// CategoricalCrossEntropy a single row is taken as sparse targets
+func oneHot(y_true *mat.Dense, classes int) *mat.Dense {
+	rows, cols := y_true.Dims()
+
+	switch {
+	case rows == 1:
+		return core.SparseToOHE(y_true, classes)
+	case cols == 1 && classes > 1:
+		return core.SparseToOHE(mat.DenseCopyOf(y_true.T()), classes)
+	}
+
+	return y_true
+}
+
+func clip(value float64) float64 {
+	return lo.Clamp(value, 1e-7, 1-1e-7)
+}
@@ -0,0 +1,101 @@
+package loss
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestWeightedLoss(t *testing.T) {
+	softmax_output := mat.NewDense(3, 3, []float64{0.7, 0.1, 0.2, 0.1, 0.5, 0.4, 0.02, 0.9, 0.08})
+	sparse_targets := mat.NewDense(1, 3, []float64{0, 1, 1})
+	one_hot_targets := mat.NewDense(3, 3, []float64{1, 0, 0, 0, 1, 0, 0, 1, 0})
+
+	unweighted := new(CategoricalCrossEntropy)
+	sample_losses := unweighted.Forward(softmax_output, one_hot_targets)
+
+	for _, targets := range []*mat.Dense{sparse_targets, one_hot_targets} {
+		loss_function := new(CategoricalCrossEntropy)
+		loss_function.SetClassWeights([]float64{2, 0.5})
+		loss_function.SetSampleWeights([]float64{1, 1, 3})
+
+		got, _ := loss_function.Calculate(softmax_output, targets, false)
+		want := (2*sample_losses.AtVec(0) + 0.5*sample_losses.AtVec(1) + 1.5*sample_losses.AtVec(2)) / 3
+		if diff := got - want; diff > 1e-12 || diff < -1e-12 {
+			t.Errorf("error: got %f | want %f", got, want)
+		}
+
+		loss_function.Backward(softmax_output, targets)
+		unweighted.Backward(softmax_output, targets)
+		for i, weight := range []float64{2, 0.5, 1.5} {
+			var want_row mat.VecDense
+			want_row.ScaleVec(weight, unweighted.GetDInputs().RowView(i))
+			if !mat.EqualApprox(loss_function.GetDInputs().RowView(i), &want_row, 1e-12) {
+				t.Errorf("error: row %d of the gradient isn't weighed by %g", i, weight)
+			}
+		}
+	}
+
+	// sample weights of another length than the batch are ignored rather than indexed out of range
+	loss_function := new(CategoricalCrossEntropy)
+	loss_function.SetSampleWeights([]float64{1, 3})
+	got, _ := loss_function.Calculate(softmax_output, one_hot_targets, false)
+	want, _ := unweighted.Calculate(softmax_output, one_hot_targets, false)
+	if diff := got - want; diff > 1e-12 || diff < -1e-12 {
+		t.Errorf("error: got %f with mismatched weights | want the unweighted %f", got, want)
+	}
+}
+
+func TestLossGradients(t *testing.T) {
+	probabilities := mat.NewDense(3, 3, []float64{0.7, 0.1, 0.2, 0.1, 0.5, 0.4, 0.02, 0.9, 0.08})
+	sparse_targets := mat.NewDense(1, 3, []float64{0, 2, 1})
+	soft_targets := mat.NewDense(3, 3, []float64{0.8, 0.1, 0.1, 0.2, 0.2, 0.6, 0, 1, 0})
+
+	sigmoid_outputs := mat.NewDense(3, 2, []float64{0.9, 0.2, 0.4, 0.6, 0.05, 0.7})
+	binary_targets := mat.NewDense(3, 2, []float64{1, 0, 0, 1, 1, 0})
+
+	linear_outputs := mat.NewDense(3, 2, []float64{0.5, -2, 3, 0.2, -0.4, 1.7})
+	regression_targets := mat.NewDense(3, 2, []float64{0.4, 0.5, -1, 0.3, 0.1, 1.2})
+
+	for _, test := range []struct {
+		name    string
+		loss    ILoss
+		outputs *mat.Dense
+		targets *mat.Dense
+	}{
+		{"binary focal", NewBinaryFocalLoss(2, 0.25), sigmoid_outputs, binary_targets},
+		{"categorical focal", NewCategoricalFocalLoss(2), probabilities, sparse_targets},
+		{"weighted binary cross-entropy", NewWeightedBinaryCrossEntropy(4), sigmoid_outputs, binary_targets},
+		{"huber", NewHuber(1), linear_outputs, regression_targets},
+		{"log-cosh", new(LogCosh), linear_outputs, regression_targets},
+		{"hinge", new(Hinge), linear_outputs, binary_targets},
+		{"squared hinge", new(SquaredHinge), linear_outputs, binary_targets},
+		{"kl divergence", new(KLDivergence), probabilities, soft_targets},
+		{"label smoothing", NewLabelSmoothingCrossEntropy(0.1), probabilities, sparse_targets},
+	} {
+		test.loss.Backward(test.outputs, test.targets)
+		gradient := test.loss.GetDInputs()
+
+		rows, cols := test.outputs.Dims()
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				numerical := func(h float64) float64 {
+					shifted := mat.DenseCopyOf(test.outputs)
+					shifted.Set(i, j, shifted.At(i, j)+h)
+					return mat.Sum(test.loss.Forward(shifted, test.targets)) / float64(rows)
+				}
+				want := (numerical(1e-6) - numerical(-1e-6)) / 2e-6
+
+				if diff := gradient.At(i, j) - want; diff > 1e-5 || diff < -1e-5 {
+					t.Errorf("error: %s got a gradient of %f at (%d, %d) | want %f", test.name, gradient.At(i, j), i, j, want)
+				}
+			}
+		}
+	}
+
+	// without focusing the focal loss is a cross-entropy
+	want, _ := new(CategoricalCrossEntropy).Calculate(probabilities, sparse_targets, false)
+	if got, _ := NewCategoricalFocalLoss(0).Calculate(probabilities, sparse_targets, false); got-want > 1e-12 || want-got > 1e-12 {
+		t.Errorf("error: got %f | want %f", got, want)
+	}
+}
@@ -1,18 +1,18 @@
 package loss
 
 import (
-	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/backend"
 	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/stat"
 	"math"
 )
 
 type MeanSquaredError struct {
-	Loss
+	Loss `json:"-"`
 }
 
 func (meanSquaredError *MeanSquaredError) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
-	sample_losses := meanSquaredError.Forward(output, y)
+	sample_losses := meanSquaredError.weigh(meanSquaredError.Forward(output, y), y)
 	average_loss := stat.Mean(sample_losses.RawVector().Data, nil)
 
 	meanSquaredError.AccumulatedSum += mat.Sum(sample_losses)
@@ -26,29 +26,21 @@ func (meanSquaredError *MeanSquaredError) Calculate(output *mat.Dense, y *mat.De
 }
 
 func (meanSquaredError *MeanSquaredError) Forward(y_true, y_pred *mat.Dense) *mat.VecDense {
-	var fn mat.Dense
+	b := backend.Get()
 
-	fn.Sub(y_true, y_pred)
-	fn.Apply(func(_, _ int, v float64) float64 {
+	fn := b.Apply(func(v float64) float64 {
 		return math.Pow(v, 2)
-	}, &fn)
+	}, b.Sub(y_true, y_pred))
 
-	sample_losses := core.MeanOnLastAxis(&fn)
-
-	return sample_losses
+	return b.MeanRows(fn)
 }
 
 func (meanSquaredError *MeanSquaredError) Backward(d_values, y_true *mat.Dense) {
-	samples := d_values.RawMatrix().Rows
-	outputs := len(d_values.RawRowView(0))
-
-	var fn mat.Dense
+	samples, outputs := d_values.Dims()
+	b := backend.Get()
 
-	fn.Sub(y_true, d_values)
-	fn.Apply(func(i, j int, v float64) float64 {
+	meanSquaredError.D_Inputs = meanSquaredError.WeighGradient(b.Apply(func(v float64) float64 {
 		result := (-2 * v) / float64(outputs)
 		return result / float64(samples)
-	}, &fn)
-
-	meanSquaredError.D_Inputs = &fn
+	}, b.Sub(y_true, d_values)), y_true)
 }
@@ -0,0 +1,43 @@
+package loss
+
+import (
+	"github.com/saent-x/ids-nn/core/backend"
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// WeightedBinaryCrossEntropy is a binary cross-entropy whose positive targets weigh PositiveWeight times as much as
+// the negative ones, e.g. the ratio of attack-free to attack frames
+type WeightedBinaryCrossEntropy struct {
+	PositiveWeight float64 `json:"positive_weight"`
+
+	Loss `json:"-"`
+}
+
+func NewWeightedBinaryCrossEntropy(positive_weight float64) *WeightedBinaryCrossEntropy {
+	return &WeightedBinaryCrossEntropy{PositiveWeight: positive_weight}
+}
+
+func (weightedBinaryCrossEntropy *WeightedBinaryCrossEntropy) Calculate(output *mat.Dense, y *mat.Dense, include_regularization bool) (float64, float64) {
+	return weightedBinaryCrossEntropy.calculate(weightedBinaryCrossEntropy.Forward(output, y), y, include_regularization)
+}
+
+func (weightedBinaryCrossEntropy *WeightedBinaryCrossEntropy) Forward(y_pred *mat.Dense, y_true *mat.Dense) *mat.VecDense {
+	weight := weightedBinaryCrossEntropy.PositiveWeight
+
+	// -(weight * y_true * log(y_pred) + (1 - y_true) * log(1 - y_pred))
+	return backend.Get().MeanRows(backend.Get().Apply2(func(y, p float64) float64 {
+		p = clip(p)
+		return -(weight*y*math.Log(p) + (1-y)*math.Log(1-p))
+	}, y_true, y_pred))
+}
+
+func (weightedBinaryCrossEntropy *WeightedBinaryCrossEntropy) Backward(d_values *mat.Dense, y_true *mat.Dense) {
+	samples, outputs := d_values.Dims()
+	weight := weightedBinaryCrossEntropy.PositiveWeight
+
+	weightedBinaryCrossEntropy.D_Inputs = weightedBinaryCrossEntropy.WeighGradient(backend.Get().Apply2(func(y, p float64) float64 {
+		p = clip(p)
+		return -(weight*y/p - (1-y)/(1-p)) / float64(outputs) / float64(samples)
+	}, y_true, d_values), y_true)
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// ReliabilityBin holds the samples whose confidence falls in [Lower, Upper): their mean Confidence & the Accuracy of
+// their predictions, the two being equal for a calibrated model
+type ReliabilityBin struct {
+	Lower      float64 `json:"lower"`
+	Upper      float64 `json:"upper"`
+	Count      int     `json:"count"`
+	Confidence float64 `json:"confidence"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// Reliability splits [0, 1] into bins of equal width & measures the mean confidence & the accuracy of the
+// predictions in each, confidences of 1 falling in the last bin
+func Reliability(confidences []float64, correct []bool, bins int) []ReliabilityBin {
+	bins = max(bins, 1)
+
+	reliability := make([]ReliabilityBin, bins)
+	for b := range reliability {
+		reliability[b].Lower, reliability[b].Upper = float64(b)/float64(bins), float64(b+1)/float64(bins)
+	}
+
+	for i, confidence := range confidences {
+		b := min(max(int(math.Floor(confidence*float64(bins))), 0), bins-1)
+		reliability[b].Count++
+		reliability[b].Confidence += confidence
+		if correct[i] {
+			reliability[b].Accuracy++
+		}
+	}
+
+	for b := range reliability {
+		if count := float64(reliability[b].Count); count > 0 {
+			reliability[b].Confidence /= count
+			reliability[b].Accuracy /= count
+		}
+	}
+
+	return reliability
+}
+
+// ExpectedCalibrationError is the gap between the confidence & the accuracy of every bin, weighted by its samples
+func ExpectedCalibrationError(reliability []ReliabilityBin) float64 {
+	total, gap := 0., 0.
+	for _, bin := range reliability {
+		total += float64(bin.Count)
+		gap += float64(bin.Count) * math.Abs(bin.Accuracy-bin.Confidence)
+	}
+	if total == 0 {
+		return 0
+	}
+
+	return gap / total
+}
+
+// PlotReliability plots the reliability diagram of the bins, the accuracy against the confidence, with the diagonal
+// of a perfectly calibrated model
+func PlotReliability(reliability []ReliabilityBin, filename string) error {
+	var points plotter.XYs
+	for _, bin := range reliability {
+		if bin.Count > 0 {
+			points = append(points, plotter.XY{X: bin.Confidence, Y: bin.Accuracy})
+		}
+	}
+	if len(points) == 0 {
+		return errors.New("no sample to plot")
+	}
+
+	p := plot.New()
+
+	p.Title.Text = "Reliability Diagram"
+	p.X.Label.Text = "Confidence"
+	p.Y.Label.Text = "Accuracy"
+	p.X.Min, p.X.Max, p.Y.Min, p.Y.Max = 0, 1, 0, 1
+	p.Legend.Top = true
+	p.Legend.Left = true
+
+	if err := plotutil.AddLinePoints(p, "model", points); err != nil {
+		return err
+	}
+
+	calibrated, err := plotter.NewLine(plotter.XYs{{X: 0, Y: 0}, {X: 1, Y: 1}})
+	if err != nil {
+		return err
+	}
+	calibrated.Dashes = []vg.Length{vg.Points(4), vg.Points(4)}
+	p.Add(calibrated)
+	p.Legend.Add("calibrated", calibrated)
+
+	return p.Save(6*vg.Inch, 6*vg.Inch, filename)
+}
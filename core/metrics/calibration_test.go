@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReliability(t *testing.T) {
+	confidences := []float64{0.95, 0.9, 1, 0.65, 0.55, 0.15}
+	correct := []bool{true, true, false, true, false, false}
+
+	bins := Reliability(confidences, correct, 5)
+	if len(bins) != 5 {
+		t.Fatalf("error: got %d bins | want 5", len(bins))
+	}
+
+	for _, test := range []struct {
+		bin                  int
+		count                int
+		confidence, accuracy float64
+	}{
+		{0, 1, 0.15, 0},
+		{1, 0, 0, 0},
+		{2, 1, 0.55, 0},
+		{3, 1, 0.65, 1},
+		{4, 3, 0.95, 2. / 3},
+	} {
+		bin := bins[test.bin]
+		if bin.Count != test.count || !near(bin.Confidence, test.confidence) || !near(bin.Accuracy, test.accuracy) {
+			t.Errorf("error: bin %d: got %+v | want %d samples, a confidence of %f & an accuracy of %f", test.bin, bin, test.count, test.confidence, test.accuracy)
+		}
+	}
+
+	if got, want := ExpectedCalibrationError(bins), (0.15+0.55+0.35+3*(0.95-2./3))/6; !near(got, want) {
+		t.Errorf("error: got an ece of %f | want %f", got, want)
+	}
+	if got := ExpectedCalibrationError(Reliability(nil, nil, 10)); got != 0 {
+		t.Errorf("error: got an ece of %f without samples | want 0", got)
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	scores := []float64{0.9, 0.8, 0.7, 0.6, 0.5, 0.4, 0.3, 0.2, 0.1, 0.05}
+	positives := []bool{true, true, false, true, false, true, false, false, false, false}
+
+	for _, test := range []struct {
+		target_fpr float64
+		want       float64
+	}{
+		{0, 0.75},      // only the 2 first, before the first negative
+		{1. / 6, 0.55}, // a single false positive
+		{2. / 6, 0.35}, // every positive with 2 false positives
+		{1, math.Nextafter(0.05, 0)},
+	} {
+		if got := ThresholdForFPR(scores, positives, test.target_fpr); !near(got, test.want) {
+			t.Errorf("error: fpr %f: got %f | want %f", test.target_fpr, got, test.want)
+		}
+	}
+
+	// F1 & F2 are best predicting the 6 first (every positive), F0.5 favours precision with the 2 first
+	for _, test := range []struct {
+		beta float64
+		want float64
+	}{
+		{1, 0.35},
+		{2, 0.35},
+		{0.5, 0.75},
+	} {
+		if got := ThresholdForFBeta(scores, positives, test.beta); !near(got, test.want) {
+			t.Errorf("error: beta %f: got %f | want %f", test.beta, got, test.want)
+		}
+	}
+
+	if got := ThresholdForFBeta(scores, make([]bool, len(scores)), 1); got != 0.5 {
+		t.Errorf("error: got %f without positives | want 0.5", got)
+	}
+	if got := ThresholdForFPR(scores, []bool{true, true, true, true, true, true, true, true, true, true}, 0); got > 0.05 {
+		t.Errorf("error: got %f without negatives | want every sample predicted positive", got)
+	}
+}
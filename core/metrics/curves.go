@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"errors"
+	"sort"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// Curve is a ROC or precision-recall curve of a class against all the others
+type Curve struct {
+	Name string
+	X, Y []float64
+}
+
+// rankedScores sorts the scores of a class in decreasing order along with whether each sample belongs to the class
+func rankedScores(scores []float64, positives []bool) ([]float64, []bool, int) {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	sorted_scores, sorted_positives := make([]float64, len(scores)), make([]bool, len(scores))
+	count := 0
+	for i, j := range order {
+		sorted_scores[i], sorted_positives[i] = scores[j], positives[j]
+		if positives[j] {
+			count++
+		}
+	}
+
+	return sorted_scores, sorted_positives, count
+}
+
+// ROCCurve returns the false & true positive rates of every threshold on the scores, from (0, 0) to (1, 1).
+// It is empty when the samples are all positive or all negative.
+func ROCCurve(scores []float64, positives []bool) (fpr, tpr []float64) {
+	sorted_scores, sorted_positives, count := rankedScores(scores, positives)
+	negatives := len(scores) - count
+	if count == 0 || negatives == 0 {
+		return nil, nil
+	}
+
+	fpr, tpr = []float64{0}, []float64{0}
+	true_positives, false_positives := 0., 0.
+	for i := range sorted_scores {
+		if sorted_positives[i] {
+			true_positives++
+		} else {
+			false_positives++
+		}
+
+		// samples with the same score are all on the same side of any threshold
+		if i+1 < len(sorted_scores) && sorted_scores[i+1] == sorted_scores[i] {
+			continue
+		}
+		fpr = append(fpr, false_positives/float64(negatives))
+		tpr = append(tpr, true_positives/float64(count))
+	}
+
+	return fpr, tpr
+}
+
+// PRCurve returns the recall & precision of every threshold on the scores, starting at a recall of 0 & a precision
+// of 1. It is empty when no sample is positive.
+func PRCurve(scores []float64, positives []bool) (recall, precision []float64) {
+	sorted_scores, sorted_positives, count := rankedScores(scores, positives)
+	if count == 0 {
+		return nil, nil
+	}
+
+	recall, precision = []float64{0}, []float64{1}
+	true_positives := 0.
+	for i := range sorted_scores {
+		if sorted_positives[i] {
+			true_positives++
+		}
+
+		if i+1 < len(sorted_scores) && sorted_scores[i+1] == sorted_scores[i] {
+			continue
+		}
+		recall = append(recall, true_positives/float64(count))
+		precision = append(precision, true_positives/float64(i+1))
+	}
+
+	return recall, precision
+}
+
+// AUC is the area under a curve by the trapezoidal rule, e.g. the ROC-AUC of ROCCurve
+func AUC(x, y []float64) float64 {
+	area := 0.
+	for i := 1; i < len(x); i++ {
+		area += (x[i] - x[i-1]) * (y[i] + y[i-1]) / 2
+	}
+
+	return area
+}
+
+// AveragePrecision is the area under a precision-recall curve as a step function, sum((R_k - R_k-1) * P_k), which
+// unlike the trapezoidal rule doesn't overestimate the area between distant thresholds
+func AveragePrecision(recall, precision []float64) float64 {
+	area := 0.
+	for i := 1; i < len(recall); i++ {
+		area += (recall[i] - recall[i-1]) * precision[i]
+	}
+
+	return area
+}
+
+// PlotROC plots the ROC curve of every class with the chance diagonal
+func PlotROC(curves []Curve, filename string) error {
+	return plotCurves(curves, "ROC Curve", "False Positive Rate", "True Positive Rate", true, filename)
+}
+
+// PlotPR plots the precision-recall curve of every class
+func PlotPR(curves []Curve, filename string) error {
+	return plotCurves(curves, "Precision-Recall Curve", "Recall", "Precision", false, filename)
+}
+
+func plotCurves(curves []Curve, title, x_label, y_label string, diagonal bool, filename string) error {
+	p := plot.New()
+
+	p.Title.Text = title
+	p.X.Label.Text = x_label
+	p.Y.Label.Text = y_label
+	p.X.Min, p.X.Max, p.Y.Min, p.Y.Max = 0, 1, 0, 1
+	p.Legend.Top = !diagonal
+
+	var lines []any
+	for _, curve := range curves {
+		if len(curve.X) == 0 {
+			continue
+		}
+
+		points := make(plotter.XYs, len(curve.X))
+		for i := range curve.X {
+			points[i].X, points[i].Y = curve.X[i], curve.Y[i]
+		}
+		lines = append(lines, curve.Name, points)
+	}
+	if len(lines) == 0 {
+		return errors.New("no curve to plot")
+	}
+
+	if err := plotutil.AddLines(p, lines...); err != nil {
+		return err
+	}
+
+	if diagonal {
+		chance, err := plotter.NewLine(plotter.XYs{{X: 0, Y: 0}, {X: 1, Y: 1}})
+		if err != nil {
+			return err
+		}
+		chance.Dashes = []vg.Length{vg.Points(4), vg.Points(4)}
+		p.Add(chance)
+	}
+
+	return p.Save(6*vg.Inch, 6*vg.Inch, filename)
+}
@@ -62,6 +62,29 @@ func CalculateMetrics(confusionMatrix [][]float64, numClasses int) (accuracy, pr
 	return
 }
 
+// MacroAverage is the mean of the per class scores of CalculateMetrics over the classes with support (samples) in
+// the confusion matrix: the classes missing from the data would only drag the average down with zeros
+func MacroAverage(scores []float64, confusionMatrix [][]float64) float64 {
+	total, classes := 0., 0
+	for i, row := range confusionMatrix {
+		support := 0.
+		for _, count := range row {
+			support += count
+		}
+
+		if support > 0 && i < len(scores) {
+			total += scores[i]
+			classes++
+		}
+	}
+
+	if classes == 0 {
+		return 0
+	}
+
+	return total / float64(classes)
+}
+
 // ConfusionMatrixHeatMap holds the confusion matrix data and implements the GridHeatMap interface
 type ConfusionMatrixHeatMap struct {
 	Matrix     [][]float64
@@ -121,6 +144,24 @@ func (l Labels) Len() int {
 
 // PlotConfusionMatrix Function to plot confusion matrix as a heatmap with dynamic tick markers based on the number of classes
 func PlotConfusionMatrix(matrix [][]float64, numClasses int, filename string) {
+	if err := PlotNamedConfusionMatrix(matrix[:numClasses], nil, filename); err != nil {
+		panic(err)
+	}
+}
+
+// ClassName returns the name of a class, or its number when it has none
+func ClassName(classNames []string, class int) string {
+	if class >= 0 && class < len(classNames) && classNames[class] != "" {
+		return classNames[class]
+	}
+
+	return fmt.Sprintf("%d", class)
+}
+
+// PlotNamedConfusionMatrix plots the confusion matrix as a heatmap with the classes named on the axes
+func PlotNamedConfusionMatrix(matrix [][]float64, classNames []string, filename string) error {
+	numClasses := len(matrix)
+
 	p := plot.New()
 
 	p.Title.Text = "Confusion Matrix"
@@ -145,7 +186,7 @@ func PlotConfusionMatrix(matrix [][]float64, numClasses int, filename string) {
 	yTicks := make([]plot.Tick, numClasses)
 
 	for i := 0; i < numClasses; i++ {
-		label := fmt.Sprintf("%d", i)
+		label := ClassName(classNames, i)
 		xTicks[i] = plot.Tick{Value: float64(i), Label: label}
 		yTicks[i] = plot.Tick{Value: float64(i), Label: label}
 	}
@@ -170,15 +211,13 @@ func PlotConfusionMatrix(matrix [][]float64, numClasses int, filename string) {
 		}
 	}
 
-	l, err1 := plotter.NewLabels(labels)
-	if err1 != nil {
-		panic(err1)
+	l, err := plotter.NewLabels(labels)
+	if err != nil {
+		return err
 	}
 	// Add the labels to the plot
 	p.Add(l)
 
 	// Save the plot as a PNG file
-	if err := p.Save(6*vg.Inch, 6*vg.Inch, filename); err != nil {
-		panic(err)
-	}
+	return p.Save(6*vg.Inch, 6*vg.Inch, filename)
 }
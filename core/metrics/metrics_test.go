@@ -0,0 +1,16 @@
+package metrics
+
+import "testing"
+
+func TestMacroAverage(t *testing.T) {
+	// class 2 has no sample, its recall of 0 isn't averaged
+	confusion_matrix := ConfusionMatrix([]float64{0, 0, 1, 1}, []float64{0, 1, 1, 2}, 3)
+	_, _, recall, _ := CalculateMetrics(confusion_matrix, 3)
+
+	if got := MacroAverage(recall, confusion_matrix); !near(got, 0.5) {
+		t.Errorf("error: got a macro recall of %f | want 0.5", got)
+	}
+	if got := MacroAverage(nil, ConfusionMatrix(nil, nil, 2)); got != 0 {
+		t.Errorf("error: got %f without samples | want 0", got)
+	}
+}
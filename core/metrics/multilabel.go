@@ -0,0 +1,119 @@
+package metrics
+
+import "gonum.org/v1/gonum/mat"
+
+// MultiLabelScores holds the metrics of multi-label predictions, where each sample can belong to several classes
+// (labels) at once, e.g. a CAN window with concurrent attacks. Targets & predictions are multi-hot: a row per sample
+// & a column per label, 1 when the sample has the label.
+type MultiLabelScores struct {
+	// HammingLoss is the fraction of wrong labels over every sample & label
+	HammingLoss float64
+	// SubsetAccuracy is the fraction of samples whose labels are all right
+	SubsetAccuracy float64
+
+	// Precision, Recall & F1 are per label
+	Precision, Recall, F1 []float64
+
+	// the micro averages pool the true/false positives of every label, the macro averages are the mean of the
+	// per label scores
+	MicroPrecision, MicroRecall, MicroF1 float64
+	MacroPrecision, MacroRecall, MacroF1 float64
+}
+
+// MultiLabel computes the scores of multi-hot predictions y_pred against the targets y_true
+func MultiLabel(y_true, y_pred mat.Matrix) MultiLabelScores {
+	rows, labels := y_true.Dims()
+
+	scores := MultiLabelScores{
+		Precision: make([]float64, labels),
+		Recall:    make([]float64, labels),
+		F1:        make([]float64, labels),
+	}
+	if rows == 0 || labels == 0 {
+		return scores
+	}
+
+	true_positives, false_positives, false_negatives := make([]float64, labels), make([]float64, labels), make([]float64, labels)
+	wrong, exact := 0., 0.
+	for i := 0; i < rows; i++ {
+		all_right := true
+		for j := 0; j < labels; j++ {
+			actual, predicted := y_true.At(i, j) >= 0.5, y_pred.At(i, j) >= 0.5
+
+			switch {
+			case actual && predicted:
+				true_positives[j]++
+			case predicted:
+				false_positives[j]++
+			case actual:
+				false_negatives[j]++
+			}
+			if actual != predicted {
+				wrong++
+				all_right = false
+			}
+		}
+		if all_right {
+			exact++
+		}
+	}
+
+	scores.HammingLoss = wrong / float64(rows*labels)
+	scores.SubsetAccuracy = exact / float64(rows)
+
+	tp, fp, fn := 0., 0., 0.
+	for j := 0; j < labels; j++ {
+		scores.Precision[j], scores.Recall[j], scores.F1[j] = precisionRecallF1(true_positives[j], false_positives[j], false_negatives[j])
+
+		scores.MacroPrecision += scores.Precision[j] / float64(labels)
+		scores.MacroRecall += scores.Recall[j] / float64(labels)
+		scores.MacroF1 += scores.F1[j] / float64(labels)
+
+		tp, fp, fn = tp+true_positives[j], fp+false_positives[j], fn+false_negatives[j]
+	}
+	scores.MicroPrecision, scores.MicroRecall, scores.MicroF1 = precisionRecallF1(tp, fp, fn)
+
+	return scores
+}
+
+// HammingLoss is the fraction of wrong labels of multi-hot predictions
+func HammingLoss(y_true, y_pred mat.Matrix) float64 {
+	return MultiLabel(y_true, y_pred).HammingLoss
+}
+
+// SubsetAccuracy is the fraction of samples whose multi-hot predictions are exactly right
+func SubsetAccuracy(y_true, y_pred mat.Matrix) float64 {
+	return MultiLabel(y_true, y_pred).SubsetAccuracy
+}
+
+func precisionRecallF1(true_positives, false_positives, false_negatives float64) (precision, recall, f1 float64) {
+	if true_positives+false_positives > 0 {
+		precision = true_positives / (true_positives + false_positives)
+	}
+	if true_positives+false_negatives > 0 {
+		recall = true_positives / (true_positives + false_negatives)
+	}
+	if precision+recall > 0 {
+		f1 = 2 * precision * recall / (precision + recall)
+	}
+
+	return
+}
+
+// BestThresholds picks, for every label, the threshold on the predicted probabilities that maximises the label's F1
+// on the given (validation) targets, a label being predicted when its probability is above its threshold.
+// Labels that never occur keep 0.5.
+func BestThresholds(probabilities, y_true mat.Matrix) []float64 {
+	rows, labels := probabilities.Dims()
+
+	thresholds := make([]float64, labels)
+	for j := range thresholds {
+		scores, positives := make([]float64, rows), make([]bool, rows)
+		for i := range scores {
+			scores[i], positives[i] = probabilities.At(i, j), y_true.At(i, j) >= 0.5
+		}
+		thresholds[j] = ThresholdForFBeta(scores, positives, 1)
+	}
+
+	return thresholds
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestMultiLabel(t *testing.T) {
+	y_true := mat.NewDense(4, 3, []float64{
+		1, 0, 0,
+		0, 1, 1,
+		0, 1, 0,
+		0, 0, 1,
+	})
+	y_pred := mat.NewDense(4, 3, []float64{
+		1, 0, 0,
+		0, 1, 0,
+		0, 1, 1,
+		0, 0, 1,
+	})
+
+	scores := MultiLabel(y_true, y_pred)
+	if scores.HammingLoss != 2./12 || scores.SubsetAccuracy != 0.5 {
+		t.Errorf("error: got hamming loss %f & subset accuracy %f | want %f & 0.5", scores.HammingLoss, scores.SubsetAccuracy, 2./12)
+	}
+	if want := []float64{1, 1, 0.5}; !reflect.DeepEqual(scores.Recall, want) {
+		t.Errorf("error: got recalls %v | want %v", scores.Recall, want)
+	}
+	// 4 true positives, a false positive & a false negative overall
+	for _, micro := range []float64{scores.MicroPrecision, scores.MicroRecall, scores.MicroF1} {
+		if micro < 0.8-1e-12 || micro > 0.8+1e-12 {
+			t.Errorf("error: got micro scores %f, %f & %f | want 0.8", scores.MicroPrecision, scores.MicroRecall, scores.MicroF1)
+			break
+		}
+	}
+	if want := (1 + 1 + 0.5) / 3; scores.MacroF1 < want-1e-12 || scores.MacroF1 > want+1e-12 {
+		t.Errorf("error: got a macro F1 of %f | want %f", scores.MacroF1, want)
+	}
+}
+
+func TestBestThresholds(t *testing.T) {
+	// label 0 is only separable above 0.2, label 1 above 0.7 & label 2 never occurs
+	probabilities := mat.NewDense(4, 3, []float64{
+		0.3, 0.9, 0.1,
+		0.25, 0.8, 0.6,
+		0.1, 0.6, 0.2,
+		0.15, 0.2, 0.3,
+	})
+	y_true := mat.NewDense(4, 3, []float64{
+		1, 1, 0,
+		1, 1, 0,
+		0, 0, 0,
+		0, 0, 0,
+	})
+
+	thresholds := BestThresholds(probabilities, y_true)
+	if want := []float64{0.2, 0.7, 0.5}; !reflect.DeepEqual(thresholds, want) {
+		t.Errorf("error: got thresholds %v | want %v", thresholds, want)
+	}
+}
@@ -0,0 +1,296 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// TimedPredictions are the predicted & true classes of frames in time order, 0 being attack-free traffic
+type TimedPredictions struct {
+	// Timestamps are in seconds, they must not decrease within a capture
+	Timestamps  []float64
+	Labels      []float64
+	Predictions []float64
+	// Captures (optional) gives the capture of every frame, each capture having its own clock
+	Captures []int
+}
+
+// Debouncer simulates how alerts are raised from the predictions of a detector: an alert is raised when at least K of
+// the last N frames of a capture are predicted as attacks, unless an alert was raised less than Cooldown seconds
+// before. The zero value raises an alert for every frame predicted as an attack.
+type Debouncer struct {
+	K, N     int
+	Cooldown float64
+}
+
+// Alerts returns the indexes of the frames of a capture, in time order, that raise an alert
+func (debouncer Debouncer) Alerts(timestamps, predictions []float64) []int {
+	k := max(debouncer.K, 1)
+	n := max(debouncer.N, k)
+
+	var alerts []int
+	window := make([]bool, n)
+	attacks := 0
+	last_alert := 0.
+	for i, prediction := range predictions {
+		slot := i % n
+		if window[slot] {
+			attacks--
+		}
+		window[slot] = prediction != 0
+		if window[slot] {
+			attacks++
+		}
+
+		if attacks >= k && prediction != 0 && (len(alerts) == 0 || timestamps[i]-last_alert >= debouncer.Cooldown) {
+			alerts = append(alerts, i)
+			last_alert = timestamps[i]
+		}
+	}
+
+	return alerts
+}
+
+// OperationalOptions tells how alerts are raised & how attack frames make up episodes
+type OperationalOptions struct {
+	Debouncer Debouncer
+	// EpisodeGap splits the attack frames of a class into separate episodes when they are more than EpisodeGap
+	// seconds apart (1 s by default)
+	EpisodeGap float64
+	// Grace is the time after the end of an episode during which alerts still count for it rather than as false
+	// alarms, e.g. for the lag of a debouncer
+	Grace float64
+}
+
+func (options OperationalOptions) episodeGap() float64 {
+	if options.EpisodeGap <= 0 {
+		return 1
+	}
+
+	return options.EpisodeGap
+}
+
+// Episode is a run of frames of an attack class in a capture
+type Episode struct {
+	Class   float64 `json:"class"`
+	Capture int     `json:"capture"`
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Frames  int     `json:"frames"`
+
+	Detected bool `json:"detected"`
+	// Latency is the time from the first frame of the episode to its first alert, in seconds
+	Latency float64 `json:"latency"`
+}
+
+// AttackDetection sums up the episodes of an attack class
+type AttackDetection struct {
+	Class         float64 `json:"class"`
+	Episodes      int     `json:"episodes"`
+	Detected      int     `json:"detected"`
+	DetectionRate float64 `json:"detection_rate"`
+	MeanLatency   float64 `json:"mean_latency"`
+	MaxLatency    float64 `json:"max_latency"`
+}
+
+// OperationalReport holds the metrics of an intrusion detector over time: the false alarms raised during attack-free
+// driving & how many attack episodes are detected & how fast. Latencies are in seconds & only cover detected episodes.
+type OperationalReport struct {
+	Frames int `json:"frames"`
+	Alerts int `json:"alerts"`
+
+	// FalseAlarms are the alerts raised outside of any episode (& its grace time)
+	FalseAlarms        int     `json:"false_alarms"`
+	AttackFreeHours    float64 `json:"attack_free_hours"`
+	FalseAlarmsPerHour float64 `json:"false_alarms_per_hour"`
+
+	Episodes         []Episode `json:"episodes"`
+	DetectedEpisodes int       `json:"detected_episodes"`
+	DetectionRate    float64   `json:"detection_rate"`
+	MeanLatency      float64   `json:"mean_latency"`
+	MedianLatency    float64   `json:"median_latency"`
+	MaxLatency       float64   `json:"max_latency"`
+
+	PerClass []AttackDetection `json:"per_class"`
+}
+
+// NewOperationalReport simulates the alerts of a detector over time-ordered predictions & measures its false alarms
+// per hour of attack-free driving, its per episode detection rate & its detection latency
+func NewOperationalReport(predictions TimedPredictions, options OperationalOptions) (*OperationalReport, error) {
+	frames := len(predictions.Timestamps)
+	if len(predictions.Labels) != frames || len(predictions.Predictions) != frames || (predictions.Captures != nil && len(predictions.Captures) != frames) {
+		return nil, fmt.Errorf("got %d timestamps, %d labels, %d predictions & %d captures", frames, len(predictions.Labels), len(predictions.Predictions), len(predictions.Captures))
+	}
+	if frames == 0 {
+		return nil, errors.New("can't evaluate without frames")
+	}
+
+	report := &OperationalReport{Frames: frames}
+	attack_free := 0.
+
+	for _, capture := range captureFrames(predictions.Captures, frames) {
+		timestamps, labels, predicted := make([]float64, len(capture.frames)), make([]float64, len(capture.frames)), make([]float64, len(capture.frames))
+		for i, frame := range capture.frames {
+			timestamps[i], labels[i], predicted[i] = predictions.Timestamps[frame], predictions.Labels[frame], predictions.Predictions[frame]
+			if i > 0 && timestamps[i] < timestamps[i-1] {
+				return nil, fmt.Errorf("capture %d: frame %d (%f s) comes before the previous one (%f s)", capture.index, frame, timestamps[i], timestamps[i-1])
+			}
+		}
+
+		episodes := findEpisodes(timestamps, labels, capture.index, options.episodeGap())
+		alerts := options.Debouncer.Alerts(timestamps, predicted)
+		report.Alerts += len(alerts)
+
+		for _, alert := range alerts {
+			t := timestamps[alert]
+
+			false_alarm := true
+			for e := range episodes {
+				if t < episodes[e].Start || t > episodes[e].End+options.Grace {
+					continue
+				}
+				false_alarm = false
+				if !episodes[e].Detected {
+					episodes[e].Detected, episodes[e].Latency = true, t-episodes[e].Start
+				}
+			}
+			if false_alarm {
+				report.FalseAlarms++
+			}
+		}
+
+		attack_free += timestamps[len(timestamps)-1] - timestamps[0] - attackTime(episodes)
+		report.Episodes = append(report.Episodes, episodes...)
+	}
+
+	report.AttackFreeHours = attack_free / 3600
+	if report.AttackFreeHours > 0 {
+		report.FalseAlarmsPerHour = float64(report.FalseAlarms) / report.AttackFreeHours
+	}
+
+	report.summarize()
+
+	return report, nil
+}
+
+type capture struct {
+	index  int
+	frames []int
+}
+
+// captureFrames groups the frames by capture, in the order the captures first appear
+func captureFrames(captures []int, frames int) []capture {
+	if captures == nil {
+		all := capture{frames: make([]int, frames)}
+		for i := range all.frames {
+			all.frames[i] = i
+		}
+		return []capture{all}
+	}
+
+	var grouped []capture
+	positions := make(map[int]int)
+	for i, index := range captures {
+		position, ok := positions[index]
+		if !ok {
+			position = len(grouped)
+			positions[index] = position
+			grouped = append(grouped, capture{index: index})
+		}
+		grouped[position].frames = append(grouped[position].frames, i)
+	}
+
+	return grouped
+}
+
+// findEpisodes splits the attack frames of a capture into episodes per class, sorted by start
+func findEpisodes(timestamps, labels []float64, capture int, gap float64) []Episode {
+	var episodes []Episode
+	open := make(map[float64]int) // the episode each class is in
+
+	for i, label := range labels {
+		if label == 0 {
+			continue
+		}
+
+		if e, ok := open[label]; ok && timestamps[i]-episodes[e].End <= gap {
+			episodes[e].End = timestamps[i]
+			episodes[e].Frames++
+			continue
+		}
+
+		open[label] = len(episodes)
+		episodes = append(episodes, Episode{Class: label, Capture: capture, Start: timestamps[i], End: timestamps[i], Frames: 1})
+	}
+
+	return episodes
+}
+
+// attackTime is the time covered by at least one of the episodes (of a capture), sorted by start
+func attackTime(episodes []Episode) float64 {
+	total, covered := 0., 0.
+	for i, episode := range episodes {
+		start := episode.Start
+		if i > 0 {
+			start = max(start, covered)
+		}
+		if episode.End > start {
+			total += episode.End - start
+		}
+		if i == 0 || episode.End > covered {
+			covered = episode.End
+		}
+	}
+
+	return total
+}
+
+// summarize computes the detection rates & latencies of the episodes
+func (report *OperationalReport) summarize() {
+	var latencies []float64
+	classes := make(map[float64]*AttackDetection)
+
+	for _, episode := range report.Episodes {
+		detection, ok := classes[episode.Class]
+		if !ok {
+			detection = &AttackDetection{Class: episode.Class}
+			classes[episode.Class] = detection
+		}
+		detection.Episodes++
+
+		if episode.Detected {
+			detection.Detected++
+			detection.MeanLatency += episode.Latency
+			detection.MaxLatency = max(detection.MaxLatency, episode.Latency)
+			latencies = append(latencies, episode.Latency)
+		}
+	}
+
+	for _, detection := range classes {
+		detection.DetectionRate = float64(detection.Detected) / float64(detection.Episodes)
+		if detection.Detected > 0 {
+			detection.MeanLatency /= float64(detection.Detected)
+		}
+		report.PerClass = append(report.PerClass, *detection)
+	}
+	sort.Slice(report.PerClass, func(i, j int) bool { return report.PerClass[i].Class < report.PerClass[j].Class })
+
+	report.DetectedEpisodes = len(latencies)
+	if len(report.Episodes) > 0 {
+		report.DetectionRate = float64(len(latencies)) / float64(len(report.Episodes))
+	}
+	if len(latencies) == 0 {
+		return
+	}
+
+	sort.Float64s(latencies)
+	for _, latency := range latencies {
+		report.MeanLatency += latency / float64(len(latencies))
+	}
+	report.MaxLatency = latencies[len(latencies)-1]
+	report.MedianLatency = latencies[len(latencies)/2]
+	if len(latencies)%2 == 0 {
+		report.MedianLatency = (latencies[len(latencies)/2-1] + latencies[len(latencies)/2]) / 2
+	}
+}
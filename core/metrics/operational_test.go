@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDebouncer(t *testing.T) {
+	timestamps := []float64{0, 1, 2, 3, 4, 5, 6, 7}
+	predictions := []float64{1, 0, 1, 1, 0, 1, 1, 1}
+
+	for _, tc := range []struct {
+		debouncer Debouncer
+		want      []int
+	}{
+		{Debouncer{}, []int{0, 2, 3, 5, 6, 7}},
+		{Debouncer{K: 2, N: 3}, []int{2, 3, 5, 6, 7}},
+		{Debouncer{K: 3, N: 3}, []int{7}},
+		{Debouncer{K: 2, N: 3, Cooldown: 3}, []int{2, 5}},
+	} {
+		if got := tc.debouncer.Alerts(timestamps, predictions); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("error: %+v got alerts %v | want %v", tc.debouncer, got, tc.want)
+		}
+	}
+}
+
+func TestOperationalReport(t *testing.T) {
+	// an hour of driving with a DoS (class 1) episode at 600-602 s, a fuzzing (class 2) one at 1800 s that goes
+	// unnoticed & a DoS one in a second capture, plus a false alarm at 3000 s
+	predictions := TimedPredictions{
+		Timestamps:  []float64{0, 600, 601, 601.5, 602, 1200, 1800, 1800.5, 3000, 3600, 0, 10, 10.2, 20},
+		Labels:      []float64{0, 1, 1, 0, 1, 0, 2, 2, 0, 0, 0, 1, 1, 0},
+		Predictions: []float64{0, 0, 1, 1, 0, 0, 0, 0, 1, 0, 0, 1, 0, 0},
+		Captures:    []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1},
+	}
+
+	report, err := NewOperationalReport(predictions, OperationalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Alerts != 4 || report.FalseAlarms != 1 {
+		t.Errorf("error: got %d alerts & %d false alarms | want 4 & 1", report.Alerts, report.FalseAlarms)
+	}
+	// 3600 - 2.5 s of the first capture & 20 - 0.2 s of the second one
+	if want := (3600 - 2.5 + 20 - 0.2) / 3600; !near(report.AttackFreeHours, want) || !near(report.FalseAlarmsPerHour, 1/want) {
+		t.Errorf("error: got %f attack-free hours & %f false alarms per hour | want %f & %f", report.AttackFreeHours, report.FalseAlarmsPerHour, want, 1/want)
+	}
+
+	if len(report.Episodes) != 3 || report.DetectedEpisodes != 2 || !near(report.DetectionRate, 2./3) {
+		t.Fatalf("error: got episodes %+v", report.Episodes)
+	}
+	if episode := report.Episodes[0]; episode.Frames != 3 || episode.End != 602 || !near(episode.Latency, 1) {
+		t.Errorf("error: got episode %+v", episode)
+	}
+	if !near(report.MeanLatency, 0.5) || !near(report.MedianLatency, 0.5) || !near(report.MaxLatency, 1) {
+		t.Errorf("error: got latencies %f, %f & %f", report.MeanLatency, report.MedianLatency, report.MaxLatency)
+	}
+
+	want := []AttackDetection{
+		{Class: 1, Episodes: 2, Detected: 2, DetectionRate: 1, MeanLatency: 0.5, MaxLatency: 1},
+		{Class: 2, Episodes: 1},
+	}
+	if !reflect.DeepEqual(report.PerClass, want) {
+		t.Errorf("error: got %+v | want %+v", report.PerClass, want)
+	}
+
+	// a late alert counts for the episode within the grace time
+	late := predictions
+	late.Predictions = []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	if report, err = NewOperationalReport(late, OperationalOptions{Grace: 10}); err != nil || report.FalseAlarms != 0 || report.DetectedEpisodes != 1 {
+		t.Errorf("error: got %+v (%v) with a grace time", report, err)
+	}
+
+	predictions.Captures = nil
+	if _, err = NewOperationalReport(predictions, OperationalOptions{}); err == nil {
+		t.Errorf("error: expected an error for frames out of time order")
+	}
+}
@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/saent-x/ids-nn/core"
+	"gonum.org/v1/gonum/mat"
+)
+
+// ClassReport holds the metrics of a class against all the others
+type ClassReport struct {
+	Name      string  `json:"name"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+	Support   int     `json:"support"`
+	// ROCAUC & PRAUC (the average precision) are 0 for a class without samples or made of every sample
+	ROCAUC float64 `json:"roc_auc"`
+	PRAUC  float64 `json:"pr_auc"`
+}
+
+// Averages holds precision, recall & F1 averaged over the classes
+type Averages struct {
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+// EvaluationReport gathers the metrics of a classifier on labelled data, it encodes to JSON (e.g. for model cards)
+// without its curves
+type EvaluationReport struct {
+	Samples          int     `json:"samples"`
+	Accuracy         float64 `json:"accuracy"`
+	BalancedAccuracy float64 `json:"balanced_accuracy"`
+	// MCC is the (multi-class) Matthews correlation coefficient
+	MCC        float64 `json:"mcc"`
+	CohenKappa float64 `json:"cohen_kappa"`
+	// ROCAUC & PRAUC are averaged over the classes that have both samples & other samples
+	ROCAUC float64 `json:"roc_auc"`
+	PRAUC  float64 `json:"pr_auc"`
+
+	// Macro averages the classes with samples (support) alike, Micro pools the samples of every class (it equals
+	// the accuracy for single-label data) & Weighted weighs each class by its support
+	Macro    Averages `json:"macro"`
+	Micro    Averages `json:"micro"`
+	Weighted Averages `json:"weighted"`
+
+	Classes         []ClassReport `json:"classes"`
+	ConfusionMatrix [][]float64   `json:"confusion_matrix"`
+
+	// ECE is the expected calibration error of the confidence (the highest probability of every sample) over the
+	// Reliability bins, see PlotReliability
+	ECE         float64          `json:"ece"`
+	Reliability []ReliabilityBin `json:"reliability"`
+
+	// Operational holds the false alarms & detection latency of the detector when they were measured on
+	// time-ordered frames, see NewOperationalReport
+	Operational *OperationalReport `json:"operational,omitempty"`
+
+	// ROC & PR hold the curve of every class, see PlotROC & PlotPR
+	ROC []Curve `json:"-"`
+	PR  []Curve `json:"-"`
+}
+
+// reliabilityBins is the number of confidence bins of the reliability diagram of a report
+const reliabilityBins = 10
+
+// NewEvaluationReport evaluates the predicted classes y_pred against the true classes y_true, the curves & AUCs being
+// computed from the predicted probabilities: a row per sample & a column per class, or a single column with the
+// probability of class 1 for binary classifiers
+func NewEvaluationReport(y_true, y_pred []float64, probabilities mat.Matrix, class_names []string) (*EvaluationReport, error) {
+	rows, cols := probabilities.Dims()
+	if rows != len(y_true) || rows != len(y_pred) {
+		return nil, fmt.Errorf("got %d labels & %d predictions for %d probabilities", len(y_true), len(y_pred), rows)
+	}
+	if rows == 0 {
+		return nil, errors.New("can't evaluate without samples")
+	}
+
+	probability := func(i, class int) float64 {
+		if class >= cols {
+			return 0
+		}
+		return probabilities.At(i, class)
+	}
+	if cols == 1 {
+		probability = func(i, class int) float64 {
+			switch class {
+			case 0:
+				return 1 - probabilities.At(i, 0)
+			case 1:
+				return probabilities.At(i, 0)
+			}
+			return 0
+		}
+	}
+
+	classes := max(cols, 2)
+	for _, labels := range [][]float64{y_true, y_pred} {
+		for _, label := range labels {
+			if label < 0 || label != math.Trunc(label) {
+				return nil, fmt.Errorf("label %g isn't a class", label)
+			}
+			classes = max(classes, int(label)+1)
+		}
+	}
+
+	report := &EvaluationReport{Samples: rows, ConfusionMatrix: ConfusionMatrix(y_true, y_pred, classes)}
+	report.summarize(classes)
+
+	confidences, correct := make([]float64, rows), make([]bool, rows)
+	for i := range confidences {
+		top := 0
+		for class := 1; class < classes; class++ {
+			if probability(i, class) > probability(i, top) {
+				top = class
+			}
+		}
+		confidences[i], correct[i] = probability(i, top), top == int(y_true[i])
+	}
+	report.Reliability = Reliability(confidences, correct, reliabilityBins)
+	report.ECE = ExpectedCalibrationError(report.Reliability)
+
+	auc_classes := 0
+	for class := 0; class < classes; class++ {
+		scores, positives := make([]float64, rows), make([]bool, rows)
+		for i := range scores {
+			scores[i], positives[i] = probability(i, class), int(y_true[i]) == class
+		}
+
+		name := ClassName(class_names, class)
+		report.Classes[class].Name = name
+
+		fpr, tpr := ROCCurve(scores, positives)
+		recall, precision := PRCurve(scores, positives)
+		report.ROC = append(report.ROC, Curve{Name: name, X: fpr, Y: tpr})
+		report.PR = append(report.PR, Curve{Name: name, X: recall, Y: precision})
+
+		if len(fpr) == 0 {
+			continue
+		}
+		report.Classes[class].ROCAUC = AUC(fpr, tpr)
+		report.Classes[class].PRAUC = AveragePrecision(recall, precision)
+
+		report.ROCAUC += report.Classes[class].ROCAUC
+		report.PRAUC += report.Classes[class].PRAUC
+		auc_classes++
+	}
+	if auc_classes > 0 {
+		report.ROCAUC /= float64(auc_classes)
+		report.PRAUC /= float64(auc_classes)
+	}
+
+	return report, nil
+}
+
+// summarize computes the per class & averaged metrics of the confusion matrix
+func (report *EvaluationReport) summarize(classes int) {
+	matrix := report.ConfusionMatrix
+	total := float64(report.Samples)
+
+	actual, predicted := make([]float64, classes), make([]float64, classes)
+	correct := 0.
+	for i := 0; i < classes; i++ {
+		for j := 0; j < classes; j++ {
+			actual[i] += matrix[i][j]
+			predicted[j] += matrix[i][j]
+		}
+		correct += matrix[i][i]
+	}
+
+	report.Classes = make([]ClassReport, classes)
+	precisions, recalls, f1s := make([]float64, classes), make([]float64, classes), make([]float64, classes)
+	for class := range report.Classes {
+		true_positives := matrix[class][class]
+
+		scores := &report.Classes[class]
+		scores.Support = int(actual[class])
+		scores.Precision, scores.Recall, scores.F1 = precisionRecallF1(true_positives, predicted[class]-true_positives, actual[class]-true_positives)
+		precisions[class], recalls[class], f1s[class] = scores.Precision, scores.Recall, scores.F1
+
+		report.Weighted.Precision += scores.Precision * actual[class] / total
+		report.Weighted.Recall += scores.Recall * actual[class] / total
+		report.Weighted.F1 += scores.F1 * actual[class] / total
+	}
+
+	report.Macro = Averages{Precision: MacroAverage(precisions, matrix), Recall: MacroAverage(recalls, matrix), F1: MacroAverage(f1s, matrix)}
+	report.Accuracy = correct / total
+	report.Micro = Averages{Precision: report.Accuracy, Recall: report.Accuracy, F1: report.Accuracy}
+	report.BalancedAccuracy = report.Macro.Recall
+
+	// both the MCC & the kappa compare the agreement with the one expected from the class frequencies alone
+	agreement, actual_squares, predicted_squares := 0., 0., 0.
+	for class := 0; class < classes; class++ {
+		agreement += actual[class] * predicted[class]
+		actual_squares += actual[class] * actual[class]
+		predicted_squares += predicted[class] * predicted[class]
+	}
+
+	if denominator := math.Sqrt((total*total - predicted_squares) * (total*total - actual_squares)); denominator > 0 {
+		report.MCC = (correct*total - agreement) / denominator
+	}
+	if expected := agreement / (total * total); expected < 1 {
+		report.CohenKappa = (report.Accuracy - expected) / (1 - expected)
+	}
+}
+
+// Encode writes the report as indented JSON to w
+func (report *EvaluationReport) Encode(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
+
+// SaveFile writes the report as JSON to the given path
+func (report *EvaluationReport) SaveFile(path string) error {
+	return core.EncodeStructToJSON(report, path)
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCurves(t *testing.T) {
+	scores := []float64{0.1, 0.4, 0.35, 0.8}
+	positives := []bool{false, false, true, true}
+
+	fpr, tpr := ROCCurve(scores, positives)
+	if auc := AUC(fpr, tpr); !near(auc, 0.75) {
+		t.Errorf("error: got a ROC-AUC of %f | want 0.75", auc)
+	}
+
+	recall, precision := PRCurve(scores, positives)
+	if ap := AveragePrecision(recall, precision); !near(ap, 0.5+0.5*2./3) {
+		t.Errorf("error: got an average precision of %f | want %f", ap, 0.5+0.5*2./3)
+	}
+
+	// tied scores make a single step
+	if fpr, _ := ROCCurve([]float64{0.5, 0.5, 0.5}, []bool{true, false, true}); len(fpr) != 2 {
+		t.Errorf("error: got %d points for tied scores | want 2", len(fpr))
+	}
+	if fpr, _ := ROCCurve(scores, make([]bool, 4)); fpr != nil {
+		t.Errorf("error: got a ROC curve without positives")
+	}
+}
+
+func TestEvaluationReport(t *testing.T) {
+	y_true := []float64{0, 0, 1, 1}
+	probabilities := mat.NewDense(4, 1, []float64{0.1, 0.4, 0.35, 0.8})
+	y_pred := []float64{0, 0, 0, 1}
+
+	report, err := NewEvaluationReport(y_true, y_pred, probabilities, []string{"normal", "attack"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"accuracy", report.Accuracy, 0.75},
+		{"balanced accuracy", report.BalancedAccuracy, 0.75},
+		{"mcc", report.MCC, 4 / math.Sqrt(48)},
+		{"kappa", report.CohenKappa, 0.5},
+		{"roc-auc", report.ROCAUC, 0.75},
+		{"attack precision", report.Classes[1].Precision, 1},
+		{"attack recall", report.Classes[1].Recall, 0.5},
+		{"macro f1", report.Macro.F1, (0.8 + 2./3) / 2},
+		{"weighted f1", report.Weighted.F1, (0.8 + 2./3) / 2},
+		{"micro f1", report.Micro.F1, 0.75},
+	} {
+		if !near(tc.got, tc.want) {
+			t.Errorf("error: %s got %f | want %f", tc.name, tc.got, tc.want)
+		}
+	}
+	if report.Classes[1].Name != "attack" || report.Classes[1].Support != 2 {
+		t.Errorf("error: got class %+v", report.Classes[1])
+	}
+
+	var buffer bytes.Buffer
+	if err = report.Encode(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	var decoded EvaluationReport
+	if err = json.Unmarshal(buffer.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.MCC != report.MCC || len(decoded.Classes) != 2 || decoded.ConfusionMatrix[1][0] != 1 {
+		t.Errorf("error: got %+v back from JSON", decoded)
+	}
+
+	dir := t.TempDir()
+	if err = PlotROC(report.ROC, filepath.Join(dir, "roc.png")); err != nil {
+		t.Error(err)
+	}
+	if err = PlotPR(report.PR, filepath.Join(dir, "pr.png")); err != nil {
+		t.Error(err)
+	}
+
+	if _, err = NewEvaluationReport(y_true, y_pred[:2], probabilities, nil); err == nil {
+		t.Errorf("error: expected an error for missing predictions")
+	}
+
+	// a class predicted without samples is reported but left out of the macro averages
+	report, err = NewEvaluationReport(y_true, []float64{0, 0, 2, 1}, probabilities, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Classes) != 3 || report.Classes[2].Support != 0 {
+		t.Fatalf("error: got the classes %+v", report.Classes)
+	}
+	if !near(report.Macro.Precision, 1) || !near(report.Macro.F1, (1+2./3)/2) || !near(report.BalancedAccuracy, 0.75) {
+		t.Errorf("error: got the macro averages %+v & a balanced accuracy of %f", report.Macro, report.BalancedAccuracy)
+	}
+}
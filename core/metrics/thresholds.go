@@ -0,0 +1,71 @@
+package metrics
+
+import "math"
+
+// ThresholdForFPR picks the lowest threshold on the scores (a sample being predicted positive when its score is
+// above it) whose false positive rate stays within target_fpr, i.e. the operating point detecting the most positives
+// for a budget of false alarms. Every sample is predicted positive when there are no negatives.
+func ThresholdForFPR(scores []float64, positives []bool, target_fpr float64) float64 {
+	if len(scores) == 0 {
+		return 0.5
+	}
+	sorted_scores, sorted_positives, count := rankedScores(scores, positives)
+	negatives := float64(len(scores) - count)
+
+	// predicting the k highest scores as positives, only cutting between distinct scores
+	best, false_positives := 0, 0.
+	for k := 1; k <= len(sorted_scores); k++ {
+		if !sorted_positives[k-1] {
+			false_positives++
+		}
+		if k < len(sorted_scores) && sorted_scores[k] == sorted_scores[k-1] {
+			continue
+		}
+
+		if negatives == 0 || false_positives/negatives <= target_fpr {
+			best = k
+		}
+	}
+
+	return cutThreshold(sorted_scores, best)
+}
+
+// ThresholdForFBeta picks the threshold on the scores that maximises the F-beta score, beta > 1 favouring recall
+// (missing fewer attacks) & beta < 1 precision (raising fewer false alarms). It is 0.5 when no sample is positive.
+func ThresholdForFBeta(scores []float64, positives []bool, beta float64) float64 {
+	sorted_scores, sorted_positives, count := rankedScores(scores, positives)
+	if count == 0 {
+		return 0.5
+	}
+
+	beta2 := beta * beta
+	threshold, best, true_positives := 0.5, -1., 0.
+	for k := 1; k <= len(sorted_scores); k++ {
+		if sorted_positives[k-1] {
+			true_positives++
+		}
+		if k < len(sorted_scores) && sorted_scores[k] == sorted_scores[k-1] {
+			continue
+		}
+
+		// (1 + beta²) TP / ((1 + beta²) TP + beta² FN + FP), k being TP + FP
+		f_beta := (1 + beta2) * true_positives / (float64(k) + beta2*float64(count))
+		if f_beta > best {
+			best, threshold = f_beta, cutThreshold(sorted_scores, k)
+		}
+	}
+
+	return threshold
+}
+
+// cutThreshold is the threshold that predicts the k first of the scores (in decreasing order) as positives
+func cutThreshold(sorted_scores []float64, k int) float64 {
+	switch {
+	case k == 0:
+		return sorted_scores[0]
+	case k < len(sorted_scores):
+		return (sorted_scores[k-1] + sorted_scores[k]) / 2
+	}
+
+	return math.Nextafter(sorted_scores[k-1], math.Inf(-1))
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/saent-x/ids-nn/core/activation"
+	"github.com/saent-x/ids-nn/core/calibration"
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Calibrate fits a calibrator of the given method (temperature, platt or isotonic) on the outputs of the model on
+// validation_data, replacing any previous one. Predict, Report & Evaluate then output calibrated probabilities.
+func (model *Model) Calibrate(method string, validation_data datamodels.Batcher, batch_size int) error {
+	_, softmax := model.OutputLayerActivation.(*activation.SoftMax)
+	calibrator, err := calibration.New(calibration.Config{Type: method, Outputs: calibration.Outputs{Softmax: softmax}})
+	if err != nil {
+		return err
+	}
+
+	model.Calibrator = nil
+	probabilities, targets, err := model.outputs(validation_data, batch_size)
+	if err != nil {
+		return err
+	}
+
+	if err = calibrator.Fit(probabilities, targets); err != nil {
+		return err
+	}
+	model.Calibrator = calibrator
+
+	return nil
+}
+
+// calibrate applies the calibrator of the model (if any) to its outputs
+func (model *Model) calibrate(outputs *mat.Dense) (*mat.Dense, error) {
+	if model.Calibrator == nil {
+		return outputs, nil
+	}
+
+	calibrated, err := model.Calibrator.Transform(outputs)
+	if err != nil {
+		return nil, fmt.Errorf("calibrating the outputs: %v", err)
+	}
+
+	return calibrated, nil
+}
+
+// AttackThreshold returns the operating point of a detector, the attack score above which a frame is predicted as
+// an attack, ok is false when the model uses the default argmax or 0.5
+func (model *Model) AttackThreshold() (threshold float64, ok bool) {
+	switch output := model.OutputLayerActivation.(type) {
+	case *activation.SoftMax:
+		if output.AttackThreshold != nil {
+			return *output.AttackThreshold, true
+		}
+	case *activation.Sigmoid:
+		if len(output.Thresholds) == 1 {
+			return output.Thresholds[0], true
+		}
+	}
+
+	return 0, false
+}
+
+// SetAttackThreshold sets the operating point of a detector whose class 0 is attack-free traffic: the attack score
+// (the probability of a single sigmoid output or 1 - p(class 0) of a softmax) above which a frame is predicted as an
+// attack, it is saved with the model
+func (model *Model) SetAttackThreshold(threshold float64) error {
+	switch output := model.OutputLayerActivation.(type) {
+	case *activation.SoftMax:
+		output.AttackThreshold = &threshold
+		return nil
+	case *activation.Sigmoid:
+		return model.SetThresholds([]float64{threshold})
+	}
+
+	return fmt.Errorf("an attack threshold needs a softmax or a single sigmoid output, the model ends with %T", model.OutputLayerActivation)
+}
+
+// OptimizeThreshold picks the attack threshold with objective (e.g. metrics.ThresholdForFPR) from the (calibrated)
+// attack scores of validation_data & whether each frame is an attack, & sets it
+func (model *Model) OptimizeThreshold(validation_data datamodels.Batcher, batch_size int, objective func(scores []float64, positives []bool) float64) (float64, error) {
+	probabilities, targets, err := model.outputs(validation_data, batch_size)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, cols := probabilities.Dims()
+	if _, softmax := model.OutputLayerActivation.(*activation.SoftMax); !softmax && cols != 1 {
+		return 0, fmt.Errorf("an attack threshold needs a softmax or a single sigmoid output, the model has %d %T outputs", cols, model.OutputLayerActivation)
+	}
+
+	scores, positives := make([]float64, rows), make([]bool, rows)
+	for i := range scores {
+		if cols == 1 {
+			scores[i], positives[i] = probabilities.At(i, 0), targets.At(i, 0) >= 0.5
+		} else {
+			scores[i], positives[i] = 1-probabilities.At(i, 0), targets.At(i, 0) < 0.5
+		}
+	}
+
+	threshold := objective(scores, positives)
+
+	return threshold, model.SetAttackThreshold(threshold)
+}
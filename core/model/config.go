@@ -0,0 +1,415 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/accuracy"
+	"github.com/saent-x/ids-nn/core/activation"
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/layer"
+	"github.com/saent-x/ids-nn/core/loss"
+	"github.com/saent-x/ids-nn/core/optimization"
+	"github.com/saent-x/ids-nn/core/scaling"
+	"github.com/saent-x/ids-nn/core/tensor"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the declarative description of a model & how to train it.
+// It can be written as JSON or YAML, e.g.
+//
+//	input: {features: 10}
+//	layers:
+//	  - {type: dense, neurons: 896, weight_l2: 1e-5, bias_l2: 1e-5}
+//	  - {type: relu}
+//	  - {type: dropout, rate: 0.1}
+//	  - {type: dense, neurons: 2}
+//	  - {type: softmax}
+//	loss: categorical_crossentropy
+//	optimizer: {type: adam, learning_rate: 0.001, schedule: {type: inverse_time, decay: 1e-3}}
+//	accuracy: categorical
+//	training: {epochs: 5, batch_size: 128, print_every: 10000}
+//	scaling: {type: robust}
+type Config struct {
+	Name      string          `json:"name,omitempty" yaml:"name,omitempty"`
+	Input     InputConfig     `json:"input" yaml:"input"`
+	Layers    []LayerConfig   `json:"layers" yaml:"layers"`
+	Loss      string          `json:"loss" yaml:"loss"`
+	Optimizer OptimizerConfig `json:"optimizer" yaml:"optimizer"`
+	Accuracy  string          `json:"accuracy" yaml:"accuracy"`
+	Training  TrainingConfig  `json:"training" yaml:"training"`
+	// Scaling is the scaler fit on the training inputs, the model applies it to every input
+	Scaling *scaling.Config `json:"scaling,omitempty" yaml:"scaling,omitempty"`
+
+	// LossOptions sets the parameters of the losses that have some, e.g. the focal losses
+	LossOptions LossOptions `json:"loss_options,omitempty" yaml:"loss_options,omitempty"`
+}
+
+// InputConfig either gives the number of features or the full named shape (without the batch axis)
+type InputConfig struct {
+	Features int          `json:"features,omitempty" yaml:"features,omitempty"`
+	Shape    []AxisConfig `json:"shape,omitempty" yaml:"shape,omitempty"`
+}
+
+type AxisConfig struct {
+	Name string `json:"name" yaml:"name"`
+	Size int    `json:"size" yaml:"size"`
+}
+
+type LayerConfig struct {
+	// Type is one of dense, relu, sigmoid, softmax, linear, dropout or reparameterization
+	Type string `json:"type" yaml:"type"`
+
+	// dense layers: Inputs is optional and inferred from the previous layer when omitted
+	Inputs   int     `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	Neurons  int     `json:"neurons,omitempty" yaml:"neurons,omitempty"`
+	WeightL1 float64 `json:"weight_l1,omitempty" yaml:"weight_l1,omitempty"`
+	WeightL2 float64 `json:"weight_l2,omitempty" yaml:"weight_l2,omitempty"`
+	BiasL1   float64 `json:"bias_l1,omitempty" yaml:"bias_l1,omitempty"`
+	BiasL2   float64 `json:"bias_l2,omitempty" yaml:"bias_l2,omitempty"`
+
+	// dropout layers
+	Rate float64 `json:"rate,omitempty" yaml:"rate,omitempty"`
+	// reparameterization layers (variational autoencoders): the weight of the KL term (1)
+	Beta float64 `json:"beta,omitempty" yaml:"beta,omitempty"`
+	// reparameterization layers: the seed of the sampled noise, 0 seeds from the clock
+	Seed int64 `json:"seed,omitempty" yaml:"seed,omitempty"`
+}
+
+// LossOptions are the parameters of the losses, a parameter left out takes its usual value (given in brackets) while
+// 0 is a value like any other, e.g. a gamma of 0 makes a focal loss a (weighted) cross-entropy
+type LossOptions struct {
+	// binary_focal (2 & 0.25) & categorical_focal (2)
+	Gamma *float64 `json:"gamma,omitempty" yaml:"gamma,omitempty"`
+	Alpha *float64 `json:"alpha,omitempty" yaml:"alpha,omitempty"`
+	// weighted_binary_crossentropy (1)
+	PositiveWeight *float64 `json:"positive_weight,omitempty" yaml:"positive_weight,omitempty"`
+	// huber (1)
+	Delta *float64 `json:"delta,omitempty" yaml:"delta,omitempty"`
+	// label_smoothing_crossentropy (0.1)
+	Smoothing *float64 `json:"smoothing,omitempty" yaml:"smoothing,omitempty"`
+}
+
+// OptimizerConfig describes the optimizer, like LossOptions the parameters left out take their usual value (given in
+// brackets) while 0 is a value like any other
+type OptimizerConfig struct {
+	// Type is one of adam, sgd, rmsprop or adagrad
+	Type         string  `json:"type" yaml:"type"`
+	LearningRate float64 `json:"learning_rate" yaml:"learning_rate"`
+	// adam, rmsprop & adagrad (1e-7)
+	Epsilon *float64 `json:"epsilon,omitempty" yaml:"epsilon,omitempty"`
+	// adam (0.9 & 0.999)
+	Beta1 *float64 `json:"beta_1,omitempty" yaml:"beta_1,omitempty"`
+	Beta2 *float64 `json:"beta_2,omitempty" yaml:"beta_2,omitempty"`
+	// rmsprop (0.9)
+	Rho      *float64        `json:"rho,omitempty" yaml:"rho,omitempty"`
+	Momentum float64         `json:"momentum,omitempty" yaml:"momentum,omitempty"`
+	MaxNorm  float64         `json:"max_norm,omitempty" yaml:"max_norm,omitempty"`
+	Schedule *ScheduleConfig `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+}
+
+// ScheduleConfig describes how the learning rate changes over iterations.
+// constant keeps it fixed, inverse_time uses lr / (1 + decay * iteration) like the optimizers' Decay.
+type ScheduleConfig struct {
+	Type  string  `json:"type" yaml:"type"`
+	Decay float64 `json:"decay,omitempty" yaml:"decay,omitempty"`
+}
+
+type TrainingConfig struct {
+	Epochs     int  `json:"epochs" yaml:"epochs"`
+	BatchSize  int  `json:"batch_size" yaml:"batch_size"`
+	PrintEvery int  `json:"print_every,omitempty" yaml:"print_every,omitempty"`
+	Shuffle    bool `json:"shuffle,omitempty" yaml:"shuffle,omitempty"`
+	// ClassWeights weighs the loss of every sample by its class (indexed by class), see Model.SetClassWeights
+	ClassWeights []float64 `json:"class_weights,omitempty" yaml:"class_weights,omitempty"`
+}
+
+// Train trains m on data for the configured epochs & batch size, the progress being printed every PrintEvery
+// steps (100 by default)
+func (training TrainingConfig) Train(m *Model, data, validation datamodels.Batcher) error {
+	print_every := training.PrintEvery
+	if print_every <= 0 {
+		print_every = 100
+	}
+
+	return m.Train(data, validation, training.Epochs, training.BatchSize, print_every)
+}
+
+var lossConstructors = map[string]func(options LossOptions) loss.ILoss{
+	"categorical_crossentropy": func(LossOptions) loss.ILoss { return new(loss.CategoricalCrossEntropy) },
+	"binary_crossentropy":      func(LossOptions) loss.ILoss { return new(loss.BinaryCrossEntropy) },
+	"mse":                      func(LossOptions) loss.ILoss { return new(loss.MeanSquaredError) },
+	"mae":                      func(LossOptions) loss.ILoss { return new(loss.MeanAbsoluteError) },
+	"binary_crossentropy_with_logits": func(LossOptions) loss.ILoss {
+		return new(loss.BCEWithLogits)
+	},
+	"binary_focal": func(options LossOptions) loss.ILoss {
+		return loss.NewBinaryFocalLoss(core.OptionalValue(options.Gamma, 2), core.OptionalValue(options.Alpha, 0.25))
+	},
+	"categorical_focal": func(options LossOptions) loss.ILoss {
+		return loss.NewCategoricalFocalLoss(core.OptionalValue(options.Gamma, 2))
+	},
+	"weighted_binary_crossentropy": func(options LossOptions) loss.ILoss {
+		return loss.NewWeightedBinaryCrossEntropy(core.OptionalValue(options.PositiveWeight, 1))
+	},
+	"huber":         func(options LossOptions) loss.ILoss { return loss.NewHuber(core.OptionalValue(options.Delta, 1)) },
+	"smooth_l1":     func(LossOptions) loss.ILoss { return loss.NewHuber(1) },
+	"log_cosh":      func(LossOptions) loss.ILoss { return new(loss.LogCosh) },
+	"hinge":         func(LossOptions) loss.ILoss { return new(loss.Hinge) },
+	"squared_hinge": func(LossOptions) loss.ILoss { return new(loss.SquaredHinge) },
+	"kl_divergence": func(LossOptions) loss.ILoss { return new(loss.KLDivergence) },
+	"label_smoothing_crossentropy": func(options LossOptions) loss.ILoss {
+		return loss.NewLabelSmoothingCrossEntropy(core.OptionalValue(options.Smoothing, 0.1))
+	},
+}
+
+var accuracyConstructors = map[string]func() accuracy.IAccuracy{
+	"categorical": func() accuracy.IAccuracy { return new(accuracy.CategoricalAccuracy) },
+	"binary":      func() accuracy.IAccuracy { return new(accuracy.BinaryAccuracy) },
+	"regression":  func() accuracy.IAccuracy { return accuracy.NewRegressionAccuracy() },
+	"multi_label": func() accuracy.IAccuracy { return new(accuracy.MultiLabelAccuracy) },
+}
+
+var activationConstructors = map[string]func() layer.ILayer{
+	"relu":    func() layer.ILayer { return new(activation.ReLU) },
+	"sigmoid": func() layer.ILayer { return new(activation.Sigmoid) },
+	"softmax": func() layer.ILayer { return new(activation.SoftMax) },
+	"linear":  func() layer.ILayer { return new(activation.Linear) },
+}
+
+// LoadConfig reads a model config, the format is picked from the file extension (.json, .yaml or .yml)
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+
+	config, err := ParseConfig(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return config, nil
+}
+
+// ParseConfig decodes & validates a config in the given format (json or yaml)
+func ParseConfig(data []byte, format string) (*Config, error) {
+	config := new(Config)
+
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(config); err != nil {
+			return nil, err
+		}
+	case "yaml", "yml":
+		decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+		decoder.KnownFields(true)
+
+		if err := decoder.Decode(config); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// InputShape returns the named input shape described by the config
+func (config *Config) InputShape() tensor.Shape {
+	if len(config.Input.Shape) == 0 {
+		return tensor.FeatureShape(config.Input.Features)
+	}
+
+	shape := tensor.NewShape(tensor.Axis{Name: tensor.Batch, Size: tensor.Unknown})
+	for _, axis := range config.Input.Shape {
+		shape = append(shape, tensor.Axis{Name: tensor.Dim(axis.Name), Size: axis.Size})
+	}
+
+	return shape
+}
+
+// Validate reports every problem found in the config at once
+func (config *Config) Validate() error {
+	var errs []error
+
+	if config.Input.Features <= 0 && len(config.Input.Shape) == 0 {
+		errs = append(errs, errors.New("input: either features or shape is required"))
+	} else if err := config.InputShape().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("input: %v", err))
+	}
+
+	if len(config.Layers) == 0 {
+		errs = append(errs, errors.New("layers: at least one layer is required"))
+	}
+
+	for i, l := range config.Layers {
+		switch l.Type {
+		case "dense":
+			if l.Neurons <= 0 {
+				errs = append(errs, fmt.Errorf("layers[%d]: dense layer needs a positive number of neurons", i))
+			}
+			if l.Inputs < 0 || l.WeightL1 < 0 || l.WeightL2 < 0 || l.BiasL1 < 0 || l.BiasL2 < 0 {
+				errs = append(errs, fmt.Errorf("layers[%d]: inputs & regularizers can't be negative", i))
+			}
+		case "dropout":
+			if l.Rate <= 0 || l.Rate >= 1 {
+				errs = append(errs, fmt.Errorf("layers[%d]: dropout rate must be in (0, 1), got %g", i, l.Rate))
+			}
+		case "reparameterization":
+			if l.Beta < 0 {
+				errs = append(errs, fmt.Errorf("layers[%d]: beta can't be negative, got %g", i, l.Beta))
+			}
+		default:
+			if _, ok := activationConstructors[l.Type]; !ok {
+				errs = append(errs, fmt.Errorf("layers[%d]: unknown layer type %q", i, l.Type))
+			}
+		}
+	}
+
+	if len(config.Layers) > 0 {
+		if _, ok := activationConstructors[config.Layers[len(config.Layers)-1].Type]; !ok {
+			errs = append(errs, errors.New("layers: the last layer must be an activation"))
+		}
+	}
+
+	if _, ok := lossConstructors[config.Loss]; !ok {
+		errs = append(errs, fmt.Errorf("loss: unknown loss %q", config.Loss))
+	}
+	options := config.LossOptions
+	if core.OptionalValue(options.Gamma, 0) < 0 || core.OptionalValue(options.PositiveWeight, 0) < 0 || core.OptionalValue(options.Delta, 0) < 0 {
+		errs = append(errs, errors.New("loss_options: gamma, positive_weight & delta can't be negative"))
+	}
+	if alpha, smoothing := core.OptionalValue(options.Alpha, 0), core.OptionalValue(options.Smoothing, 0); alpha < 0 || alpha > 1 || smoothing < 0 || smoothing >= 1 {
+		errs = append(errs, errors.New("loss_options: alpha must be in [0, 1] & smoothing in [0, 1)"))
+	}
+	if _, ok := accuracyConstructors[config.Accuracy]; !ok {
+		errs = append(errs, fmt.Errorf("accuracy: unknown accuracy %q", config.Accuracy))
+	}
+
+	switch config.Optimizer.Type {
+	case "adam", "sgd", "rmsprop", "adagrad":
+	default:
+		errs = append(errs, fmt.Errorf("optimizer: unknown optimizer %q", config.Optimizer.Type))
+	}
+	if config.Optimizer.LearningRate <= 0 {
+		errs = append(errs, errors.New("optimizer: learning_rate must be positive"))
+	}
+	if core.OptionalValue(config.Optimizer.Epsilon, 0) < 0 {
+		errs = append(errs, errors.New("optimizer: epsilon can't be negative"))
+	}
+	for _, rate := range []*float64{config.Optimizer.Beta1, config.Optimizer.Beta2, config.Optimizer.Rho} {
+		if value := core.OptionalValue(rate, 0); value < 0 || value >= 1 {
+			errs = append(errs, errors.New("optimizer: beta_1, beta_2 & rho must be in [0, 1)"))
+			break
+		}
+	}
+	if schedule := config.Optimizer.Schedule; schedule != nil {
+		switch schedule.Type {
+		case "constant":
+		case "inverse_time":
+			if schedule.Decay < 0 {
+				errs = append(errs, errors.New("optimizer.schedule: decay can't be negative"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("optimizer.schedule: unknown schedule %q", schedule.Type))
+		}
+	}
+
+	if config.Training.Epochs < 0 || config.Training.BatchSize < 0 || config.Training.PrintEvery < 0 {
+		errs = append(errs, errors.New("training: epochs, batch_size & print_every can't be negative"))
+	}
+
+	for class, weight := range config.Training.ClassWeights {
+		if weight < 0 {
+			errs = append(errs, fmt.Errorf("training: the weight of class %d can't be negative", class))
+		}
+	}
+
+	if config.Scaling != nil {
+		if err := config.Scaling.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("scaling: %v", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// FromConfig builds & finalizes the model described by the config, using the same constructors as the Go API
+func FromConfig(config *Config) (*Model, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	model := New()
+	model.SetInputShape(config.InputShape())
+
+	inputs := model.InputShape.FeatureSize()
+	for _, l := range config.Layers {
+		switch l.Type {
+		case "dense":
+			if l.Inputs > 0 {
+				inputs = l.Inputs
+			}
+			model.Add(layer.CreateLayer(inputs, l.Neurons, l.WeightL1, l.WeightL2, l.BiasL1, l.BiasL2))
+			inputs = l.Neurons
+		case "dropout":
+			model.Add(layer.NewDropoutLayer(l.Rate))
+		case "reparameterization":
+			model.Add(layer.NewReparameterization(core.DefaultValue(l.Beta, 1), l.Seed))
+			inputs /= 2
+		default:
+			model.Add(activationConstructors[l.Type]())
+		}
+	}
+
+	model.Set(lossConstructors[config.Loss](config.LossOptions), config.Optimizer.build(), accuracyConstructors[config.Accuracy]())
+
+	if config.Scaling != nil {
+		// not fitted yet, see Model.FitScaler
+		model.Scaler, _ = scaling.New(*config.Scaling)
+	}
+
+	if err := model.Finalize(); err != nil {
+		return nil, err
+	}
+	if config.Training.ClassWeights != nil {
+		model.SetClassWeights(config.Training.ClassWeights)
+	}
+
+	return model, nil
+}
+
+func (config OptimizerConfig) build() optimization.IOptimizer {
+	decay := 0.
+	if config.Schedule != nil && config.Schedule.Type == "inverse_time" {
+		decay = config.Schedule.Decay
+	}
+
+	epsilon := core.OptionalValue(config.Epsilon, 1e-7)
+
+	switch config.Type {
+	case "sgd":
+		return optimization.CreateStochasticGradientDescent(config.LearningRate, decay, config.Momentum)
+	case "rmsprop":
+		return optimization.CreateRootMeanSquarePropagation(config.LearningRate, decay, epsilon, core.OptionalValue(config.Rho, 0.9))
+	case "adagrad":
+		return optimization.CreateAdaptiveGradient(config.LearningRate, decay, epsilon)
+	default:
+		return optimization.CreateAdaptiveMomentum(config.LearningRate, decay, epsilon, core.OptionalValue(config.Beta1, 0.9), core.OptionalValue(config.Beta2, 0.999), config.MaxNorm)
+	}
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/saent-x/ids-nn/core/accuracy"
+	"github.com/saent-x/ids-nn/core/activation"
+	"github.com/saent-x/ids-nn/core/layer"
+	"github.com/saent-x/ids-nn/core/loss"
+	"github.com/saent-x/ids-nn/core/optimization"
+)
+
+func TestModelFromConfig(t *testing.T) {
+	config, err := LoadConfig("../../configs/can_ids.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config_model, err := FromConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go_model := New()
+
+	go_model.Add(layer.CreateLayer(10, 896, 0, 1e-5, 0, 1e-5))
+	go_model.Add(new(activation.ReLU))
+	go_model.Add(layer.NewDropoutLayer(0.1))
+	go_model.Add(layer.CreateLayer(896, 896, 0, 1e-5, 0, 1e-5))
+	go_model.Add(new(activation.ReLU))
+	go_model.Add(layer.CreateLayer(896, 2, 0, 1e-5, 0, 1e-5))
+	go_model.Add(new(activation.SoftMax))
+
+	go_model.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.001, 1e-3, 1e-7, 0.9, 0.999, 1.0), new(accuracy.CategoricalAccuracy))
+	if err = go_model.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, want := config_model.Summary(128).String(), go_model.Summary(128).String()
+	if got != want {
+		t.Errorf("error: config model doesn't match the go model\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	got_optimizer := *config_model.Optimizer.(*optimization.AdaptiveMomentum)
+	want_optimizer := *go_model.Optimizer.(*optimization.AdaptiveMomentum)
+	if got_optimizer != want_optimizer {
+		t.Errorf("error: got optimizer %+v | want %+v", got_optimizer, want_optimizer)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := ParseConfig([]byte(`{
+		"input": {"features": 10},
+		"layers": [{"type": "dense"}, {"type": "dropout", "rate": 1.5}, {"type": "dense", "neurons": 2}],
+		"loss": "cross_entropy",
+		"optimizer": {"type": "adam", "learning_rate": 0.001, "schedule": {"type": "cosine"}},
+		"accuracy": "categorical"
+	}`), "json")
+
+	if err == nil {
+		t.Fatal("error: expected the config to be rejected")
+	}
+
+	fmt.Println(err)
+}
+
+func TestLossOptions(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+input: {features: 2}
+layers:
+  - {type: dense, neurons: 3}
+  - {type: softmax}
+loss: categorical_focal
+loss_options: {gamma: 3}
+optimizer: {type: adam, learning_rate: 0.001}
+accuracy: categorical
+`), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := FromConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the loss parameters are saved with the model
+	var buffer bytes.Buffer
+	if err = new(ModelDataProvider).Encode(&buffer, m); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := new(ModelDataProvider).Load(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if focal, ok := loaded.Lossfn.(*loss.CategoricalFocalLoss); !ok || focal.Gamma != 3 {
+		t.Errorf("error: got loss %#v | want a categorical focal loss with a gamma of 3", loaded.Lossfn)
+	}
+
+	smoothing := 1.
+	config.LossOptions.Smoothing = &smoothing
+	if _, err = FromConfig(config); err == nil {
+		t.Errorf("error: expected an error for a smoothing of 1")
+	}
+
+	// 0 is a value, the options left out take their usual value
+	config, err = ParseConfig([]byte(`
+input: {features: 2}
+layers:
+  - {type: dense, neurons: 1}
+  - {type: sigmoid}
+loss: binary_focal
+loss_options: {alpha: 0}
+optimizer: {type: adam, learning_rate: 0.001}
+accuracy: binary
+`), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err = FromConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if focal, ok := m.Lossfn.(*loss.BinaryFocalLoss); !ok || focal.Alpha != 0 || focal.Gamma != 2 {
+		t.Errorf("error: got loss %#v | want a binary focal loss with an alpha of 0 & a gamma of 2", m.Lossfn)
+	}
+
+	// the same goes for the optimizer
+	config.Optimizer.Beta1 = new(float64)
+	if m, err = FromConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if adam, ok := m.Optimizer.(*optimization.AdaptiveMomentum); !ok || adam.Beta_1 != 0 || adam.Beta_2 != 0.999 || adam.Epsilon != 1e-7 {
+		t.Errorf("error: got optimizer %#v | want adam with a beta_1 of 0", m.Optimizer)
+	}
+	rho := 1.
+	config.Optimizer.Rho = &rho
+	if _, err = FromConfig(config); err == nil {
+		t.Errorf("error: expected an error for a rho of 1")
+	}
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/datasets"
+	"github.com/saent-x/ids-nn/core/metrics"
+)
+
+// FoldMetrics are the scores of a model on the test rows of a fold, precision, recall & F1 being averaged over the
+// classes present in the fold (macro average)
+type FoldMetrics struct {
+	Loss      float64
+	Accuracy  float64
+	Precision float64
+	Recall    float64
+	F1        float64
+}
+
+// CrossValidation holds the scores of every fold & their mean & standard deviation
+type CrossValidation struct {
+	Folds []FoldMetrics
+	Mean  FoldMetrics
+	Std   FoldMetrics
+}
+
+// CrossValidate trains a fresh model from newModel on the training rows of every fold & scores it on the fold's test
+// rows (see datasets.StratifiedKFold, GroupKFold & TimeSeriesSplit). The model's scaler, if any, is fit on the
+// training rows of each fold only.
+func CrossValidate(newModel func() (*Model, error), data datamodels.TrainingData, folds []datasets.Fold, training TrainingConfig) (*CrossValidation, error) {
+	if len(folds) == 0 {
+		return nil, errors.New("cross-validation needs at least one fold")
+	}
+
+	result := new(CrossValidation)
+	for i, fold := range folds {
+		if len(fold.Train) == 0 || len(fold.Test) == 0 {
+			return nil, fmt.Errorf("fold %d: both the training & the test rows are needed", i)
+		}
+
+		model, err := newModel()
+		if err != nil {
+			return nil, fmt.Errorf("fold %d: %v", i, err)
+		}
+
+		train, test := datasets.Subset(data, fold.Train), datasets.Subset(data, fold.Test)
+		if training.Shuffle {
+			train = datasets.Subset(train, core.ShuffleSlice(core.GetRange(len(fold.Train))))
+		}
+
+		if err = model.FitScaler(train.X); err != nil {
+			return nil, fmt.Errorf("fold %d: fitting the scaler: %v", i, err)
+		}
+		if err = training.Train(model, train, nil); err != nil {
+			return nil, fmt.Errorf("fold %d: %v", i, err)
+		}
+
+		scores, err := model.score(test, training.BatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("fold %d: %v", i, err)
+		}
+		result.Folds = append(result.Folds, scores)
+	}
+
+	result.Mean, result.Std = foldStatistics(result.Folds)
+
+	return result, nil
+}
+
+// score computes the loss, accuracy & macro precision, recall & F1 of the model on data
+func (model *Model) score(data datamodels.TrainingData, batch_size int) (FoldMetrics, error) {
+	if err := model.Evaluate(datamodels.ValidationData{X: data.X, Y: data.Y}, batch_size); err != nil {
+		return FoldMetrics{}, err
+	}
+
+	var scores FoldMetrics
+	scores.Loss, _ = model.LossFunction().CalculateAccumulated(false)
+
+	outputs, err := model.Predict(data.X, batch_size)
+	if err != nil {
+		return FoldMetrics{}, err
+	}
+	predictions := datasets.ClassLabels(model.OutputLayerActivation.Predictions(outputs))
+	labels := datasets.ClassLabels(data.Y)
+
+	_, classes := outputs.Dims()
+	for _, values := range [][]float64{predictions, labels} {
+		for _, class := range values {
+			classes = max(classes, int(class)+1)
+		}
+	}
+
+	confusion_matrix := metrics.ConfusionMatrix(labels, predictions, classes)
+	accuracy, precision, recall, f1 := metrics.CalculateMetrics(confusion_matrix, classes)
+
+	scores.Accuracy = accuracy[0]
+	scores.Precision, scores.Recall, scores.F1 = metrics.MacroAverage(precision, confusion_matrix), metrics.MacroAverage(recall, confusion_matrix), metrics.MacroAverage(f1, confusion_matrix)
+
+	return scores, nil
+}
+
+// foldStatistics returns the mean & the (population) standard deviation of every score over the folds
+func foldStatistics(folds []FoldMetrics) (FoldMetrics, FoldMetrics) {
+	fields := func(scores *FoldMetrics) []*float64 {
+		return []*float64{&scores.Loss, &scores.Accuracy, &scores.Precision, &scores.Recall, &scores.F1}
+	}
+
+	var mean_scores, std_scores FoldMetrics
+	for j, field := range fields(&mean_scores) {
+		values := make([]float64, len(folds))
+		for i := range folds {
+			values[i] = *fields(&folds[i])[j]
+		}
+
+		m := mean(values)
+		*field = m
+
+		variance := 0.
+		for _, v := range values {
+			variance += (v - m) * (v - m)
+		}
+		*fields(&std_scores)[j] = math.Sqrt(variance / float64(len(values)))
+	}
+
+	return mean_scores, std_scores
+}
+
+func mean(values []float64) float64 {
+	sum := 0.
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
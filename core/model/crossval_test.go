@@ -0,0 +1,66 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/accuracy"
+	"github.com/saent-x/ids-nn/core/activation"
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/datasets"
+	"github.com/saent-x/ids-nn/core/layer"
+	"github.com/saent-x/ids-nn/core/loss"
+	"github.com/saent-x/ids-nn/core/optimization"
+	"github.com/saent-x/ids-nn/core/scaling"
+)
+
+func TestCrossValidate(t *testing.T) {
+	X, y := core.SpiralData(100, 3)
+	data := datamodels.TrainingData{X: X, Y: y}
+
+	folds, err := datasets.StratifiedKFold(datasets.ClassLabels(y), 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models := 0
+	newModel := func() (*Model, error) {
+		models++
+
+		m := New()
+		m.Add(layer.CreateLayer(2, 64, 0, 5e-4, 0, 5e-4))
+		m.Add(new(activation.ReLU))
+		m.Add(layer.CreateLayer(64, 3, 0, 0, 0, 0))
+		m.Add(new(activation.SoftMax))
+		m.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.05, 5e-5, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+		m.Scaler = new(scaling.StandardScaler)
+
+		return m, m.Finalize()
+	}
+
+	result, err := CrossValidate(newModel, data, folds, TrainingConfig{Epochs: 100, Shuffle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if models != 3 || len(result.Folds) != 3 {
+		t.Fatalf("error: got %d models & %d fold scores | want 3", models, len(result.Folds))
+	}
+
+	sum := 0.
+	for _, fold := range result.Folds {
+		sum += fold.Accuracy
+	}
+	if math.Abs(result.Mean.Accuracy-sum/3) > 1e-12 || result.Std.Accuracy < 0 {
+		t.Errorf("error: got a mean accuracy of %f & a std of %f for the folds %+v", result.Mean.Accuracy, result.Std.Accuracy, result.Folds)
+	}
+	// a spiral is easy enough to beat chance on unseen points
+	if result.Mean.Accuracy < 0.5 || result.Mean.F1 <= 0 {
+		t.Errorf("error: got mean scores %+v", result.Mean)
+	}
+
+	if _, err := CrossValidate(newModel, data, []datasets.Fold{{Train: folds[0].Train}}, TrainingConfig{Epochs: 1}); err == nil {
+		t.Errorf("error: expected an error for a fold without test rows")
+	}
+}
@@ -7,13 +7,15 @@ import (
 	"github.com/saent-x/ids-nn/core"
 	"github.com/saent-x/ids-nn/core/accuracy"
 	"github.com/saent-x/ids-nn/core/activation"
+	"github.com/saent-x/ids-nn/core/calibration"
 	"github.com/saent-x/ids-nn/core/layer"
 	"github.com/saent-x/ids-nn/core/loss"
 	datawrappers "github.com/saent-x/ids-nn/core/model/data_wrappers"
 	"github.com/saent-x/ids-nn/core/optimization"
+	"github.com/saent-x/ids-nn/core/scaling"
 	"gonum.org/v1/gonum/mat"
 	"io"
-	"log"
+	"os"
 	"reflect"
 )
 
@@ -21,6 +23,39 @@ type ModelDataProvider struct {
 }
 
 func (modelDataProvider *ModelDataProvider) Save(filename string, model *Model) error {
+	err := modelDataProvider.SaveFile(fmt.Sprintf("./saved_models/%s.json", filename), model)
+	if err != nil {
+		fmt.Printf("Error writing JSON bytes to file: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// SaveFile writes the model as JSON to the given path
+func (modelDataProvider *ModelDataProvider) SaveFile(path string, model *Model) error {
+	wrapper, err := modelDataProvider.wrap(model)
+	if err != nil {
+		return err
+	}
+
+	return core.EncodeStructToJSON(wrapper, path)
+}
+
+// Encode writes the model as JSON to w
+func (modelDataProvider *ModelDataProvider) Encode(w io.Writer, model *Model) error {
+	wrapper, err := modelDataProvider.wrap(model)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(wrapper)
+}
+
+func (modelDataProvider *ModelDataProvider) wrap(model *Model) (datawrappers.ModelWrapper, error) {
 	layers := make([]datawrappers.LayerWrapper, 0)
 	for i := 0; i < len(model.Layers); i++ {
 		modelLayer := model.Layers[i]
@@ -45,6 +80,14 @@ func (modelDataProvider *ModelDataProvider) Save(filename string, model *Model)
 
 			layers = append(layers, lw)
 		}
+		if l, ok := model.Layers[i].(*layer.Reparameterization); ok {
+			lw := datawrappers.LayerWrapper{
+				Type: reflect.TypeOf(l).String(),
+				Beta: l.Beta,
+				Seed: l.Seed,
+			}
+			layers = append(layers, lw)
+		}
 		if l, ok := model.Layers[i].(*activation.ReLU); ok {
 			lw := datawrappers.LayerWrapper{
 				Type: reflect.TypeOf(l).String(),
@@ -69,7 +112,7 @@ func (modelDataProvider *ModelDataProvider) Save(filename string, model *Model)
 			}
 			layers = append(layers, lw)
 		}
-		if l, ok := model.Layers[i].(*activation.SoftmaxCatCrossEntropy); ok {
+		if l, ok := model.Layers[i].(activation.FusedOutput); ok {
 			lw := datawrappers.LayerWrapper{
 				Type: reflect.TypeOf(l).String(),
 			}
@@ -82,25 +125,62 @@ func (modelDataProvider *ModelDataProvider) Save(filename string, model *Model)
 		Obj:  model.Optimizer,
 	}
 
-	modelWrapper := datawrappers.ModelWrapper{
-		layers,
-		reflect.TypeOf(model.Lossfn).String(),
-		reflect.TypeOf(model.Accuracy).String(),
-		optimizer,
+	var scaler *scaling.Config
+	if model.Scaler != nil {
+		config, err := scaling.ConfigOf(model.Scaler)
+		if err != nil {
+			return datawrappers.ModelWrapper{}, err
+		}
+		scaler = &config
 	}
 
-	//d, err := json.MarshalIndent(modelWrapper, "", "  ")
-	//if err != nil {
-	//	return err
-	//}
+	var calibrator *calibration.Config
+	if model.Calibrator != nil {
+		config, err := calibration.ConfigOf(model.Calibrator)
+		if err != nil {
+			return datawrappers.ModelWrapper{}, err
+		}
+		calibrator = &config
+	}
+
+	var attack_threshold *float64
+	if softmax, ok := model.OutputLayerActivation.(*activation.SoftMax); ok {
+		attack_threshold = softmax.AttackThreshold
+	}
 
-	err := core.EncodeStructToJSON(modelWrapper, fmt.Sprintf("./saved_models/%s.json", filename))
+	loss_parameters, err := json.Marshal(model.Lossfn)
 	if err != nil {
-		fmt.Printf("Error writing JSON bytes to file: %v\n", err)
-		return err
+		return datawrappers.ModelWrapper{}, err
+	}
+	if string(loss_parameters) == "{}" {
+		loss_parameters = nil
 	}
 
-	return nil
+	return datawrappers.ModelWrapper{
+		Layers:         layers,
+		Loss:           reflect.TypeOf(model.Lossfn).String(),
+		LossParameters: loss_parameters,
+		Accuracy:       reflect.TypeOf(model.Accuracy).String(),
+		Optimizer:      optimizer,
+		Classes:        model.ClassNames,
+		Features:       model.FeatureNames,
+		Scaler:         scaler,
+		Thresholds:     model.Thresholds(),
+
+		Calibration:     calibrator,
+		AttackThreshold: attack_threshold,
+	}, nil
+}
+
+// LoadFile opens & decodes a model saved with Save/SaveFile
+func (modelDataProvider *ModelDataProvider) LoadFile(path string) (*Model, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return modelDataProvider.Load(file)
 }
 
 func (modelDataProvider *ModelDataProvider) Load(file io.Reader) (*Model, error) {
@@ -111,10 +191,15 @@ func (modelDataProvider *ModelDataProvider) Load(file io.Reader) (*Model, error)
 	decoder := json.NewDecoder(file)
 	err := decoder.Decode(&retrievedModel)
 	if err != nil {
-		log.Fatalf("Failed to decode JSON: %v", err)
+		return (&Model{}), fmt.Errorf("failed to decode model JSON: %v", err)
 	}
 
-	model := Model{}
+	model := Model{ClassNames: retrievedModel.Classes, FeatureNames: retrievedModel.Features}
+	if retrievedModel.Scaler != nil {
+		if model.Scaler, err = scaling.New(*retrievedModel.Scaler); err != nil {
+			return (&Model{}), fmt.Errorf("invalid scaler: %v", err)
+		}
+	}
 
 	// fill model layers
 	for i := 0; i < len(retrievedModel.Layers); i++ {
@@ -135,6 +220,9 @@ func (modelDataProvider *ModelDataProvider) Load(file io.Reader) (*Model, error)
 			}
 			model.Add(&l)
 		}
+		if layer_.Type == reflect.TypeOf(&layer.Reparameterization{}).String() {
+			model.Add(layer.NewReparameterization(layer_.Beta, layer_.Seed))
+		}
 		if layer_.Type == reflect.TypeOf(&activation.ReLU{}).String() {
 			model.Add(&activation.ReLU{})
 		}
@@ -147,9 +235,11 @@ func (modelDataProvider *ModelDataProvider) Load(file io.Reader) (*Model, error)
 		if layer_.Type == reflect.TypeOf(&activation.SoftMax{}).String() {
 			model.Add(&activation.SoftMax{})
 		}
-		if layer_.Type == reflect.TypeOf(&activation.SoftmaxCatCrossEntropy{}).String() {
-			//model.Add(activation.SoftmaxCatCrossEntropy{})
-			panic("un-implemented func/mapping")
+		// a fused output is loaded as its activation, Finalize fuses it with the loss again
+		for _, fused := range []activation.FusedOutput{&activation.SoftmaxCatCrossEntropy{}, &activation.SigmoidBinaryCrossEntropy{}} {
+			if layer_.Type == reflect.TypeOf(fused).String() {
+				model.Add(fused.Activation())
+			}
 		}
 
 	}
@@ -164,9 +254,34 @@ func (modelDataProvider *ModelDataProvider) Load(file io.Reader) (*Model, error)
 		lossfn = &loss.MeanSquaredError{}
 	case reflect.TypeOf(&loss.MeanAbsoluteError{}).String():
 		lossfn = &loss.MeanAbsoluteError{}
+	case reflect.TypeOf(&loss.BCEWithLogits{}).String():
+		lossfn = &loss.BCEWithLogits{}
+	case reflect.TypeOf(&loss.BinaryFocalLoss{}).String():
+		lossfn = &loss.BinaryFocalLoss{}
+	case reflect.TypeOf(&loss.CategoricalFocalLoss{}).String():
+		lossfn = &loss.CategoricalFocalLoss{}
+	case reflect.TypeOf(&loss.WeightedBinaryCrossEntropy{}).String():
+		lossfn = &loss.WeightedBinaryCrossEntropy{}
+	case reflect.TypeOf(&loss.Huber{}).String():
+		lossfn = &loss.Huber{}
+	case reflect.TypeOf(&loss.LogCosh{}).String():
+		lossfn = &loss.LogCosh{}
+	case reflect.TypeOf(&loss.Hinge{}).String():
+		lossfn = &loss.Hinge{}
+	case reflect.TypeOf(&loss.SquaredHinge{}).String():
+		lossfn = &loss.SquaredHinge{}
+	case reflect.TypeOf(&loss.KLDivergence{}).String():
+		lossfn = &loss.KLDivergence{}
+	case reflect.TypeOf(&loss.LabelSmoothingCrossEntropy{}).String():
+		lossfn = &loss.LabelSmoothingCrossEntropy{}
 	default:
 		return (&Model{}), errors.New("invalid loss value")
 	}
+	if retrievedModel.LossParameters != nil {
+		if err = json.Unmarshal(retrievedModel.LossParameters, lossfn); err != nil {
+			return (&Model{}), fmt.Errorf("invalid loss parameters: %v", err)
+		}
+	}
 
 	var accuracy_ accuracy.IAccuracy
 	switch retrievedModel.Accuracy {
@@ -176,6 +291,8 @@ func (modelDataProvider *ModelDataProvider) Load(file io.Reader) (*Model, error)
 		accuracy_ = &accuracy.BinaryAccuracy{}
 	case reflect.TypeOf(&accuracy.RegressionAccuracy{}).String():
 		accuracy_ = &accuracy.RegressionAccuracy{}
+	case reflect.TypeOf(&accuracy.MultiLabelAccuracy{}).String():
+		accuracy_ = &accuracy.MultiLabelAccuracy{}
 	default:
 		return (&Model{}), errors.New("invalid accuracy value")
 	}
@@ -243,7 +360,24 @@ func (modelDataProvider *ModelDataProvider) Load(file io.Reader) (*Model, error)
 	}
 
 	model.Set(lossfn, optimizer, accuracy_)
-	model.Finalize()
+	if err = model.Finalize(); err != nil {
+		return (&Model{}), err
+	}
+	if retrievedModel.Thresholds != nil {
+		if err = model.SetThresholds(retrievedModel.Thresholds); err != nil {
+			return (&Model{}), err
+		}
+	}
+	if retrievedModel.AttackThreshold != nil {
+		if err = model.SetAttackThreshold(*retrievedModel.AttackThreshold); err != nil {
+			return (&Model{}), err
+		}
+	}
+	if retrievedModel.Calibration != nil {
+		if model.Calibrator, err = calibration.New(*retrievedModel.Calibration); err != nil {
+			return (&Model{}), fmt.Errorf("invalid calibration: %v", err)
+		}
+	}
 
 	return &model, nil
 }
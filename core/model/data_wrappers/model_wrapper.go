@@ -1,10 +1,28 @@
 package datawrappers
 
+import (
+	"encoding/json"
+
+	"github.com/saent-x/ids-nn/core/calibration"
+	"github.com/saent-x/ids-nn/core/scaling"
+)
+
 type ModelWrapper struct {
-	Layers    []LayerWrapper
-	Loss      string `json:"loss,omitempty"`
-	Accuracy  string `json:"accuracy,omitempty"`
-	Optimizer OptimizerWrapper
+	Layers []LayerWrapper
+	Loss   string `json:"loss,omitempty"`
+	// LossParameters holds the settings of losses that have some, e.g. the gamma of a focal loss
+	LossParameters json.RawMessage `json:"loss_parameters,omitempty"`
+	Accuracy       string          `json:"accuracy,omitempty"`
+	Optimizer      OptimizerWrapper
+	Classes        []string        `json:"classes,omitempty"`
+	Features       []string        `json:"features,omitempty"`
+	Scaler         *scaling.Config `json:"scaler,omitempty"`
+	// Thresholds are the per label thresholds of a multi-label (sigmoid) model
+	Thresholds []float64 `json:"thresholds,omitempty"`
+
+	// Calibration is the fitted calibrator of the outputs & AttackThreshold the operating point of a softmax model
+	Calibration     *calibration.Config `json:"calibration,omitempty"`
+	AttackThreshold *float64            `json:"attack_threshold,omitempty"`
 }
 
 type LayerWrapper struct {
@@ -25,6 +43,11 @@ type LayerWrapper struct {
 	Weight_Regularizer_L2 float64 `json:"weight___regularizer___l_2,omitempty"`
 	Biases_Regularizer_L1 float64 `json:"biases___regularizer___l_1,omitempty"`
 	Biases_Regularizer_L2 float64 `json:"biases___regularizer___l_2,omitempty"`
+
+	// Beta is the weight of the KL term of a reparameterization layer
+	Beta float64 `json:"beta,omitempty"`
+	// Seed is the seed of the noise of a reparameterization layer
+	Seed int64 `json:"seed,omitempty"`
 }
 
 type MatDenseWrapper struct {
@@ -0,0 +1,602 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/layer"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// FeatureImportance is how much an input feature weighs on the model, see Importances
+type FeatureImportance struct {
+	Feature    string  `json:"feature"`
+	Importance float64 `json:"importance"`
+	// Std is the standard deviation of the importance over the repeats or the samples it is the mean of
+	Std float64 `json:"std"`
+}
+
+// Importances are the importances of the input features, sorted by decreasing magnitude
+type Importances []FeatureImportance
+
+// newImportances names & sorts the importances of every feature (column)
+func (model *Model) newImportances(importances, stds []float64) Importances {
+	result := make(Importances, len(importances))
+	for j, importance := range importances {
+		result[j] = FeatureImportance{Feature: model.FeatureName(j), Importance: importance, Std: stds[j]}
+	}
+
+	return result.sorted()
+}
+
+func (importances Importances) String() string {
+	var builder strings.Builder
+
+	writer := tabwriter.NewWriter(&builder, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(writer, "feature\timportance\tstd\t\n")
+	for _, importance := range importances {
+		fmt.Fprintf(writer, "%s\t%.6f\t%.6f\t\n", importance.Feature, importance.Importance, importance.Std)
+	}
+	writer.Flush()
+
+	return builder.String()
+}
+
+// Plot draws the importances as a bar chart, the most important feature on top
+func (importances Importances) Plot(title, filename string) error {
+	if len(importances) == 0 {
+		return errors.New("no feature to plot")
+	}
+
+	values := make(plotter.Values, len(importances))
+	names := make([]string, len(importances))
+	for i, importance := range importances {
+		values[len(importances)-1-i] = importance.Importance
+		names[len(importances)-1-i] = importance.Feature
+	}
+
+	p := plot.New()
+
+	p.Title.Text = title
+	p.X.Label.Text = "Importance"
+
+	bars, err := plotter.NewBarChart(values, vg.Points(12))
+	if err != nil {
+		return err
+	}
+	bars.Horizontal = true
+	p.Add(bars)
+	p.NominalY(names...)
+
+	return p.Save(6*vg.Inch, vg.Length(len(importances))*vg.Points(20)+vg.Inch, filename)
+}
+
+// Attributions split an output of the model into contributions of the input features (columns) for every sample
+// (row). They explain the outputs before calibration, with respect to the inputs the network sees (scaled by the
+// model's scaler, so the contributions of features of different units compare).
+type Attributions struct {
+	Features []string
+	// Output is the output explained, e.g. the attack class of a softmax
+	Output int
+	// Base is what the attributions of a sample add up from: the output at the baseline of integrated gradients &
+	// the mean output on the background of KernelSHAP (0 for saliency)
+	Base   float64
+	Values *mat.Dense
+}
+
+// Importances are the mean absolute attributions of every feature, the features weighing the most on the output
+// over the samples
+func (attributions *Attributions) Importances() Importances {
+	rows, cols := attributions.Values.Dims()
+
+	importances := make(Importances, cols)
+	for j := range importances {
+		column := mat.Col(nil, j, attributions.Values)
+		for i := range column {
+			column[i] = math.Abs(column[i])
+		}
+
+		mean, std := stat.PopMeanStdDev(column, nil)
+		if rows == 1 {
+			std = 0
+		}
+		importances[j] = FeatureImportance{Feature: attributions.Features[j], Importance: mean, Std: std}
+	}
+
+	return importances.sorted()
+}
+
+// Sample returns the attributions of the sample in row i, e.g. to tell why a frame was flagged
+func (attributions *Attributions) Sample(i int) Importances {
+	importances := make(Importances, len(attributions.Features))
+	for j, feature := range attributions.Features {
+		importances[j] = FeatureImportance{Feature: feature, Importance: attributions.Values.At(i, j)}
+	}
+
+	return importances.sorted()
+}
+
+func (importances Importances) sorted() Importances {
+	sort.SliceStable(importances, func(a, b int) bool {
+		return math.Abs(importances[a].Importance) > math.Abs(importances[b].Importance)
+	})
+
+	return importances
+}
+
+func (model *Model) attributions(output int, base float64, values *mat.Dense) *Attributions {
+	_, cols := values.Dims()
+
+	features := make([]string, cols)
+	for j := range features {
+		features[j] = model.FeatureName(j)
+	}
+
+	return &Attributions{Features: features, Output: output, Base: base, Values: values}
+}
+
+// PermutationOptions configures PermutationImportance
+type PermutationOptions struct {
+	// Repeats is the number of times every feature is shuffled (5 by default)
+	Repeats int
+	// Seed makes the shuffles reproducible, 0 seeds from the clock
+	Seed int64
+	// BatchSize is the batch size of the predictions (0 for a single batch)
+	BatchSize int
+	// Score scores the (calibrated) outputs of the model for the targets y, higher being better, e.g. a macro F1 to
+	// measure the detection rather than the fit. The mean loss of the calibrated outputs, negated, is used when nil.
+	Score func(outputs, y *mat.Dense) float64
+}
+
+// PermutationImportance measures how much the model relies on every input feature: the drop of its score on data
+// when the values of the feature are shuffled between the samples, breaking its relation to the targets. The
+// importance is the mean drop over the repeats & Std its standard deviation.
+func (model *Model) PermutationImportance(data datamodels.TrainingData, options PermutationOptions) (Importances, error) {
+	if data.X == nil || data.Y == nil {
+		return nil, errors.New("permutation importance needs samples & their targets")
+	}
+
+	repeats := options.Repeats
+	if repeats <= 0 {
+		repeats = 5
+	}
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	// the stacked outputs of the batches are scored with the unfused loss: a fused one reads the inputs its
+	// activation cached, which only hold the last batch
+	sample_weights := model.Lossfn.GetSampleWeights()
+	model.Lossfn.SetSampleWeights(nil)
+	defer model.Lossfn.SetSampleWeights(sample_weights)
+	score := func(X *mat.Dense) (float64, error) {
+		outputs, err := model.rawOutputs(X, options.BatchSize)
+		if err != nil {
+			return 0, err
+		}
+		calibrated, err := model.calibrate(outputs)
+		if err != nil {
+			return 0, err
+		}
+		if options.Score == nil {
+			return -stat.Mean(model.Lossfn.Forward(calibrated, model.alignLabels(data.Y, data.X.RawMatrix().Rows)).RawVector().Data, nil), nil
+		}
+
+		return options.Score(calibrated, data.Y), nil
+	}
+
+	baseline, err := score(data.X)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, cols := data.X.Dims()
+	importances, stds := make([]float64, cols), make([]float64, cols)
+	shuffled := mat.DenseCopyOf(data.X)
+	for j := 0; j < cols; j++ {
+		column := mat.Col(nil, j, data.X)
+
+		drops := make([]float64, repeats)
+		for r := range drops {
+			for i, k := range rng.Perm(rows) {
+				shuffled.Set(i, j, column[k])
+			}
+
+			permuted, err := score(shuffled)
+			if err != nil {
+				return nil, err
+			}
+			drops[r] = baseline - permuted
+		}
+		shuffled.SetCol(j, column)
+
+		importances[j], stds[j] = stat.PopMeanStdDev(drops, nil)
+	}
+
+	return model.newImportances(importances, stds), nil
+}
+
+// Saliency returns the gradient of an output of the model with respect to every (scaled) input feature of every
+// sample of X, through the Backward chain of the layers: how much a small change of a feature moves the output
+func (model *Model) Saliency(X *mat.Dense, output int) (*Attributions, error) {
+	scaled, err := model.scale(X)
+	if err != nil {
+		return nil, err
+	}
+
+	gradients, _, err := model.inputGradients(scaled, output)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.attributions(output, 0, gradients), nil
+}
+
+// IntegratedGradients attributes an output of the model for every sample of X to its features (Sundararajan et al.
+// 2017): the difference between the sample & the baseline times the mean gradient along the straight path between
+// them, approximated with steps (50 by default) trapezoids. The attributions of a sample add up to its output minus
+// the output at the baseline (Attributions.Base), up to the approximation.
+// The baseline is a single sample in the units of X, zeros in the scaled space when nil (the mean of the training
+// data with a StandardScaler, not with the other scalers or without a scaler).
+func (model *Model) IntegratedGradients(X, baseline *mat.Dense, output, steps int) (*Attributions, error) {
+	if steps <= 0 {
+		steps = 50
+	}
+
+	scaled, err := model.scale(X)
+	if err != nil {
+		return nil, err
+	}
+	rows, cols := scaled.Dims()
+
+	scaled_baseline := mat.NewDense(1, cols, nil)
+	if baseline != nil {
+		if b_rows, b_cols := baseline.Dims(); b_rows != 1 || b_cols != cols {
+			return nil, fmt.Errorf("the baseline must be a single sample of %d features, got %dx%d", cols, b_rows, b_cols)
+		}
+		if scaled_baseline, err = model.scale(baseline); err != nil {
+			return nil, err
+		}
+	}
+
+	path := mat.NewDense(rows, cols, nil)
+	mean_gradients := mat.NewDense(rows, cols, nil)
+	var base float64
+	for k := 0; k <= steps; k++ {
+		alpha := float64(k) / float64(steps)
+		path.Apply(func(i, j int, _ float64) float64 {
+			return scaled_baseline.At(0, j) + alpha*(scaled.At(i, j)-scaled_baseline.At(0, j))
+		}, path)
+
+		gradients, outputs, err := model.inputGradients(path, output)
+		if err != nil {
+			return nil, err
+		}
+		if k == 0 {
+			base = outputs.At(0, output)
+		}
+
+		weight := 1 / float64(steps)
+		if k == 0 || k == steps {
+			weight /= 2
+		}
+		gradients.Scale(weight, gradients)
+		mean_gradients.Add(mean_gradients, gradients)
+	}
+
+	mean_gradients.Apply(func(i, j int, v float64) float64 {
+		return v * (scaled.At(i, j) - scaled_baseline.At(0, j))
+	}, mean_gradients)
+
+	return model.attributions(output, base, mean_gradients), nil
+}
+
+// SHAPOptions configures KernelSHAP
+type SHAPOptions struct {
+	// Background holds the samples (in the units of the explained samples) the features missing from a coalition are
+	// taken from, e.g. a few dozens of attack-free frames
+	Background *mat.Dense
+	// Coalitions is the number of coalitions of features evaluated per sample (2048 by default), every coalition is
+	// evaluated when there are fewer
+	Coalitions int
+	// Seed makes the sampled coalitions reproducible, 0 seeds from the clock
+	Seed int64
+}
+
+// KernelSHAP approximates the Shapley values of the features of every sample of X for an output of the model
+// (Lundberg & Lee 2017): the output of coalitions of features, the others taken from the background samples, is
+// fitted by a linear model weighted by the Shapley kernel. The values of a sample add up to its output minus the
+// mean output on the background (Attributions.Base).
+func (model *Model) KernelSHAP(X *mat.Dense, output int, options SHAPOptions) (*Attributions, error) {
+	if options.Background == nil {
+		return nil, errors.New("kernel SHAP needs background samples")
+	}
+	scaled, err := model.scale(X)
+	if err != nil {
+		return nil, err
+	}
+	background, err := model.scale(options.Background)
+	if err != nil {
+		return nil, fmt.Errorf("background: %v", err)
+	}
+
+	rows, cols := scaled.Dims()
+	if _, b_cols := background.Dims(); b_cols != cols {
+		return nil, fmt.Errorf("got background samples of %d features for samples of %d", b_cols, cols)
+	}
+
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	// a single feature has no coalition but the empty & the full ones, its value is the whole output - base
+	var coalitions [][]bool
+	var weights []float64
+	if cols > 1 {
+		coalitions, weights = shapleyCoalitions(cols, options.Coalitions, rand.New(rand.NewSource(seed)))
+	}
+
+	base_outputs, err := model.explainedOutput(background, output)
+	if err != nil {
+		return nil, err
+	}
+	base := stat.Mean(base_outputs, nil)
+
+	values := mat.NewDense(rows, cols, nil)
+	for i := 0; i < rows; i++ {
+		sample := scaled.RawRowView(i)
+
+		outputs, err := model.explainedOutput(scaled.Slice(i, i+1, 0, cols).(*mat.Dense), output)
+		if err != nil {
+			return nil, err
+		}
+
+		if cols == 1 {
+			values.Set(i, 0, outputs[0]-base)
+			continue
+		}
+
+		coalition_outputs, err := model.coalitionOutputs(sample, background, coalitions, output)
+		if err != nil {
+			return nil, err
+		}
+
+		shapley_values, err := shapleyValues(coalitions, weights, coalition_outputs, base, outputs[0])
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %v", i, err)
+		}
+		values.SetRow(i, shapley_values)
+	}
+
+	return model.attributions(output, base, values), nil
+}
+
+// inputGradients returns the gradients of an output of the model w.r.t. its scaled inputs & the outputs of the model
+func (model *Model) inputGradients(scaled *mat.Dense, output int) (*mat.Dense, *mat.Dense, error) {
+	outputs := model.forward(scaled, false)
+
+	rows, cols := outputs.Dims()
+	if output < 0 || output >= cols {
+		return nil, nil, fmt.Errorf("the model has %d outputs, can't explain output %d", cols, output)
+	}
+
+	d_outputs := mat.NewDense(rows, cols, nil)
+	for i := 0; i < rows; i++ {
+		d_outputs.Set(i, output, 1)
+	}
+	model.backpropagate(len(model.Layers)-1, d_outputs)
+
+	return mat.DenseCopyOf(model.Layers[0].(layer.ILayer).GetDInputs()), mat.DenseCopyOf(outputs), nil
+}
+
+// rawOutputs returns the outputs of the model for X before calibration, in batches of batch_size
+func (model *Model) rawOutputs(X *mat.Dense, batch_size int) (*mat.Dense, error) {
+	scaled, err := model.scale(X)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.forwardScaled(scaled, batch_size)
+}
+
+// forwardScaled runs the inference pass on scaled inputs, in batches of batch_size
+func (model *Model) forwardScaled(scaled *mat.Dense, batch_size int) (*mat.Dense, error) {
+	rows, _ := scaled.Dims()
+
+	var outputs *mat.Dense
+	start := 0
+	for batch, err := range (datamodels.ValidationData{X: scaled}).Batches(batch_size) {
+		if err != nil {
+			return nil, err
+		}
+
+		output := model.forward(batch.X, false)
+		batch_rows, cols := output.Dims()
+		if outputs == nil {
+			outputs = mat.NewDense(rows, cols, nil)
+		}
+		outputs.Slice(start, start+batch_rows, 0, cols).(*mat.Dense).Copy(output)
+		start += batch_rows
+	}
+
+	return outputs, nil
+}
+
+// explainedOutput returns an output of the model for every scaled sample
+func (model *Model) explainedOutput(scaled *mat.Dense, output int) ([]float64, error) {
+	outputs, err := model.forwardScaled(scaled, explainBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	if _, cols := outputs.Dims(); output < 0 || output >= cols {
+		return nil, fmt.Errorf("the model has %d outputs, can't explain output %d", cols, output)
+	}
+
+	return mat.Col(nil, output, outputs), nil
+}
+
+// explainBatchSize bounds the samples run at once when explaining
+const explainBatchSize = 4096
+
+// coalitionOutputs returns the mean output over the background of every coalition, the features of the coalition
+// being the ones of sample
+func (model *Model) coalitionOutputs(sample []float64, background *mat.Dense, coalitions [][]bool, output int) ([]float64, error) {
+	background_rows, cols := background.Dims()
+
+	inputs := mat.NewDense(len(coalitions)*background_rows, cols, nil)
+	for c, coalition := range coalitions {
+		for b := 0; b < background_rows; b++ {
+			row := inputs.RawRowView(c*background_rows + b)
+			copy(row, background.RawRowView(b))
+			for j, present := range coalition {
+				if present {
+					row[j] = sample[j]
+				}
+			}
+		}
+	}
+
+	outputs, err := model.explainedOutput(inputs, output)
+	if err != nil {
+		return nil, err
+	}
+
+	means := make([]float64, len(coalitions))
+	for c := range coalitions {
+		means[c] = stat.Mean(outputs[c*background_rows:(c+1)*background_rows], nil)
+	}
+
+	return means, nil
+}
+
+// shapleyCoalitions returns every coalition of features but the empty & the full ones with their Shapley kernel
+// weight when there are at most samples (2048 by default), samples coalitions drawn from the kernel otherwise
+func shapleyCoalitions(features, samples int, rng *rand.Rand) ([][]bool, []float64) {
+	if samples <= 0 {
+		samples = 2048
+	}
+	samples = max(samples, 2*features)
+
+	// the kernel weight of a coalition of size s is (M - 1) / (C(M, s) s (M - s))
+	kernel := func(size int) float64 {
+		return float64(features-1) / (binomial(features, size) * float64(size) * float64(features-size))
+	}
+
+	var coalitions [][]bool
+	var weights []float64
+	if features < 31 && 1<<features-2 <= samples {
+		for mask := 1; mask < 1<<features-1; mask++ {
+			coalition, size := make([]bool, features), 0
+			for j := range coalition {
+				if coalition[j] = mask&(1<<j) != 0; coalition[j] {
+					size++
+				}
+			}
+			coalitions, weights = append(coalitions, coalition), append(weights, kernel(size))
+		}
+		return coalitions, weights
+	}
+
+	// the sizes are drawn with the total weight of their coalitions, which are then drawn uniformly
+	size_weights := make([]float64, features-1)
+	for s := 1; s < features; s++ {
+		size_weights[s-1] = kernel(s) * binomial(features, s)
+	}
+	for len(coalitions) < samples {
+		size := 1 + sampleIndex(size_weights, rng)
+
+		coalition := make([]bool, features)
+		for _, j := range rng.Perm(features)[:size] {
+			coalition[j] = true
+		}
+		coalitions, weights = append(coalitions, coalition), append(weights, 1)
+	}
+
+	return coalitions, weights
+}
+
+// shapleyValues solves the weighted least squares of KernelSHAP, the values adding up to output - base: the last
+// feature's value is eliminated as output - base - the sum of the others
+func shapleyValues(coalitions [][]bool, weights, coalition_outputs []float64, base, output float64) ([]float64, error) {
+	features := len(coalitions[0])
+	total := output - base
+
+	indicator := func(present bool) float64 {
+		if present {
+			return 1
+		}
+		return 0
+	}
+
+	A := mat.NewDense(len(coalitions), features-1, nil)
+	b := mat.NewVecDense(len(coalitions), nil)
+	for c, coalition := range coalitions {
+		w := math.Sqrt(weights[c])
+		last := indicator(coalition[features-1])
+		for j := 0; j < features-1; j++ {
+			A.Set(c, j, w*(indicator(coalition[j])-last))
+		}
+		b.SetVec(c, w*(coalition_outputs[c]-base-last*total))
+	}
+
+	// the normal equations, with a tiny ridge in case the sampled coalitions don't pin every value down
+	var normal mat.Dense
+	normal.Mul(A.T(), A)
+	for j := 0; j < features-1; j++ {
+		normal.Set(j, j, normal.At(j, j)+1e-9)
+	}
+	var rhs, solution mat.VecDense
+	rhs.MulVec(A.T(), b)
+	if err := solution.SolveVec(&normal, &rhs); err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, features)
+	rest := total
+	for j := 0; j < features-1; j++ {
+		values[j] = solution.AtVec(j)
+		rest -= values[j]
+	}
+	values[features-1] = rest
+
+	return values, nil
+}
+
+func binomial(n, k int) float64 {
+	return math.Round(math.Exp(lgamma(n+1) - lgamma(k+1) - lgamma(n-k+1)))
+}
+
+func lgamma(n int) float64 {
+	value, _ := math.Lgamma(float64(n))
+	return value
+}
+
+// sampleIndex draws an index with a probability proportional to its weight
+func sampleIndex(weights []float64, rng *rand.Rand) int {
+	total := 0.
+	for _, w := range weights {
+		total += w
+	}
+
+	draw := rng.Float64() * total
+	for i, w := range weights {
+		if draw -= w; draw < 0 {
+			return i
+		}
+	}
+
+	return len(weights) - 1
+}
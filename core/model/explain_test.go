@@ -0,0 +1,290 @@
+package model
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/saent-x/ids-nn/core/accuracy"
+	"github.com/saent-x/ids-nn/core/activation"
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/layer"
+	"github.com/saent-x/ids-nn/core/loss"
+	"github.com/saent-x/ids-nn/core/optimization"
+	"github.com/saent-x/ids-nn/core/scaling"
+	"gonum.org/v1/gonum/mat"
+)
+
+// frames returns samples of 4 features whose class only depends on the first 2, the first weighing the most
+func frames(samples int, random *rand.Rand) datamodels.TrainingData {
+	X, y := mat.NewDense(samples, 4, nil), mat.NewDense(1, samples, nil)
+	for i := 0; i < samples; i++ {
+		row := []float64{random.NormFloat64() * 10, random.NormFloat64(), random.NormFloat64(), 100 + random.NormFloat64()}
+		X.SetRow(i, row)
+		if row[0]/10+row[1]/4 > 0 {
+			y.Set(0, i, 1)
+		}
+	}
+
+	return datamodels.TrainingData{X: X, Y: y}
+}
+
+func explainedModel(t *testing.T, data datamodels.TrainingData) *Model {
+	t.Helper()
+
+	m := New()
+	m.Add(layer.CreateLayer(4, 16, 0, 0, 0, 0))
+	m.Add(new(activation.ReLU))
+	m.Add(layer.NewDropoutLayer(0.1))
+	m.Add(layer.CreateLayer(16, 2, 0, 0, 0, 0))
+	m.Add(new(activation.SoftMax))
+	m.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.02, 5e-5, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+	m.Scaler = new(scaling.StandardScaler)
+	if err := m.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	m.SetFeatureNames([]string{"ID", "df1", "df2", "time_interval"})
+
+	if err := m.FitScaler(data.X); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Train(data, nil, 100, 0, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestPermutationImportance(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	data := frames(400, random)
+	m := explainedModel(t, data)
+
+	importances, err := m.PermutationImportance(data, PermutationOptions{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(importances) != 4 || importances[0].Feature != "ID" || importances[1].Feature != "df1" {
+		t.Fatalf("error: got %v | want ID then df1 first", importances)
+	}
+	for _, importance := range importances[2:] {
+		if math.Abs(importance.Importance) > importances[1].Importance/5 {
+			t.Errorf("error: got an importance of %f for %s, which the class doesn't depend on", importance.Importance, importance.Feature)
+		}
+	}
+
+	// the table & the bar chart name the features
+	if table := importances.String(); !strings.Contains(table, "time_interval") || !strings.Contains(table, "importance") {
+		t.Errorf("error: got the table\n%s", table)
+	}
+	plot_path := filepath.Join(t.TempDir(), "importance.png")
+	if err = importances.Plot("Permutation importance", plot_path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(plot_path); err != nil {
+		t.Error(err)
+	}
+
+	// a custom score, the accuracy
+	importances, err = m.PermutationImportance(data, PermutationOptions{Seed: 1, Repeats: 2, Score: func(outputs, y *mat.Dense) float64 {
+		predictions, correct := m.OutputLayerActivation.Predictions(outputs), 0.
+		for i, prediction := range predictions.RawMatrix().Data {
+			if prediction == y.At(0, i) {
+				correct++
+			}
+		}
+		return correct / float64(len(predictions.RawMatrix().Data))
+	}})
+	if err != nil || importances[0].Feature != "ID" || importances[0].Importance < 0.2 {
+		t.Errorf("error: got %v | want ID to cost more than 0.2 of accuracy (%v)", importances, err)
+	}
+
+	// the feature names are saved with the model
+	var buffer bytes.Buffer
+	if err = new(ModelDataProvider).Encode(&buffer, m); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := new(ModelDataProvider).Load(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.FeatureName(3) != "time_interval" || loaded.FeatureName(4) != "feature_4" {
+		t.Errorf("error: got the features %v", loaded.FeatureNames)
+	}
+}
+
+func TestPermutationImportanceInBatches(t *testing.T) {
+	data := frames(400, rand.New(rand.NewSource(3)))
+
+	m := New()
+	m.Add(layer.CreateLayer(4, 8, 0, 0, 0, 0))
+	m.Add(new(activation.ReLU))
+	m.Add(layer.CreateLayer(8, 1, 0, 0, 0, 0))
+	m.Add(new(activation.Sigmoid))
+	m.Set(new(loss.BinaryCrossEntropy), optimization.CreateAdaptiveMomentum(0.02, 5e-5, 1e-7, 0.9, 0.999, 0), new(accuracy.BinaryAccuracy))
+	m.Scaler = new(scaling.StandardScaler)
+	if err := m.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if m.FusedOutput == nil {
+		t.Fatal("error: expected the fused sigmoid & binary cross-entropy")
+	}
+	if err := m.FitScaler(data.X); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Train(data, nil, 50, 0, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	// the batches don't change the scores: the fused loss would only see the last batch of 400 % 128 samples
+	want, err := m.PermutationImportance(data, PermutationOptions{Seed: 1, Repeats: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// scoring leaves the sample weights of the loss as they were
+	sample_weights := make([]float64, 400)
+	m.Lossfn.SetSampleWeights(sample_weights)
+	got, err := m.PermutationImportance(data, PermutationOptions{Seed: 1, Repeats: 2, BatchSize: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Lossfn.GetSampleWeights()) != 400 {
+		t.Error("error: the sample weights of the loss were reset")
+	}
+	for i := range want {
+		if got[i].Feature != want[i].Feature || math.Abs(got[i].Importance-want[i].Importance) > 1e-9 {
+			t.Errorf("error: got %v in batches | want %v", got[i], want[i])
+		}
+	}
+}
+
+func TestGradientAttributions(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	data := frames(400, random)
+	m := explainedModel(t, data)
+	X := mat.DenseCopyOf(data.X.Slice(0, 20, 0, 4))
+
+	saliency, err := m.Saliency(X, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the gradients match finite differences of the attack probability w.r.t. the scaled inputs
+	scaled, _ := m.scale(X)
+	const h = 1e-6
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 4; j++ {
+			perturbed := mat.DenseCopyOf(scaled.Slice(i, i+1, 0, 4))
+			perturbed.Set(0, j, perturbed.At(0, j)+h)
+			up := m.forward(perturbed, false).At(0, 1)
+			perturbed.Set(0, j, perturbed.At(0, j)-2*h)
+			down := m.forward(perturbed, false).At(0, 1)
+
+			if want := (up - down) / (2 * h); math.Abs(saliency.Values.At(i, j)-want) > 1e-5 {
+				t.Errorf("error: sample %d, %s: got a gradient of %f | want %f", i, saliency.Features[j], saliency.Values.At(i, j), want)
+			}
+		}
+	}
+	if importances := saliency.Importances(); importances[0].Feature != "ID" {
+		t.Errorf("error: got the saliencies %v | want ID first", importances)
+	}
+
+	// the integrated gradients of a sample add up to its output minus the output at the baseline
+	integrated, err := m.IntegratedGradients(X, nil, 1, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := m.forward(scaled, false)
+	for i := 0; i < 20; i++ {
+		if got, want := mat.Sum(integrated.Values.Slice(i, i+1, 0, 4)), outputs.At(i, 1)-integrated.Base; math.Abs(got-want) > 1e-3 {
+			t.Errorf("error: sample %d: the attributions add up to %f | want %f", i, got, want)
+		}
+	}
+	if sample := integrated.Sample(0); len(sample) != 4 || math.Abs(sample[0].Importance) < math.Abs(sample[3].Importance) {
+		t.Errorf("error: got the attributions %v of a sample", sample)
+	}
+
+	if _, err = m.Saliency(X, 2); err == nil {
+		t.Error("error: expected an error for an output the model doesn't have")
+	}
+	if _, err = m.IntegratedGradients(X, mat.NewDense(1, 3, nil), 1, 10); err == nil {
+		t.Error("error: expected an error for a baseline of another size")
+	}
+}
+
+func TestKernelSHAP(t *testing.T) {
+	// the Shapley values of a linear model are w * (x - the mean of the background)
+	weights := []float64{2, -1, 0.5, 0}
+	m := New()
+	m.Add(layer.CreateLayer(4, 1, 0, 0, 0, 0))
+	m.Add(new(activation.Linear))
+	m.Set(new(loss.MeanSquaredError), optimization.CreateAdaptiveMomentum(0.01, 0, 1e-7, 0.9, 0.999, 0), new(accuracy.RegressionAccuracy))
+	if err := m.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	m.TrainableLayers[0].SetParameters(datamodels.ModelParameter{Weights: mat.NewDense(4, 1, weights), Biases: mat.NewDense(1, 1, []float64{3})})
+
+	random := rand.New(rand.NewSource(3))
+	background, X := mat.NewDense(30, 4, nil), mat.NewDense(5, 4, nil)
+	for _, samples := range []*mat.Dense{background, X} {
+		samples.Apply(func(_, _ int, _ float64) float64 { return random.NormFloat64() }, samples)
+	}
+
+	// every coalition & 10 coalitions sampled from the kernel
+	for _, coalitions := range []int{0, 10} {
+		shap, err := m.KernelSHAP(X, 0, SHAPOptions{Background: background, Coalitions: coalitions, Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 5; i++ {
+			for j, w := range weights {
+				if want := w * (X.At(i, j) - mat.Sum(background.ColView(j))/30); math.Abs(shap.Values.At(i, j)-want) > 1e-6 {
+					t.Errorf("error: %d coalitions, sample %d, feature %d: got %f | want %f", coalitions, i, j, shap.Values.At(i, j), want)
+				}
+			}
+		}
+	}
+
+	if _, err := m.KernelSHAP(X, 0, SHAPOptions{}); err == nil {
+		t.Error("error: expected an error without background samples")
+	}
+
+	// a single feature gets the whole difference to the base
+	single := New()
+	single.Add(layer.CreateLayer(1, 1, 0, 0, 0, 0))
+	single.Add(new(activation.Linear))
+	single.Set(new(loss.MeanSquaredError), optimization.CreateAdaptiveMomentum(0.01, 0, 1e-7, 0.9, 0.999, 0), new(accuracy.RegressionAccuracy))
+	if err := single.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	single.TrainableLayers[0].SetParameters(datamodels.ModelParameter{Weights: mat.NewDense(1, 1, []float64{2}), Biases: mat.NewDense(1, 1, []float64{3})})
+	shap, err := single.KernelSHAP(mat.NewDense(2, 1, []float64{1, -1}), 0, SHAPOptions{Background: mat.NewDense(2, 1, []float64{0, 1}), Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shap.Values.At(0, 0) != 1 || shap.Values.At(1, 0) != -3 || shap.Base != 4 {
+		t.Errorf("error: got the values %v & the base %f | want [1 -3] & 4", mat.Formatted(shap.Values.T()), shap.Base)
+	}
+}
+
+func TestShapleyCoalitions(t *testing.T) {
+	coalitions, weights := shapleyCoalitions(3, 0, rand.New(rand.NewSource(1)))
+	if len(coalitions) != 6 {
+		t.Fatalf("error: got %d coalitions of 3 features | want 6", len(coalitions))
+	}
+	// (M - 1) / (C(M, s) s (M - s)) is 1/3 for every coalition of 1 or 2 of 3 features
+	for c, weight := range weights {
+		if math.Abs(weight-1./3) > 1e-12 {
+			t.Errorf("error: coalition %v: got a weight of %f | want 1/3", coalitions[c], weight)
+		}
+	}
+
+	if coalitions, _ = shapleyCoalitions(20, 100, rand.New(rand.NewSource(1))); len(coalitions) != 100 {
+		t.Errorf("error: got %d sampled coalitions | want 100", len(coalitions))
+	}
+}
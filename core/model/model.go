@@ -1,34 +1,61 @@
 package model
 
 import (
+	"errors"
 	"fmt"
-	"github.com/saent-x/ids-nn/core"
 	"github.com/saent-x/ids-nn/core/accuracy"
 	"github.com/saent-x/ids-nn/core/activation"
+	"github.com/saent-x/ids-nn/core/calibration"
 	"github.com/saent-x/ids-nn/core/datamodels"
 	"github.com/saent-x/ids-nn/core/layer"
 	"github.com/saent-x/ids-nn/core/loss"
+	"github.com/saent-x/ids-nn/core/metrics"
 	"github.com/saent-x/ids-nn/core/optimization"
+	"github.com/saent-x/ids-nn/core/scaling"
 	"github.com/saent-x/ids-nn/core/serializer"
+	"github.com/saent-x/ids-nn/core/tensor"
 	"gonum.org/v1/gonum/mat"
+	"strconv"
 )
 
 type Model struct {
-	Layers                  []any
-	TrainableLayers         []*layer.Layer
-	Lossfn                  loss.ILoss
-	Optimizer               optimization.IOptimizer
-	InputLayer              *layer.InputLayer
-	OutputLayerActivation   activation.IActivation
-	Accuracy                accuracy.IAccuracy
-	SoftMaxClassifierOutput *activation.SoftmaxCatCrossEntropy
+	Layers                []any
+	TrainableLayers       []*layer.Layer
+	Lossfn                loss.ILoss
+	Optimizer             optimization.IOptimizer
+	InputLayer            *layer.InputLayer
+	OutputLayerActivation activation.IActivation
+	Accuracy              accuracy.IAccuracy
+	// FusedOutput replaces the output activation & the loss in the backward pass (& the loss computation) when they
+	// have a fused form, e.g. SoftMax & CategoricalCrossEntropy, see Finalize
+	FusedOutput activation.FusedOutput
+
+	// InputShape describes a single batch entering the network; OutputShapes holds the shape
+	// inferred for each entry of Layers at Finalize time
+	InputShape   tensor.Shape
+	OutputShapes []tensor.Shape
+
+	// ClassNames names the classes the model predicts (indexed by class), they are saved with the model
+	ClassNames []string
+	// FeatureNames names the input features (indexed by column), they are saved with the model & label its explanations
+	FeatureNames []string
+
+	// ClassWeights weighs the loss of every sample by its class, see SetClassWeights
+	ClassWeights []float64
+
+	// Scaler, when set, scales every input with the statistics it was fit on (the training data), it is saved
+	// with the model so that inference data is scaled the same way
+	Scaler scaling.Scaler
+
+	// Calibrator, when set, corrects the probabilities output by the model, see Calibrate; it is saved with the model
+	Calibrator calibration.Calibrator
 }
 
 func New() *Model {
 	return &Model{
-		Layers:                  []any{},
-		SoftMaxClassifierOutput: nil,
-		Lossfn:                  nil,
+		Layers:      []any{},
+		FusedOutput: nil,
+		Lossfn:      nil,
 	}
 }
 
@@ -36,6 +63,87 @@ func (model *Model) Add(layer layer.ILayer) {
 	model.Layers = append(model.Layers, layer)
 }
 
+// SetInputShape declares the (named) shape of the data fed to the model, it is checked against every layer at Finalize
+func (model *Model) SetInputShape(shape tensor.Shape) {
+	model.InputShape = shape
+}
+
+// FitScaler fits the model's scaler on the training inputs, it's a no-op for models without a scaler
+func (model *Model) FitScaler(X mat.Matrix) error {
+	if model.Scaler == nil {
+		return nil
+	}
+
+	return model.Scaler.Fit(X)
+}
+
+// scale applies the model's scaler to the inputs X
+func (model *Model) scale(X *mat.Dense) (*mat.Dense, error) {
+	if _, cols := X.Dims(); model.InputShape != nil && cols != model.InputShape.FeatureSize() {
+		return nil, fmt.Errorf("got inputs of %d features but the model takes %d", cols, model.InputShape.FeatureSize())
+	}
+
+	if model.Scaler == nil {
+		return X, nil
+	}
+
+	scaled, err := model.Scaler.Transform(X)
+	if err != nil {
+		return nil, fmt.Errorf("scaling the inputs: %v", err)
+	}
+
+	return scaled, nil
+}
+
+// SetClassWeights weighs the loss & the gradient of every sample by the weight of its class (indexed by class), e.g.
+// with resampling.BalancedClassWeights to make up for tiny attack classes
+func (model *Model) SetClassWeights(weights []float64) {
+	model.ClassWeights = weights
+	model.Lossfn.SetClassWeights(weights)
+	if model.FusedOutput != nil {
+		model.FusedOutput.SetClassWeights(weights)
+	}
+}
+
+// setSampleWeights weighs the samples of the next batch, see datamodels.WeightedData
+func (model *Model) setSampleWeights(batch datamodels.Batch) error {
+	if rows, _ := batch.X.Dims(); batch.W != nil && len(batch.W) != rows {
+		return fmt.Errorf("got %d sample weights for a batch of %d samples", len(batch.W), rows)
+	}
+
+	model.Lossfn.SetSampleWeights(batch.W)
+	if model.FusedOutput != nil {
+		model.FusedOutput.SetSampleWeights(batch.W)
+	}
+
+	return nil
+}
+
+// SetClassNames names the predicted classes, e.g. from datasets.LabelMap.Names
+func (model *Model) SetClassNames(names []string) {
+	model.ClassNames = append([]string(nil), names...)
+}
+
+// SetFeatureNames names the input features, e.g. with datasets.CANSchema.FeatureNames (arbitration_id, df1..df8,
+// time_interval), the columns of the csv SaveMatrixToCSV writes
+func (model *Model) SetFeatureNames(names []string) {
+	model.FeatureNames = append([]string(nil), names...)
+}
+
+// FeatureName returns the name of an input feature, feature_<column> when it has none
+func (model *Model) FeatureName(column int) string {
+	if column >= 0 && column < len(model.FeatureNames) && model.FeatureNames[column] != "" {
+		return model.FeatureNames[column]
+	}
+
+	return "feature_" + strconv.Itoa(column)
+}
+
+// ClassName returns the name of a predicted class, its number when it has none
+func (model *Model) ClassName(class int) string {
+	return metrics.ClassName(model.ClassNames, class)
+}
+
 func (model *Model) Set(lossfn loss.ILoss, optimizer optimization.IOptimizer, accuracy accuracy.IAccuracy) {
 	if lossfn != nil {
 		model.Lossfn = lossfn
@@ -48,67 +156,131 @@ func (model *Model) Set(lossfn loss.ILoss, optimizer optimization.IOptimizer, ac
 	}
 }
 
-func (model *Model) Train(training_data datamodels.TrainingData, validation_data datamodels.ValidationData, epochs int, batch_size int, print_every int) {
-	model.Accuracy.Init(training_data.Y, false)
-
-	var train_steps int
-	train_steps = 1
+// LossFunction returns the loss the model computes: its fused output when it has one (see Finalize), Lossfn otherwise
+func (model *Model) LossFunction() loss.ILoss {
+	if model.FusedOutput != nil {
+		return model.FusedOutput
+	}
 
-	if batch_size > 0 {
-		len_X := training_data.X.RawMatrix().Rows
-		train_steps = len_X / batch_size
+	return model.Lossfn
+}
 
-		if train_steps*batch_size < len_X {
-			train_steps += 1
+// latentLoss sums the KL terms of the reparameterization layers for the last training batch
+func (model *Model) latentLoss() float64 {
+	loss := 0.
+	for _, l := range model.Layers {
+		if reparameterization, ok := l.(*layer.Reparameterization); ok {
+			loss += reparameterization.Loss()
 		}
 	}
+
+	return loss
+}
+
+// Train fits the model on training_data for the given number of epochs, evaluating validation_data (if any)
+// after every epoch. Both can be in-memory data (datamodels.TrainingData/ValidationData) or a streaming
+// datasets.DataLoader; labels are aligned to the output activation batch by batch.
+func (model *Model) Train(training_data datamodels.Batcher, validation_data datamodels.Batcher, epochs int, batch_size int, print_every int) error {
+	if data, ok := training_data.(datamodels.TrainingData); ok && data.Y != nil {
+		// every target is at hand, let the accuracy calibrate itself on all of them
+		model.Accuracy.Init(model.alignLabels(data.Y, data.X.RawMatrix().Rows), false)
+	}
+
 	for epoch := 1; epoch < epochs+1; epoch++ {
 		fmt.Println("epoch: ", epoch)
 
 		// reset accumulated loss and accuracy
-		model.Lossfn.NewPass()
+		model.LossFunction().NewPass()
 		model.Accuracy.NewPass()
 
-		for _, step := range core.GetRange(train_steps) {
-			var batch_X, batch_Y *mat.Dense
-			if batch_size <= 0 {
-				batch_X = training_data.X
-				batch_Y = training_data.Y
-			} else {
-				batch_X, batch_Y = core.GetBatch(training_data, step, batch_size)
+		step := 0
+		printed := false
+		var accuracy_, loss_value, data_loss, regularization_loss, latent_loss_sum float64
+		samples := 0
+
+		for batch, err := range training_data.Batches(batch_size) {
+			if err != nil {
+				return fmt.Errorf("epoch %d, step %d: %v", epoch, step, err)
 			}
 
-			output := model.forward(batch_X, true)
+			output, batch_Y, err := model.step(batch)
+			if err != nil {
+				return fmt.Errorf("epoch %d, step %d: %v", epoch, step, err)
+			}
 
-			data_loss, regularization_loss := model.Lossfn.Calculate(output, batch_Y, true)
-			loss_value := data_loss + regularization_loss
+			data_loss, regularization_loss = model.LossFunction().Calculate(output, batch_Y, true)
+			// the KL terms of the variational layers are optimized along the loss, they're reported with the regularization
+			latent_loss := model.latentLoss()
+			regularization_loss += latent_loss
+			rows, _ := output.Dims()
+			latent_loss_sum += latent_loss * float64(rows)
+			samples += rows
+			loss_value = data_loss + regularization_loss
 
 			predictions := model.OutputLayerActivation.Predictions(output)
-			accuracy_ := model.Accuracy.Calculate(predictions, batch_Y)
+			accuracy_ = model.Accuracy.Calculate(predictions, batch_Y)
 
-			model.Backward(output, batch_Y)
-
-			model.Optimizer.PreUpdateParams()
-			for i := 0; i < len(model.TrainableLayers); i++ {
-				model.Optimizer.UpdateParams(model.TrainableLayers[i])
-			}
-			model.Optimizer.PostUpdateParams()
+			model.update(output, batch_Y)
 
-			if step%print_every == 0 || step == train_steps-1 {
+			printed = print_every > 0 && step%print_every == 0
+			if printed {
 				fmt.Printf("step: %d, acc: %.3f, loss: %.3f, data-loss: %.3f, rg-loss: %.3f, lr: %f\n", step, accuracy_, loss_value, data_loss, regularization_loss, model.Optimizer.GetCurrentLearningRate())
 			}
+			step++
+		}
+
+		if step == 0 {
+			return fmt.Errorf("epoch %d: the training data has no samples", epoch)
+		}
+		// the number of steps of a stream isn't known upfront so the last step is reported once the pass is over
+		if !printed {
+			fmt.Printf("step: %d, acc: %.3f, loss: %.3f, data-loss: %.3f, rg-loss: %.3f, lr: %f\n", step-1, accuracy_, loss_value, data_loss, regularization_loss, model.Optimizer.GetCurrentLearningRate())
 		}
 
-		epoch_data_loss, epoch_regularization_loss := model.Lossfn.CalculateAccumulated(true)
+		epoch_data_loss, epoch_regularization_loss := model.LossFunction().CalculateAccumulated(true)
+		epoch_regularization_loss += latent_loss_sum / float64(samples)
 		epoch_loss := epoch_data_loss + epoch_regularization_loss
 		epoch_accuracy := model.Accuracy.CalculateAccumulated()
 
 		fmt.Printf("training -> acc: %.3f, loss: %.3f, data-loss: %.3f, rg-loss: %.3f, lr: %f\n", epoch_accuracy, epoch_loss, epoch_data_loss, epoch_regularization_loss, model.Optimizer.GetCurrentLearningRate())
 
-		if validation_data != (datamodels.ValidationData{}) {
-			model.Evaluate(validation_data, batch_size)
+		if validation_data != nil {
+			if err := model.Evaluate(validation_data, batch_size); err != nil {
+				return err
+			}
 		}
 	}
+
+	return nil
+}
+
+// step runs the forward pass of a training batch, returning the outputs & the aligned targets the batch's loss,
+// accuracy & update are computed from
+func (model *Model) step(batch datamodels.Batch) (*mat.Dense, *mat.Dense, error) {
+	batch_X, err := model.scale(batch.X)
+	if err != nil {
+		return nil, nil, err
+	}
+	batch_Y := model.alignLabels(batch.Y, batch.X.RawMatrix().Rows)
+	if err = model.setSampleWeights(batch); err != nil {
+		return nil, nil, err
+	}
+
+	// streamed data is only seen batch by batch, this is a no-op once the accuracy is initialised
+	model.Accuracy.Init(batch_Y, false)
+
+	return model.forward(batch_X, true), batch_Y, nil
+}
+
+// update backpropagates the loss of the outputs of the last step & lets the optimizer update the parameters
+func (model *Model) update(output, batch_Y *mat.Dense) {
+	model.Backward(output, batch_Y)
+
+	model.Optimizer.PreUpdateParams()
+	for i := 0; i < len(model.TrainableLayers); i++ {
+		model.Optimizer.UpdateParams(model.TrainableLayers[i])
+	}
+	model.Optimizer.PostUpdateParams()
 }
 
 func (model *Model) forward(X *mat.Dense, training bool) *mat.Dense {
@@ -125,31 +297,36 @@ func (model *Model) forward(X *mat.Dense, training bool) *mat.Dense {
 }
 
 func (model *Model) Backward(output, y *mat.Dense) {
-	if model.SoftMaxClassifierOutput != nil {
-		model.SoftMaxClassifierOutput.Backward(output, y)
-		model.Layers[len(model.Layers)-1].(layer.ILayer).SetDInputs(model.SoftMaxClassifierOutput.GetDInputs())
-
-		for i := len(model.Layers) - 1; i >= 0; i-- {
-			// lets skip the first value (which is same as last in the model.Layers slice)
-			if i != len(model.Layers)-1 {
-				model.Layers[i].(layer.ILayer).Backward(model.Layers[i].(layer.ILayer).GetNextLayer().(layer.ILayer).GetDInputs())
-			}
-		}
+	if model.FusedOutput != nil {
+		model.FusedOutput.Backward(output, y)
+		// the fused output already went through the output activation
+		model.Layers[len(model.Layers)-1].(layer.ILayer).SetDInputs(model.FusedOutput.GetDInputs())
+		model.backpropagate(len(model.Layers)-2, model.FusedOutput.GetDInputs())
 		return
 	}
 
 	model.Lossfn.Backward(output, y)
+	model.backpropagate(len(model.Layers)-1, model.Lossfn.GetDInputs())
+}
 
-	for i := len(model.Layers) - 1; i >= 0; i-- {
-		if i == len(model.Layers)-1 {
-			model.Layers[i].(layer.ILayer).Backward(model.Lossfn.GetDInputs())
-		} else {
-			model.Layers[i].(layer.ILayer).Backward(model.Layers[i].(layer.ILayer).GetNextLayer().(layer.ILayer).GetDInputs())
+// backpropagate passes d_values, the gradients w.r.t. the outputs of layer last, back through the layers down to the
+// first one, whose D_Inputs end up holding the gradients w.r.t. the (scaled) inputs of the model
+func (model *Model) backpropagate(last int, d_values *mat.Dense) {
+	for i := last; i >= 0; i-- {
+		if i < last {
+			d_values = model.Layers[i].(layer.ILayer).GetNextLayer().(layer.ILayer).GetDInputs()
 		}
+		model.Layers[i].(layer.ILayer).Backward(d_values)
 	}
 }
 
-func (model *Model) Finalize() {
+// Finalize links the layers together, picks the trainable ones and runs shape inference.
+// The returned error describes the first layer whose expected input doesn't match the previous layer's output.
+func (model *Model) Finalize() error {
+	if len(model.Layers) == 0 {
+		return errors.New("the model has no layers")
+	}
+
 	model.InputLayer = new(layer.InputLayer)
 	layers_count := len(model.Layers)
 
@@ -185,49 +362,112 @@ func (model *Model) Finalize() {
 		model.Lossfn.RememberTrainableLayers(model.TrainableLayers)
 	}
 
-	_, isSoftmax := model.Layers[len(model.Layers)-1].(activation.SoftMax)
-	_, isCatCrossEntropy := model.Lossfn.(*loss.CategoricalCrossEntropy)
-
-	if isSoftmax && isCatCrossEntropy {
-		model.SoftMaxClassifierOutput = new(activation.SoftmaxCatCrossEntropy)
+	model.FusedOutput = nil
+	if output, ok := model.Layers[len(model.Layers)-1].(layer.ILayer); ok && model.Lossfn != nil {
+		if model.FusedOutput = activation.Fuse(output, model.Lossfn); model.FusedOutput != nil {
+			model.FusedOutput.RememberTrainableLayers(model.TrainableLayers)
+			model.FusedOutput.SetClassWeights(model.ClassWeights)
+		}
 	}
 
+	return model.inferShapes()
 }
 
-func (model *Model) Evaluate(validation_data datamodels.ValidationData, batch_size int) {
-	validation_steps := 1
+// inferShapes propagates InputShape through the layers (deriving it from the first dense layer when unset)
+func (model *Model) inferShapes() error {
+	model.OutputShapes = nil
 
-	if batch_size > 0 {
-		len_X_val := validation_data.X.RawMatrix().Rows
-		validation_steps = len_X_val / batch_size
+	if len(model.Layers) == 0 {
+		return errors.New("the model has no layers")
+	}
 
-		if validation_steps*batch_size < len_X_val {
-			validation_steps += 1
+	if model.InputShape == nil {
+		first, ok := model.Layers[0].(*layer.Layer)
+		if !ok {
+			return nil
 		}
+		model.InputShape = tensor.FeatureShape(first.Weights.RawMatrix().Rows)
+	}
+
+	if err := model.InputShape.Validate(); err != nil {
+		return fmt.Errorf("model input: %v", err)
+	}
+
+	shape := model.InputShape
+	for i, l := range model.Layers {
+		output, err := l.(layer.ILayer).InferShape(shape)
+		if err != nil {
+			return fmt.Errorf("layer %d (%T): %v", i, l, err)
+		}
+
+		model.OutputShapes = append(model.OutputShapes, output)
+		shape = output
+	}
+
+	return nil
+}
+
+// alignLabels puts the targets of samples samples into the layout the output activation produces: a sparse 1 x N
+// row for softmax classifiers and N x outputs otherwise, so callers can pass either orientation
+func (model *Model) alignLabels(y *mat.Dense, samples int) *mat.Dense {
+	if y == nil {
+		return nil
 	}
 
-	model.Lossfn.NewPass()
+	if _, ok := model.OutputLayerActivation.(*activation.SoftMax); ok {
+		return tensor.LabelsAsRow(y, samples)
+	}
+
+	outputs := 1
+	if len(model.OutputShapes) > 0 {
+		outputs = model.OutputShapes[len(model.OutputShapes)-1].FeatureSize()
+	}
+
+	return tensor.LabelsAsColumns(y, outputs)
+}
+
+// Evaluate reports the loss & accuracy of the model on validation_data, nothing is reported for empty data
+func (model *Model) Evaluate(validation_data datamodels.Batcher, batch_size int) error {
+	model.LossFunction().NewPass()
 	model.Accuracy.NewPass()
 
-	for _, step := range core.GetRange(validation_steps) {
-		var batch_X_val, batch_Y_val *mat.Dense
-		if batch_size <= 0 {
-			batch_X_val = validation_data.X
-			batch_Y_val = validation_data.Y
-		} else {
-			batch_X_val, batch_Y_val = core.GetBatch(validation_data, step, batch_size)
+	steps := 0
+	for batch, err := range validation_data.Batches(batch_size) {
+		if err != nil {
+			return fmt.Errorf("validation step %d: %v", steps, err)
 		}
-		output := model.forward(batch_X_val, false)
 
-		_, _ = model.Lossfn.Calculate(output, batch_Y_val, false)
-		predictions := model.OutputLayerActivation.Predictions(output)
+		batch_X, err := model.scale(batch.X)
+		if err != nil {
+			return fmt.Errorf("validation step %d: %v", steps, err)
+		}
+
+		batch_Y_val := model.alignLabels(batch.Y, batch.X.RawMatrix().Rows)
+		if err = model.setSampleWeights(batch); err != nil {
+			return fmt.Errorf("validation step %d: %v", steps, err)
+		}
+		output := model.forward(batch_X, false)
+
+		_, _ = model.LossFunction().Calculate(output, batch_Y_val, false)
+		calibrated, err := model.calibrate(output)
+		if err != nil {
+			return fmt.Errorf("validation step %d: %v", steps, err)
+		}
+		predictions := model.OutputLayerActivation.Predictions(calibrated)
 		_ = model.Accuracy.Calculate(predictions, batch_Y_val)
+		steps++
+	}
+
+	if steps == 0 {
+		return nil
 	}
 
-	validation_loss, _ := model.Lossfn.CalculateAccumulated(false)
+	validation_loss, _ := model.LossFunction().CalculateAccumulated(false)
 	validation_accuracy := model.Accuracy.CalculateAccumulated()
 
 	fmt.Printf("\nValidation -> acc: %f loss: %f\n\n", validation_accuracy, validation_loss)
+
+	return nil
 }
 
 func (model *Model) getParameters() []datamodels.ModelParameter {
@@ -264,39 +504,13 @@ func (model *Model) LoadParameters(filename string) {
 	model.SetParameters(data)
 }
 
-func (model *Model) Predict(X *mat.Dense, batchSize int) *mat.Dense {
-	predictionSteps := 1
-
-	if batchSize > 0 {
-		lenX := X.RawMatrix().Rows
-		predictionSteps = lenX / batchSize
-
-		if predictionSteps*batchSize < lenX {
-			predictionSteps += 1
-		}
-	}
-
-	var outputs [][]float64
-	for _, step := range core.GetRange(predictionSteps) {
-		var batch_X *mat.Dense
-		if batchSize <= 0 {
-			batch_X = X
-		} else {
-			batch_X = core.GetSingleBatch(X, step, batchSize)
-		}
-		batchOutput := model.forward(batch_X, false)
-		for x := 0; x < batchOutput.RawMatrix().Rows; x++ {
-			row := mat.Row(nil, x, batchOutput)
-			outputs = append(outputs, row)
-		}
-	}
-
-	matrix := mat.NewDense(len(outputs), len(outputs[0]), nil)
-
-	// convert outputs to *mat.Dense
-	for i := 0; i < len(outputs); i++ {
-		matrix.SetRow(i, outputs[i])
+// Predict returns the (calibrated) outputs of the model for the samples of X, in batches of batchSize (0 for a
+// single batch)
+func (model *Model) Predict(X *mat.Dense, batchSize int) (*mat.Dense, error) {
+	outputs, err := model.rawOutputs(X, batchSize)
+	if err != nil {
+		return nil, err
 	}
 
-	return matrix
+	return model.calibrate(outputs)
 }
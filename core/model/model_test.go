@@ -1,10 +1,14 @@
 package model
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/saent-x/ids-nn/core/metrics"
 	"gonum.org/v1/gonum/stat"
+	"math"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/saent-x/ids-nn/core"
@@ -16,6 +20,8 @@ import (
 	"github.com/saent-x/ids-nn/core/loss"
 	"github.com/saent-x/ids-nn/core/mock"
 	"github.com/saent-x/ids-nn/core/optimization"
+	"github.com/saent-x/ids-nn/core/scaling"
+	"github.com/saent-x/ids-nn/core/tensor"
 	"gonum.org/v1/gonum/mat"
 )
 
@@ -43,7 +49,6 @@ func TestRegressionModel(t *testing.T) {
 func TestBinaryModel(t *testing.T) {
 	X, y := mock.BinaryMockTestData2()
 	X_test, y_test := core.SpiralData(100, 2)
-	y_test_reshape := mat.NewDense(y_test.RawMatrix().Cols, y_test.RawMatrix().Rows, y_test.RawMatrix().Data)
 
 	binary_categorical_model := New()
 
@@ -57,7 +62,7 @@ func TestBinaryModel(t *testing.T) {
 
 	binary_categorical_model.Finalize()
 
-	binary_categorical_model.Train(datamodels.TrainingData{X, y}, datamodels.ValidationData{X_test, y_test_reshape}, 10000, 0, 100)
+	binary_categorical_model.Train(datamodels.TrainingData{X, y}, datamodels.ValidationData{X_test, y_test}, 10000, 0, 100)
 }
 
 func TestCategoricalModel(t *testing.T) {
@@ -203,7 +208,7 @@ func TestModelInference(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	confidences := model.Predict(can_data, 100)
+	confidences := predict(t, model, can_data, 100)
 	predictions := model.OutputLayerActivation.Predictions(confidences)
 
 	_ = map[int]string{
@@ -278,7 +283,7 @@ func TestModel_Predict(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	confidences := model.Predict(core.FirstN(testing_data.X, 5), 0)
+	confidences := predict(t, model, core.FirstN(testing_data.X, 5), 0)
 	predictions := model.OutputLayerActivation.Predictions(confidences)
 
 	fmt.Println(mat.Formatted(predictions))
@@ -307,3 +312,617 @@ func TestCategoricalAccuracy(t *testing.T) {
 
 	fmt.Println(mat.Formatted(result))
 }
+
+func TestFinalizeShapeInference(t *testing.T) {
+	sequence_model := New()
+	sequence_model.SetInputShape(tensor.SequenceShape(4, 10))
+
+	sequence_model.Add(layer.CreateLayer(40, 16, 0, 0, 0, 0))
+	sequence_model.Add(new(activation.ReLU))
+	sequence_model.Add(layer.CreateLayer(16, 2, 0, 0, 0, 0))
+	sequence_model.Add(new(activation.SoftMax))
+
+	sequence_model.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.001, 0, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+
+	if err := sequence_model.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := sequence_model.OutputShapes[len(sequence_model.OutputShapes)-1]
+	if !got.Equal(tensor.FeatureShape(2)) {
+		t.Errorf("error: got output shape %v | want %v", got, tensor.FeatureShape(2))
+	}
+
+	mismatched_model := New()
+	mismatched_model.SetInputShape(tensor.FeatureShape(10))
+
+	mismatched_model.Add(layer.CreateLayer(10, 16, 0, 0, 0, 0))
+	mismatched_model.Add(new(activation.ReLU))
+	mismatched_model.Add(layer.CreateLayer(8, 2, 0, 0, 0, 0))
+	mismatched_model.Add(new(activation.SoftMax))
+
+	mismatched_model.Set(new(loss.CategoricalCrossEntropy), nil, new(accuracy.CategoricalAccuracy))
+
+	if err := mismatched_model.Finalize(); err == nil {
+		t.Errorf("error: expected a shape mismatch error from Finalize")
+	} else {
+		fmt.Println(err)
+	}
+
+	if err := New().Finalize(); err == nil {
+		t.Errorf("error: expected an error from Finalize on a model without layers")
+	}
+}
+
+func TestModelSummary(t *testing.T) {
+	summary_model := New()
+
+	summary_model.Add(layer.CreateLayer(10, 16, 0, 5e-4, 0, 5e-4))
+	summary_model.Add(new(activation.ReLU))
+	summary_model.Add(layer.NewDropoutLayer(0.1))
+	summary_model.Add(layer.CreateLayer(16, 2, 0, 0, 0, 0))
+	summary_model.Add(new(activation.SoftMax))
+
+	summary_model.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.001, 0, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+	if err := summary_model.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	summary := summary_model.Summary(128)
+	fmt.Println(summary)
+	fmt.Println(summary.DOT())
+
+	if want := 10*16 + 16 + 16*2 + 2; summary.TrainableParams != want {
+		t.Errorf("error: got %d trainable params | want %d", summary.TrainableParams, want)
+	}
+	if summary.Layers[0].Regularizers != "W-L2=0.0005 B-L2=0.0005" {
+		t.Errorf("error: unexpected regularizers %q", summary.Layers[0].Regularizers)
+	}
+}
+
+func TestTrainWithDataLoader(t *testing.T) {
+	X, y := core.SpiralData(100, 3)
+
+	newSpiralModel := func() *Model {
+		m := New()
+		m.Add(layer.CreateLayer(2, 32, 0, 5e-4, 0, 5e-4))
+		m.Add(new(activation.ReLU))
+		m.Add(layer.CreateLayer(32, 3, 0, 0, 0, 0))
+		m.Add(new(activation.SoftMax))
+		m.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.02, 5e-5, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+		m.Finalize()
+
+		return m
+	}
+
+	in_memory := newSpiralModel()
+	streamed := newSpiralModel()
+	streamed.SetParameters(in_memory.getParameters())
+
+	// without shuffling a loader must produce exactly the batches of the in-memory data
+	loader := datasets.NewDataLoader(datasets.NewMatrixDataset(X, y), datasets.DataLoaderOptions{Prefetch: 2})
+
+	if err := in_memory.Train(datamodels.TrainingData{X: X, Y: y}, nil, 5, 64, 100); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := streamed.Train(loader, loader, 5, 64, 100); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	for i, parameter := range in_memory.getParameters() {
+		other := streamed.getParameters()[i]
+		if !mat.EqualApprox(parameter.Weights, other.Weights, 1e-12) || !mat.EqualApprox(parameter.Biases, other.Biases, 1e-12) {
+			t.Errorf("error: parameters of layer %d differ between in-memory & streamed training", i)
+		}
+	}
+
+	shuffled := datasets.NewDataLoader(datasets.NewMatrixDataset(X, y), datasets.DataLoaderOptions{Shuffle: true, DropLast: true})
+	if err := streamed.Train(shuffled, datamodels.ValidationData{X: X, Y: y}, 2, 64, 100); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+}
+
+func TestScalerSavedWithModel(t *testing.T) {
+	X, y := core.SpiralData(100, 3)
+
+	// training data far from the unit range
+	var raw mat.Dense
+	raw.Scale(1000, X)
+
+	m := New()
+	m.Add(layer.CreateLayer(2, 16, 0, 0, 0, 0))
+	m.Add(new(activation.ReLU))
+	m.Add(layer.CreateLayer(16, 3, 0, 0, 0, 0))
+	m.Add(new(activation.SoftMax))
+	m.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.02, 5e-5, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+	m.Scaler = new(scaling.StandardScaler)
+	if err := m.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Train(datamodels.TrainingData{X: &raw, Y: y}, nil, 1, 0, 100); err == nil {
+		t.Errorf("error: expected an error for a scaler that isn't fitted")
+	}
+	if err := m.FitScaler(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Train(datamodels.TrainingData{X: &raw, Y: y}, nil, 2, 64, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	modelDataProvider := new(ModelDataProvider)
+	if err := modelDataProvider.Encode(&buffer, m); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := modelDataProvider.Load(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a few unscaled rows are scaled with the training statistics, so they get the same predictions as in training
+	inference := mat.DenseCopyOf(raw.Slice(0, 5, 0, 2))
+	if got, want := predict(t, loaded, inference, 0), predict(t, m, mat.DenseCopyOf(&raw), 0).Slice(0, 5, 0, 3); !mat.EqualApprox(got, want, 1e-12) {
+		t.Errorf("error: got %v | want %v", mat.Formatted(got), mat.Formatted(want))
+	}
+
+	if _, err = loaded.Predict(mat.NewDense(1, 3, nil), 0); err == nil {
+		t.Errorf("error: expected an error for inputs of 3 features")
+	}
+}
+
+func TestWeightedTraining(t *testing.T) {
+	X, y := core.SpiralData(50, 3)
+
+	m := New()
+	m.Add(layer.CreateLayer(2, 16, 0, 0, 0, 0))
+	m.Add(new(activation.ReLU))
+	m.Add(layer.CreateLayer(16, 3, 0, 0, 0, 0))
+	m.Add(new(activation.SoftMax))
+	m.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.02, 5e-5, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+	if err := m.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	before := make([]*mat.Dense, 0)
+	for _, parameter := range m.getParameters() {
+		before = append(before, mat.DenseCopyOf(parameter.Weights))
+	}
+
+	// samples that weigh nothing don't move the model
+	if err := m.Train(datamodels.WeightedData{X: X, Y: y, Weights: make([]float64, 150)}, nil, 2, 64, 100); err != nil {
+		t.Fatal(err)
+	}
+	for i, parameter := range m.getParameters() {
+		if !mat.Equal(parameter.Weights, before[i]) {
+			t.Errorf("error: the weights of layer %d changed with samples weighing 0", i)
+		}
+	}
+
+	if err := m.Train(datamodels.WeightedData{X: X, Y: y, Weights: make([]float64, 10)}, nil, 1, 64, 100); err == nil {
+		t.Errorf("error: expected an error for missing sample weights")
+	}
+
+	// a class weighing nothing doesn't count in the loss
+	m.SetClassWeights([]float64{1, 1, 0})
+	if err := m.Evaluate(datamodels.ValidationData{X: X, Y: y}, 0); err != nil {
+		t.Fatal(err)
+	}
+	weighted_loss, _ := m.LossFunction().CalculateAccumulated(false)
+
+	m.SetClassWeights(nil)
+	rows := make([]int, 0, 100)
+	for row, class := range datasets.ClassLabels(y) {
+		if class != 2 {
+			rows = append(rows, row)
+		}
+	}
+	subset := datasets.Subset(datamodels.TrainingData{X: X, Y: y}, rows)
+	if err := m.Evaluate(datamodels.ValidationData{X: subset.X, Y: subset.Y}, 0); err != nil {
+		t.Fatal(err)
+	}
+	subset_loss, _ := m.LossFunction().CalculateAccumulated(false)
+
+	if diff := weighted_loss*150/100 - subset_loss; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("error: got a weighted loss of %f | want %f", weighted_loss*150/100, subset_loss)
+	}
+}
+
+func TestFusedOutputs(t *testing.T) {
+	newModel := func(output layer.ILayer, lossfn loss.ILoss) *Model {
+		m := New()
+		m.Add(layer.CreateLayer(2, 8, 0, 0, 0, 0))
+		m.Add(new(activation.ReLU))
+		m.Add(layer.CreateLayer(8, 3, 0, 0, 0, 0))
+		m.Add(output)
+		m.Set(lossfn, optimization.CreateAdaptiveMomentum(0.01, 0, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+		if err := m.Finalize(); err != nil {
+			t.Fatal(err)
+		}
+
+		return m
+	}
+
+	if m := newModel(new(activation.SoftMax), new(loss.CategoricalCrossEntropy)); reflect.TypeOf(m.FusedOutput) != reflect.TypeOf(&activation.SoftmaxCatCrossEntropy{}) {
+		t.Errorf("error: got fused output %T for softmax & categorical cross-entropy", m.FusedOutput)
+	}
+	if m := newModel(new(activation.Sigmoid), new(loss.BinaryCrossEntropy)); reflect.TypeOf(m.FusedOutput) != reflect.TypeOf(&activation.SigmoidBinaryCrossEntropy{}) {
+		t.Errorf("error: got fused output %T for sigmoid & binary cross-entropy", m.FusedOutput)
+	}
+	if m := newModel(new(activation.Sigmoid), new(loss.MeanSquaredError)); m.FusedOutput != nil {
+		t.Errorf("error: got fused output %T for sigmoid & mean squared error", m.FusedOutput)
+	}
+
+	// a fused output saved among the layers is loaded as its activation
+	m := newModel(new(activation.SoftMax), new(loss.CategoricalCrossEntropy))
+	var buffer bytes.Buffer
+	if err := new(ModelDataProvider).Encode(&buffer, m); err != nil {
+		t.Fatal(err)
+	}
+	saved := strings.Replace(buffer.String(), `"Type": "*activation.SoftMax"`, `"Type": "*activation.SoftmaxCatCrossEntropy"`, 1)
+
+	loaded, err := new(ModelDataProvider).Load(strings.NewReader(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.OutputLayerActivation.(*activation.SoftMax); !ok || loaded.FusedOutput == nil {
+		t.Errorf("error: got output %T fused as %T", loaded.OutputLayerActivation, loaded.FusedOutput)
+	}
+
+	X, _ := core.SpiralData(5, 3)
+	if !mat.EqualApprox(predict(t, loaded, X, 0), predict(t, m, X, 0), 1e-12) {
+		t.Errorf("error: the loaded model predicts differently")
+	}
+}
+
+func TestMultiLabelModel(t *testing.T) {
+	// 3 labels that can be set together: x0 > 0.5, x1 > 0.5 & the rarer x0 + x1 > 1.4
+	X := mat.NewDense(400, 2, nil)
+	y := mat.NewDense(400, 3, nil)
+	for i := 0; i < 400; i++ {
+		x0, x1 := float64(i%20)/19, float64(i/20)/19
+		X.SetRow(i, []float64{x0, x1})
+		for j, set := range []bool{x0 > 0.5, x1 > 0.5, x0+x1 > 1.4} {
+			if set {
+				y.Set(i, j, 1)
+			}
+		}
+	}
+	data := datamodels.TrainingData{X: X, Y: y}
+
+	m := New()
+	m.Add(layer.CreateLayer(2, 16, 0, 0, 0, 0))
+	m.Add(new(activation.ReLU))
+	m.Add(layer.CreateLayer(16, 3, 0, 0, 0, 0))
+	m.Add(new(activation.Sigmoid))
+	m.Set(new(loss.BinaryCrossEntropy), optimization.CreateAdaptiveMomentum(0.02, 0, 1e-7, 0.9, 0.999, 0), new(accuracy.MultiLabelAccuracy))
+	if err := m.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Train(data, nil, 300, 0, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	default_scores, err := m.EvaluateMultiLabel(data, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thresholds, err := m.TuneThresholds(data, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thresholds) != 3 || !reflect.DeepEqual(m.Thresholds(), thresholds) {
+		t.Fatalf("error: got thresholds %v, the model has %v", thresholds, m.Thresholds())
+	}
+
+	scores, err := m.EvaluateMultiLabel(data, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores.MacroF1 < default_scores.MacroF1 {
+		t.Errorf("error: got a macro F1 of %f with tuned thresholds | want at least %f", scores.MacroF1, default_scores.MacroF1)
+	}
+	if scores.SubsetAccuracy < 0.8 || scores.HammingLoss > 0.1 {
+		t.Errorf("error: got a subset accuracy of %f & a hamming loss of %f", scores.SubsetAccuracy, scores.HammingLoss)
+	}
+
+	// the thresholds are saved with the model
+	var buffer bytes.Buffer
+	if err = new(ModelDataProvider).Encode(&buffer, m); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := new(ModelDataProvider).Load(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Thresholds(), thresholds) {
+		t.Errorf("error: got thresholds %v | want %v", loaded.Thresholds(), thresholds)
+	}
+	if _, ok := loaded.Accuracy.(*accuracy.MultiLabelAccuracy); !ok {
+		t.Errorf("error: got accuracy %T", loaded.Accuracy)
+	}
+
+	if err = m.SetThresholds([]float64{0.5}); err == nil {
+		t.Errorf("error: expected an error for a threshold per output missing")
+	}
+}
+
+func TestReport(t *testing.T) {
+	X, y := core.SpiralData(20, 3)
+
+	m := New()
+	m.Add(layer.CreateLayer(2, 16, 0, 0, 0, 0))
+	m.Add(new(activation.ReLU))
+	m.Add(layer.CreateLayer(16, 3, 0, 0, 0, 0))
+	m.Add(new(activation.SoftMax))
+	m.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.02, 5e-5, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+	if err := m.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	m.SetClassNames([]string{"a", "b", "c"})
+	if err := m.Train(datamodels.TrainingData{X: X, Y: y}, nil, 50, 0, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	// the last batch holds a single sample
+	report, err := m.Report(datamodels.ValidationData{X: X, Y: y}, 59)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Samples != 60 || len(report.Classes) != 3 || report.Classes[2].Name != "c" || len(report.ROC) != 3 {
+		t.Fatalf("error: got report %+v", report)
+	}
+
+	predictions := datasets.ClassLabels(m.OutputLayerActivation.Predictions(predict(t, m, X, 0)))
+	correct := 0.
+	for i, label := range datasets.ClassLabels(y) {
+		if predictions[i] == label {
+			correct++
+		}
+	}
+	if want := correct / 60; report.Accuracy != want {
+		t.Errorf("error: got an accuracy of %f | want %f", report.Accuracy, want)
+	}
+	if report.ROCAUC <= 0.5 {
+		t.Errorf("error: got a ROC-AUC of %f for a trained model", report.ROCAUC)
+	}
+
+	// one-hot targets give the same report, the single sample of the last batch being one-hot too
+	one_hot := core.SparseToOHE(y, 3)
+	one_hot_report, err := m.Report(datamodels.ValidationData{X: X, Y: one_hot}, 59)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if one_hot_report.Accuracy != report.Accuracy || one_hot_report.Macro != report.Macro {
+		t.Errorf("error: got an accuracy of %f with one-hot targets | want %f", one_hot_report.Accuracy, report.Accuracy)
+	}
+	last := X.Slice(59, 60, 0, 2).(*mat.Dense)
+	want, err := m.PartialFit(last, y.Slice(0, 1, 59, 60).(*mat.Dense))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.PartialFit(last, one_hot.Slice(59, 60, 0, 3).(*mat.Dense))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got[0]-want[0]) > 0.1 {
+		t.Errorf("error: got a loss of %f for the one-hot target of a sample | want about %f", got[0], want[0])
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	X, y := core.SpiralData(30, 3)
+
+	m := New()
+	m.Add(layer.CreateLayer(2, 16, 0, 0, 0, 0))
+	m.Add(new(activation.ReLU))
+	m.Add(layer.CreateLayer(16, 3, 0, 0, 0, 0))
+	m.Add(new(activation.SoftMax))
+	m.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.02, 5e-5, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+	if err := m.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Train(datamodels.TrainingData{X: X, Y: y}, nil, 50, 0, 1000); err != nil {
+		t.Fatal(err)
+	}
+	data := datamodels.ValidationData{X: X, Y: y}
+
+	// the mean negative log-likelihood of the classes
+	nll := func(probabilities *mat.Dense) float64 {
+		total := 0.
+		for i, label := range datasets.ClassLabels(y) {
+			total -= math.Log(probabilities.At(i, int(label))) / 90
+		}
+		return total
+	}
+
+	raw := predict(t, m, X, 0)
+	if err := m.Calibrate("temperature", data, 32); err != nil {
+		t.Fatal(err)
+	}
+	calibrated := predict(t, m, X, 0)
+	if before, after := nll(raw), nll(calibrated); after > before {
+		t.Errorf("error: got a log loss of %f after the calibration | want at most %f", after, before)
+	}
+	for i := 0; i < 90; i++ {
+		if sum := mat.Sum(calibrated.RowView(i)); math.Abs(sum-1) > 1e-9 {
+			t.Fatalf("error: row %d: got a sum of %f | want 1", i, sum)
+		}
+	}
+
+	// no frame of class 0 may be predicted as an attack
+	threshold, err := m.OptimizeThreshold(data, 32, func(scores []float64, positives []bool) float64 {
+		return metrics.ThresholdForFPR(scores, positives, 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := m.AttackThreshold(); !ok || got != threshold || threshold <= 0 {
+		t.Fatalf("error: got an attack threshold of %f, the model has %f", threshold, got)
+	}
+	predictions := datasets.ClassLabels(m.OutputLayerActivation.Predictions(predict(t, m, X, 0)))
+	for i, label := range datasets.ClassLabels(y) {
+		if label == 0 && predictions[i] != 0 {
+			t.Errorf("error: frame %d of class 0 predicted as %g", i, predictions[i])
+		}
+	}
+
+	// the calibration & the threshold are saved with the model
+	var buffer bytes.Buffer
+	if err = new(ModelDataProvider).Encode(&buffer, m); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := new(ModelDataProvider).Load(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := loaded.AttackThreshold(); !ok || got != threshold {
+		t.Errorf("error: got an attack threshold of %f | want %f", got, threshold)
+	}
+	if !mat.EqualApprox(predict(t, loaded, X, 0), calibrated, 1e-12) {
+		t.Error("error: the loaded model gives other probabilities")
+	}
+	if !reflect.DeepEqual(datasets.ClassLabels(loaded.OutputLayerActivation.Predictions(predict(t, loaded, X, 0))), predictions) {
+		t.Error("error: the loaded model gives other predictions")
+	}
+
+	// a threshold of 0 flags every frame with any attack probability & is saved too
+	if err = loaded.SetAttackThreshold(0); err != nil {
+		t.Fatal(err)
+	}
+	buffer.Reset()
+	if err = new(ModelDataProvider).Encode(&buffer, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err = new(ModelDataProvider).Load(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := loaded.AttackThreshold(); !ok || got != 0 {
+		t.Errorf("error: got an attack threshold of %f (%v) | want 0", got, ok)
+	}
+	for i, prediction := range datasets.ClassLabels(loaded.OutputLayerActivation.Predictions(predict(t, loaded, X, 0))) {
+		if calibrated.At(i, 0) < 1 && prediction == 0 {
+			t.Errorf("error: frame %d with an attack probability of %g predicted as attack-free", i, 1-calibrated.At(i, 0))
+		}
+	}
+
+	if err = m.Calibrate("beta", data, 32); err == nil {
+		t.Error("error: expected an error for an unknown calibration")
+	}
+}
+
+func TestPartialFit(t *testing.T) {
+	X, y := core.SpiralData(50, 3)
+
+	newModel := func() *Model {
+		m := New()
+		m.Add(layer.CreateLayer(2, 16, 0, 0, 0, 0))
+		m.Add(new(activation.ReLU))
+		m.Add(layer.CreateLayer(16, 3, 0, 0, 0, 0))
+		m.Add(new(activation.SoftMax))
+		m.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.02, 5e-5, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+		if err := m.Finalize(); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	trained, online := newModel(), newModel()
+	for i, parameter := range trained.getParameters() {
+		online.TrainableLayers[i].SetParameters(datamodels.ModelParameter{Weights: mat.DenseCopyOf(parameter.Weights), Biases: mat.DenseCopyOf(parameter.Biases)})
+	}
+
+	// a partial fit on every batch is an epoch of training, the optimizer state carrying over from batch to batch
+	if err := trained.Train(datamodels.TrainingData{X: X, Y: y}, nil, 1, 50, 100); err != nil {
+		t.Fatal(err)
+	}
+	for start := 0; start < 150; start += 50 {
+		losses, err := online.PartialFit(mat.DenseCopyOf(X.Slice(start, start+50, 0, 2)), mat.DenseCopyOf(y.Slice(0, 1, start, start+50)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(losses) != 50 {
+			t.Fatalf("error: got %d sample losses | want 50", len(losses))
+		}
+	}
+	for i, parameter := range online.getParameters() {
+		if want := trained.getParameters()[i].Weights; !mat.EqualApprox(parameter.Weights, want, 1e-12) {
+			t.Errorf("error: the weights of layer %d differ from training", i)
+		}
+	}
+
+	if _, err := online.PartialFit(X, nil); err == nil {
+		t.Error("error: expected an error for a batch without targets")
+	}
+
+	// the scaler statistics follow the stream
+	online.Scaler = new(scaling.StandardScaler)
+	if err := online.FitScaler(X.Slice(0, 75, 0, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := online.UpdateScaler(X.Slice(75, 150, 0, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if samples := online.Scaler.(*scaling.StandardScaler).Samples; samples != 150 {
+		t.Errorf("error: the scaler saw %d samples | want 150", samples)
+	}
+	online.Scaler = new(scaling.RobustScaler)
+	if err := online.UpdateScaler(X); err == nil {
+		t.Error("error: expected an error updating a robust scaler")
+	}
+}
+
+func TestReparameterization(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+input: {features: 4}
+layers:
+  - {type: reparameterization, beta: 0.5, seed: 3}
+  - {type: linear}
+loss: mse
+optimizer: {type: adam, learning_rate: 0.01}
+accuracy: regression
+`), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the means are 1 & the log-variances 0: the KL divergence of a sample is 0.5 per latent feature
+	X := mat.NewDense(3, 4, []float64{1, 1, 0, 0, 1, 1, 0, 0, 1, 1, 0, 0})
+	var outputs []*mat.Dense
+	for i := 0; i < 2; i++ {
+		m, err := FromConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reparameterization := m.Layers[0].(*layer.Reparameterization)
+		reparameterization.Forward(X, true)
+		outputs = append(outputs, reparameterization.Output)
+
+		if got := m.latentLoss(); math.Abs(got-0.5) > 1e-12 {
+			t.Errorf("error: got a latent loss of %f | want 0.5", got)
+		}
+	}
+
+	// the same seed draws the same noise
+	if !mat.Equal(outputs[0], outputs[1]) {
+		t.Error("error: the outputs of the same seed differ")
+	}
+	other := layer.NewReparameterization(0.5, 4)
+	other.Forward(X, true)
+	if mat.Equal(outputs[0], other.Output) {
+		t.Error("error: the outputs of another seed are the same")
+	}
+}
+
+// predict returns the outputs of m for X, failing the test on an error
+func predict(t *testing.T, m *Model, X *mat.Dense, batch_size int) *mat.Dense {
+	t.Helper()
+
+	outputs, err := m.Predict(X, batch_size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return outputs
+}
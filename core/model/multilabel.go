@@ -0,0 +1,119 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/activation"
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/metrics"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Thresholds returns the per label thresholds of a model ending with a sigmoid, nil when it uses the default 0.5
+func (model *Model) Thresholds() []float64 {
+	if sigmoid, ok := model.OutputLayerActivation.(*activation.Sigmoid); ok {
+		return sigmoid.Thresholds
+	}
+
+	return nil
+}
+
+// SetThresholds sets the threshold of every output of a multi-label model (ending with a sigmoid) above which
+// the label is predicted, they are saved with the model
+func (model *Model) SetThresholds(thresholds []float64) error {
+	sigmoid, ok := model.OutputLayerActivation.(*activation.Sigmoid)
+	if !ok {
+		return fmt.Errorf("thresholds need a sigmoid output, the model ends with %T", model.OutputLayerActivation)
+	}
+	if len(model.OutputShapes) > 0 {
+		if outputs := model.OutputShapes[len(model.OutputShapes)-1].FeatureSize(); len(thresholds) != outputs {
+			return fmt.Errorf("got %d thresholds for %d outputs", len(thresholds), outputs)
+		}
+	}
+
+	sigmoid.Thresholds = append([]float64(nil), thresholds...)
+
+	return nil
+}
+
+// TuneThresholds picks the threshold of every label that maximises its F1 on validation_data (see
+// metrics.BestThresholds) & sets them
+func (model *Model) TuneThresholds(validation_data datamodels.Batcher, batch_size int) ([]float64, error) {
+	if _, ok := model.OutputLayerActivation.(*activation.Sigmoid); !ok {
+		return nil, fmt.Errorf("thresholds need a sigmoid output, the model ends with %T", model.OutputLayerActivation)
+	}
+
+	probabilities, targets, err := model.outputs(validation_data, batch_size)
+	if err != nil {
+		return nil, err
+	}
+
+	thresholds := metrics.BestThresholds(probabilities, targets)
+
+	return thresholds, model.SetThresholds(thresholds)
+}
+
+// EvaluateMultiLabel computes the hamming loss, subset accuracy & per label, micro & macro precision, recall & F1
+// of the (thresholded) predictions of a multi-label model on data
+func (model *Model) EvaluateMultiLabel(data datamodels.Batcher, batch_size int) (metrics.MultiLabelScores, error) {
+	probabilities, targets, err := model.outputs(data, batch_size)
+	if err != nil {
+		return metrics.MultiLabelScores{}, err
+	}
+
+	return metrics.MultiLabel(targets, model.OutputLayerActivation.Predictions(probabilities)), nil
+}
+
+// outputs stacks the (calibrated) outputs of the model & the targets of every batch of data, class labels being
+// one-hot encoded like the outputs
+func (model *Model) outputs(data datamodels.Batcher, batch_size int) (*mat.Dense, *mat.Dense, error) {
+	var outputs, targets []float64
+	rows, cols := 0, 0
+
+	for batch, err := range data.Batches(batch_size) {
+		if err != nil {
+			return nil, nil, err
+		}
+		if batch.Y == nil {
+			return nil, nil, errors.New("the data has no targets")
+		}
+
+		batch_X, err := model.scale(batch.X)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		output, err := model.calibrate(model.forward(batch_X, false))
+		if err != nil {
+			return nil, nil, err
+		}
+		batch_y := model.alignLabels(batch.Y, batch.X.RawMatrix().Rows)
+
+		batch_rows, batch_cols := output.Dims()
+		if y_rows, y_cols := batch_y.Dims(); y_rows != batch_rows || y_cols != batch_cols {
+			// sparse class labels of a softmax output
+			labels, err := classLabels(batch_y, batch_rows)
+			if err != nil || batch_cols == 1 {
+				return nil, nil, fmt.Errorf("got %dx%d targets for %dx%d outputs", y_rows, y_cols, batch_rows, batch_cols)
+			}
+			for _, label := range labels {
+				if label < 0 || int(label) >= batch_cols {
+					return nil, nil, fmt.Errorf("got the class %g for %d outputs", label, batch_cols)
+				}
+			}
+			batch_y = core.SparseToOHE(mat.NewDense(1, len(labels), labels), batch_cols)
+		}
+
+		outputs = append(outputs, mat.DenseCopyOf(output).RawMatrix().Data...)
+		targets = append(targets, mat.DenseCopyOf(batch_y).RawMatrix().Data...)
+		rows, cols = rows+batch_rows, batch_cols
+	}
+
+	if rows == 0 {
+		return nil, nil, errors.New("the data has no samples")
+	}
+
+	return mat.NewDense(rows, cols, outputs), mat.NewDense(rows, cols, targets), nil
+}
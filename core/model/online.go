@@ -0,0 +1,52 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/scaling"
+	"gonum.org/v1/gonum/mat"
+)
+
+// PartialFit updates the model with a single step on the samples of X & their targets y, continuing from the
+// current state of the optimizer (its momentums, caches & learning rate decay), e.g. to follow traffic that drifts
+// after a firmware update without retraining from scratch. It returns the loss of every sample computed before the
+// update, a prequential estimate of how well the model knew the new samples (see online.Learner).
+func (model *Model) PartialFit(X, y *mat.Dense) ([]float64, error) {
+	if X == nil || y == nil {
+		return nil, errors.New("partial fit: the batch has no samples or no targets")
+	}
+	if rows, _ := X.Dims(); rows == 0 {
+		return nil, errors.New("partial fit: the batch has no samples")
+	}
+
+	output, batch_Y, err := model.step(datamodels.Batch{X: X, Y: y})
+	if err != nil {
+		return nil, fmt.Errorf("partial fit: %v", err)
+	}
+
+	sample_losses := model.LossFunction().Forward(output, batch_Y).RawVector().Data
+	model.LossFunction().Calculate(output, batch_Y, true)
+	model.Accuracy.Calculate(model.OutputLayerActivation.Predictions(output), batch_Y)
+
+	model.update(output, batch_Y)
+
+	return append([]float64(nil), sample_losses...), nil
+}
+
+// UpdateScaler adds the samples of X to the statistics of the model's scaler, which must be a
+// scaling.PartialFitter, it's a no-op for models without a scaler.
+// The inputs of the model shift with the statistics, so they are best updated along with PartialFit.
+func (model *Model) UpdateScaler(X mat.Matrix) error {
+	if model.Scaler == nil {
+		return nil
+	}
+
+	scaler, ok := model.Scaler.(scaling.PartialFitter)
+	if !ok {
+		return fmt.Errorf("the %T of the model can't be updated incrementally", model.Scaler)
+	}
+
+	return scaler.PartialFit(X)
+}
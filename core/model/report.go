@@ -0,0 +1,70 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/datasets"
+	"github.com/saent-x/ids-nn/core/metrics"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Report evaluates the classifier on labelled data: per class, macro, micro & weighted precision, recall & F1, MCC,
+// Cohen's kappa, balanced accuracy of its predictions & the ROC-AUC & PR-AUC of its probabilities (see
+// metrics.EvaluationReport)
+func (model *Model) Report(data datamodels.Batcher, batch_size int) (*metrics.EvaluationReport, error) {
+	var probabilities, labels, predictions []float64
+	cols := 0
+
+	for batch, err := range data.Batches(batch_size) {
+		if err != nil {
+			return nil, err
+		}
+		if batch.Y == nil {
+			return nil, errors.New("the data has no labels")
+		}
+
+		batch_X, err := model.scale(batch.X)
+		if err != nil {
+			return nil, err
+		}
+		output, err := model.calibrate(model.forward(batch_X, false))
+		if err != nil {
+			return nil, err
+		}
+
+		rows, batch_cols := output.Dims()
+		batch_labels, err := classLabels(model.alignLabels(batch.Y, rows), rows)
+		if err != nil {
+			return nil, err
+		}
+
+		batch_predictions, err := classLabels(model.OutputLayerActivation.Predictions(output), rows)
+		if err != nil {
+			return nil, err
+		}
+
+		probabilities = append(probabilities, mat.DenseCopyOf(output).RawMatrix().Data...)
+		labels = append(labels, batch_labels...)
+		predictions = append(predictions, batch_predictions...)
+		cols = batch_cols
+	}
+
+	if len(labels) == 0 {
+		return nil, errors.New("the data has no samples")
+	}
+
+	return metrics.NewEvaluationReport(labels, predictions, mat.NewDense(len(labels), cols, probabilities), model.ClassNames)
+}
+
+// classLabels reads the class of each of the samples of a batch from its targets with datasets.ClassLabels
+func classLabels(y *mat.Dense, samples int) ([]float64, error) {
+	labels := datasets.ClassLabels(y)
+	if len(labels) != samples {
+		rows, cols := y.Dims()
+		return nil, fmt.Errorf("got %dx%d labels for %d samples", rows, cols, samples)
+	}
+
+	return labels, nil
+}
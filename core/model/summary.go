@@ -0,0 +1,251 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/saent-x/ids-nn/core/layer"
+	"github.com/saent-x/ids-nn/core/optimization"
+	"github.com/saent-x/ids-nn/core/tensor"
+)
+
+const bytesPerValue = 8 // every value is a float64
+
+type LayerSummary struct {
+	Name               string
+	Type               string
+	OutputShape        tensor.Shape
+	TrainableParams    int
+	NonTrainableParams int
+	Regularizers       string
+
+	// ActivationBytes is the memory held by the layer for a batch (inputs, outputs & input gradients)
+	ActivationBytes int64
+}
+
+type ModelSummary struct {
+	InputShape tensor.Shape
+	Layers     []LayerSummary
+	Loss       string
+	Optimizer  string
+	Accuracy   string
+
+	TrainableParams    int
+	NonTrainableParams int
+
+	// BatchSize is the batch size the memory estimate was made for
+	BatchSize       int
+	ParameterBytes  int64
+	ActivationBytes int64
+}
+
+// TotalBytes is the estimated memory needed to train the model with the summary's batch size
+func (summary ModelSummary) TotalBytes() int64 {
+	return summary.ParameterBytes + summary.ActivationBytes
+}
+
+// Summary describes the architecture of a finalized model. batch_size is used to estimate the
+// memory needed for activations & gradients during training.
+func (model *Model) Summary(batch_size int) ModelSummary {
+	if batch_size <= 0 {
+		batch_size = 1
+	}
+
+	summary := ModelSummary{
+		InputShape: model.InputShape,
+		Loss:       typeName(model.Lossfn),
+		Optimizer:  typeName(model.Optimizer),
+		Accuracy:   typeName(model.Accuracy),
+		BatchSize:  batch_size,
+	}
+
+	input_size := 0
+	if model.InputShape != nil {
+		input_size = model.InputShape.FeatureSize()
+	}
+
+	for i, l := range model.Layers {
+		layer_summary := LayerSummary{
+			Name:         fmt.Sprintf("%s_%d", layerKind(l), i),
+			Type:         typeName(l),
+			Regularizers: "-",
+		}
+
+		if i < len(model.OutputShapes) {
+			layer_summary.OutputShape = model.OutputShapes[i]
+		}
+
+		if parameterized, ok := l.(layer.IParameterized); ok {
+			layer_summary.TrainableParams, layer_summary.NonTrainableParams = parameterized.ParameterCount()
+		}
+
+		if dense, ok := l.(*layer.Layer); ok {
+			layer_summary.Regularizers = regularizers(dense)
+		}
+
+		output_size := input_size
+		if layer_summary.OutputShape != nil {
+			output_size = layer_summary.OutputShape.FeatureSize()
+		}
+
+		// inputs are copied for backpropagation, outputs are kept & gradients w.r.t. the inputs are produced
+		layer_summary.ActivationBytes = int64(batch_size*(2*input_size+output_size)) * bytesPerValue
+		input_size = output_size
+
+		summary.Layers = append(summary.Layers, layer_summary)
+		summary.TrainableParams += layer_summary.TrainableParams
+		summary.NonTrainableParams += layer_summary.NonTrainableParams
+		summary.ActivationBytes += layer_summary.ActivationBytes
+	}
+
+	// parameters are held alongside their gradients & the optimizer's per-parameter state
+	copies := int64(2 + optimizerSlots(model.Optimizer))
+	summary.ParameterBytes = int64(summary.TrainableParams)*copies*bytesPerValue + int64(summary.NonTrainableParams)*bytesPerValue
+
+	return summary
+}
+
+// PrintSummary prints the summary table to stdout
+func (model *Model) PrintSummary(batch_size int) {
+	fmt.Println(model.Summary(batch_size))
+}
+
+func (summary ModelSummary) String() string {
+	var builder strings.Builder
+
+	writer := tabwriter.NewWriter(&builder, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "Layer\tType\tOutput Shape\tTrainable\tNon-trainable\tRegularizers\tMemory/batch\n")
+	fmt.Fprintf(writer, "input\t-\t%s\t0\t0\t-\t-\n", shapeString(summary.InputShape))
+
+	for _, l := range summary.Layers {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n", l.Name, l.Type, shapeString(l.OutputShape), l.TrainableParams, l.NonTrainableParams, l.Regularizers, formatBytes(l.ActivationBytes))
+	}
+	writer.Flush()
+
+	fmt.Fprintf(&builder, "\nloss: %s, optimizer: %s, accuracy: %s\n", summary.Loss, summary.Optimizer, summary.Accuracy)
+	fmt.Fprintf(&builder, "total params: %d (trainable: %d, non-trainable: %d)\n", summary.TrainableParams+summary.NonTrainableParams, summary.TrainableParams, summary.NonTrainableParams)
+	fmt.Fprintf(&builder, "estimated memory (batch size %d): params %s + activations %s = %s\n", summary.BatchSize, formatBytes(summary.ParameterBytes), formatBytes(summary.ActivationBytes), formatBytes(summary.TotalBytes()))
+
+	return builder.String()
+}
+
+// DOT renders the layer graph in Graphviz DOT format (e.g. `dot -Tpng model.dot -o model.png`)
+func (summary ModelSummary) DOT() string {
+	var builder strings.Builder
+
+	builder.WriteString("digraph model {\n")
+	builder.WriteString("  rankdir=TB;\n")
+	builder.WriteString("  node [shape=record, fontname=\"Helvetica\"];\n")
+	fmt.Fprintf(&builder, "  input [label=\"{input|%s}\", shape=Mrecord];\n", dotEscape(shapeString(summary.InputShape)))
+
+	previous := "input"
+	for i, l := range summary.Layers {
+		node := fmt.Sprintf("layer_%d", i)
+		label := fmt.Sprintf("{%s|%s|%s", l.Name, l.Type, shapeString(l.OutputShape))
+		if l.TrainableParams+l.NonTrainableParams > 0 {
+			label += fmt.Sprintf("|params: %d", l.TrainableParams+l.NonTrainableParams)
+		}
+		if l.Regularizers != "-" {
+			label += "|" + l.Regularizers
+		}
+		label += "}"
+
+		fmt.Fprintf(&builder, "  %s [label=\"%s\"];\n", node, dotEscape(label))
+		fmt.Fprintf(&builder, "  %s -> %s;\n", previous, node)
+		previous = node
+	}
+
+	if summary.Loss != "-" {
+		fmt.Fprintf(&builder, "  loss [label=\"%s\", shape=ellipse];\n", dotEscape(summary.Loss))
+		fmt.Fprintf(&builder, "  %s -> loss [style=dashed];\n", previous)
+	}
+
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+func regularizers(dense *layer.Layer) string {
+	var parts []string
+
+	for _, r := range []struct {
+		name  string
+		value float64
+	}{
+		{"W-L1", dense.Weight_Regularizer_L1},
+		{"W-L2", dense.Weight_Regularizer_L2},
+		{"B-L1", dense.Biases_Regularizer_L1},
+		{"B-L2", dense.Biases_Regularizer_L2},
+	} {
+		if r.value > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%g", r.name, r.value))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "-"
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// optimizerSlots is the number of per-parameter buffers an optimizer keeps (momentums, caches)
+func optimizerSlots(optimizer optimization.IOptimizer) int {
+	switch o := optimizer.(type) {
+	case *optimization.AdaptiveMomentum:
+		return 2
+	case *optimization.RootMeanSquarePropagation, *optimization.AdaptiveGradient:
+		return 1
+	case *optimization.StochasticGradientDescent:
+		if o.Momentum > 0 {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// layerKind gives a short lowercase name for a layer, e.g. dense, relu, dropout
+func layerKind(l any) string {
+	kind := strings.TrimSuffix(strings.ToLower(reflect.Indirect(reflect.ValueOf(l)).Type().Name()), "layer")
+	if kind == "" {
+		return "dense"
+	}
+
+	return kind
+}
+
+func typeName(v any) string {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return "-"
+	}
+
+	return reflect.TypeOf(v).String()
+}
+
+func shapeString(shape tensor.Shape) string {
+	if shape == nil {
+		return "?"
+	}
+
+	return shape.String()
+}
+
+func dotEscape(label string) string {
+	return strings.NewReplacer("\"", "\\\"", "<", "\\<", ">", "\\>").Replace(label)
+}
+
+func formatBytes(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+
+	value := float64(n)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
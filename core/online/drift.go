@@ -0,0 +1,155 @@
+package online
+
+import (
+	"math"
+
+	"github.com/saent-x/ids-nn/core"
+)
+
+// DriftDetector watches a stream of values, e.g. the loss of every new sample or the scores of an
+// anomaly.AnomalyDetector, & reports when their distribution changes
+type DriftDetector interface {
+	// Update adds a value & returns whether a drift was detected with it
+	Update(value float64) bool
+	Reset()
+}
+
+// PageHinkley detects an increase of the mean of the values (e.g. the loss going up after a firmware update): it
+// sums the deviations of the values from their running mean (minus Delta) & reports a drift when the sum rises more
+// than Threshold above its minimum. It starts over after a drift.
+type PageHinkley struct {
+	// Delta is the deviation tolerated from the mean (0.005 by default)
+	Delta float64
+	// Threshold is the rise of the cumulative deviation that is a drift (50 by default), higher thresholds give
+	// fewer false alarms but later detections
+	Threshold float64
+	// Alpha weighs the past deviations down at every value, 1 (the default when 0) keeps them all
+	Alpha float64
+	// MinSamples is the number of values seen before any drift is reported (30 by default)
+	MinSamples int
+
+	samples     int
+	mean, sum   float64
+	minimum_sum float64
+}
+
+func (detector *PageHinkley) Update(value float64) bool {
+	detector.samples++
+	detector.mean += (value - detector.mean) / float64(detector.samples)
+	detector.sum = core.DefaultValue(detector.Alpha, 1)*detector.sum + value - detector.mean - core.DefaultValue(detector.Delta, 0.005)
+	detector.minimum_sum = math.Min(detector.minimum_sum, detector.sum)
+
+	if detector.samples < defaultCount(detector.MinSamples, 30) || detector.sum-detector.minimum_sum <= core.DefaultValue(detector.Threshold, 50) {
+		return false
+	}
+
+	detector.Reset()
+	return true
+}
+
+func (detector *PageHinkley) Reset() {
+	detector.samples, detector.mean, detector.sum, detector.minimum_sum = 0, 0, 0, 0
+}
+
+// ADWIN (ADaptive WINdowing, Bifet & Gavaldà 2007) keeps a window of the latest values & drops its older part
+// whenever the means of the two parts differ by more than chance would allow with confidence Delta, so it detects
+// drifts of the mean either way & its window holds the values since the last drift.
+// The window is kept as is (up to MaxWindow values) rather than in the exponential histogram of the paper.
+type ADWIN struct {
+	// Delta is the confidence of the cuts (0.002 by default), lower values give fewer false alarms
+	Delta float64
+	// MaxWindow is the number of values kept at most (1000 by default)
+	MaxWindow int
+	// Clock is the number of values added between two checks of the window (32 by default)
+	Clock int
+	// MinSubWindow is the number of values of the smallest part of a cut (5 by default)
+	MinSubWindow int
+
+	window []float64
+	ticks  int
+}
+
+func (detector *ADWIN) Update(value float64) bool {
+	detector.window = append(detector.window, value)
+	if max_window := defaultCount(detector.MaxWindow, 1000); len(detector.window) > max_window {
+		detector.window = detector.window[len(detector.window)-max_window:]
+	}
+
+	if detector.ticks++; detector.ticks < defaultCount(detector.Clock, 32) {
+		return false
+	}
+	detector.ticks = 0
+
+	drift := false
+	for detector.cut() {
+		drift = true
+	}
+
+	return drift
+}
+
+// cut drops the older part of the window at the first split whose parts have different means, if any
+func (detector *ADWIN) cut() bool {
+	n := len(detector.window)
+	min_sub_window := defaultCount(detector.MinSubWindow, 5)
+	if n < 2*min_sub_window {
+		return false
+	}
+
+	total, squares := 0., 0.
+	for _, v := range detector.window {
+		total, squares = total+v, squares+v*v
+	}
+	variance := math.Max(squares/float64(n)-total*total/float64(n*n), 0)
+	log_term := math.Log(2 * math.Log(float64(n)) / core.DefaultValue(detector.Delta, 0.002))
+
+	head := 0.
+	for k := 1; k <= n-min_sub_window; k++ {
+		head += detector.window[k-1]
+		if k < min_sub_window {
+			continue
+		}
+
+		n0, n1 := float64(k), float64(n-k)
+		m := 1 / (1/n0 + 1/n1)
+		epsilon := math.Sqrt(2/m*variance*log_term) + 2/(3*m)*log_term
+
+		if math.Abs(head/n0-(total-head)/n1) > epsilon {
+			detector.window = append([]float64(nil), detector.window[k:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (detector *ADWIN) Reset() {
+	detector.window, detector.ticks = nil, 0
+}
+
+// Width returns the number of values in the window
+func (detector *ADWIN) Width() int {
+	return len(detector.window)
+}
+
+// Mean returns the mean of the values in the window
+func (detector *ADWIN) Mean() float64 {
+	if len(detector.window) == 0 {
+		return 0
+	}
+
+	total := 0.
+	for _, v := range detector.window {
+		total += v
+	}
+
+	return total / float64(len(detector.window))
+}
+
+func defaultCount(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+
+	return value
+}
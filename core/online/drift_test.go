@@ -0,0 +1,49 @@
+package online
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDriftDetectors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		detector DriftDetector
+	}{
+		{"page-hinkley", &PageHinkley{Threshold: 2}},
+		{"adwin", new(ADWIN)},
+	} {
+		random := rand.New(rand.NewSource(1))
+
+		// the loss is steady for 1000 samples then jumps from 0.1 to 0.5
+		for i := 0; i < 1000; i++ {
+			if test.detector.Update(0.1 + random.NormFloat64()*0.05) {
+				t.Errorf("error: %s: got a drift at sample %d of steady values", test.name, i)
+			}
+		}
+
+		detected := -1
+		for i := 0; i < 200 && detected < 0; i++ {
+			if test.detector.Update(0.5 + random.NormFloat64()*0.05) {
+				detected = i
+			}
+		}
+		if detected < 0 || detected > 100 {
+			t.Errorf("error: %s: got the drift %d samples after it | want within 100 samples", test.name, detected)
+		}
+	}
+
+	// ADWIN drops the values before the drift
+	adwin := &ADWIN{Clock: 1}
+	for i := 0; i < 300; i++ {
+		adwin.Update(float64(i / 200))
+	}
+	if width, mean := adwin.Width(), adwin.Mean(); width > 150 || mean < 0.9 {
+		t.Errorf("error: got a window of %d values with a mean of %f | want the values since the drift", width, mean)
+	}
+
+	adwin.Reset()
+	if adwin.Width() != 0 {
+		t.Errorf("error: got %d values after a reset", adwin.Width())
+	}
+}
@@ -0,0 +1,135 @@
+package online
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/saent-x/ids-nn/core"
+	"github.com/saent-x/ids-nn/core/model"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Learner keeps a trained model up to date with the traffic it sees, batch by batch: every batch of new labelled
+// samples updates the model with Model.PartialFit, mixed with replayed older samples so it keeps detecting the
+// attacks learnt before, while a drift detector watches the loss of the new samples (computed before the update)
+// to tell when the traffic changed.
+type Learner struct {
+	Model *model.Model
+
+	// Replay, when set, keeps a sample of the traffic seen & mixes it into every update
+	Replay *ReplayBuffer
+	// ReplayRatio is the number of replayed samples per new sample (1 by default)
+	ReplayRatio float64
+	// UpdateScaler adds every new batch to the statistics of the model's scaler (a scaling.PartialFitter)
+	UpdateScaler bool
+
+	// Detector, when set, watches the loss of every new sample
+	Detector DriftDetector
+	// OnDrift is called after the update of a batch in which the detector reported a drift, to adapt to it, e.g.
+	// raising the learning rate, refitting the scaler or alerting an operator
+	OnDrift func(learner *Learner) error
+	// Drifts is the number of drifts detected so far
+	Drifts int
+}
+
+// Step reports the update of a single batch
+type Step struct {
+	// Loss is the mean loss of the new samples before the update
+	Loss float64
+	// Drift tells whether the detector reported a drift in the batch
+	Drift bool
+}
+
+// Learn updates the model with the samples of X & their targets y, which may be a sparse 1 x N row or have a row
+// per sample
+func (learner *Learner) Learn(X, y *mat.Dense) (Step, error) {
+	if learner.Model == nil {
+		return Step{}, errors.New("the learner has no model")
+	}
+	if X == nil || y == nil {
+		return Step{}, errors.New("the batch has no samples or no targets")
+	}
+
+	if learner.UpdateScaler {
+		if err := learner.Model.UpdateScaler(X); err != nil {
+			return Step{}, fmt.Errorf("updating the scaler: %v", err)
+		}
+	}
+
+	batch_X, batch_Y, err := learner.withReplay(X, y)
+	if err != nil {
+		return Step{}, err
+	}
+
+	losses, err := learner.Model.PartialFit(batch_X, batch_Y)
+	if err != nil {
+		return Step{}, err
+	}
+
+	rows, _ := X.Dims()
+	losses = losses[:rows]
+
+	if learner.Replay != nil {
+		if err = learner.Replay.Add(X, y); err != nil {
+			return Step{}, fmt.Errorf("replay buffer: %v", err)
+		}
+	}
+
+	step := Step{}
+	for _, loss := range losses {
+		step.Loss += loss / float64(rows)
+		if learner.Detector != nil && learner.Detector.Update(loss) {
+			step.Drift = true
+		}
+	}
+
+	if step.Drift {
+		learner.Drifts++
+		if learner.OnDrift != nil {
+			if err = learner.OnDrift(learner); err != nil {
+				return step, fmt.Errorf("adapting to the drift: %v", err)
+			}
+		}
+	}
+
+	return step, nil
+}
+
+// withReplay appends replayed samples to the batch, the new samples come first
+func (learner *Learner) withReplay(X, y *mat.Dense) (*mat.Dense, *mat.Dense, error) {
+	if learner.Replay == nil || learner.Replay.Len() == 0 {
+		return X, y, nil
+	}
+
+	rows, cols := X.Dims()
+	replay_X, replay_Y := learner.Replay.Sample(int(math.Ceil(core.DefaultValue(learner.ReplayRatio, 1) * float64(rows))))
+	if replay_X == nil {
+		return X, y, nil
+	}
+
+	targets, err := targetRows(y, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	replayed, _ := replay_X.Dims()
+	if _, replay_cols := replay_X.Dims(); replay_cols != cols {
+		return nil, nil, fmt.Errorf("the replay buffer holds samples of %d features but got %d", replay_cols, cols)
+	}
+	if _, target_cols := replay_Y.Dims(); target_cols != len(targets[0]) {
+		return nil, nil, fmt.Errorf("the replay buffer holds targets of %d values but got %d", target_cols, len(targets[0]))
+	}
+
+	batch_X := mat.NewDense(rows+replayed, cols, nil)
+	batch_X.Stack(X, replay_X)
+
+	batch_Y := mat.NewDense(rows+replayed, len(targets[0]), nil)
+	for i, target := range targets {
+		batch_Y.SetRow(i, target)
+	}
+	for i := 0; i < replayed; i++ {
+		batch_Y.SetRow(rows+i, replay_Y.RawRowView(i))
+	}
+
+	return batch_X, batch_Y, nil
+}
@@ -0,0 +1,181 @@
+package online
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/model"
+	"gonum.org/v1/gonum/mat"
+)
+
+const classifierConfig = `
+input: {features: 2}
+layers:
+  - {type: dense, neurons: 16}
+  - {type: relu}
+  - {type: dense, neurons: 2}
+  - {type: softmax}
+loss: categorical_crossentropy
+optimizer: {type: adam, learning_rate: 0.01}
+accuracy: categorical
+scaling: {type: standard}
+`
+
+// traffic returns samples labelled 1 (attack) when their column feature is positive, a firmware update moving the
+// attacks from a feature to the other
+func traffic(samples, column int, random *rand.Rand) (*mat.Dense, *mat.Dense) {
+	X, y := mat.NewDense(samples, 2, nil), mat.NewDense(1, samples, nil)
+	for i := 0; i < samples; i++ {
+		X.SetRow(i, []float64{random.NormFloat64(), random.NormFloat64()})
+		if X.At(i, column) > 0 {
+			y.Set(0, i, 1)
+		}
+	}
+
+	return X, y
+}
+
+func accuracy(t *testing.T, m *model.Model, X, y *mat.Dense) float64 {
+	t.Helper()
+
+	outputs, err := m.Predict(X, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	predictions := m.OutputLayerActivation.Predictions(outputs)
+
+	correct := 0.
+	for i, prediction := range predictions.RawMatrix().Data {
+		if prediction == y.At(0, i) {
+			correct++
+		}
+	}
+
+	return correct / float64(len(predictions.RawMatrix().Data))
+}
+
+func TestLearner(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	config, err := model.ParseConfig([]byte(classifierConfig), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := model.FromConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	X, y := traffic(500, 0, random)
+	if err = m.FitScaler(X); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Train(datamodels.TrainingData{X: X, Y: y}, nil, 20, 50, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	learner := &Learner{
+		Model:        m,
+		Replay:       NewReplayBuffer(200, 1),
+		ReplayRatio:  0.5,
+		UpdateScaler: true,
+		Detector:     &PageHinkley{Threshold: 5},
+	}
+	adapted := 0
+	learner.OnDrift = func(learner *Learner) error {
+		adapted++
+		return nil
+	}
+
+	// the traffic the model was trained on
+	for i := 0; i < 20; i++ {
+		X, y := traffic(20, 0, random)
+		step, err := learner.Learn(X, y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if step.Drift {
+			t.Errorf("error: got a drift at batch %d before the firmware update", i)
+		}
+	}
+
+	// after the update, the drift is detected & the model learns the new traffic
+	first_drift := -1
+	for i := 0; i < 150; i++ {
+		X, y := traffic(20, 1, random)
+		step, err := learner.Learn(X, y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if step.Drift && first_drift < 0 {
+			first_drift = i
+		}
+	}
+	X_new, y_new := traffic(500, 1, random)
+	if first_drift < 0 || first_drift > 5 {
+		t.Errorf("error: got the first drift at batch %d after the update | want within 5 batches", first_drift)
+	}
+	if learner.Drifts == 0 || adapted != learner.Drifts {
+		t.Errorf("error: got %d drifts & %d adaptations", learner.Drifts, adapted)
+	}
+
+	if got := accuracy(t, m, X_new, y_new); got < 0.9 {
+		t.Errorf("error: got an accuracy of %f on the new traffic | want at least 0.9", got)
+	}
+	if learner.Replay.Len() != 200 || learner.Replay.Seen != 20*20+150*20 {
+		t.Errorf("error: the replay buffer holds %d of %d samples", learner.Replay.Len(), learner.Replay.Seen)
+	}
+
+	// the adaptation errors are reported
+	learner.Detector, learner.OnDrift = &PageHinkley{MinSamples: 1, Threshold: 1e-9, Delta: -1}, func(*Learner) error { return errors.New("offline") }
+	if _, err = learner.Learn(traffic(20, 1, random)); err == nil {
+		t.Error("error: expected the error of the adaptation")
+	}
+}
+
+func TestReplayBuffer(t *testing.T) {
+	buffer := NewReplayBuffer(100, 1)
+	if X, y := buffer.Sample(10); X != nil || y != nil {
+		t.Error("error: got samples from an empty buffer")
+	}
+
+	// every sample is kept with the same probability: about as many of the first & the second half are kept
+	first := 0
+	for i := 0; i < 100; i++ {
+		X := mat.NewDense(10, 1, nil)
+		for j := 0; j < 10; j++ {
+			X.Set(j, 0, float64(10*i+j))
+		}
+		if err := buffer.Add(X, mat.NewDense(1, 10, []float64{0, 1, 0, 1, 0, 1, 0, 1, 0, 1})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sample := range buffer.X {
+		if sample[0] < 500 {
+			first++
+		}
+	}
+	if buffer.Len() != 100 || buffer.Seen != 1000 || first < 35 || first > 65 {
+		t.Errorf("error: got %d samples of %d with %d of the first half", buffer.Len(), buffer.Seen, first)
+	}
+
+	// targets are kept with their sample
+	X, y := buffer.Sample(30)
+	if rows, _ := X.Dims(); rows != 30 {
+		t.Fatalf("error: got %d samples | want 30", rows)
+	}
+	for i := 0; i < 30; i++ {
+		if want := float64(int(X.At(i, 0)) % 2); y.At(i, 0) != want {
+			t.Errorf("error: got the target %f for the sample %f | want %f", y.At(i, 0), X.At(i, 0), want)
+		}
+	}
+
+	if err := buffer.Add(mat.NewDense(2, 3, nil), mat.NewDense(1, 2, nil)); err == nil {
+		t.Error("error: expected an error for samples of another size")
+	}
+	if err := buffer.Add(mat.NewDense(2, 1, nil), mat.NewDense(3, 1, nil)); err == nil {
+		t.Error("error: expected an error for targets of another number of samples")
+	}
+}
@@ -0,0 +1,114 @@
+package online
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ReplayBuffer keeps a uniform sample of every sample added so far (reservoir sampling), so the batches of an online
+// model can mix in older traffic & the model doesn't forget the attacks it learnt before a drift (catastrophic
+// forgetting)
+type ReplayBuffer struct {
+	// Capacity is the number of samples kept
+	Capacity int
+	// Seen is the number of samples added so far
+	Seen int
+
+	X, Y [][]float64
+
+	rng *rand.Rand
+}
+
+// NewReplayBuffer makes a buffer of capacity samples, a seed of 0 seeds from the clock
+func NewReplayBuffer(capacity int, seed int64) *ReplayBuffer {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &ReplayBuffer{Capacity: capacity, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Len returns the number of samples held
+func (buffer *ReplayBuffer) Len() int {
+	return len(buffer.X)
+}
+
+// Add offers the samples of X & their targets y to the buffer, which keeps every sample seen with the same
+// probability once it is full. Targets may be a sparse 1 x N row or have a row per sample.
+func (buffer *ReplayBuffer) Add(X, y *mat.Dense) error {
+	rows, cols := X.Dims()
+	targets, err := targetRows(y, rows)
+	if err != nil {
+		return err
+	}
+	if len(buffer.X) > 0 && cols != len(buffer.X[0]) {
+		return fmt.Errorf("the buffer holds samples of %d features but got %d", len(buffer.X[0]), cols)
+	}
+	if len(buffer.Y) > 0 && len(targets) > 0 && len(targets[0]) != len(buffer.Y[0]) {
+		return fmt.Errorf("the buffer holds targets of %d values but got %d", len(buffer.Y[0]), len(targets[0]))
+	}
+
+	for i := 0; i < rows; i++ {
+		buffer.Seen++
+
+		slot := len(buffer.X)
+		if slot >= buffer.Capacity {
+			if slot = buffer.rng.Intn(buffer.Seen); slot >= buffer.Capacity {
+				continue
+			}
+		}
+
+		sample, target := mat.Row(nil, i, X), targets[i]
+		if slot == len(buffer.X) {
+			buffer.X, buffer.Y = append(buffer.X, sample), append(buffer.Y, target)
+		} else {
+			buffer.X[slot], buffer.Y[slot] = sample, target
+		}
+	}
+
+	return nil
+}
+
+// Sample returns n samples of the buffer drawn without replacement (every sample when it holds fewer) & their targets
+// with a row per sample, both are nil when the buffer is empty
+func (buffer *ReplayBuffer) Sample(n int) (*mat.Dense, *mat.Dense) {
+	n = min(n, len(buffer.X))
+	if n <= 0 {
+		return nil, nil
+	}
+
+	X := mat.NewDense(n, len(buffer.X[0]), nil)
+	y := mat.NewDense(n, len(buffer.Y[0]), nil)
+	for i, k := range buffer.rng.Perm(len(buffer.X))[:n] {
+		X.SetRow(i, buffer.X[k])
+		y.SetRow(i, buffer.Y[k])
+	}
+
+	return X, y
+}
+
+// targetRows splits targets into a row per sample, a sparse 1 x N row of labels gives a label per row
+func targetRows(y *mat.Dense, samples int) ([][]float64, error) {
+	y_rows, y_cols := y.Dims()
+
+	if y_rows == 1 && y_cols == samples {
+		targets := make([][]float64, samples)
+		for i := range targets {
+			targets[i] = []float64{y.At(0, i)}
+		}
+		return targets, nil
+	}
+	if y_rows != samples {
+		return nil, fmt.Errorf("got %dx%d targets for %d samples", y_rows, y_cols, samples)
+	}
+
+	targets := make([][]float64, samples)
+	for i := range targets {
+		targets[i] = mat.Row(nil, i, y)
+	}
+
+	return targets, nil
+}
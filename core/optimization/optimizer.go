@@ -1,6 +1,9 @@
 package optimization
 
-import "github.com/saent-x/ids-nn/core/layer"
+import (
+	"github.com/saent-x/ids-nn/core/layer"
+	"gonum.org/v1/gonum/mat"
+)
 
 type IOptimizer interface {
 	PreUpdateParams()
@@ -19,3 +22,10 @@ type Optimizer struct {
 func (o *Optimizer) GetCurrentLearningRate() float64 {
 	return o.CurrentLearningRate
 }
+
+// zerosLike returns a zero-filled matrix with the same shape as m (used to initialize momentums/caches)
+func zerosLike(m *mat.Dense) *mat.Dense {
+	rows, cols := m.Dims()
+
+	return mat.NewDense(rows, cols, nil)
+}
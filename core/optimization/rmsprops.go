@@ -1,8 +1,8 @@
 package optimization
 
 import (
+	"github.com/saent-x/ids-nn/core/backend"
 	"github.com/saent-x/ids-nn/core/layer"
-	"gonum.org/v1/gonum/mat"
 	"math"
 )
 
@@ -32,63 +32,27 @@ func (self *RootMeanSquarePropagation) PreUpdateParams() {
 }
 
 func (self *RootMeanSquarePropagation) UpdateParams(layer *layer.Layer) {
-	var new_weights, new_biases mat.Dense
+	b := backend.Get()
 
 	if layer.Weights_Cache == nil || layer.Biases_Cache == nil {
-		layer.Weights_Cache = mat.DenseCopyOf(layer.Weights)
-		layer.Biases_Cache = mat.DenseCopyOf(layer.Biases)
-
-		layer.Weights_Cache.Zero()
-		layer.Biases_Cache.Zero()
+		layer.Weights_Cache = zerosLike(layer.Weights)
+		layer.Biases_Cache = zerosLike(layer.Biases)
 	}
 
-	var dweights_sqr_rho, dbiases_sqr_rho, weights_cache_rho, bias_cache_rho mat.Dense
-
-	dweights_sqr_rho.Apply(func(i, j int, v float64) float64 {
-		return (1 - self.Rho) * math.Pow(v, 2)
-	}, layer.D_Weights)
-	dbiases_sqr_rho.Apply(func(i, j int, v float64) float64 {
-		return (1 - self.Rho) * math.Pow(v, 2)
-	}, layer.D_Biases)
-
-	weights_cache_rho.Apply(func(i, j int, v float64) float64 {
-		return self.Rho * v
-	}, layer.Weights_Cache)
-	bias_cache_rho.Apply(func(i, j int, v float64) float64 {
-		return self.Rho * v
-	}, layer.Biases_Cache)
-
-	layer.Weights_Cache.Add(&weights_cache_rho, &dweights_sqr_rho)
-	layer.Biases_Cache.Add(&bias_cache_rho, &dbiases_sqr_rho)
-
-	new_weights.Apply(func(i, j int, v float64) float64 {
-		return -self.CurrentLearningRate * v
-	}, layer.D_Weights)
-	new_biases.Apply(func(i, j int, v float64) float64 {
-		return -self.CurrentLearningRate * v
-	}, layer.D_Biases)
-
-	var weights_epsilon_sum, biases_epsilon_sum mat.Dense
-
-	weights_epsilon_sum.Apply(func(i, j int, v float64) float64 {
-		return math.Sqrt(v) + self.Epsilon
-	}, layer.Weights_Cache)
-	biases_epsilon_sum.Apply(func(i, j int, v float64) float64 {
-		return math.Sqrt(v) + self.Epsilon
-	}, layer.Biases_Cache)
-
-	var weights_div, biases_div mat.Dense
-
-	weights_div.DivElem(&new_weights, &weights_epsilon_sum)
-	biases_div.DivElem(&new_biases, &biases_epsilon_sum)
+	cache_update := func(c, d float64) float64 {
+		return self.Rho*c + (1-self.Rho)*math.Pow(d, 2)
+	}
 
-	var weights_sum, biases_sum mat.Dense
+	layer.Weights_Cache = b.Apply2(cache_update, layer.Weights_Cache, layer.D_Weights)
+	layer.Biases_Cache = b.Apply2(cache_update, layer.Biases_Cache, layer.D_Biases)
 
-	weights_sum.Add(layer.Weights, &weights_div)
-	biases_sum.Add(layer.Biases, &biases_div)
+	// -learning rate * gradients / (sqrt(cache) + epsilon)
+	step := func(d, c float64) float64 {
+		return (-self.CurrentLearningRate * d) / (math.Sqrt(c) + self.Epsilon)
+	}
 
-	layer.Weights = mat.DenseCopyOf(&weights_sum)
-	layer.Biases = mat.DenseCopyOf(&biases_sum)
+	layer.Weights = b.Add(layer.Weights, b.Apply2(step, layer.D_Weights, layer.Weights_Cache))
+	layer.Biases = b.Add(layer.Biases, b.Apply2(step, layer.D_Biases, layer.Biases_Cache))
 }
 
 func (self *RootMeanSquarePropagation) PostUpdateParams() {
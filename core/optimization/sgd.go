@@ -1,8 +1,8 @@
 package optimization
 
 import (
+	"github.com/saent-x/ids-nn/core/backend"
 	"github.com/saent-x/ids-nn/core/layer"
-	"gonum.org/v1/gonum/mat"
 )
 
 type StochasticGradientDescent struct {
@@ -29,60 +29,31 @@ func (self *StochasticGradientDescent) PreUpdateParams() {
 }
 
 func (self *StochasticGradientDescent) UpdateParams(layer *layer.Layer) {
-	var new_weights, new_biases mat.Dense
+	b := backend.Get()
 
 	if self.Momentum > 0 {
 		if layer.Weights_Momentum == nil || layer.Biases_Momentum == nil {
-			layer.Weights_Momentum = mat.DenseCopyOf(&new_weights)
-			layer.Biases_Momentum = mat.DenseCopyOf(&new_biases)
-
-			layer.Weights_Momentum.Zero()
-			layer.Biases_Momentum.Zero()
+			layer.Weights_Momentum = zerosLike(layer.Weights)
+			layer.Biases_Momentum = zerosLike(layer.Biases)
 		}
 
-		var weight_momentum_mul, bias_momentum_mul, dweights_lr_mul, dbiases_lr_mul mat.Dense
-
-		weight_momentum_mul.Apply(func(i, j int, v float64) float64 {
-			return self.Momentum * v
-		}, layer.Weights_Momentum)
-
-		dweights_lr_mul.Apply(func(i, j int, v float64) float64 {
-			return self.CurrentLearningRate * v
-		}, layer.D_Weights)
-
-		new_weights.Sub(&weight_momentum_mul, &dweights_lr_mul)
-
-		bias_momentum_mul.Apply(func(i, j int, v float64) float64 {
-			return self.Momentum * v
-		}, layer.Biases_Momentum)
-
-		dbiases_lr_mul.Apply(func(i, j int, v float64) float64 {
-			return self.CurrentLearningRate * v
-		}, layer.D_Biases)
-
-		new_biases.Sub(&bias_momentum_mul, &dbiases_lr_mul)
+		// momentum * previous updates - learning rate * gradients
+		momentum_update := func(m, d float64) float64 {
+			return self.Momentum*m - self.CurrentLearningRate*d
+		}
 
-		layer.Weights_Momentum = &new_weights
-		layer.Biases_Momentum = &new_biases
+		layer.Weights_Momentum = b.Apply2(momentum_update, layer.Weights_Momentum, layer.D_Weights)
+		layer.Biases_Momentum = b.Apply2(momentum_update, layer.Biases_Momentum, layer.D_Biases)
 
-	} else {
-		// multiply by the negative of the learning rate
-		new_weights.Apply(func(i, j int, v float64) float64 {
-			return -self.CurrentLearningRate * v
-		}, layer.D_Weights)
+		layer.Weights = b.Add(layer.Weights, layer.Weights_Momentum)
+		layer.Biases = b.Add(layer.Biases, layer.Biases_Momentum)
 
-		new_biases.Apply(func(i, j int, v float64) float64 {
-			return -self.CurrentLearningRate * v
-		}, layer.D_Biases)
+		return
 	}
 
-	var weights_sum, biases_sum mat.Dense
-
-	weights_sum.Add(layer.Weights, &new_weights)
-	biases_sum.Add(layer.Biases, &new_biases)
-
-	layer.Weights = mat.DenseCopyOf(&weights_sum)
-	layer.Biases = mat.DenseCopyOf(&biases_sum)
+	// multiply by the negative of the learning rate
+	layer.Weights = b.Add(layer.Weights, b.Scale(-self.CurrentLearningRate, layer.D_Weights))
+	layer.Biases = b.Add(layer.Biases, b.Scale(-self.CurrentLearningRate, layer.D_Biases))
 }
 
 func (self *StochasticGradientDescent) PostUpdateParams() {
@@ -0,0 +1,245 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/saent-x/ids-nn/core/datamodels"
+	"github.com/saent-x/ids-nn/core/datasets"
+	"github.com/saent-x/ids-nn/core/model"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Pipeline chains every step between raw CAN records & named predictions:
+//
+//	parser & feature extractor (Schema) → scaler (Model.Scaler) → network (Model) → label decoder (Model.ClassNames)
+//
+// It is saved as a single artifact, so production reads & scales frames exactly like training did.
+// The model classifies frames one by one, windowed models aren't supported.
+type Pipeline struct {
+	Schema *datasets.CANSchema
+	Model  *model.Model
+
+	// extractor keeps the state of the live traffic classified with PredictFrame
+	extractor *datasets.FeatureExtractor
+}
+
+// Prediction is the class predicted for a frame
+type Prediction struct {
+	Class int
+	// Name is the name of the class, its number when the class isn't named
+	Name string
+	// Outputs is the output of the model for the frame, e.g. the confidence of every class
+	Outputs []float64
+}
+
+// New bundles a schema (DefaultCANSchema if nil) & a model taking the schema's features as inputs.
+// The model takes the class names of the schema's label map when it has none.
+func New(schema *datasets.CANSchema, m *model.Model) (*Pipeline, error) {
+	if m == nil {
+		return nil, errors.New("the pipeline has no model")
+	}
+	if schema == nil {
+		schema = datasets.DefaultCANSchema()
+	}
+	if err := schema.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+
+	if len(m.TrainableLayers) > 0 {
+		if inputs, _ := m.TrainableLayers[0].Weights.Dims(); inputs != schema.NumFeatures() {
+			return nil, fmt.Errorf("the model takes %d inputs but the schema extracts %d features", inputs, schema.NumFeatures())
+		}
+	}
+
+	if len(m.ClassNames) == 0 && schema.Classes != nil {
+		m.SetClassNames(schema.Classes.Names())
+	}
+
+	return &Pipeline{Schema: schema, Model: m}, nil
+}
+
+// Fit reads the labelled captures at path (a capture, a folder of captures or a folder of class folders), fits the
+// model's scaler on their features & trains the model on them
+func (pipeline *Pipeline) Fit(path string, training model.TrainingConfig) error {
+	data, err := datasets.LoadCANDatasetFrom(path, pipeline.Schema, training.Shuffle)
+	if err != nil {
+		return err
+	}
+
+	return pipeline.fit(data, training)
+}
+
+// FitFrames fits the pipeline like Fit on the labelled frames of a single capture
+func (pipeline *Pipeline) FitFrames(reader datasets.FrameReader, training model.TrainingConfig) error {
+	x, y, err := datasets.ReadFrames(reader, pipeline.Schema)
+	if err != nil {
+		return err
+	}
+	if len(x) == 0 {
+		return errors.New("the capture has no frames")
+	}
+
+	X := mat.NewDense(len(x), len(x[0]), nil)
+	for i, row := range x {
+		X.SetRow(i, row)
+	}
+
+	return pipeline.fit(datamodels.TrainingData{X: X, Y: mat.NewDense(1, len(y), y)}, training)
+}
+
+func (pipeline *Pipeline) fit(data datamodels.TrainingData, training model.TrainingConfig) error {
+	if err := pipeline.Model.FitScaler(data.X); err != nil {
+		return fmt.Errorf("fitting the scaler: %v", err)
+	}
+
+	return training.Train(pipeline.Model, data, nil)
+}
+
+// Predict classifies every frame of a capture
+func (pipeline *Pipeline) Predict(reader datasets.FrameReader) ([]Prediction, error) {
+	x, _, err := datasets.ReadFrames(reader, pipeline.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return pipeline.predict(x)
+}
+
+// PredictPath classifies every frame of the captures at path, in the order Fit reads them
+func (pipeline *Pipeline) PredictPath(path string) ([]Prediction, error) {
+	x, _, err := datasets.ReadCANPath(path, pipeline.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return pipeline.predict(x)
+}
+
+// PredictFrame classifies the next frame of live traffic, the frames must come in order (see Reset)
+func (pipeline *Pipeline) PredictFrame(frame datasets.Frame) (Prediction, error) {
+	if pipeline.extractor == nil {
+		pipeline.extractor = pipeline.Schema.NewFeatureExtractor()
+	}
+
+	predictions, err := pipeline.predict([][]float64{pipeline.extractor.Update(frame)})
+	if err != nil {
+		return Prediction{}, err
+	}
+
+	return predictions[0], nil
+}
+
+// Reset forgets the traffic seen by PredictFrame, e.g. before the frames of another bus
+func (pipeline *Pipeline) Reset() {
+	pipeline.extractor = nil
+}
+
+func (pipeline *Pipeline) predict(x [][]float64) ([]Prediction, error) {
+	if len(x) == 0 {
+		return nil, nil
+	}
+
+	X := mat.NewDense(len(x), len(x[0]), nil)
+	for i, row := range x {
+		X.SetRow(i, row)
+	}
+
+	outputs, err := pipeline.Model.Predict(X, 0)
+	if err != nil {
+		return nil, fmt.Errorf("predicting: %v", err)
+	}
+	classes := pipeline.Model.OutputLayerActivation.Predictions(outputs)
+	rows, _ := classes.Dims()
+
+	predictions := make([]Prediction, len(x))
+	for i := range predictions {
+		// softmax gives a 1 x N row of classes, sigmoid & linear outputs a row per frame
+		var class float64
+		if rows == 1 {
+			class = classes.At(0, i)
+		} else {
+			class = classes.At(i, 0)
+		}
+
+		predictions[i] = Prediction{
+			Class:   int(class),
+			Name:    pipeline.Model.ClassName(int(class)),
+			Outputs: mat.Row(nil, i, outputs),
+		}
+	}
+
+	return predictions, nil
+}
+
+// artifact is the saved form of a pipeline, the model being saved like ModelDataProvider does
+type artifact struct {
+	Schema *datasets.CANSchema `json:"schema"`
+	Model  json.RawMessage     `json:"model"`
+}
+
+// Encode writes the pipeline as a single JSON artifact to w
+func (pipeline *Pipeline) Encode(w io.Writer) error {
+	var model_json bytes.Buffer
+	if err := new(model.ModelDataProvider).Encode(&model_json, pipeline.Model); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(artifact{Schema: pipeline.Schema, Model: model_json.Bytes()})
+}
+
+// SaveFile writes the pipeline to path, see Encode
+func (pipeline *Pipeline) SaveFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = pipeline.Encode(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Load reads a pipeline written by Encode
+func Load(r io.Reader) (*Pipeline, error) {
+	var saved artifact
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("failed to decode pipeline JSON: %v", err)
+	}
+	if saved.Schema == nil || len(saved.Model) == 0 {
+		return nil, errors.New("the pipeline needs both a schema & a model")
+	}
+
+	m, err := new(model.ModelDataProvider).Load(bytes.NewReader(saved.Model))
+	if err != nil {
+		return nil, err
+	}
+
+	return New(saved.Schema, m)
+}
+
+// LoadFile opens & reads a pipeline saved with SaveFile
+func LoadFile(path string) (*Pipeline, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pipeline, err := Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return pipeline, nil
+}
@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/saent-x/ids-nn/core/accuracy"
+	"github.com/saent-x/ids-nn/core/activation"
+	"github.com/saent-x/ids-nn/core/datasets"
+	"github.com/saent-x/ids-nn/core/layer"
+	"github.com/saent-x/ids-nn/core/loss"
+	"github.com/saent-x/ids-nn/core/model"
+	"github.com/saent-x/ids-nn/core/optimization"
+	"github.com/saent-x/ids-nn/core/scaling"
+)
+
+// capture has n frames in the default layout, every other frame being an attack on id 0x001
+func capture(n int) string {
+	var builder strings.Builder
+	builder.WriteString("timestamp,arbitration_id,data_field,attack\n")
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			fmt.Fprintf(&builder, "%f,001,0101010101010101,1\n", float64(i)*0.001)
+		} else {
+			fmt.Fprintf(&builder, "%f,3A0,00000000000000FF,0\n", float64(i)*0.001)
+		}
+	}
+
+	return builder.String()
+}
+
+func newModel(inputs int) *model.Model {
+	m := model.New()
+	m.Add(layer.CreateLayer(inputs, 8, 0, 0, 0, 0))
+	m.Add(new(activation.ReLU))
+	m.Add(layer.CreateLayer(8, 2, 0, 0, 0, 0))
+	m.Add(new(activation.SoftMax))
+	m.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.05, 0, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
+	m.Scaler = new(scaling.RobustScaler)
+	m.Finalize()
+
+	return m
+}
+
+func TestPipeline(t *testing.T) {
+	schema := datasets.DefaultCANSchema()
+	schema.Classes = &datasets.LabelMap{Folders: []datasets.ClassFolder{
+		{Folder: "attack-free", Class: 0, Name: "Normal"},
+		{Folder: "dos", Class: 1, Name: "DoS"},
+	}}
+
+	p, err := New(schema, newModel(schema.NumFeatures()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Model.ClassNames, []string{"Normal", "DoS"}) {
+		t.Errorf("error: got class names %v", p.Model.ClassNames)
+	}
+
+	reader, err := schema.NewReader(strings.NewReader(capture(64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.FitFrames(reader, model.TrainingConfig{Epochs: 30, BatchSize: 16}); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, _ = schema.NewReader(strings.NewReader(capture(8)))
+	predictions, err := p.Predict(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, prediction := range predictions {
+		if want := []string{"DoS", "Normal"}[i%2]; prediction.Name != want || prediction.Class != 1-i%2 || len(prediction.Outputs) != 2 {
+			t.Errorf("error: frame %d: got %+v | want %s", i, prediction, want)
+		}
+	}
+
+	// the saved pipeline predicts the same, also frame by frame
+	var buffer bytes.Buffer
+	if err = p.Encode(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Schema, p.Schema) {
+		t.Errorf("error: got schema %+v | want %+v", loaded.Schema, p.Schema)
+	}
+
+	reader, _ = schema.NewReader(strings.NewReader(capture(8)))
+	for i := 0; ; i++ {
+		frame, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		prediction, err := loaded.PredictFrame(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prediction.Name != predictions[i].Name {
+			t.Errorf("error: frame %d: got %+v | want %+v", i, prediction, predictions[i])
+		}
+	}
+}
+
+func TestPipelineMismatch(t *testing.T) {
+	if _, err := New(datasets.DefaultCANSchema(), newModel(4)); err == nil {
+		t.Errorf("error: expected an error for a model taking 4 inputs")
+	}
+
+	// a model without a fitted scaler can't predict
+	p, err := New(nil, newModel(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.PredictFrame(datasets.Frame{ID: 1, DLC: 8, Data: make([]byte, 8)}); err == nil {
+		t.Errorf("error: expected an error for a scaler that isn't fitted")
+	}
+}
@@ -0,0 +1,77 @@
+package resampling
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// neighbours finds the nearest rows among a set of rows, by brute force with the found neighbours cached per row
+type neighbours struct {
+	x    [][]float64
+	rows []int
+	k    int
+
+	cache map[int][]int
+}
+
+func newNeighbours(x [][]float64, rows []int, k int) *neighbours {
+	return &neighbours{x: x, rows: rows, k: k, cache: make(map[int][]int)}
+}
+
+// nearest returns the (up to) k rows of the set closest to row, row itself excluded
+func (neighbours *neighbours) nearest(row int) []int {
+	if nearest, ok := neighbours.cache[row]; ok {
+		return nearest
+	}
+
+	type candidate struct {
+		row      int
+		distance float64
+	}
+
+	candidates := make([]candidate, 0, len(neighbours.rows))
+	for _, other := range neighbours.rows {
+		if other != row {
+			candidates = append(candidates, candidate{other, squaredDistance(neighbours.x[row], neighbours.x[other])})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	nearest := make([]int, 0, neighbours.k)
+	for _, c := range candidates[:min(neighbours.k, len(candidates))] {
+		nearest = append(nearest, c.row)
+	}
+	neighbours.cache[row] = nearest
+
+	return nearest
+}
+
+// interpolate draws a new row on the segment between the seed-th row of the set & one of its nearest neighbours,
+// a row without neighbours (a class of one row) is copied
+func (neighbours *neighbours) interpolate(seed int, rng *rand.Rand) []float64 {
+	row := neighbours.x[neighbours.rows[seed]]
+
+	nearest := neighbours.nearest(neighbours.rows[seed])
+	if len(nearest) == 0 {
+		return append([]float64(nil), row...)
+	}
+
+	other := neighbours.x[nearest[rng.Intn(len(nearest))]]
+	gap := rng.Float64()
+
+	synthetic := make([]float64, len(row))
+	for j := range row {
+		synthetic[j] = row[j] + gap*(other[j]-row[j])
+	}
+
+	return synthetic
+}
+
+func squaredDistance(a, b []float64) float64 {
+	distance := 0.
+	for j := range a {
+		distance += (a[j] - b[j]) * (a[j] - b[j])
+	}
+
+	return distance
+}
@@ -0,0 +1,279 @@
+// Package resampling rebalances the classes of a dataset, e.g. tiny attack classes such as Standstill against
+// attack-free traffic, by drawing extra rows for the small classes (RandomOversample, SMOTE & ADASYN) or dropping rows
+// of the large ones (RandomUndersample). BalancedClassWeights gives the alternative of weighing the loss instead.
+//
+// Rows are feature vectors as read by the datasets package & labels are class numbers. SMOTE & ADASYN interpolate
+// between neighbouring rows, so the features should be scaled first for the distances to make sense.
+package resampling
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Options tells how many rows each class should have after resampling
+type Options struct {
+	// Targets gives the number of rows wanted for some classes, the classes that aren't listed are resampled to the
+	// size of the largest class (oversampling) or of the smallest one (undersampling). A class is never undersampled
+	// by an oversampler, nor oversampled by an undersampler.
+	Targets map[float64]int
+	// K is the number of neighbours SMOTE & ADASYN interpolate with (5 by default)
+	K int
+	// Seed makes the resampling reproducible
+	Seed int64
+}
+
+func (options Options) neighbours() int {
+	if options.K <= 0 {
+		return 5
+	}
+
+	return options.K
+}
+
+// ClassCounts returns the number of rows of every class
+func ClassCounts(y []float64) map[float64]int {
+	counts := make(map[float64]int)
+	for _, label := range y {
+		counts[label]++
+	}
+
+	return counts
+}
+
+// BalancedClassWeights weighs every class by n / (classes * count), indexed by class, so every class weighs as much
+// in the loss whatever its size. Classes without rows weigh 0.
+func BalancedClassWeights(y []float64) ([]float64, error) {
+	if len(y) == 0 {
+		return nil, errors.New("can't weigh the classes of an empty dataset")
+	}
+
+	counts := ClassCounts(y)
+
+	largest := 0
+	for class := range counts {
+		if class < 0 || class != math.Trunc(class) {
+			return nil, fmt.Errorf("class %g isn't a non-negative integer", class)
+		}
+		largest = max(largest, int(class))
+	}
+
+	weights := make([]float64, largest+1)
+	for class, count := range counts {
+		weights[int(class)] = float64(len(y)) / float64(len(counts)*count)
+	}
+
+	return weights, nil
+}
+
+// RandomOversample draws copies of random rows of the small classes until they reach their target size. The new rows
+// follow the original ones.
+func RandomOversample(x [][]float64, y []float64, options Options) ([][]float64, []float64, error) {
+	return oversample(x, y, options, func(class float64, rows []int, n int, rng *rand.Rand) ([][]float64, error) {
+		synthetic := make([][]float64, n)
+		for i := range synthetic {
+			synthetic[i] = append([]float64(nil), x[rows[rng.Intn(len(rows))]]...)
+		}
+
+		return synthetic, nil
+	})
+}
+
+// RandomUndersample keeps random rows of the large classes, down to their target size, the rows stay in their order
+func RandomUndersample(x [][]float64, y []float64, options Options) ([][]float64, []float64, error) {
+	if err := check(x, y); err != nil {
+		return nil, nil, err
+	}
+
+	classes := classRows(y)
+
+	smallest := len(y)
+	for _, rows := range classes {
+		smallest = min(smallest, len(rows))
+	}
+
+	rng := rand.New(rand.NewSource(options.Seed))
+
+	var kept []int
+	for _, class := range sortedClasses(classes) {
+		rows := classes[class]
+
+		target, ok := options.Targets[class]
+		if !ok {
+			target = smallest
+		}
+		if target < 0 {
+			return nil, nil, fmt.Errorf("class %g: invalid target of %d rows", class, target)
+		}
+
+		if target < len(rows) {
+			rows = append([]int(nil), rows...)
+			rng.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+			rows = rows[:target]
+		}
+		kept = append(kept, rows...)
+	}
+	sort.Ints(kept)
+
+	resampled_x, resampled_y := make([][]float64, len(kept)), make([]float64, len(kept))
+	for i, row := range kept {
+		resampled_x[i], resampled_y[i] = x[row], y[row]
+	}
+
+	return resampled_x, resampled_y, nil
+}
+
+// SMOTE (Synthetic Minority Over-sampling TEchnique) grows the small classes with rows interpolated between a random
+// row of the class & one of its K nearest neighbours in the class. The new rows follow the original ones.
+func SMOTE(x [][]float64, y []float64, options Options) ([][]float64, []float64, error) {
+	return oversample(x, y, options, func(class float64, rows []int, n int, rng *rand.Rand) ([][]float64, error) {
+		neighbours := newNeighbours(x, rows, options.neighbours())
+
+		synthetic := make([][]float64, n)
+		for i := range synthetic {
+			synthetic[i] = neighbours.interpolate(rng.Intn(len(rows)), rng)
+		}
+
+		return synthetic, nil
+	})
+}
+
+// ADASYN (ADAptive SYNthetic sampling) grows the small classes like SMOTE but draws more rows around the rows that are
+// the hardest to learn: those whose K nearest neighbours in the whole dataset mostly belong to other classes
+func ADASYN(x [][]float64, y []float64, options Options) ([][]float64, []float64, error) {
+	k := options.neighbours()
+	all := make([]int, len(x))
+	for i := range all {
+		all[i] = i
+	}
+	dataset := newNeighbours(x, all, k)
+
+	return oversample(x, y, options, func(class float64, rows []int, n int, rng *rand.Rand) ([][]float64, error) {
+		// the share of every row's neighbours that belong to another class
+		hardness := make([]float64, len(rows))
+		total := 0.
+		for i, row := range rows {
+			others := 0
+			nearest := dataset.nearest(row)
+			for _, neighbour := range nearest {
+				if y[neighbour] != class {
+					others++
+				}
+			}
+			if len(nearest) > 0 {
+				hardness[i] = float64(others) / float64(len(nearest))
+			}
+			total += hardness[i]
+		}
+
+		neighbours := newNeighbours(x, rows, k)
+
+		synthetic := make([][]float64, n)
+		for i := range synthetic {
+			// a class that is well apart from the others is grown uniformly, like SMOTE does
+			seed := rng.Intn(len(rows))
+			if total > 0 {
+				seed = weightedChoice(hardness, total, rng)
+			}
+			synthetic[i] = neighbours.interpolate(seed, rng)
+		}
+
+		return synthetic, nil
+	})
+}
+
+// oversample draws the rows each small class is missing from generate & appends them to the dataset
+func oversample(x [][]float64, y []float64, options Options, generate func(class float64, rows []int, n int, rng *rand.Rand) ([][]float64, error)) ([][]float64, []float64, error) {
+	if err := check(x, y); err != nil {
+		return nil, nil, err
+	}
+
+	classes := classRows(y)
+
+	largest := 0
+	for _, rows := range classes {
+		largest = max(largest, len(rows))
+	}
+
+	rng := rand.New(rand.NewSource(options.Seed))
+
+	resampled_x, resampled_y := append([][]float64(nil), x...), append([]float64(nil), y...)
+	for _, class := range sortedClasses(classes) {
+		rows := classes[class]
+
+		target, ok := options.Targets[class]
+		if !ok {
+			target = largest
+		}
+		if target < 0 {
+			return nil, nil, fmt.Errorf("class %g: invalid target of %d rows", class, target)
+		}
+		if target <= len(rows) {
+			continue
+		}
+
+		synthetic, err := generate(class, rows, target-len(rows), rng)
+		if err != nil {
+			return nil, nil, fmt.Errorf("class %g: %v", class, err)
+		}
+
+		resampled_x = append(resampled_x, synthetic...)
+		for range synthetic {
+			resampled_y = append(resampled_y, class)
+		}
+	}
+
+	return resampled_x, resampled_y, nil
+}
+
+func check(x [][]float64, y []float64) error {
+	if len(x) != len(y) {
+		return fmt.Errorf("got %d rows & %d labels", len(x), len(y))
+	}
+	if len(x) == 0 {
+		return errors.New("can't resample an empty dataset")
+	}
+
+	for i, row := range x {
+		if len(row) != len(x[0]) {
+			return fmt.Errorf("row %d has %d features, expected %d", i, len(row), len(x[0]))
+		}
+	}
+
+	return nil
+}
+
+func classRows(y []float64) map[float64][]int {
+	classes := make(map[float64][]int)
+	for row, label := range y {
+		classes[label] = append(classes[label], row)
+	}
+
+	return classes
+}
+
+// sortedClasses lists the classes in increasing order, so a seed always gives the same resampling
+func sortedClasses(classes map[float64][]int) []float64 {
+	sorted := make([]float64, 0, len(classes))
+	for class := range classes {
+		sorted = append(sorted, class)
+	}
+	sort.Float64s(sorted)
+
+	return sorted
+}
+
+func weightedChoice(weights []float64, total float64, rng *rand.Rand) int {
+	target := rng.Float64() * total
+	for i, weight := range weights {
+		if target < weight {
+			return i
+		}
+		target -= weight
+	}
+
+	return len(weights) - 1
+}
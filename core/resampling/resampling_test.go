@@ -0,0 +1,102 @@
+package resampling
+
+import (
+	"reflect"
+	"testing"
+)
+
+// imbalanced has 6 rows of class 0, 3 of class 1 & a single row of class 2
+func imbalanced() ([][]float64, []float64) {
+	x := [][]float64{
+		{0, 0}, {0, 1}, {1, 0}, {1, 1}, {0.5, 0.5}, {0.2, 0.8},
+		{10, 10}, {10, 11}, {11, 10},
+		{20, 20},
+	}
+	y := []float64{0, 0, 0, 0, 0, 0, 1, 1, 1, 2}
+
+	return x, y
+}
+
+func TestOversamplers(t *testing.T) {
+	x, y := imbalanced()
+
+	for name, oversample := range map[string]func([][]float64, []float64, Options) ([][]float64, []float64, error){
+		"random": RandomOversample,
+		"smote":  SMOTE,
+		"adasyn": ADASYN,
+	} {
+		resampled_x, resampled_y, err := oversample(x, y, Options{K: 2, Seed: 3})
+		if err != nil {
+			t.Fatalf("error: %s: %v", name, err)
+		}
+
+		if got, want := ClassCounts(resampled_y), map[float64]int{0: 6, 1: 6, 2: 6}; !reflect.DeepEqual(got, want) {
+			t.Errorf("error: %s got %v | want %v", name, got, want)
+		}
+		if !reflect.DeepEqual(resampled_x[:len(x)], x) || !reflect.DeepEqual(resampled_y[:len(y)], y) {
+			t.Errorf("error: %s didn't keep the original rows first", name)
+		}
+
+		// every new row lies within the bounding box of its class
+		bounds := map[float64][2]float64{0: {0, 1}, 1: {10, 11}, 2: {20, 20}}
+		for i := len(x); i < len(resampled_x); i++ {
+			for _, v := range resampled_x[i] {
+				if v < bounds[resampled_y[i]][0] || v > bounds[resampled_y[i]][1] {
+					t.Errorf("error: %s drew row %v for class %g", name, resampled_x[i], resampled_y[i])
+				}
+			}
+		}
+
+		again_x, _, _ := oversample(x, y, Options{K: 2, Seed: 3})
+		if !reflect.DeepEqual(again_x, resampled_x) {
+			t.Errorf("error: %s gave other rows for the same seed", name)
+		}
+	}
+
+	_, resampled_y, err := SMOTE(x, y, Options{Targets: map[float64]int{1: 4, 2: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ClassCounts(resampled_y), map[float64]int{0: 6, 1: 4, 2: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("error: got %v | want %v", got, want)
+	}
+
+	if _, _, err := SMOTE(x, y[:3], Options{}); err == nil {
+		t.Errorf("error: expected an error for mismatched rows & labels")
+	}
+	if _, _, err := RandomOversample(nil, nil, Options{}); err == nil {
+		t.Errorf("error: expected an error for an empty dataset")
+	}
+}
+
+func TestRandomUndersample(t *testing.T) {
+	x, y := imbalanced()
+
+	resampled_x, resampled_y, err := RandomUndersample(x, y, Options{Targets: map[float64]int{0: 2}, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := ClassCounts(resampled_y), map[float64]int{0: 2, 1: 1, 2: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("error: got %v | want %v", got, want)
+	}
+	if len(resampled_x) != 4 || resampled_y[len(resampled_y)-1] != 2 {
+		t.Errorf("error: got rows %v labelled %v", resampled_x, resampled_y)
+	}
+}
+
+func TestBalancedClassWeights(t *testing.T) {
+	_, y := imbalanced()
+
+	weights, err := BalancedClassWeights(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{10. / 18, 10. / 9, 10. / 3}; !reflect.DeepEqual(weights, want) {
+		t.Errorf("error: got %v | want %v", weights, want)
+	}
+
+	if _, err := BalancedClassWeights([]float64{0, 1.5}); err == nil {
+		t.Errorf("error: expected an error for a fractional class")
+	}
+}
@@ -0,0 +1,146 @@
+package scaling
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Config describes a scaler, both in a model config (where only Type & its options are set) and when the model
+// is saved (where the fitted Center & Scale are kept too, along with the Moments PartialFit updates), e.g.
+//
+//	scaling:
+//	  type: per_column
+//	  scalers:
+//	    - {type: maxabs, columns: [0]}
+//	    - {type: robust, columns: [1, 2, 3, 4, 5, 6, 7, 8, 9]}
+type Config struct {
+	// Type is one of standard, minmax, robust, maxabs or per_column
+	Type string `json:"type" yaml:"type"`
+
+	// Columns are the columns scaled by the scalers of a per_column scaler
+	Columns []int `json:"columns,omitempty" yaml:"columns,omitempty"`
+	// Range is the [min, max] output range of minmax scalers, [0, 1] when omitted
+	Range []float64 `json:"range,omitempty" yaml:"range,omitempty"`
+	// Quantiles are the [low, high] quantiles of robust scalers, [0.25, 0.75] when omitted
+	Quantiles []float64 `json:"quantiles,omitempty" yaml:"quantiles,omitempty"`
+	// Scalers are the column groups of a per_column scaler
+	Scalers []Config `json:"scalers,omitempty" yaml:"scalers,omitempty"`
+
+	Affine  `yaml:",inline"`
+	Moments `yaml:",inline"`
+}
+
+func (config *Config) Validate() error {
+	_, err := New(*config)
+	return err
+}
+
+// New builds the scaler described by config, it is already fitted when config holds fitted statistics
+func New(config Config) (Scaler, error) {
+	if len(config.Center) != len(config.Scale) {
+		return nil, fmt.Errorf("%s scaler: got %d centers for %d scales", config.Type, len(config.Center), len(config.Scale))
+	}
+	for _, scale := range config.Scale {
+		if scale == 0 {
+			return nil, fmt.Errorf("%s scaler: a scale can't be 0", config.Type)
+		}
+	}
+
+	affine := Affine{Center: config.Center, Scale: config.Scale}
+	if err := config.Moments.validate(); err != nil {
+		return nil, fmt.Errorf("%s scaler: %v", config.Type, err)
+	}
+	if config.Samples > 0 && len(config.Mean) != len(config.Scale) {
+		return nil, fmt.Errorf("%s scaler: got moments of %d columns for %d scales", config.Type, len(config.Mean), len(config.Scale))
+	}
+	moments := config.Moments
+
+	switch config.Type {
+	case "standard":
+		return &StandardScaler{Affine: affine, Moments: moments}, nil
+	case "maxabs":
+		return &MaxAbsScaler{Affine: affine, Moments: moments}, nil
+	case "minmax":
+		scaler := &MinMaxScaler{Affine: affine, Moments: moments}
+		if len(config.Range) != 0 {
+			if len(config.Range) != 2 || config.Range[1] <= config.Range[0] {
+				return nil, fmt.Errorf("minmax scaler: invalid range %v", config.Range)
+			}
+			scaler.Min, scaler.Max = config.Range[0], config.Range[1]
+		}
+		return scaler, nil
+	case "robust":
+		scaler := &RobustScaler{Affine: affine}
+		if len(config.Quantiles) != 0 {
+			if len(config.Quantiles) != 2 || config.Quantiles[0] < 0 || config.Quantiles[1] > 1 || config.Quantiles[1] <= config.Quantiles[0] {
+				return nil, fmt.Errorf("robust scaler: invalid quantiles %v", config.Quantiles)
+			}
+			scaler.QuantileLow, scaler.QuantileHigh = config.Quantiles[0], config.Quantiles[1]
+		}
+		return scaler, nil
+	case "per_column":
+		if len(config.Scalers) == 0 {
+			return nil, errors.New("per_column scaler: no scalers")
+		}
+
+		var errs []error
+		scaler := new(PerColumn)
+		for i, group := range config.Scalers {
+			if len(group.Columns) == 0 {
+				errs = append(errs, fmt.Errorf("per_column scaler %d: no columns", i))
+			}
+			if group.Type == "per_column" {
+				errs = append(errs, fmt.Errorf("per_column scaler %d: per_column scalers can't be nested", i))
+				continue
+			}
+
+			inner, err := New(group)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("per_column scaler %d: %v", i, err))
+				continue
+			}
+			scaler.Scalers = append(scaler.Scalers, ColumnScaler{Columns: group.Columns, Scaler: inner})
+		}
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+		return scaler, nil
+	default:
+		return nil, fmt.Errorf("unknown scaler type %q (expected standard, minmax, robust, maxabs or per_column)", config.Type)
+	}
+}
+
+// ConfigOf describes scaler with its fitted statistics, so it can be saved & rebuilt with New
+func ConfigOf(scaler Scaler) (Config, error) {
+	switch s := scaler.(type) {
+	case *StandardScaler:
+		return Config{Type: "standard", Affine: s.Affine, Moments: s.Moments}, nil
+	case *MaxAbsScaler:
+		return Config{Type: "maxabs", Affine: s.Affine, Moments: s.Moments}, nil
+	case *MinMaxScaler:
+		config := Config{Type: "minmax", Affine: s.Affine, Moments: s.Moments}
+		if s.Min != 0 || s.Max != 0 {
+			config.Range = []float64{s.Min, s.Max}
+		}
+		return config, nil
+	case *RobustScaler:
+		config := Config{Type: "robust", Affine: s.Affine}
+		if s.QuantileLow != 0 || s.QuantileHigh != 0 {
+			config.Quantiles = []float64{s.QuantileLow, s.QuantileHigh}
+		}
+		return config, nil
+	case *PerColumn:
+		config := Config{Type: "per_column"}
+		for _, group := range s.Scalers {
+			inner, err := ConfigOf(group.Scaler)
+			if err != nil {
+				return Config{}, err
+			}
+			inner.Columns = group.Columns
+			config.Scalers = append(config.Scalers, inner)
+		}
+		return config, nil
+	default:
+		return Config{}, fmt.Errorf("can't describe scaler %T", scaler)
+	}
+}
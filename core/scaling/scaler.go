@@ -0,0 +1,309 @@
+package scaling
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// epsilon is the machine epsilon of float64
+const epsilon = 2.220446049250313e-16
+
+// Scaler learns per-column statistics with Fit & applies them with Transform, so data seen later (e.g. at
+// inference) is scaled with the statistics of the data the scaler was fit on
+type Scaler interface {
+	Fit(X mat.Matrix) error
+	Transform(X mat.Matrix) (*mat.Dense, error)
+	InverseTransform(X mat.Matrix) (*mat.Dense, error)
+}
+
+// Affine holds the fitted statistics of the scalers that compute (x - Center) / Scale for every column.
+// Constant columns (up to rounding errors) get a Scale of 1, so they are only centered instead of dividing by zero.
+type Affine struct {
+	Center []float64 `json:"center,omitempty" yaml:"center,omitempty"`
+	Scale  []float64 `json:"scale,omitempty" yaml:"scale,omitempty"`
+}
+
+func (affine *Affine) check(X mat.Matrix) error {
+	if len(affine.Scale) == 0 {
+		return errors.New("the scaler isn't fitted")
+	}
+	if _, cols := X.Dims(); cols != len(affine.Scale) {
+		return fmt.Errorf("the scaler was fitted on %d columns but got %d", len(affine.Scale), cols)
+	}
+
+	return nil
+}
+
+func (affine *Affine) Transform(X mat.Matrix) (*mat.Dense, error) {
+	if err := affine.check(X); err != nil {
+		return nil, err
+	}
+
+	rows, cols := X.Dims()
+	result := mat.NewDense(rows, cols, nil)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			result.Set(i, j, (X.At(i, j)-affine.Center[j])/affine.Scale[j])
+		}
+	}
+
+	return result, nil
+}
+
+func (affine *Affine) InverseTransform(X mat.Matrix) (*mat.Dense, error) {
+	if err := affine.check(X); err != nil {
+		return nil, err
+	}
+
+	rows, cols := X.Dims()
+	result := mat.NewDense(rows, cols, nil)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			result.Set(i, j, X.At(i, j)*affine.Scale[j]+affine.Center[j])
+		}
+	}
+
+	return result, nil
+}
+
+// fit computes Center & Scale from every column with stats, which returns the center & the scale of a column
+func (affine *Affine) fit(X mat.Matrix, stats func(column []float64) (float64, float64)) error {
+	rows, cols := X.Dims()
+	if rows == 0 {
+		return errors.New("can't fit a scaler without samples")
+	}
+
+	affine.set(cols, func(j int) (float64, float64) {
+		return stats(mat.Col(nil, j, X))
+	})
+
+	return nil
+}
+
+// set computes Center & Scale of cols columns with stats, which returns the center & the scale of column j
+func (affine *Affine) set(cols int, stats func(j int) (float64, float64)) {
+	affine.Center, affine.Scale = make([]float64, cols), make([]float64, cols)
+	for j := 0; j < cols; j++ {
+		center, scale := stats(j)
+		if math.Abs(scale) < 10*epsilon*math.Max(1, math.Abs(center)) || math.IsNaN(scale) || math.IsInf(scale, 0) {
+			scale = 1
+		}
+		affine.Center[j], affine.Scale[j] = center, scale
+	}
+}
+
+// StandardScaler removes the mean of every column & divides it by its standard deviation
+type StandardScaler struct {
+	Affine
+	Moments
+}
+
+func (scaler *StandardScaler) Fit(X mat.Matrix) error {
+	scaler.Affine, scaler.Moments = Affine{}, Moments{}
+	return scaler.PartialFit(X)
+}
+
+func (scaler *StandardScaler) PartialFit(X mat.Matrix) error {
+	if err := resumable(&scaler.Affine, &scaler.Moments); err != nil {
+		return err
+	}
+	if err := scaler.update(X); err != nil {
+		return err
+	}
+
+	scaler.set(len(scaler.Mean), func(j int) (float64, float64) {
+		return scaler.Mean[j], math.Sqrt(scaler.Variance[j])
+	})
+
+	return nil
+}
+
+// MinMaxScaler maps every column to the [Min, Max] range seen when fitting ([0, 1] unless set)
+type MinMaxScaler struct {
+	Min, Max float64
+	Affine
+	Moments
+}
+
+func (scaler *MinMaxScaler) Fit(X mat.Matrix) error {
+	scaler.Affine, scaler.Moments = Affine{}, Moments{}
+	return scaler.PartialFit(X)
+}
+
+func (scaler *MinMaxScaler) PartialFit(X mat.Matrix) error {
+	low, high := scaler.Min, scaler.Max
+	if low == 0 && high == 0 {
+		high = 1
+	}
+	if high <= low {
+		return fmt.Errorf("invalid min-max range [%g, %g]", low, high)
+	}
+	if err := resumable(&scaler.Affine, &scaler.Moments); err != nil {
+		return err
+	}
+
+	if err := scaler.update(X); err != nil {
+		return err
+	}
+
+	scaler.set(len(scaler.Minimum), func(j int) (float64, float64) {
+		// (x - minimum) / (maximum - minimum) * (high - low) + low, constant columns map to low
+		scale := (scaler.Maximum[j] - scaler.Minimum[j]) / (high - low)
+		if scale == 0 {
+			scale = 1
+		}
+		return scaler.Minimum[j] - low*scale, scale
+	})
+
+	return nil
+}
+
+// RobustScaler removes the median of every column & divides it by its interquartile range (or the range between
+// the QuantileLow & QuantileHigh quantiles when set), so outliers barely weigh on the scaling
+type RobustScaler struct {
+	QuantileLow, QuantileHigh float64
+	Affine
+}
+
+func (scaler *RobustScaler) Fit(X mat.Matrix) error {
+	low, high := scaler.QuantileLow, scaler.QuantileHigh
+	if low == 0 && high == 0 {
+		low, high = 0.25, 0.75
+	}
+	if low < 0 || high > 1 || high <= low {
+		return fmt.Errorf("invalid quantile range [%g, %g]", low, high)
+	}
+
+	return scaler.fit(X, func(column []float64) (float64, float64) {
+		sort.Float64s(column)
+
+		median := stat.Quantile(0.5, stat.Empirical, column, nil)
+		return median, stat.Quantile(high, stat.Empirical, column, nil) - stat.Quantile(low, stat.Empirical, column, nil)
+	})
+}
+
+// MaxAbsScaler divides every column by its largest absolute value, keeping zeros (& sparsity) as they are
+type MaxAbsScaler struct {
+	Affine
+	Moments
+}
+
+func (scaler *MaxAbsScaler) Fit(X mat.Matrix) error {
+	scaler.Affine, scaler.Moments = Affine{}, Moments{}
+	return scaler.PartialFit(X)
+}
+
+func (scaler *MaxAbsScaler) PartialFit(X mat.Matrix) error {
+	if err := resumable(&scaler.Affine, &scaler.Moments); err != nil {
+		return err
+	}
+	if err := scaler.update(X); err != nil {
+		return err
+	}
+
+	scaler.set(len(scaler.Maximum), func(j int) (float64, float64) {
+		return 0, math.Max(math.Abs(scaler.Minimum[j]), math.Abs(scaler.Maximum[j]))
+	})
+
+	return nil
+}
+
+// ColumnScaler scales the given columns with Scaler
+type ColumnScaler struct {
+	Columns []int
+	Scaler  Scaler
+}
+
+// PerColumn scales groups of columns with their own scaler, columns that aren't in any group are left as they are
+type PerColumn struct {
+	Scalers []ColumnScaler
+}
+
+func NewPerColumn(scalers ...ColumnScaler) *PerColumn {
+	return &PerColumn{Scalers: scalers}
+}
+
+func (scaler *PerColumn) columns(X mat.Matrix, columns []int) (*mat.Dense, error) {
+	rows, cols := X.Dims()
+
+	result := mat.NewDense(rows, len(columns), nil)
+	for k, j := range columns {
+		if j < 0 || j >= cols {
+			return nil, fmt.Errorf("column %d out of range for %d columns", j, cols)
+		}
+		for i := 0; i < rows; i++ {
+			result.Set(i, k, X.At(i, j))
+		}
+	}
+
+	return result, nil
+}
+
+func (scaler *PerColumn) Fit(X mat.Matrix) error {
+	for i, group := range scaler.Scalers {
+		columns, err := scaler.columns(X, group.Columns)
+		if err == nil {
+			err = group.Scaler.Fit(columns)
+		}
+		if err != nil {
+			return fmt.Errorf("scaler %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// PartialFit updates the statistics of every group, every scaler must be a PartialFitter
+func (scaler *PerColumn) PartialFit(X mat.Matrix) error {
+	for i, group := range scaler.Scalers {
+		inner, ok := group.Scaler.(PartialFitter)
+		if !ok {
+			return fmt.Errorf("scaler %d: a %T can't be fit incrementally", i, group.Scaler)
+		}
+
+		columns, err := scaler.columns(X, group.Columns)
+		if err == nil {
+			err = inner.PartialFit(columns)
+		}
+		if err != nil {
+			return fmt.Errorf("scaler %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (scaler *PerColumn) apply(X mat.Matrix, transform func(Scaler, mat.Matrix) (*mat.Dense, error)) (*mat.Dense, error) {
+	result := mat.DenseCopyOf(X)
+
+	for i, group := range scaler.Scalers {
+		columns, err := scaler.columns(X, group.Columns)
+		if err != nil {
+			return nil, fmt.Errorf("scaler %d: %v", i, err)
+		}
+
+		scaled, err := transform(group.Scaler, columns)
+		if err != nil {
+			return nil, fmt.Errorf("scaler %d: %v", i, err)
+		}
+
+		for k, j := range group.Columns {
+			result.SetCol(j, mat.Col(nil, k, scaled))
+		}
+	}
+
+	return result, nil
+}
+
+func (scaler *PerColumn) Transform(X mat.Matrix) (*mat.Dense, error) {
+	return scaler.apply(X, Scaler.Transform)
+}
+
+func (scaler *PerColumn) InverseTransform(X mat.Matrix) (*mat.Dense, error) {
+	return scaler.apply(X, Scaler.InverseTransform)
+}
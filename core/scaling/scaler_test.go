@@ -0,0 +1,209 @@
+package scaling
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestScalers(t *testing.T) {
+	// the second column is constant, the third has an outlier
+	training := mat.NewDense(5, 3, []float64{
+		1, 7, 0,
+		2, 7, 1,
+		3, 7, 2,
+		4, 7, 3,
+		5, 7, 100,
+	})
+
+	for _, test := range []struct {
+		name   string
+		scaler Scaler
+		want   []float64 // the scaled first row
+	}{
+		{"standard", new(StandardScaler), []float64{-math.Sqrt(2), 0, -21.2 / math.Sqrt(1553.36)}},
+		{"minmax", new(MinMaxScaler), []float64{0, 0, 0}},
+		{"minmax [-1, 1]", &MinMaxScaler{Min: -1, Max: 1}, []float64{-1, -1, -1}},
+		{"robust", new(RobustScaler), []float64{-1, 0, -1}},
+		{"maxabs", new(MaxAbsScaler), []float64{0.2, 1, 0}},
+	} {
+		if _, err := test.scaler.Transform(training); err == nil {
+			t.Errorf("error: %s: expected an error before fitting", test.name)
+		}
+		if err := test.scaler.Fit(training); err != nil {
+			t.Fatalf("error: %s: %v", test.name, err)
+		}
+
+		scaled, err := test.scaler.Transform(training)
+		if err != nil {
+			t.Fatalf("error: %s: %v", test.name, err)
+		}
+		if !mat.EqualApprox(scaled.RowView(0), mat.NewVecDense(3, test.want), 1e-9) {
+			t.Errorf("error: %s: got %v | want %v", test.name, mat.Row(nil, 0, scaled), test.want)
+		}
+
+		restored, err := test.scaler.InverseTransform(scaled)
+		if err != nil || !mat.EqualApprox(restored, training, 1e-9) {
+			t.Errorf("error: %s: the inverse transform doesn't give the data back: %v", test.name, err)
+		}
+
+		// inference data is scaled with the training statistics, not its own
+		inference := mat.NewDense(1, 3, []float64{3, 7, 2})
+		if got, _ := test.scaler.Transform(inference); !mat.EqualApprox(got.RowView(0), scaled.RowView(2), 1e-9) {
+			t.Errorf("error: %s: got %v | want %v", test.name, mat.Row(nil, 0, got), mat.Row(nil, 2, scaled))
+		}
+
+		if _, err := test.scaler.Transform(mat.NewDense(1, 2, nil)); err == nil {
+			t.Errorf("error: %s: expected an error for a wrong number of columns", test.name)
+		}
+	}
+}
+
+func TestPerColumnConfig(t *testing.T) {
+	scaler, err := New(Config{Type: "per_column", Scalers: []Config{
+		{Type: "maxabs", Columns: []int{0}},
+		{Type: "minmax", Columns: []int{2}, Range: []float64{-1, 1}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	X := mat.NewDense(3, 3, []float64{
+		-4, 10, 0,
+		2, 20, 5,
+		1, 30, 10,
+	})
+	if err = scaler.Fit(X); err != nil {
+		t.Fatal(err)
+	}
+
+	// the middle column isn't scaled
+	want := mat.NewDense(3, 3, []float64{
+		-1, 10, -1,
+		0.5, 20, 0,
+		0.25, 30, 1,
+	})
+	scaled, err := scaler.Transform(X)
+	if err != nil || !mat.EqualApprox(scaled, want, 1e-9) {
+		t.Fatalf("error: got %v | want %v (%v)", mat.Formatted(scaled), mat.Formatted(want), err)
+	}
+
+	// a fitted scaler survives a JSON round trip
+	config, err := ConfigOf(scaler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Config
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := New(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := restored.Transform(X); err != nil || !mat.EqualApprox(got, want, 1e-9) {
+		t.Errorf("error: the restored scaler gives %v (%v)", mat.Formatted(got), err)
+	}
+
+	for _, invalid := range []Config{
+		{Type: "zscore"},
+		{Type: "per_column"},
+		{Type: "per_column", Scalers: []Config{{Type: "standard"}}},
+		{Type: "robust", Quantiles: []float64{0.75, 0.25}},
+		{Type: "standard", Affine: Affine{Center: []float64{0}, Scale: []float64{0}}},
+	} {
+		if _, err := New(invalid); err == nil {
+			t.Errorf("error: expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestRobustScaleConstant(t *testing.T) {
+	scaled, err := RobustScale([]float64{3, 3, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range scaled {
+		if v != 0 {
+			t.Errorf("error: got %v | want zeros", scaled)
+		}
+	}
+}
+
+func TestPartialFit(t *testing.T) {
+	X := mat.NewDense(6, 2, []float64{
+		1, -3,
+		2, 0,
+		3, 0,
+		4, 8,
+		5, 1,
+		9, 1,
+	})
+
+	for _, test := range []struct {
+		name           string
+		batch, partial PartialFitter
+	}{
+		{"standard", new(StandardScaler), new(StandardScaler)},
+		{"minmax", &MinMaxScaler{Min: -1, Max: 1}, &MinMaxScaler{Min: -1, Max: 1}},
+		{"maxabs", new(MaxAbsScaler), new(MaxAbsScaler)},
+		{"per_column", NewPerColumn(ColumnScaler{Columns: []int{1}, Scaler: new(StandardScaler)}), NewPerColumn(ColumnScaler{Columns: []int{1}, Scaler: new(StandardScaler)})},
+	} {
+		if err := test.batch.Fit(X); err != nil {
+			t.Fatal(err)
+		}
+
+		// fitting 1, 3 & 2 samples one after the other gives the statistics of fitting them at once
+		if err := test.partial.Fit(X.Slice(0, 1, 0, 2)); err != nil {
+			t.Fatal(err)
+		}
+		for _, batch := range [][2]int{{1, 4}, {4, 6}} {
+			if err := test.partial.PartialFit(X.Slice(batch[0], batch[1], 0, 2)); err != nil {
+				t.Fatalf("error: %s: %v", test.name, err)
+			}
+		}
+
+		want, _ := test.batch.Transform(X)
+		got, err := test.partial.Transform(X)
+		if err != nil || !mat.EqualApprox(got, want, 1e-9) {
+			t.Errorf("error: %s: got %v | want %v (%v)", test.name, mat.Formatted(got), mat.Formatted(want), err)
+		}
+	}
+
+	// the moments are saved with the scaler, so a loaded scaler keeps updating them
+	scaler := new(StandardScaler)
+	if err := scaler.Fit(X.Slice(0, 3, 0, 2)); err != nil {
+		t.Fatal(err)
+	}
+	config, _ := ConfigOf(scaler)
+	data, _ := json.Marshal(config)
+	var decoded Config
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := New(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = restored.(PartialFitter).PartialFit(X.Slice(3, 6, 0, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if moments := restored.(*StandardScaler).Moments; moments.Samples != 6 || math.Abs(moments.Mean[0]-4) > 1e-9 || math.Abs(moments.Variance[0]-40./6) > 1e-9 {
+		t.Errorf("error: got %+v | want the moments of the 6 samples", moments)
+	}
+
+	// a scaler saved without its moments can't resume from them
+	stale, _ := New(Config{Type: "standard", Affine: Affine{Center: []float64{0, 0}, Scale: []float64{1, 1}}})
+	if err = stale.(PartialFitter).PartialFit(X); err == nil {
+		t.Error("error: expected an error updating a scaler saved without its moments")
+	}
+	if err = NewPerColumn(ColumnScaler{Columns: []int{0}, Scaler: new(RobustScaler)}).PartialFit(X); err == nil {
+		t.Error("error: expected an error updating a robust scaler incrementally")
+	}
+}
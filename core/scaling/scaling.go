@@ -35,6 +35,7 @@ func scale2(value float64, maxValue float64) float64 {
 	return value / maxValue
 }
 
+// RobustScale scales data with its own median & IQR, use a RobustScaler to reuse the statistics on other data
 func RobustScale(data []float64) ([]float64, error) {
 	// Make a sorted copy of the data for quantile calculations
 	sortedData := append([]float64(nil), data...) // copy of data
@@ -48,9 +49,9 @@ func RobustScale(data []float64) ([]float64, error) {
 	q3 := stat.Quantile(0.75, stat.Empirical, sortedData, nil)
 	iqr := q3 - q1
 
-	// Avoid division by zero if IQR is 0
+	// constant data is only centered, instead of dividing by a zero IQR
 	if iqr == 0 {
-		return nil, errors.New("IQR is zero; data might be constant")
+		iqr = 1
 	}
 
 	// Apply robust scaling
@@ -0,0 +1,97 @@
+package scaling
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// PartialFitter is a scaler whose statistics can be updated batch by batch, e.g. as traffic streams in after the
+// model is deployed: Fit starts the statistics over while PartialFit adds the samples of X to them.
+// The standard, minmax & maxabs scalers are PartialFitters (so is a per_column scaler of them), the quantiles of the
+// robust scaler need every sample at once.
+type PartialFitter interface {
+	Scaler
+	PartialFit(X mat.Matrix) error
+}
+
+// Moments are the running statistics of every column of the samples seen so far, they are saved with the scaler so
+// that a loaded model keeps updating them
+type Moments struct {
+	Samples  int       `json:"samples,omitempty" yaml:"samples,omitempty"`
+	Mean     []float64 `json:"mean,omitempty" yaml:"mean,omitempty"`
+	Variance []float64 `json:"variance,omitempty" yaml:"variance,omitempty"`
+	Minimum  []float64 `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum  []float64 `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+}
+
+func (moments *Moments) validate() error {
+	if moments.Samples == 0 {
+		return nil
+	}
+	if moments.Samples < 0 {
+		return fmt.Errorf("got %d samples", moments.Samples)
+	}
+
+	cols := len(moments.Mean)
+	for _, stats := range [][]float64{moments.Variance, moments.Minimum, moments.Maximum} {
+		if len(stats) != cols {
+			return fmt.Errorf("the moments of %d samples have statistics of %d & %d columns", moments.Samples, cols, len(stats))
+		}
+	}
+
+	return nil
+}
+
+// resumable checks that a fitted scaler kept the moments it was fitted with, otherwise PartialFit would start over
+func resumable(affine *Affine, moments *Moments) error {
+	if len(affine.Scale) > 0 && moments.Samples == 0 {
+		return errors.New("the scaler was saved without its moments, fit it again to update it incrementally")
+	}
+
+	return nil
+}
+
+// update adds the samples of X to the moments, merging the mean & the (population) variance of X with the ones
+// seen so far (Chan et al.'s parallel algorithm)
+func (moments *Moments) update(X mat.Matrix) error {
+	rows, cols := X.Dims()
+	if rows == 0 {
+		return errors.New("can't fit a scaler without samples")
+	}
+	if moments.Samples > 0 && cols != len(moments.Mean) {
+		return fmt.Errorf("the scaler was fitted on %d columns but got %d", len(moments.Mean), cols)
+	}
+	if moments.Samples == 0 {
+		*moments = Moments{Mean: make([]float64, cols), Variance: make([]float64, cols), Minimum: make([]float64, cols), Maximum: make([]float64, cols)}
+	}
+
+	seen, total := float64(moments.Samples), float64(moments.Samples+rows)
+	for j := 0; j < cols; j++ {
+		column := mat.Col(nil, j, X)
+		mean := calculateMean(column)
+		std := calculateStdDev(column, mean)
+
+		minimum, maximum := column[0], column[0]
+		for _, v := range column {
+			minimum, maximum = math.Min(minimum, v), math.Max(maximum, v)
+		}
+
+		if moments.Samples == 0 {
+			moments.Mean[j], moments.Variance[j], moments.Minimum[j], moments.Maximum[j] = mean, std*std, minimum, maximum
+			continue
+		}
+
+		delta := mean - moments.Mean[j]
+		squares := moments.Variance[j]*seen + std*std*float64(rows) + delta*delta*seen*float64(rows)/total
+
+		moments.Mean[j] += delta * float64(rows) / total
+		moments.Variance[j] = squares / total
+		moments.Minimum[j], moments.Maximum[j] = math.Min(moments.Minimum[j], minimum), math.Max(moments.Maximum[j], maximum)
+	}
+	moments.Samples += rows
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package tensor
+
+import (
+	"github.com/saent-x/ids-nn/core/backend"
+	"gonum.org/v1/gonum/mat"
+)
+
+// LabelsAsRow converts the class labels of samples samples to the 1 x N sparse row expected by categorical
+// losses/accuracies. It accepts a 1 x N row, an N x 1 column or an N x C one-hot matrix, a single sample's 1 x C
+// row being one-hot.
+func LabelsAsRow(y *mat.Dense, samples int) *mat.Dense {
+	rows, cols := y.Dims()
+
+	switch {
+	case rows == 1 && (samples != 1 || cols == 1):
+		return y
+	case cols == 1:
+		return mat.NewDense(1, rows, mat.Col(nil, 0, y))
+	default:
+		sparse := mat.NewDense(1, rows, nil)
+		for i, idx := range backend.Get().ArgMaxRows(y) {
+			sparse.Set(0, i, float64(idx))
+		}
+		return sparse
+	}
+}
+
+// LabelsAsColumns converts targets to the N x outputs layout produced by sigmoid/linear outputs,
+// transposing a 1 x N row when there is a single output.
+func LabelsAsColumns(y *mat.Dense, outputs int) *mat.Dense {
+	rows, cols := y.Dims()
+
+	if rows == 1 && cols != outputs {
+		return mat.NewDense(cols, 1, mat.Row(nil, 0, y))
+	}
+
+	return y
+}
@@ -0,0 +1,37 @@
+package tensor
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestLabelsLayouts(t *testing.T) {
+	want := mat.NewDense(1, 3, []float64{0, 2, 1})
+
+	column := mat.NewDense(3, 1, []float64{0, 2, 1})
+	oneHot := mat.NewDense(3, 3, []float64{1, 0, 0, 0, 0, 1, 0, 1, 0})
+
+	if !mat.Equal(LabelsAsRow(column, 3), want) {
+		t.Errorf("error: column labels were not converted to a row")
+	}
+	if !mat.Equal(LabelsAsRow(oneHot, 3), want) {
+		t.Errorf("error: one-hot labels were not converted to a sparse row")
+	}
+
+	if !mat.Equal(LabelsAsRow(want, 3), want) {
+		t.Errorf("error: sparse labels were changed")
+	}
+
+	// the one-hot targets of a single sample, e.g. the last batch of a pass
+	if got := LabelsAsRow(mat.NewDense(1, 3, []float64{0, 0, 1}), 1); !mat.Equal(got, mat.NewDense(1, 1, []float64{2})) {
+		t.Errorf("error: got %v for the one-hot labels of a sample | want [2]", mat.Formatted(got))
+	}
+	if got := LabelsAsRow(mat.NewDense(1, 1, []float64{2}), 1); got.At(0, 0) != 2 {
+		t.Errorf("error: got %v for the sparse label of a sample | want [2]", mat.Formatted(got))
+	}
+
+	if r, c := LabelsAsColumns(want, 1).Dims(); r != 3 || c != 1 {
+		t.Errorf("error: got %dx%d | want 3x1", r, c)
+	}
+}
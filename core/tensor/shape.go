@@ -0,0 +1,168 @@
+package tensor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dim names an axis of a tensor
+type Dim string
+
+const (
+	Batch    Dim = "batch"
+	Time     Dim = "time"
+	Channel  Dim = "channel"
+	Height   Dim = "height"
+	Width    Dim = "width"
+	Features Dim = "features"
+)
+
+// Unknown marks an axis whose size is only known at runtime (usually the batch axis)
+const Unknown = -1
+
+type Axis struct {
+	Name Dim
+	Size int
+}
+
+// Shape is an ordered list of named axes, e.g. (batch=?, time=32, features=10)
+type Shape []Axis
+
+func NewShape(axes ...Axis) Shape {
+	return append(Shape{}, axes...)
+}
+
+// FeatureShape describes plain tabular data: (batch, features)
+func FeatureShape(features int) Shape {
+	return NewShape(Axis{Name: Batch, Size: Unknown}, Axis{Name: Features, Size: features})
+}
+
+// SequenceShape describes windows of consecutive samples: (batch, time, features)
+func SequenceShape(steps, features int) Shape {
+	return NewShape(Axis{Name: Batch, Size: Unknown}, Axis{Name: Time, Size: steps}, Axis{Name: Features, Size: features})
+}
+
+// ImageShape describes image data: (batch, channel, height, width)
+func ImageShape(channels, height, width int) Shape {
+	return NewShape(Axis{Name: Batch, Size: Unknown}, Axis{Name: Channel, Size: channels}, Axis{Name: Height, Size: height}, Axis{Name: Width, Size: width})
+}
+
+func (shape Shape) Rank() int {
+	return len(shape)
+}
+
+// Index returns the position of the named axis or -1 if the shape doesn't have it
+func (shape Shape) Index(name Dim) int {
+	for i, axis := range shape {
+		if axis.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Size returns the size of the named axis
+func (shape Shape) Size(name Dim) (int, bool) {
+	idx := shape.Index(name)
+	if idx < 0 {
+		return 0, false
+	}
+
+	return shape[idx].Size, true
+}
+
+// Sizes returns the size of every axis in order
+func (shape Shape) Sizes() []int {
+	sizes := make([]int, len(shape))
+	for i, axis := range shape {
+		sizes[i] = axis.Size
+	}
+
+	return sizes
+}
+
+// BatchSize returns the size of the batch axis (Unknown if it isn't fixed yet)
+func (shape Shape) BatchSize() int {
+	size, ok := shape.Size(Batch)
+	if !ok {
+		return Unknown
+	}
+
+	return size
+}
+
+// FeatureSize is the number of values per sample, i.e. the product of all non-batch axes.
+// This is the width a sample has once it is flattened into a row of a 2D matrix.
+func (shape Shape) FeatureSize() int {
+	size := 1
+	for _, axis := range shape {
+		if axis.Name == Batch {
+			continue
+		}
+		size *= axis.Size
+	}
+
+	return size
+}
+
+// WithBatch returns a copy of the shape with the batch axis fixed to n (prepending one if missing)
+func (shape Shape) WithBatch(n int) Shape {
+	result := append(Shape{}, shape...)
+
+	if idx := result.Index(Batch); idx >= 0 {
+		result[idx].Size = n
+		return result
+	}
+
+	return append(Shape{{Name: Batch, Size: n}}, result...)
+}
+
+// Equal compares names & sizes; an Unknown size matches any size
+func (shape Shape) Equal(other Shape) bool {
+	if len(shape) != len(other) {
+		return false
+	}
+
+	for i := range shape {
+		if shape[i].Name != other[i].Name {
+			return false
+		}
+		if shape[i].Size != other[i].Size && shape[i].Size != Unknown && other[i].Size != Unknown {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Validate checks that axis names are unique and that all sizes (except Unknown) are positive
+func (shape Shape) Validate() error {
+	seen := map[Dim]bool{}
+
+	for _, axis := range shape {
+		if seen[axis.Name] {
+			return fmt.Errorf("shape %v: duplicate axis %q", shape, axis.Name)
+		}
+		seen[axis.Name] = true
+
+		if axis.Size <= 0 && axis.Size != Unknown {
+			return fmt.Errorf("shape %v: axis %q must have a positive size", shape, axis.Name)
+		}
+	}
+
+	return nil
+}
+
+func (shape Shape) String() string {
+	parts := make([]string, len(shape))
+	for i, axis := range shape {
+		size := "?"
+		if axis.Size != Unknown {
+			size = fmt.Sprintf("%d", axis.Size)
+		}
+		parts[i] = fmt.Sprintf("%s=%s", axis.Name, size)
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
+}
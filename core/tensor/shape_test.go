@@ -0,0 +1,12 @@
+package tensor
+
+import "testing"
+
+func TestShapeString(t *testing.T) {
+	got := SequenceShape(32, 10).String()
+	want := "(batch=?, time=32, features=10)"
+
+	if got != want {
+		t.Errorf("error: got %s | want %s", got, want)
+	}
+}
@@ -7,13 +7,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/saent-x/ids-nn/core/backend"
 	"github.com/saent-x/ids-nn/core/datamodels"
 	"github.com/saent-x/ids-nn/core/scaling"
 	"github.com/samber/lo"
 	"golang.org/x/image/draw"
 	"gonum.org/v1/gonum/floats"
 	"gonum.org/v1/gonum/mat"
-	"gonum.org/v1/gonum/stat"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
@@ -178,14 +178,7 @@ func PlotSineData(X, Y *mat.Dense) bool {
 }
 
 func MeanOnLastAxis(matrix *mat.Dense) *mat.VecDense {
-	means := mat.NewVecDense(matrix.RawMatrix().Rows, nil)
-
-	for i := 0; i < matrix.RawMatrix().Rows; i++ {
-		row := matrix.RawRowView(i)
-		means.SetVec(i, stat.Mean(row, nil))
-	}
-
-	return means
+	return backend.Get().MeanRows(matrix)
 }
 
 func Sign(v float64) float64 {
@@ -354,48 +347,28 @@ func ContainsNaN(val *mat.Dense) {
 	}
 }
 
+// SubtractUnevenMatrices subtracts the column vector B from every column of A
 func SubtractUnevenMatrices(A, B *mat.Dense) *mat.Dense {
-	rowsA, colsA := A.Dims()
+	rowsA, _ := A.Dims()
 	rowsB, colsB := B.Dims()
 
 	if rowsA != rowsB || colsB != 1 {
 		panic("Matrix dimensions do not match for broadcasting")
 	}
 
-	// Create a result matrix with the same dimensions as A
-	result := mat.NewDense(rowsA, colsA, nil)
-
-	// Subtract B from A, broadcasting B across columns
-	for i := 0; i < rowsA; i++ {
-		for j := 0; j < colsA; j++ {
-			// Subtract corresponding element from B (broadcasted across columns)
-			result.Set(i, j, A.At(i, j)-B.At(i, 0))
-		}
-	}
-
-	return result
+	return backend.Get().BroadcastSub(A, B)
 }
 
+// DivideUnevenMatrices divides every column of A by the column vector B
 func DivideUnevenMatrices(A, B *mat.Dense) *mat.Dense {
-	rowsA, colsA := A.Dims()
+	rowsA, _ := A.Dims()
 	rowsB, colsB := B.Dims()
 
 	if rowsA != rowsB || colsB != 1 {
 		panic("Matrix dimensions do not match for broadcasting")
 	}
 
-	// Create a result matrix with the same dimensions as A
-	result := mat.NewDense(rowsA, colsA, nil)
-
-	// Subtract B from A, broadcasting B across columns
-	for i := 0; i < rowsA; i++ {
-		for j := 0; j < colsA; j++ {
-			// Divide corresponding element from B (broadcasted across columns)
-			result.Set(i, j, A.At(i, j)/B.At(i, 0))
-		}
-	}
-
-	return result
+	return backend.Get().BroadcastDiv(A, B)
 }
 
 func EncodeStructToJSON(d interface{}, filename string) error {
@@ -417,7 +390,8 @@ func EncodeStructToJSON(d interface{}, filename string) error {
 	return nil
 }
 
-func SaveMatrixToCSV(traindata datamodels.TrainingData, filename string) error {
+// SaveMatrixToCSV writes the features & labels of traindata as csv, header names the feature columns followed by the label
+func SaveMatrixToCSV(traindata datamodels.TrainingData, header []string, filename string) error {
 	matrix := traindata.X
 	file, err := os.Create(filename)
 	if err != nil {
@@ -429,9 +403,11 @@ func SaveMatrixToCSV(traindata datamodels.TrainingData, filename string) error {
 	defer writer.Flush()
 
 	rows, cols := matrix.Dims()
-	firstrow := []string{"arbitration_id", "df1", "df2", "df3", "df4", "df5", "df6", "df7", "df8","time_interval", "attack" }
+	if len(header) != cols+1 {
+		return fmt.Errorf("header has %d columns, the data has %d features & a label", len(header), cols)
+	}
 
-	if err := writer.Write(firstrow); err != nil {
+	if err := writer.Write(header); err != nil {
 		return fmt.Errorf("could not write row to CSV: %v", err)
 	}
 
@@ -739,26 +715,26 @@ func GetSingleBatch(X *mat.Dense, step, batchSize int) *mat.Dense {
 
 func getBatch(X *mat.Dense, y *mat.Dense, step, batchSize int) (*mat.Dense, *mat.Dense) {
 	rows, cols := X.Dims()
-	_, yCols := y.Dims()
+	yRows, yCols := y.Dims()
 
 	// Define start and end of the slice (batch)
 	start := step * batchSize
 	end := (step + 1) * batchSize
 
-	// Ensure the end index doesn't exceed the number of rows in X or columns in y
+	// Ensure the end index doesn't exceed the number of rows in X
 	if end > rows {
 		end = rows
 	}
 
-	// Slice the X matrix and y vector
+	// Slice the X matrix
 	batch_X := mat.DenseCopyOf(X.Slice(start, end, 0, cols))
 
-	if end > yCols {
-		end = yCols
+	// sparse labels may be stored as a 1 x N row, every other layout has a row per sample
+	if yRows == 1 && yCols == rows {
+		return batch_X, mat.DenseCopyOf(y.Slice(0, 1, start, end))
 	}
-	batch_y := mat.DenseCopyOf(y.Slice(0, 1, start, end))
 
-	return batch_X, batch_y
+	return batch_X, mat.DenseCopyOf(y.Slice(start, end, 0, yCols))
 }
 
 func CreateDenseMatrix(rows, cols int, data []float64) *mat.Dense {
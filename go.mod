@@ -7,6 +7,7 @@ require (
 	golang.org/x/image v0.21.0
 	gonum.org/v1/gonum v0.15.1
 	gonum.org/v1/plot v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -134,8 +134,12 @@ func TestCANDatasetTraining() {
 
 	CAN_dataset_model.Set(new(loss.CategoricalCrossEntropy), optimization.CreateAdaptiveMomentum(0.005, 5e-5, 1e-7, 0.9, 0.999, 0), new(accuracy.CategoricalAccuracy))
 
-	CAN_dataset_model.Finalize()
-	CAN_dataset_model.Train(training_data, testing_data, 10, 2000, 10000)
+	if err := CAN_dataset_model.Finalize(); err != nil {
+		log.Fatal(err)
+	}
+	if err := CAN_dataset_model.Train(training_data, testing_data, 10, 2000, 10000); err != nil {
+		log.Fatal(err)
+	}
 
 	//	CAN_dataset_model.SaveParameters("CAN_dataset_model_parameters")
 